@@ -0,0 +1,213 @@
+package betting
+
+import (
+	"cmp"
+	"slices"
+	"time"
+
+	"github.com/edd/relaxovisionmonolith/footballdata"
+)
+
+// Strategy decides how much of the bankroll to stake on a bet
+type Strategy string
+
+// Staking strategies
+const (
+	Flat         Strategy = "flat"         // the same stake on every bet
+	Proportional Strategy = "proportional" // a fixed share of the current bankroll
+	Kelly        Strategy = "kelly"        // a fraction of the Kelly stake
+)
+
+// Strategies lists the staking strategies a backtest compares
+var Strategies = []Strategy{Flat, Proportional, Kelly}
+
+// StakingOptions configures a bankroll simulation
+type StakingOptions struct {
+	InitialBankroll float64 `json:"initialBankroll"`
+	FlatStake       float64 `json:"flatStake"`
+	Proportion      float64 `json:"proportion"`    // share of the bankroll for proportional staking
+	KellyFraction   float64 `json:"kellyFraction"` // e.g. 0.25 for quarter Kelly
+	MinEdge         float64 `json:"minEdge"`
+}
+
+// DefaultStakingOptions returns a 1000 unit bankroll with 10 unit flat
+// stakes, 2% proportional stakes and quarter Kelly
+func DefaultStakingOptions() StakingOptions {
+	return StakingOptions{
+		InitialBankroll: 1000,
+		FlatStake:       10,
+		Proportion:      0.02,
+		KellyFraction:   0.25,
+		MinEdge:         DefaultMinEdge,
+	}
+}
+
+// Bet is a settled value bet
+type Bet struct {
+	ValueBet
+	Won bool `json:"won"`
+}
+
+// BankrollPoint is the bankroll after settling a bet
+type BankrollPoint struct {
+	Time     time.Time `json:"time"`
+	MatchID  int       `json:"matchId"`
+	Stake    float64   `json:"stake"`
+	Profit   float64   `json:"profit"`
+	Bankroll float64   `json:"bankroll"`
+}
+
+// SimulationResult summarises replaying bets with one staking strategy
+type SimulationResult struct {
+	Strategy      Strategy        `json:"strategy"`
+	Bets          int             `json:"bets"`
+	Wins          int             `json:"wins"`
+	Staked        float64         `json:"staked"`
+	Profit        float64         `json:"profit"`
+	ROI           float64         `json:"roi"` // profit per unit staked
+	FinalBankroll float64         `json:"finalBankroll"`
+	MaxDrawdown   float64         `json:"maxDrawdown"` // largest fall from a peak, as a share of the peak
+	Bankrupt      bool            `json:"bankrupt,omitempty"`
+	Curve         []BankrollPoint `json:"curve"`
+}
+
+// StrategyResults holds a simulation per staking strategy
+type StrategyResults map[Strategy]*SimulationResult
+
+// Simulate replays settled bets in kickoff order, staking with the given
+// strategy, until the bets run out or the bankroll is gone
+func Simulate(bets []Bet, strategy Strategy, opts StakingOptions) *SimulationResult {
+	bets = slices.Clone(bets)
+	slices.SortStableFunc(bets, func(a, b Bet) int {
+		return cmp.Or(a.Kickoff.Compare(b.Kickoff), cmp.Compare(a.MatchID, b.MatchID))
+	})
+
+	result := &SimulationResult{
+		Strategy:      strategy,
+		FinalBankroll: opts.InitialBankroll,
+		Curve:         []BankrollPoint{},
+	}
+
+	bankroll, peak := opts.InitialBankroll, opts.InitialBankroll
+	for _, bet := range bets {
+		stake := min(stakeFor(bet, strategy, bankroll, opts), bankroll)
+		if stake <= 0 {
+			continue
+		}
+
+		profit := -stake
+		if bet.Won {
+			profit = stake * (bet.Odds - 1)
+			result.Wins++
+		}
+		bankroll += profit
+		result.Bets++
+		result.Staked += stake
+		result.Profit += profit
+		result.Curve = append(result.Curve, BankrollPoint{
+			Time:     bet.Kickoff,
+			MatchID:  bet.MatchID,
+			Stake:    stake,
+			Profit:   profit,
+			Bankroll: bankroll,
+		})
+
+		peak = max(peak, bankroll)
+		if peak > 0 {
+			result.MaxDrawdown = max(result.MaxDrawdown, (peak-bankroll)/peak)
+		}
+		if bankroll <= 0 {
+			result.Bankrupt = true
+			break
+		}
+	}
+
+	result.FinalBankroll = bankroll
+	if result.Staked > 0 {
+		result.ROI = result.Profit / result.Staked
+	}
+
+	return result
+}
+
+// stakeFor returns the stake a strategy places on a bet
+func stakeFor(bet Bet, strategy Strategy, bankroll float64, opts StakingOptions) float64 {
+	switch strategy {
+	case Flat:
+		return opts.FlatStake
+	case Proportional:
+		return bankroll * opts.Proportion
+	case Kelly:
+		return bankroll * opts.KellyFraction * bet.KellyStake
+	}
+	return 0
+}
+
+// HistoricalPrediction is a pre-kickoff prediction of a finished match
+type HistoricalPrediction struct {
+	Match
+	Result Selection `json:"result"`
+	// Probabilities by provider, including the ensemble
+	Probabilities map[string]footballdata.MatchProbabilities `json:"probabilities"`
+	Odds          []footballdata.OddsSnapshot                `json:"odds"` // closing odds
+}
+
+// Backtest compares staking strategies on historical predictions
+type Backtest struct {
+	Provider      string                     `json:"provider"` // provider of the overall and competition results
+	Options       StakingOptions             `json:"options"`
+	Matches       int                        `json:"matches"` // predicted matches with closing odds
+	Overall       StrategyResults            `json:"overall"`
+	ByCompetition map[string]StrategyResults `json:"byCompetition"`
+	ByProvider    map[string]StrategyResults `json:"byProvider"`
+}
+
+// runBacktest finds the value bets each provider would have placed and
+// simulates every staking strategy on them
+func runBacktest(predictions []HistoricalPrediction, provider string, opts StakingOptions) *Backtest {
+	byProvider := make(map[string][]Bet)
+	matches := 0
+	for _, p := range predictions {
+		if len(p.Odds) == 0 {
+			continue
+		}
+		matches++
+
+		for name, probs := range p.Probabilities {
+			for _, vb := range FindValueBets(p.Match, name, probs, p.Odds, opts.MinEdge) {
+				byProvider[name] = append(byProvider[name], Bet{ValueBet: vb, Won: vb.Selection == p.Result})
+			}
+		}
+	}
+
+	backtest := &Backtest{
+		Provider:      provider,
+		Options:       opts,
+		Matches:       matches,
+		Overall:       simulateAll(byProvider[provider], opts),
+		ByCompetition: make(map[string]StrategyResults),
+		ByProvider:    make(map[string]StrategyResults, len(byProvider)),
+	}
+
+	byCompetition := make(map[string][]Bet)
+	for _, bet := range byProvider[provider] {
+		byCompetition[bet.Competition] = append(byCompetition[bet.Competition], bet)
+	}
+	for competition, bets := range byCompetition {
+		backtest.ByCompetition[competition] = simulateAll(bets, opts)
+	}
+	for name, bets := range byProvider {
+		backtest.ByProvider[name] = simulateAll(bets, opts)
+	}
+
+	return backtest
+}
+
+// simulateAll runs every staking strategy on the same bets
+func simulateAll(bets []Bet, opts StakingOptions) StrategyResults {
+	results := make(StrategyResults, len(Strategies))
+	for _, strategy := range Strategies {
+		results[strategy] = Simulate(bets, strategy, opts)
+	}
+	return results
+}
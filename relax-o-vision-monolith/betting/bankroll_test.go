@@ -0,0 +1,142 @@
+package betting
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/edd/relaxovisionmonolith/footballdata"
+)
+
+func TestSimulate(t *testing.T) {
+	t.Parallel()
+
+	day := time.Date(2024, 3, 1, 15, 0, 0, 0, time.UTC)
+	bet := func(matchID int, days int, odds, kelly float64, won bool) Bet {
+		return Bet{
+			ValueBet: ValueBet{Match: Match{MatchID: matchID, Kickoff: day.AddDate(0, 0, days)}, Odds: odds, KellyStake: kelly},
+			Won:      won,
+		}
+	}
+	// Out of kickoff order on purpose: lose, win at 3.0, lose
+	bets := []Bet{
+		bet(2, 1, 3.0, 0.2, true),
+		bet(1, 0, 2.0, 0.1, false),
+		bet(3, 2, 2.0, 0.1, false),
+	}
+	opts := StakingOptions{InitialBankroll: 100, FlatStake: 10, Proportion: 0.1, KellyFraction: 0.5}
+
+	tests := []struct {
+		strategy    Strategy
+		stakes      []float64
+		final       float64
+		maxDrawdown float64
+	}{
+		{strategy: Flat, stakes: []float64{10, 10, 10}, final: 100, maxDrawdown: 0.1},
+		{strategy: Proportional, stakes: []float64{10, 9, 10.8}, final: 97.2, maxDrawdown: 0.1},
+		{strategy: Kelly, stakes: []float64{5, 9.5, 5.7}, final: 108.3, maxDrawdown: 0.05},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.strategy), func(t *testing.T) {
+			t.Parallel()
+
+			result := Simulate(bets, tt.strategy, opts)
+			if result.Bets != 3 || result.Wins != 1 {
+				t.Errorf("bets/wins = %d/%d, want 3/1", result.Bets, result.Wins)
+			}
+			if len(result.Curve) != len(tt.stakes) {
+				t.Fatalf("got %d curve points, want %d", len(result.Curve), len(tt.stakes))
+			}
+			for i, point := range result.Curve {
+				if point.MatchID != i+1 {
+					t.Errorf("point %d is match %d, want kickoff order", i, point.MatchID)
+				}
+				if math.Abs(point.Stake-tt.stakes[i]) > 1e-9 {
+					t.Errorf("point %d stake = %v, want %v", i, point.Stake, tt.stakes[i])
+				}
+			}
+			if math.Abs(result.FinalBankroll-tt.final) > 1e-9 {
+				t.Errorf("FinalBankroll = %v, want %v", result.FinalBankroll, tt.final)
+			}
+			if math.Abs(result.Profit-(tt.final-100)) > 1e-9 {
+				t.Errorf("Profit = %v, want %v", result.Profit, tt.final-100)
+			}
+			if math.Abs(result.MaxDrawdown-tt.maxDrawdown) > 1e-9 {
+				t.Errorf("MaxDrawdown = %v, want %v", result.MaxDrawdown, tt.maxDrawdown)
+			}
+			if want := result.Profit / result.Staked; math.Abs(result.ROI-want) > 1e-9 {
+				t.Errorf("ROI = %v, want %v", result.ROI, want)
+			}
+		})
+	}
+}
+
+func TestSimulateBankrupt(t *testing.T) {
+	t.Parallel()
+
+	bets := []Bet{
+		{ValueBet: ValueBet{Match: Match{MatchID: 1}, Odds: 2}},
+		{ValueBet: ValueBet{Match: Match{MatchID: 2}, Odds: 2}},
+		{ValueBet: ValueBet{Match: Match{MatchID: 3}, Odds: 2}, Won: true},
+	}
+
+	result := Simulate(bets, Flat, StakingOptions{InitialBankroll: 15, FlatStake: 10})
+	if !result.Bankrupt || result.Bets != 2 {
+		t.Errorf("Bankrupt/Bets = %v/%d, want true/2", result.Bankrupt, result.Bets)
+	}
+	if result.Curve[1].Stake != 5 || result.FinalBankroll != 0 {
+		t.Errorf("last stake = %v, final bankroll = %v, want 5 and 0", result.Curve[1].Stake, result.FinalBankroll)
+	}
+	if result.MaxDrawdown != 1 {
+		t.Errorf("MaxDrawdown = %v, want 1", result.MaxDrawdown)
+	}
+}
+
+func TestRunBacktest(t *testing.T) {
+	t.Parallel()
+
+	odds := []footballdata.OddsSnapshot{{Bookmaker: "bet365", Odds: footballdata.Odds{HomeWin: 2, Draw: 3.5, AwayWin: 4}}}
+	homeValue := footballdata.MatchProbabilities{HomeWin: 0.6, Draw: 0.2, AwayWin: 0.2}
+	noValue := footballdata.MatchProbabilities{HomeWin: 0.45, Draw: 0.3, AwayWin: 0.25}
+
+	predictions := []HistoricalPrediction{
+		{
+			Match:         Match{MatchID: 1, Competition: "Premier League"},
+			Result:        Home,
+			Probabilities: map[string]footballdata.MatchProbabilities{"ensemble": homeValue, "claude": homeValue},
+			Odds:          odds,
+		},
+		{
+			Match:         Match{MatchID: 2, Competition: "La Liga"},
+			Result:        Away,
+			Probabilities: map[string]footballdata.MatchProbabilities{"ensemble": homeValue, "claude": noValue},
+			Odds:          odds,
+		},
+		{
+			Match:         Match{MatchID: 3, Competition: "La Liga"},
+			Result:        Home,
+			Probabilities: map[string]footballdata.MatchProbabilities{"ensemble": homeValue},
+		},
+	}
+
+	backtest := runBacktest(predictions, "ensemble", DefaultStakingOptions())
+
+	if backtest.Matches != 2 {
+		t.Errorf("Matches = %d, want 2 with odds", backtest.Matches)
+	}
+	if flat := backtest.Overall[Flat]; flat.Bets != 2 || flat.Wins != 1 || flat.Profit != 0 {
+		t.Errorf("overall flat = %+v, want 2 bets, 1 win and no profit", flat)
+	}
+	if len(backtest.ByCompetition) != 2 || backtest.ByCompetition["La Liga"][Flat].Bets != 1 {
+		t.Errorf("ByCompetition = %+v, want one La Liga bet", backtest.ByCompetition)
+	}
+	if claude := backtest.ByProvider["claude"][Kelly]; claude.Bets != 1 || claude.Profit <= 0 {
+		t.Errorf("claude kelly = %+v, want one winning bet", claude)
+	}
+	for _, strategy := range Strategies {
+		if backtest.ByProvider["ensemble"][strategy] == nil {
+			t.Errorf("ensemble is missing the %s strategy", strategy)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package betting
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/edd/relaxovisionmonolith/footballdata"
+	"github.com/edd/relaxovisionmonolith/predictions"
+)
+
+// ValueBetFilter selects upcoming matches to check for value. Zero values don't filter.
+type ValueBetFilter struct {
+	CompetitionID int
+	Provider      string // defaults to the ensemble prediction
+	MinEdge       float64
+}
+
+// BacktestFilter selects the finished matches a backtest replays. Zero values don't filter.
+type BacktestFilter struct {
+	CompetitionID int
+	Provider      string    // provider of the overall and competition results, defaults to the ensemble
+	From          time.Time // inclusive kickoff
+	To            time.Time // exclusive kickoff
+	Options       StakingOptions
+}
+
+// Service finds value bets and backtests staking strategies on stored predictions
+type Service struct {
+	db   *sql.DB
+	odds *footballdata.Repository
+}
+
+// NewService creates a new betting service
+func NewService(db *sql.DB) *Service {
+	return &Service{
+		db:   db,
+		odds: footballdata.NewRepository(db),
+	}
+}
+
+// ValueBets returns value bets on upcoming matches, using each match's latest
+// prediction and the bookmakers' current odds, ordered by kickoff
+func (s *Service) ValueBets(ctx context.Context, filter ValueBetFilter) ([]ValueBet, error) {
+	provider := cmp.Or(filter.Provider, predictions.EnsembleProvider)
+
+	upcoming, err := s.loadPredictions(ctx, `m.status IN ('SCHEDULED', 'TIMED') AND m.utc_date > NOW()`,
+		filter.CompetitionID, time.Time{}, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+
+	bets := []ValueBet{}
+	for _, p := range upcoming {
+		probs, ok := p.Probabilities[provider]
+		if !ok {
+			continue
+		}
+		bets = append(bets, FindValueBets(p.Match, provider, probs, p.Odds, filter.MinEdge)...)
+	}
+
+	return bets, nil
+}
+
+// Backtest replays value bets on finished matches that were predicted
+// before kickoff, staking with every strategy
+func (s *Service) Backtest(ctx context.Context, filter BacktestFilter) (*Backtest, error) {
+	provider := cmp.Or(filter.Provider, predictions.EnsembleProvider)
+
+	finished, err := s.loadPredictions(ctx,
+		`m.status = 'FINISHED' AND m.home_score_ft IS NOT NULL AND m.away_score_ft IS NOT NULL AND p.created_at < m.utc_date`,
+		filter.CompetitionID, filter.From, filter.To)
+	if err != nil {
+		return nil, err
+	}
+
+	return runBacktest(finished, provider, filter.Options), nil
+}
+
+// loadPredictions returns the latest completed prediction of each match
+// matching the condition, with per-provider probabilities and closing odds,
+// ordered by kickoff
+func (s *Service) loadPredictions(ctx context.Context, condition string, competitionID int, from, to time.Time) ([]HistoricalPrediction, error) {
+	query := `
+		SELECT DISTINCT ON (m.id)
+		       m.id, COALESCE(m.competition_id, 0), COALESCE(c.name, ''),
+		       COALESCE(ht.name, m.home_team->>'name', ''), COALESCE(awt.name, m.away_team->>'name', ''),
+		       m.utc_date, m.home_score_ft, m.away_score_ft,
+		       p.home_win_prob, p.draw_prob, p.away_win_prob, p.agent_outputs
+		FROM matches m
+		JOIN predictions p ON p.match_id = m.id AND p.status = 'completed'
+		LEFT JOIN competitions c ON c.id = m.competition_id
+		LEFT JOIN teams ht ON ht.id = m.home_team_id
+		LEFT JOIN teams awt ON awt.id = m.away_team_id
+		WHERE ` + condition + `
+		  AND ($1 = 0 OR m.competition_id = $1)
+		  AND ($2::timestamp IS NULL OR m.utc_date >= $2)
+		  AND ($3::timestamp IS NULL OR m.utc_date < $3)
+		ORDER BY m.id, p.created_at DESC
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, competitionID,
+		sql.NullTime{Time: from, Valid: !from.IsZero()},
+		sql.NullTime{Time: to, Valid: !to.IsZero()},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query predictions: %w", err)
+	}
+	defer rows.Close()
+
+	var loaded []HistoricalPrediction
+	for rows.Next() {
+		var p HistoricalPrediction
+		var ensemble footballdata.MatchProbabilities
+		var homeScore, awayScore sql.NullInt64
+		var agentOutputsJSON []byte
+
+		err := rows.Scan(&p.MatchID, &p.CompetitionID, &p.Competition, &p.HomeTeam, &p.AwayTeam,
+			&p.Kickoff, &homeScore, &awayScore,
+			&ensemble.HomeWin, &ensemble.Draw, &ensemble.AwayWin, &agentOutputsJSON)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan prediction: %w", err)
+		}
+
+		var agentOutputs []predictions.AgentOutput
+		if len(agentOutputsJSON) > 0 {
+			if err := json.Unmarshal(agentOutputsJSON, &agentOutputs); err != nil {
+				slog.Error("Failed to unmarshal agent outputs", "matchId", p.MatchID, "error", err)
+			}
+		}
+		p.Probabilities = providerProbabilities(agentOutputs)
+		p.Probabilities[predictions.EnsembleProvider] = ensemble
+
+		if homeScore.Valid && awayScore.Valid {
+			p.Result = resultOf(homeScore.Int64, awayScore.Int64)
+		}
+
+		loaded = append(loaded, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate predictions: %w", err)
+	}
+
+	ids := make([]int, len(loaded))
+	for i, p := range loaded {
+		ids[i] = p.MatchID
+	}
+	closing, err := s.odds.ClosingOdds(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range loaded {
+		loaded[i].Odds = closing[loaded[i].MatchID]
+	}
+
+	slices.SortFunc(loaded, func(a, b HistoricalPrediction) int {
+		return cmp.Or(a.Kickoff.Compare(b.Kickoff), cmp.Compare(a.MatchID, b.MatchID))
+	})
+
+	return loaded, nil
+}
+
+// providerProbabilities averages each LLM provider's probabilities across the agents
+func providerProbabilities(outputs []predictions.AgentOutput) map[string]footballdata.MatchProbabilities {
+	sums := make(map[string]footballdata.MatchProbabilities)
+	counts := make(map[string]int)
+	for _, output := range outputs {
+		for _, po := range output.ProviderOutputs {
+			sum := sums[po.Provider]
+			sum.HomeWin += po.HomeWinProb
+			sum.Draw += po.DrawProb
+			sum.AwayWin += po.AwayWinProb
+			sums[po.Provider] = sum
+			counts[po.Provider]++
+		}
+	}
+
+	probabilities := make(map[string]footballdata.MatchProbabilities, len(sums)+1)
+	for provider, sum := range sums {
+		n := float64(counts[provider])
+		probabilities[provider] = footballdata.MatchProbabilities{
+			HomeWin: sum.HomeWin / n,
+			Draw:    sum.Draw / n,
+			AwayWin: sum.AwayWin / n,
+		}
+	}
+	return probabilities
+}
+
+// resultOf returns the winning selection of a final score
+func resultOf(homeScore, awayScore int64) Selection {
+	switch {
+	case homeScore > awayScore:
+		return Home
+	case awayScore > homeScore:
+		return Away
+	}
+	return Draw
+}
@@ -0,0 +1,104 @@
+package betting
+
+import (
+	"time"
+
+	"github.com/edd/relaxovisionmonolith/footballdata"
+)
+
+// Selection is the match outcome a bet is placed on
+type Selection string
+
+// Bet selections
+const (
+	Home Selection = "home"
+	Draw Selection = "draw"
+	Away Selection = "away"
+)
+
+// DefaultMinEdge is the probability edge over the bookmaker's price a bet needs
+const DefaultMinEdge = 0.03
+
+// Match identifies the fixture a bet is on
+type Match struct {
+	MatchID       int       `json:"matchId"`
+	CompetitionID int       `json:"competitionId"`
+	Competition   string    `json:"competition"`
+	HomeTeam      string    `json:"homeTeam"`
+	AwayTeam      string    `json:"awayTeam"`
+	Kickoff       time.Time `json:"kickoff"`
+}
+
+// ValueBet is an outcome we rate more likely than the best available price implies
+type ValueBet struct {
+	Match
+	Provider           string    `json:"provider"`
+	Selection          Selection `json:"selection"`
+	Probability        float64   `json:"probability"` // our probability of the selection
+	Odds               float64   `json:"odds"`        // best decimal price across bookmakers
+	Bookmaker          string    `json:"bookmaker"`
+	ImpliedProbability float64   `json:"impliedProbability"` // 1 / odds, including the margin
+	MarketProbability  float64   `json:"marketProbability"`  // margin-free bookmaker consensus
+	Edge               float64   `json:"edge"`               // probability - implied probability
+	ExpectedValue      float64   `json:"expectedValue"`      // expected profit per unit staked
+	KellyStake         float64   `json:"kellyStake"`         // full Kelly fraction of the bankroll
+}
+
+// FindValueBets returns the outcomes whose probability beats the best price
+// among the bookmakers' odds by at least minEdge
+func FindValueBets(match Match, provider string, probs footballdata.MatchProbabilities, odds []footballdata.OddsSnapshot, minEdge float64) []ValueBet {
+	market := footballdata.ConsensusProbabilities(odds)
+	if market == nil {
+		return nil
+	}
+
+	var bets []ValueBet
+	for _, outcome := range []struct {
+		selection   Selection
+		probability float64
+		market      float64
+		price       func(footballdata.Odds) float64
+	}{
+		{Home, probs.HomeWin, market.HomeWin, func(o footballdata.Odds) float64 { return o.HomeWin }},
+		{Draw, probs.Draw, market.Draw, func(o footballdata.Odds) float64 { return o.Draw }},
+		{Away, probs.AwayWin, market.AwayWin, func(o footballdata.Odds) float64 { return o.AwayWin }},
+	} {
+		var best footballdata.OddsSnapshot
+		for _, s := range odds {
+			if s.Valid() && outcome.price(s.Odds) > outcome.price(best.Odds) {
+				best = s
+			}
+		}
+
+		price := outcome.price(best.Odds)
+		edge := outcome.probability - 1/price
+		if edge < minEdge {
+			continue
+		}
+
+		bets = append(bets, ValueBet{
+			Match:              match,
+			Provider:           provider,
+			Selection:          outcome.selection,
+			Probability:        outcome.probability,
+			Odds:               price,
+			Bookmaker:          best.Bookmaker,
+			ImpliedProbability: 1 / price,
+			MarketProbability:  outcome.market,
+			Edge:               edge,
+			ExpectedValue:      outcome.probability*price - 1,
+			KellyStake:         kellyFraction(outcome.probability, price),
+		})
+	}
+
+	return bets
+}
+
+// kellyFraction is the share of the bankroll the Kelly criterion stakes on a
+// bet, or 0 when the bet has no edge
+func kellyFraction(probability, odds float64) float64 {
+	if odds <= 1 {
+		return 0
+	}
+	return max(0, (probability*odds-1)/(odds-1))
+}
@@ -0,0 +1,138 @@
+package betting
+
+import (
+	"math"
+	"testing"
+
+	"github.com/edd/relaxovisionmonolith/footballdata"
+)
+
+func TestFindValueBets(t *testing.T) {
+	t.Parallel()
+
+	odds := []footballdata.OddsSnapshot{
+		{Bookmaker: "bet365", Odds: footballdata.Odds{HomeWin: 2.0, Draw: 3.5, AwayWin: 4.0}},
+		{Bookmaker: "pinnacle", Odds: footballdata.Odds{HomeWin: 2.1, Draw: 3.4, AwayWin: 3.8}},
+		{Bookmaker: "broken", Odds: footballdata.Odds{HomeWin: 9, Draw: 0, AwayWin: 9}},
+	}
+
+	tests := []struct {
+		name    string
+		probs   footballdata.MatchProbabilities
+		odds    []footballdata.OddsSnapshot
+		minEdge float64
+		want    []Selection
+	}{
+		{
+			name:    "no edge",
+			probs:   footballdata.MatchProbabilities{HomeWin: 0.45, Draw: 0.28, AwayWin: 0.27},
+			odds:    odds,
+			minEdge: DefaultMinEdge,
+		},
+		{
+			name:    "home value at the best price",
+			probs:   footballdata.MatchProbabilities{HomeWin: 0.55, Draw: 0.25, AwayWin: 0.20},
+			odds:    odds,
+			minEdge: DefaultMinEdge,
+			want:    []Selection{Home},
+		},
+		{
+			name:    "edge below the minimum",
+			probs:   footballdata.MatchProbabilities{HomeWin: 0.49, Draw: 0.26, AwayWin: 0.25},
+			odds:    odds,
+			minEdge: 0.05,
+		},
+		{
+			name:    "draw and away value",
+			probs:   footballdata.MatchProbabilities{HomeWin: 0.15, Draw: 0.40, AwayWin: 0.45},
+			odds:    odds,
+			minEdge: DefaultMinEdge,
+			want:    []Selection{Draw, Away},
+		},
+		{
+			name:    "no odds",
+			probs:   footballdata.MatchProbabilities{HomeWin: 0.9, Draw: 0.05, AwayWin: 0.05},
+			minEdge: DefaultMinEdge,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			bets := FindValueBets(Match{MatchID: 7}, "ensemble", tt.probs, tt.odds, tt.minEdge)
+			if len(bets) != len(tt.want) {
+				t.Fatalf("got %d value bets, want %d: %+v", len(bets), len(tt.want), bets)
+			}
+			for i, bet := range bets {
+				if bet.Selection != tt.want[i] {
+					t.Errorf("bet %d selection = %s, want %s", i, bet.Selection, tt.want[i])
+				}
+				if bet.MatchID != 7 || bet.Provider != "ensemble" {
+					t.Errorf("bet %d = %+v, want match 7 from the ensemble", i, bet)
+				}
+			}
+		})
+	}
+}
+
+func TestFindValueBetsPricing(t *testing.T) {
+	t.Parallel()
+
+	odds := []footballdata.OddsSnapshot{
+		{Bookmaker: "bet365", Odds: footballdata.Odds{HomeWin: 2.0, Draw: 4.0, AwayWin: 4.0}},
+		{Bookmaker: "pinnacle", Odds: footballdata.Odds{HomeWin: 2.5, Draw: 3.0, AwayWin: 3.5}},
+	}
+
+	bets := FindValueBets(Match{}, "ensemble", footballdata.MatchProbabilities{HomeWin: 0.5, Draw: 0.25, AwayWin: 0.25}, odds, DefaultMinEdge)
+	if len(bets) != 1 {
+		t.Fatalf("got %d value bets, want 1: %+v", len(bets), bets)
+	}
+
+	bet := bets[0]
+	if bet.Bookmaker != "pinnacle" || bet.Odds != 2.5 {
+		t.Errorf("best price = %s @ %v, want pinnacle @ 2.5", bet.Bookmaker, bet.Odds)
+	}
+	for _, c := range []struct {
+		name      string
+		got, want float64
+	}{
+		{"implied probability", bet.ImpliedProbability, 0.4},
+		{"edge", bet.Edge, 0.1},
+		{"expected value", bet.ExpectedValue, 0.25},
+		{"kelly stake", bet.KellyStake, 0.25 / 1.5},
+	} {
+		if math.Abs(c.got-c.want) > 1e-9 {
+			t.Errorf("%s = %v, want %v", c.name, c.got, c.want)
+		}
+	}
+	if bet.MarketProbability <= 0 || bet.MarketProbability >= bet.Probability {
+		t.Errorf("market probability = %v, want between 0 and %v", bet.MarketProbability, bet.Probability)
+	}
+}
+
+func TestKellyFraction(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		probability float64
+		odds        float64
+		want        float64
+	}{
+		{name: "even money edge", probability: 0.6, odds: 2, want: 0.2},
+		{name: "no edge", probability: 0.5, odds: 2, want: 0},
+		{name: "negative edge", probability: 0.3, odds: 2, want: 0},
+		{name: "invalid odds", probability: 0.9, odds: 1, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := kellyFraction(tt.probability, tt.odds); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("kellyFraction(%v, %v) = %v, want %v", tt.probability, tt.odds, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+// Command fakefootballdata runs a local stand-in for the football-data.org API.
+//
+// Point the app at it with FOOTBALL_DATA_BASE_URL=http://localhost:8089/v4.
+package main
+
+import (
+	"flag"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/edd/relaxovisionmonolith/footballdata/fakeserver"
+)
+
+func main() {
+	addr := flag.String("addr", ":8089", "address to listen on")
+	fixturesDir := flag.String("fixtures", "", "fixture directory (defaults to the bundled fixtures)")
+	apiKey := flag.String("api-key", "", "required X-Auth-Token value (empty accepts any token)")
+	rateLimit := flag.Int("rate-limit", fakeserver.DefaultRequestsPerMinute, "requests per minute per token (0 disables)")
+	start := flag.String("start", "2024-09-14T12:00:00Z", "simulated start time (RFC 3339)")
+	speed := flag.Float64("speed", 1, "simulated seconds per real second (0 freezes the clock)")
+	flag.Parse()
+
+	startTime, err := time.Parse(time.RFC3339, *start)
+	if err != nil {
+		slog.Error("Invalid start time", "start", *start, "error", err)
+		os.Exit(1)
+	}
+
+	var fixtures *fakeserver.Fixtures
+	if *fixturesDir != "" {
+		fixtures, err = fakeserver.LoadFixturesDir(*fixturesDir)
+	} else {
+		fixtures, err = fakeserver.DefaultFixtures()
+	}
+	if err != nil {
+		slog.Error("Failed to load fixtures", "error", err)
+		os.Exit(1)
+	}
+
+	server := fakeserver.New(fixtures, fakeserver.Config{
+		APIKey:            *apiKey,
+		RequestsPerMinute: *rateLimit,
+		Clock:             fakeserver.NewClock(startTime, *speed),
+	})
+
+	slog.Info("Fake football-data.org server listening",
+		"addr", *addr,
+		"competitions", len(fixtures.Competitions),
+		"start", startTime,
+		"speed", *speed,
+	)
+	if err := http.ListenAndServe(*addr, server); err != nil {
+		slog.Error("Fake football-data.org server stopped", "error", err)
+		os.Exit(1)
+	}
+}
@@ -58,6 +58,32 @@ func runMigrations(db *sql.DB) error {
 		"migrations/002_create_teams.sql",
 		"migrations/003_create_matches.sql",
 		"migrations/004_create_predictions.sql",
+		"migrations/008_create_prediction_outcomes.sql",
+		"migrations/010_normalize_matches.sql",
+		"migrations/011_match_group.sql",
+		"migrations/012_create_match_statistics.sql",
+		"migrations/013_create_referees.sql",
+		"migrations/014_schedule_features.sql",
+		"migrations/015_create_match_features.sql",
+		"migrations/016_match_schedule_sequence.sql",
+		"migrations/017_create_odds_snapshots.sql",
+		"migrations/018_prediction_goal_markets.sql",
+		"migrations/019_prediction_scheduler.sql",
+		"migrations/020_prediction_revisions.sql",
+		"migrations/021_prediction_idempotency_keys.sql",
+		"migrations/022_prediction_batches.sql",
+		"migrations/023_hypothetical_predictions.sql",
+		"migrations/024_match_probability_timeline.sql",
+		"migrations/025_prediction_calibration.sql",
+		"migrations/026_prediction_uncertainty.sql",
+		"migrations/027_prediction_debate.sql",
+		"migrations/028_match_statistics_availability.sql",
+		"migrations/029_match_result_updated_at.sql",
+		"migrations/030_prediction_job_attempts.sql",
+		"migrations/031_undrawn_match_teams.sql",
+		"migrations/032_graded_revisions.sql",
+		"migrations/033_match_card_availability.sql",
+		"migrations/034_prediction_batch_heartbeat.sql",
 	}
 
 	for _, migration := range migrations {
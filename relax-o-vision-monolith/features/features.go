@@ -0,0 +1,115 @@
+package features
+
+import (
+	"time"
+
+	"github.com/edd/relaxovisionmonolith/footballdata"
+)
+
+// Version identifies the feature set. Bump it whenever features are added,
+// removed or computed differently so stored rows from older sets are recomputed.
+const Version = 1
+
+// MatchFeatures is the point-in-time feature set for a match: everything in
+// it is computed from data available before kickoff
+type MatchFeatures struct {
+	MatchID         int                               `json:"matchId"`
+	Version         int                               `json:"version"`
+	CompetitionID   int                               `json:"competitionId"`
+	Competition     string                            `json:"competition"`
+	CompetitionCode string                            `json:"competitionCode"`
+	Status          string                            `json:"status"`
+	Kickoff         time.Time                         `json:"kickoff"`
+	Home            TeamFeatures                      `json:"home"`
+	Away            TeamFeatures                      `json:"away"`
+	HeadToHead      *footballdata.HeadToHead          `json:"headToHead,omitempty"` // from the home team's side
+	Market          *footballdata.MarketProbabilities `json:"market,omitempty"`     // closing consensus
+	Referee         *footballdata.RefereeProfile      `json:"referee,omitempty"`
+	Fingerprint     string                            `json:"fingerprint"` // hash of the inputs, used to detect data changes
+	ComputedAt      time.Time                         `json:"computedAt"`
+}
+
+// TeamFeatures holds one team's features before a match
+type TeamFeatures struct {
+	TeamID          int                              `json:"teamId"`
+	Name            string                           `json:"name"`
+	Elo             float64                          `json:"elo"`
+	Form            *footballdata.TeamForm           `json:"form,omitempty"`
+	Standing        *footballdata.TeamStanding       `json:"standing,omitempty"`
+	UnderlyingStats *footballdata.TeamStatsAggregate `json:"underlyingStats,omitempty"`
+	Schedule        *footballdata.ScheduleFeatures   `json:"schedule,omitempty"`
+}
+
+// Vector flattens the features into named numeric values for backtests and
+// model training. Features that are unknown for the match are left out.
+func (f *MatchFeatures) Vector() map[string]float64 {
+	v := map[string]float64{
+		"elo_diff":          f.Home.Elo - f.Away.Elo,
+		"elo_home_expected": footballdata.EloExpectedScore(f.Home.Elo, f.Away.Elo),
+	}
+	f.Home.addTo(v, "home_")
+	f.Away.addTo(v, "away_")
+
+	if f.HeadToHead != nil && f.HeadToHead.TotalMatches > 0 {
+		n := float64(f.HeadToHead.TotalMatches)
+		v["h2h_matches"] = n
+		v["h2h_home_win_rate"] = float64(f.HeadToHead.Team1Wins) / n
+		v["h2h_draw_rate"] = float64(f.HeadToHead.Draws) / n
+		v["h2h_away_win_rate"] = float64(f.HeadToHead.Team2Wins) / n
+		v["h2h_goal_difference"] = float64(f.HeadToHead.Team1Goals-f.HeadToHead.Team2Goals) / n
+	}
+
+	if f.Market != nil {
+		v["market_home_win"] = f.Market.HomeWin
+		v["market_draw"] = f.Market.Draw
+		v["market_away_win"] = f.Market.AwayWin
+	}
+
+	if f.Referee != nil && f.Referee.Matches > 0 {
+		v["referee_home_win_rate"] = f.Referee.HomeWinRate
+		v["referee_goals_per_game"] = f.Referee.GoalsPerGame
+	}
+
+	return v
+}
+
+// addTo adds a team's features to a vector under the given prefix
+func (t *TeamFeatures) addTo(v map[string]float64, prefix string) {
+	v[prefix+"elo"] = t.Elo
+
+	if t.Form != nil {
+		v[prefix+"form_score"] = t.Form.FormScore
+		v[prefix+"home_ppg"] = t.Form.HomeForm
+		v[prefix+"away_ppg"] = t.Form.AwayForm
+		v[prefix+"last5_goals_for"] = float64(t.Form.Last5GoalsFor)
+		v[prefix+"last5_goals_against"] = float64(t.Form.Last5GoalsAgainst)
+	}
+
+	if t.Standing != nil && t.Standing.PlayedGames > 0 {
+		played := float64(t.Standing.PlayedGames)
+		v[prefix+"position"] = float64(t.Standing.Position)
+		v[prefix+"points_per_game"] = float64(t.Standing.Points) / played
+		v[prefix+"goal_difference_per_game"] = float64(t.Standing.GoalDifference) / played
+	}
+
+	if s := t.UnderlyingStats; s != nil && s.Matches > 0 {
+		v[prefix+"shots_for"] = s.ShotsFor
+		v[prefix+"shots_against"] = s.ShotsAgainst
+		if s.XGMatches > 0 {
+			v[prefix+"xg_for"] = s.XGFor
+			v[prefix+"xg_against"] = s.XGAgainst
+			v[prefix+"goals_minus_xg"] = s.GoalsMinusXG
+		}
+	}
+
+	if s := t.Schedule; s != nil {
+		if s.DaysSinceLastMatch != nil {
+			v[prefix+"rest_days"] = *s.DaysSinceLastMatch
+		}
+		v[prefix+"matches_last_14_days"] = float64(s.MatchesLast14Days)
+		v[prefix+"upcoming_big_matches"] = float64(s.UpcomingBigMatches)
+		if s.TravelKm != nil {
+			v[prefix+"travel_km"] = *s.TravelKm
+		}
+	}
+}
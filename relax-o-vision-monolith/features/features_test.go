@@ -0,0 +1,49 @@
+package features
+
+import (
+	"math"
+	"testing"
+
+	"github.com/edd/relaxovisionmonolith/footballdata"
+)
+
+func TestMatchFeatures_Vector(t *testing.T) {
+	t.Parallel()
+
+	rest := 3.5
+	f := &MatchFeatures{
+		Home: TeamFeatures{
+			Elo:      1600,
+			Standing: &footballdata.TeamStanding{Position: 2, PlayedGames: 10, Points: 22, GoalDifference: 8},
+			Schedule: &footballdata.ScheduleFeatures{DaysSinceLastMatch: &rest, MatchesLast14Days: 3},
+		},
+		Away:       TeamFeatures{Elo: 1500},
+		HeadToHead: &footballdata.HeadToHead{TotalMatches: 4, Team1Wins: 2, Draws: 1, Team2Wins: 1, Team1Goals: 6, Team2Goals: 4},
+		Market:     &footballdata.MarketProbabilities{HomeWin: 0.5, Draw: 0.3, AwayWin: 0.2},
+	}
+
+	v := f.Vector()
+
+	want := map[string]float64{
+		"elo_diff":                      100,
+		"home_position":                 2,
+		"home_points_per_game":          2.2,
+		"home_goal_difference_per_game": 0.8,
+		"home_rest_days":                3.5,
+		"home_matches_last_14_days":     3,
+		"h2h_home_win_rate":             0.5,
+		"h2h_goal_difference":           0.5,
+		"market_draw":                   0.3,
+	}
+	for name, value := range want {
+		if got, ok := v[name]; !ok || math.Abs(got-value) > 1e-9 {
+			t.Errorf("%s = %v (present %v), want %v", name, got, ok, value)
+		}
+	}
+
+	for _, missing := range []string{"away_position", "away_rest_days", "home_xg_for", "referee_home_win_rate"} {
+		if _, ok := v[missing]; ok {
+			t.Errorf("%s present, want unknown features left out", missing)
+		}
+	}
+}
@@ -0,0 +1,376 @@
+package features
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/edd/relaxovisionmonolith/footballdata"
+)
+
+// fingerprintWindow covers the upcoming fixtures used by the schedule features
+const fingerprintWindow = 7 * 24 * time.Hour
+
+// Store computes match features and persists them per match and feature-set version
+type Store struct {
+	db        *sql.DB
+	repo      *footballdata.Repository
+	form      *footballdata.FormAnalyzer
+	h2h       *footballdata.H2HAnalyzer
+	standings *footballdata.StandingsCalculator
+	schedule  *footballdata.ScheduleCalculator
+	elo       *footballdata.EloCalculator
+}
+
+// NewStore creates a new feature store
+func NewStore(db *sql.DB) *Store {
+	return &Store{
+		db:        db,
+		repo:      footballdata.NewRepository(db),
+		form:      footballdata.NewFormAnalyzer(db),
+		h2h:       footballdata.NewH2HAnalyzer(db),
+		standings: footballdata.NewStandingsCalculator(db),
+		schedule:  footballdata.NewScheduleCalculator(db),
+		elo:       footballdata.NewEloCalculator(db),
+	}
+}
+
+// Get returns a match's features, recomputing them when the stored set is
+// from another version or its inputs have changed since it was computed
+func (s *Store) Get(ctx context.Context, matchID int) (*MatchFeatures, error) {
+	match, err := s.loadMatch(ctx, matchID)
+	if err != nil {
+		return nil, err
+	}
+
+	fingerprint, err := s.fingerprint(ctx, match)
+	if err != nil {
+		return nil, err
+	}
+
+	stored, err := s.load(ctx, matchID)
+	if err != nil {
+		return nil, err
+	}
+	if stored != nil && stored.Fingerprint == fingerprint {
+		// The match's own result is not an input, so its status may have moved on
+		stored.Status = match.Status
+		return stored, nil
+	}
+
+	return s.compute(ctx, match, fingerprint)
+}
+
+// Compute recomputes and stores a match's features regardless of what is stored
+func (s *Store) Compute(ctx context.Context, matchID int) (*MatchFeatures, error) {
+	match, err := s.loadMatch(ctx, matchID)
+	if err != nil {
+		return nil, err
+	}
+
+	fingerprint, err := s.fingerprint(ctx, match)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.compute(ctx, match, fingerprint)
+}
+
+// RefreshCompetition brings the features of a competition's upcoming matches up to date
+func (s *Store) RefreshCompetition(ctx context.Context, competitionID int) error {
+	ids, err := s.matchIDs(ctx, `
+		SELECT id FROM matches
+		WHERE competition_id = $1 AND status IN ('SCHEDULED', 'TIMED')
+		ORDER BY utc_date
+	`, competitionID)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		if _, err := s.Get(ctx, id); err != nil {
+			slog.Error("Failed to refresh match features", "matchId", id, "error", err)
+		}
+	}
+
+	slog.Info("Refreshed match features", "competitionId", competitionID, "matches", len(ids))
+	return nil
+}
+
+// compute builds a match's features from data before kickoff and stores them.
+// Missing inputs (no table for a cup tie, no imported statistics) are left empty.
+func (s *Store) compute(ctx context.Context, match *MatchFeatures, fingerprint string) (*MatchFeatures, error) {
+	f := *match
+	f.Version = Version
+	f.Fingerprint = fingerprint
+	f.ComputedAt = time.Now()
+
+	if err := s.computeTeams(ctx, &f); err != nil {
+		return nil, err
+	}
+
+	schedule, err := s.schedule.ComputeMatchSchedule(ctx, f.MatchID)
+	if err != nil {
+		slog.Warn("Failed to compute schedule features", "matchId", f.MatchID, "error", err)
+	} else {
+		f.Home.Schedule, f.Away.Schedule = &schedule.Home, &schedule.Away
+	}
+
+	market, err := s.repo.ClosingProbabilities(ctx, []int{f.MatchID})
+	if err != nil {
+		slog.Warn("Failed to compute market features", "matchId", f.MatchID, "error", err)
+	} else if m, ok := market[f.MatchID]; ok {
+		f.Market = &m
+	}
+
+	if f.Referee, err = s.repo.GetMatchRefereeProfile(ctx, f.MatchID); err != nil {
+		slog.Debug("No referee features for match", "matchId", f.MatchID, "error", err)
+	}
+
+	if err := s.save(ctx, &f); err != nil {
+		return nil, err
+	}
+
+	return &f, nil
+}
+
+// computeTeams fills both teams' ratings, form, standings and underlying
+// statistics, and their head-to-head record, from data before kickoff
+func (s *Store) computeTeams(ctx context.Context, f *MatchFeatures) error {
+	ratings, err := s.elo.RatingsAt(ctx, f.Kickoff)
+	if err != nil {
+		return err
+	}
+
+	for _, team := range []*TeamFeatures{&f.Home, &f.Away} {
+		team.Elo = footballdata.EloInitialRating
+		if rating, ok := ratings[team.TeamID]; ok {
+			team.Elo = rating
+		}
+
+		if team.Form, err = s.form.AnalyzeTeamFormAt(ctx, team.TeamID, f.Kickoff); err != nil {
+			slog.Warn("Failed to compute form features", "matchId", f.MatchID, "teamId", team.TeamID, "error", err)
+		}
+
+		if f.CompetitionID > 0 {
+			if team.Standing, err = s.standings.TeamStandingAt(ctx, f.CompetitionID, team.TeamID, f.Kickoff); err != nil {
+				slog.Warn("Failed to compute standing features", "matchId", f.MatchID, "teamId", team.TeamID, "error", err)
+			}
+		}
+
+		stats, err := s.repo.GetTeamStatsAggregate(ctx, team.TeamID, f.Kickoff, footballdata.DefaultStatsWindow)
+		if err != nil {
+			slog.Warn("Failed to aggregate statistics features", "matchId", f.MatchID, "teamId", team.TeamID, "error", err)
+		} else if stats.Matches > 0 {
+			team.UnderlyingStats = stats
+		}
+	}
+
+	if f.HeadToHead, err = s.h2h.AnalyzeHeadToHeadAt(ctx, f.Home.TeamID, f.Away.TeamID, f.Kickoff); err != nil {
+		slog.Warn("Failed to compute head-to-head features", "matchId", f.MatchID, "error", err)
+	}
+
+	return nil
+}
+
+// Hypothetical computes the features of a match that is not scheduled
+// between two teams at a date, without storing them. Standings come from the
+// competition when one is given; fixture-specific inputs (schedule, odds and
+// referee) are left empty
+func (s *Store) Hypothetical(ctx context.Context, homeTeamID, awayTeamID, competitionID int, kickoff time.Time) (*MatchFeatures, error) {
+	f := MatchFeatures{
+		Version:       Version,
+		CompetitionID: competitionID,
+		Kickoff:       kickoff,
+		Home:          TeamFeatures{TeamID: homeTeamID},
+		Away:          TeamFeatures{TeamID: awayTeamID},
+		ComputedAt:    time.Now(),
+	}
+
+	for _, team := range []*TeamFeatures{&f.Home, &f.Away} {
+		t, err := s.repo.GetTeam(ctx, team.TeamID)
+		if err != nil {
+			return nil, err
+		}
+		team.Name = t.Name
+	}
+
+	if competitionID > 0 {
+		competition, err := s.repo.GetCompetition(ctx, competitionID)
+		if err != nil {
+			return nil, err
+		}
+		f.Competition, f.CompetitionCode = competition.Name, competition.Code
+	}
+
+	if err := s.computeTeams(ctx, &f); err != nil {
+		return nil, err
+	}
+
+	return &f, nil
+}
+
+// loadMatch loads the match details features are keyed on
+func (s *Store) loadMatch(ctx context.Context, matchID int) (*MatchFeatures, error) {
+	query := `
+		SELECT m.id, m.competition_id, COALESCE(c.name, ''), COALESCE(c.code, ''), m.status, m.utc_date,
+		       m.home_team_id, COALESCE(ht.name, ''), m.away_team_id, COALESCE(at.name, '')
+		FROM matches m
+		LEFT JOIN competitions c ON c.id = m.competition_id
+		LEFT JOIN teams ht ON ht.id = m.home_team_id
+		LEFT JOIN teams at ON at.id = m.away_team_id
+		WHERE m.id = $1
+	`
+
+	var f MatchFeatures
+	var homeTeamID, awayTeamID sql.NullInt64
+	err := s.db.QueryRowContext(ctx, query, matchID).Scan(
+		&f.MatchID,
+		&f.CompetitionID,
+		&f.Competition,
+		&f.CompetitionCode,
+		&f.Status,
+		&f.Kickoff,
+		&homeTeamID,
+		&f.Home.Name,
+		&awayTeamID,
+		&f.Away.Name,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("match not found")
+		}
+		return nil, fmt.Errorf("failed to get match: %w", err)
+	}
+
+	if !homeTeamID.Valid || !awayTeamID.Valid {
+		return nil, fmt.Errorf("invalid team data structure")
+	}
+	f.Home.TeamID = int(homeTeamID.Int64)
+	f.Away.TeamID = int(awayTeamID.Int64)
+
+	return &f, nil
+}
+
+// fingerprint hashes the stored data a match's features depend on: both
+// teams' fixtures before kickoff (scores and statistics), the kickoff times
+// of their fixtures in the schedule window after it, the version of the
+// results feeding the ratings, the pre-kickoff odds snapshots, the referees
+// and the venues
+func (s *Store) fingerprint(ctx context.Context, match *MatchFeatures) (string, error) {
+	ratingsVersion, err := s.elo.RatingsVersion(ctx, match.Kickoff)
+	if err != nil {
+		return "", err
+	}
+
+	query := `
+		SELECT md5(concat_ws('|',
+			(SELECT string_agg(concat_ws(':', m.id, m.status, m.utc_date, m.home_score_ft, m.away_score_ft, s.updated_at), ',' ORDER BY m.id)
+			 FROM matches m
+			 LEFT JOIN match_statistics s ON s.match_id = m.id
+			 WHERE (m.home_team_id IN ($1, $2) OR m.away_team_id IN ($1, $2)) AND m.utc_date < $3),
+			(SELECT string_agg(concat_ws(':', m.id, m.utc_date, m.competition_id, m.stage,
+			                             m.status IN ('POSTPONED', 'CANCELLED', 'SUSPENDED')), ',' ORDER BY m.id)
+			 FROM matches m
+			 WHERE (m.home_team_id IN ($1, $2) OR m.away_team_id IN ($1, $2)) AND m.utc_date >= $3 AND m.utc_date <= $4),
+			$6::text,
+			(SELECT concat_ws(':', COUNT(*), MAX(captured_at), SUM(home_win + draw + away_win))
+			 FROM odds_snapshots
+			 WHERE match_id = $5 AND captured_at < $3),
+			(SELECT string_agg(concat_ws(':', referee_id, type), ',' ORDER BY referee_id)
+			 FROM match_referees
+			 WHERE match_id = $5),
+			(SELECT string_agg(concat_ws(':', id, venue_latitude, venue_longitude), ',' ORDER BY id)
+			 FROM teams
+			 WHERE id IN ($1, $2))
+		))
+	`
+
+	var fingerprint string
+	err = s.db.QueryRowContext(ctx, query,
+		match.Home.TeamID,
+		match.Away.TeamID,
+		match.Kickoff,
+		match.Kickoff.Add(fingerprintWindow),
+		match.MatchID,
+		ratingsVersion,
+	).Scan(&fingerprint)
+	if err != nil {
+		return "", fmt.Errorf("failed to fingerprint feature inputs: %w", err)
+	}
+
+	return fingerprint, nil
+}
+
+// load returns a match's stored features for the current version, or nil
+func (s *Store) load(ctx context.Context, matchID int) (*MatchFeatures, error) {
+	query := `SELECT features FROM match_features WHERE match_id = $1 AND version = $2`
+
+	var data []byte
+	err := s.db.QueryRowContext(ctx, query, matchID, Version).Scan(&data)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get match features: %w", err)
+	}
+
+	var f MatchFeatures
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal match features: %w", err)
+	}
+
+	return &f, nil
+}
+
+// save upserts a match's features
+func (s *Store) save(ctx context.Context, f *MatchFeatures) error {
+	data, err := json.Marshal(f)
+	if err != nil {
+		return fmt.Errorf("failed to marshal match features: %w", err)
+	}
+
+	query := `
+		INSERT INTO match_features (match_id, version, features, fingerprint, kickoff, computed_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (match_id, version) DO UPDATE SET
+			features = EXCLUDED.features,
+			fingerprint = EXCLUDED.fingerprint,
+			kickoff = EXCLUDED.kickoff,
+			computed_at = EXCLUDED.computed_at
+	`
+
+	_, err = s.db.ExecContext(ctx, query, f.MatchID, f.Version, data, f.Fingerprint, f.Kickoff, f.ComputedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save match features: %w", err)
+	}
+
+	return nil
+}
+
+// matchIDs runs a query selecting match IDs
+func (s *Store) matchIDs(ctx context.Context, query string, args ...any) ([]int, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query matches: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan match: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate matches: %w", err)
+	}
+
+	return ids, nil
+}
@@ -0,0 +1,297 @@
+package footballdata
+
+import (
+	"sort"
+	"time"
+)
+
+// Knockout stages in the order they are played
+var knockoutStages = []string{
+	"PLAYOFFS",
+	"LAST_64",
+	"LAST_32",
+	"LAST_16",
+	"QUARTER_FINALS",
+	"SEMI_FINALS",
+	"FINAL",
+}
+
+// Bracket formats describing how teams reach the knockout rounds
+const (
+	FormatKnockout = "KNOCKOUT" // knockout rounds only
+	FormatGroups   = "GROUPS"   // group stage followed by knockout rounds
+	FormatLeague   = "LEAGUE"   // single league phase followed by knockout rounds
+)
+
+// Ways a knockout tie can be decided
+const (
+	DecidedRegularTime = "REGULAR_TIME"
+	DecidedAggregate   = "AGGREGATE"
+	DecidedAwayGoals   = "AWAY_GOALS"
+	DecidedExtraTime   = "EXTRA_TIME"
+	DecidedPenalties   = "PENALTIES"
+)
+
+// awayGoalsAbolished is when UEFA dropped the away goals rule
+var awayGoalsAbolished = time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC)
+
+// KnockoutRules describes how a cup competition's knockout rounds are played
+type KnockoutRules struct {
+	TwoLegged       bool `json:"twoLegged"`       // ties other than the final have two legs
+	AwayGoals       bool `json:"awayGoals"`       // away goals break level aggregates
+	GroupQualifiers int  `json:"groupQualifiers"` // teams advancing from each group
+}
+
+// twoLeggedCompetitions lists competition codes whose knockout ties have two legs
+var twoLeggedCompetitions = map[string]bool{
+	"CL": true,
+	"EL": true,
+}
+
+// KnockoutRulesFor returns the knockout rules for a competition season
+func KnockoutRulesFor(code string, seasonStart time.Time) KnockoutRules {
+	rules := KnockoutRules{GroupQualifiers: 2}
+	if twoLeggedCompetitions[code] {
+		rules.TwoLegged = true
+		rules.AwayGoals = !seasonStart.IsZero() && seasonStart.Before(awayGoalsAbolished)
+	}
+	return rules
+}
+
+// Tie is a knockout pairing played over one or two legs
+type Tie struct {
+	Stage         string        `json:"stage"`
+	TeamA         Team          `json:"teamA"` // home side in the first leg
+	TeamB         Team          `json:"teamB"`
+	Legs          []MatchResult `json:"legs"`
+	AggregateA    int           `json:"aggregateA"`
+	AggregateB    int           `json:"aggregateB"`
+	WinnerID      int           `json:"winnerId,omitempty"`
+	DecidedBy     string        `json:"decidedBy,omitempty"`
+	TotalLegs     int           `json:"totalLegs"`
+	CompletedLegs int           `json:"completedLegs"`
+}
+
+// BracketRound is one knockout round
+type BracketRound struct {
+	Stage string `json:"stage"`
+	Legs  int    `json:"legs"`
+	Ties  []Tie  `json:"ties"`
+}
+
+// Bracket models a cup competition's season from its stored matches
+type Bracket struct {
+	CompetitionID int             `json:"competitionId"`
+	SeasonID      int             `json:"seasonId"`
+	Format        string          `json:"format"`
+	Tables        []StandingTable `json:"tables,omitempty"` // group or league phase tables
+	Rounds        []BracketRound  `json:"rounds"`
+	Rules         KnockoutRules   `json:"rules"`
+
+	phase      []MatchResult // group or league phase matches, finished or not
+	tableRules StandingsRules
+}
+
+// BuildBracket builds a bracket from a season's matches in chronological order
+func BuildBracket(results []MatchResult, rules KnockoutRules, tableRules StandingsRules) *Bracket {
+	bracket := &Bracket{Format: FormatKnockout, Rules: rules, tableRules: tableRules}
+
+	byStage := make(map[string][]MatchResult)
+	for _, result := range results {
+		switch {
+		case result.Group != nil || result.Stage == "GROUP_STAGE":
+			bracket.Format = FormatGroups
+			bracket.phase = append(bracket.phase, result)
+		case result.Stage == "LEAGUE_STAGE":
+			bracket.Format = FormatLeague
+			bracket.phase = append(bracket.phase, result)
+		default:
+			byStage[result.Stage] = append(byStage[result.Stage], result)
+		}
+	}
+
+	if len(bracket.phase) > 0 {
+		bracket.Tables = BuildStandingTables(bracket.phase, TableOptions{Type: TableTotal, Rules: tableRules})
+	}
+
+	// Rounds run from the first knockout stage seen, or the one the phase feeds
+	// into, through to the final
+	first := -1
+	for i, stage := range knockoutStages {
+		if len(byStage[stage]) > 0 {
+			first = i
+			break
+		}
+	}
+	if entry := bracket.entryStage(); entry >= 0 && (first < 0 || entry < first) {
+		first = entry
+	}
+	if first < 0 {
+		return bracket
+	}
+
+	for _, stage := range knockoutStages[first:] {
+		round := BracketRound{Stage: stage, Legs: rules.legsFor(stage)}
+		round.Ties = buildTies(stage, byStage[stage], round.Legs, rules)
+		for _, tie := range round.Ties {
+			if len(tie.Legs) > round.Legs {
+				round.Legs = len(tie.Legs)
+			}
+		}
+		bracket.Rounds = append(bracket.Rounds, round)
+	}
+
+	return bracket
+}
+
+// entryStage returns the index of the knockout stage fed by the group or
+// league phase, or -1 when there is none
+func (b *Bracket) entryStage() int {
+	switch b.Format {
+	case FormatLeague:
+		return stageIndex("PLAYOFFS")
+	case FormatGroups:
+		qualifiers := len(b.Tables) * b.Rules.GroupQualifiers
+		return stageIndex(stageForTeams(qualifiers))
+	default:
+		return -1
+	}
+}
+
+// legsFor returns the default number of legs for a stage
+func (r KnockoutRules) legsFor(stage string) int {
+	if r.TwoLegged && stage != "FINAL" {
+		return 2
+	}
+	return 1
+}
+
+// stageIndex returns a stage's position in knockoutStages, or -1
+func stageIndex(stage string) int {
+	for i, s := range knockoutStages {
+		if s == stage {
+			return i
+		}
+	}
+	return -1
+}
+
+// stageForTeams names the knockout round contested by n teams
+func stageForTeams(n int) string {
+	switch {
+	case n > 32:
+		return "LAST_64"
+	case n > 16:
+		return "LAST_32"
+	case n > 8:
+		return "LAST_16"
+	case n > 4:
+		return "QUARTER_FINALS"
+	case n > 2:
+		return "SEMI_FINALS"
+	default:
+		return "FINAL"
+	}
+}
+
+// buildTies pairs a stage's matches into ties and resolves finished ones
+func buildTies(stage string, matches []MatchResult, legs int, rules KnockoutRules) []Tie {
+	type pair struct{ low, high int }
+
+	ties := make(map[pair]*Tie)
+	var order []pair
+	for _, match := range matches {
+		// Fixtures with a team still to be decided are not a tie yet
+		if match.HomeTeam.ID == 0 || match.AwayTeam.ID == 0 {
+			continue
+		}
+		key := pair{match.HomeTeam.ID, match.AwayTeam.ID}
+		if key.low > key.high {
+			key.low, key.high = key.high, key.low
+		}
+		tie, ok := ties[key]
+		if !ok {
+			tie = &Tie{Stage: stage, TeamA: match.HomeTeam, TeamB: match.AwayTeam}
+			ties[key] = tie
+			order = append(order, key)
+		}
+		tie.Legs = append(tie.Legs, match)
+	}
+
+	result := make([]Tie, 0, len(order))
+	for _, key := range order {
+		tie := ties[key]
+		sort.SliceStable(tie.Legs, func(i, j int) bool {
+			return tie.Legs[i].UTCDate.Before(tie.Legs[j].UTCDate)
+		})
+		tie.TeamA, tie.TeamB = tie.Legs[0].HomeTeam, tie.Legs[0].AwayTeam
+		tie.TotalLegs = max(legs, len(tie.Legs))
+		resolveTie(tie, rules)
+		result = append(result, *tie)
+	}
+
+	return result
+}
+
+// resolveTie totals the finished legs of a tie and decides it once all legs are played
+func resolveTie(tie *Tie, rules KnockoutRules) {
+	tie.AggregateA, tie.AggregateB, tie.CompletedLegs = 0, 0, 0
+	var awayA, awayB int
+	for _, leg := range tie.Legs {
+		if !leg.Finished {
+			continue
+		}
+		tie.CompletedLegs++
+		if leg.HomeTeam.ID == tie.TeamA.ID {
+			tie.AggregateA += leg.HomeGoals
+			tie.AggregateB += leg.AwayGoals
+			awayB += leg.AwayGoals
+		} else {
+			tie.AggregateA += leg.AwayGoals
+			tie.AggregateB += leg.HomeGoals
+			awayA += leg.AwayGoals
+		}
+	}
+
+	if tie.CompletedLegs < tie.TotalLegs {
+		return
+	}
+
+	last := tie.Legs[len(tie.Legs)-1]
+	lastWinner := 0
+	switch last.Winner {
+	case "HOME_TEAM":
+		lastWinner = last.HomeTeam.ID
+	case "AWAY_TEAM":
+		lastWinner = last.AwayTeam.ID
+	}
+
+	// A shoot-out decides the tie whatever the aggregate says, since
+	// football-data.org scores may include the penalties
+	if last.Duration == "PENALTY_SHOOTOUT" && lastWinner != 0 {
+		tie.WinnerID, tie.DecidedBy = lastWinner, DecidedPenalties
+		return
+	}
+
+	decidedBy := DecidedAggregate
+	if tie.TotalLegs == 1 {
+		decidedBy = DecidedRegularTime
+	}
+	if last.Duration == "EXTRA_TIME" {
+		decidedBy = DecidedExtraTime
+	}
+
+	switch {
+	case tie.AggregateA > tie.AggregateB:
+		tie.WinnerID, tie.DecidedBy = tie.TeamA.ID, decidedBy
+	case tie.AggregateB > tie.AggregateA:
+		tie.WinnerID, tie.DecidedBy = tie.TeamB.ID, decidedBy
+	case rules.AwayGoals && tie.TotalLegs == 2 && awayA != awayB:
+		tie.WinnerID, tie.DecidedBy = tie.TeamA.ID, DecidedAwayGoals
+		if awayB > awayA {
+			tie.WinnerID = tie.TeamB.ID
+		}
+	case lastWinner != 0:
+		tie.WinnerID, tie.DecidedBy = lastWinner, decidedBy
+	}
+}
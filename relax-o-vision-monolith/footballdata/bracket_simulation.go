@@ -0,0 +1,488 @@
+package footballdata
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/rand/v2"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+)
+
+// StageWinner is the pseudo-stage reported for winning the competition
+const StageWinner = "WINNER"
+
+// BracketTeamOdds holds a team's chances of reaching each knockout round
+type BracketTeamOdds struct {
+	Team               Team               `json:"team"`
+	ReachProbabilities map[string]float64 `json:"reachProbabilities"` // keyed by stage, plus WINNER
+	WinProb            float64            `json:"winProb"`
+}
+
+// BracketSimulation is the outcome of a Monte Carlo tournament simulation
+type BracketSimulation struct {
+	CompetitionID int               `json:"competitionId"`
+	SeasonID      int               `json:"seasonId"`
+	Format        string            `json:"format"`
+	Iterations    int               `json:"iterations"`
+	Stages        []string          `json:"stages"`
+	Teams         []BracketTeamOdds `json:"teams"`
+	GeneratedAt   time.Time         `json:"generatedAt"`
+}
+
+// entrant is a team entering a knockout round
+type entrant struct {
+	teamID int
+	seeded bool
+}
+
+// SimulateBracket plays out the rest of a tournament many times and reports
+// how often each team reaches each round. Unfinished group or league phase
+// matches are simulated first; undrawn rounds are paired at random with
+// seeded teams kept apart where the format seeds them.
+func SimulateBracket(bracket *Bracket, opts SimulationOptions) ([]BracketTeamOdds, error) {
+	if len(bracket.Rounds) == 0 {
+		return nil, fmt.Errorf("competition has no knockout rounds")
+	}
+
+	iterations := simulationIterations(opts.Iterations)
+	model := newGoalModel(append(append([]MatchResult{}, bracket.phase...), bracket.knockoutResults()...))
+
+	teams := bracket.teams()
+	index := make(map[int]int, len(teams))
+	for i, team := range teams {
+		index[team.ID] = i
+	}
+	direct := bracket.directEntrants()
+
+	seed := opts.Seed
+	if seed == 0 {
+		seed = uint64(time.Now().UnixNano())
+	}
+
+	workers := min(runtime.GOMAXPROCS(0), iterations)
+
+	// reach[team][round] counts how often a team played in a round; the
+	// extra last column counts titles
+	reach := make([][]int, len(teams))
+	for i := range reach {
+		reach[i] = make([]int, len(bracket.Rounds)+1)
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		runs := iterations / workers
+		if w < iterations%workers {
+			runs++
+		}
+
+		wg.Add(1)
+		go func(worker uint64, runs int) {
+			defer wg.Done()
+
+			rng := rand.New(rand.NewPCG(seed, worker))
+			local := make([][]int, len(teams))
+			for i := range local {
+				local[i] = make([]int, len(bracket.Rounds)+1)
+			}
+
+			for run := 0; run < runs; run++ {
+				entrants := bracket.phaseEntrants(rng, model)
+				for round, teams := range direct {
+					entrants[round] = append(entrants[round], teams...)
+				}
+
+				var winners []entrant
+				for r, round := range bracket.Rounds {
+					pool := append(winners, entrants[r]...)
+					for _, e := range pool {
+						local[index[e.teamID]][r]++
+					}
+					winners = bracket.playRound(rng, model, round, pool)
+				}
+				if len(winners) == 1 {
+					local[index[winners[0].teamID]][len(bracket.Rounds)]++
+				}
+			}
+
+			mu.Lock()
+			for t := range local {
+				for r, n := range local[t] {
+					reach[t][r] += n
+				}
+			}
+			mu.Unlock()
+		}(uint64(w), runs)
+	}
+	wg.Wait()
+
+	n := float64(iterations)
+	odds := make([]BracketTeamOdds, 0, len(teams))
+	for t, team := range teams {
+		o := BracketTeamOdds{Team: team, ReachProbabilities: make(map[string]float64, len(bracket.Rounds)+1)}
+		for r, round := range bracket.Rounds {
+			o.ReachProbabilities[round.Stage] = float64(reach[t][r]) / n
+		}
+		o.WinProb = float64(reach[t][len(bracket.Rounds)]) / n
+		o.ReachProbabilities[StageWinner] = o.WinProb
+		odds = append(odds, o)
+	}
+
+	sort.SliceStable(odds, func(i, j int) bool {
+		if odds[i].WinProb != odds[j].WinProb {
+			return odds[i].WinProb > odds[j].WinProb
+		}
+		return odds[i].Team.Name < odds[j].Team.Name
+	})
+
+	return odds, nil
+}
+
+// knockoutResults returns the legs of every known tie
+func (b *Bracket) knockoutResults() []MatchResult {
+	var results []MatchResult
+	for _, round := range b.Rounds {
+		for _, tie := range round.Ties {
+			results = append(results, tie.Legs...)
+		}
+	}
+	return results
+}
+
+// teams returns every team taking part, ordered by name
+func (b *Bracket) teams() []Team {
+	seen := make(map[int]Team)
+	for _, result := range append(append([]MatchResult{}, b.phase...), b.knockoutResults()...) {
+		seen[result.HomeTeam.ID] = result.HomeTeam
+		seen[result.AwayTeam.ID] = result.AwayTeam
+	}
+
+	teams := make([]Team, 0, len(seen))
+	for _, team := range seen {
+		teams = append(teams, team)
+	}
+	sort.Slice(teams, func(i, j int) bool { return teams[i].Name < teams[j].Name })
+	return teams
+}
+
+// directEntrants returns, per round, teams that appear in a known tie without
+// having come through the phase or an earlier round (byes, or teams dropping
+// in from another competition)
+func (b *Bracket) directEntrants() map[int][]entrant {
+	known := make(map[int]bool)
+	for _, result := range b.phase {
+		known[result.HomeTeam.ID] = true
+		known[result.AwayTeam.ID] = true
+	}
+
+	direct := make(map[int][]entrant)
+	for r, round := range b.Rounds {
+		var fresh []int
+		for _, tie := range round.Ties {
+			for _, id := range []int{tie.TeamA.ID, tie.TeamB.ID} {
+				if id != 0 && !known[id] {
+					fresh = append(fresh, id)
+				}
+			}
+		}
+		// Without a phase, every team in the first round enters there
+		for _, id := range fresh {
+			known[id] = true
+			direct[r] = append(direct[r], entrant{teamID: id})
+		}
+	}
+
+	return direct
+}
+
+// phaseEntrants simulates what remains of the group or league phase and
+// returns the qualifiers for each round
+func (b *Bracket) phaseEntrants(rng *rand.Rand, model *goalModel) map[int][]entrant {
+	entrants := make(map[int][]entrant)
+	if len(b.phase) == 0 {
+		return entrants
+	}
+
+	tables := b.Tables
+	if b.phaseUnfinished() {
+		phase := make([]MatchResult, len(b.phase))
+		copy(phase, b.phase)
+		for i := range phase {
+			if !phase[i].Finished {
+				phase[i].HomeGoals, phase[i].AwayGoals = model.sampleScore(rng, phase[i].HomeTeam.ID, phase[i].AwayTeam.ID)
+				phase[i].Finished = true
+			}
+		}
+		tables = BuildStandingTables(phase, TableOptions{Type: TableTotal, Rules: b.tableRules})
+	}
+
+	round := func(stage string) int {
+		for r, round := range b.Rounds {
+			if round.Stage == stage {
+				return r
+			}
+		}
+		return -1
+	}
+
+	switch b.Format {
+	case FormatGroups:
+		r := round(knockoutStages[b.entryStage()])
+		if r < 0 {
+			return entrants
+		}
+		for _, table := range tables {
+			for pos := 0; pos < b.Rules.GroupQualifiers && pos < len(table.Table); pos++ {
+				entrants[r] = append(entrants[r], entrant{teamID: table.Table[pos].Team.ID, seeded: pos == 0})
+			}
+		}
+	case FormatLeague:
+		// Top eight go straight to the last 16; ninth to 24th play off,
+		// with ninth to 16th seeded
+		playoffs, last16 := round("PLAYOFFS"), round("LAST_16")
+		for _, table := range tables {
+			for pos, row := range table.Table {
+				switch {
+				case pos < 8 && last16 >= 0:
+					entrants[last16] = append(entrants[last16], entrant{teamID: row.Team.ID, seeded: true})
+				case pos >= 8 && pos < 24 && playoffs >= 0:
+					entrants[playoffs] = append(entrants[playoffs], entrant{teamID: row.Team.ID, seeded: pos < 16})
+				}
+			}
+		}
+	}
+
+	return entrants
+}
+
+// phaseUnfinished reports whether any group or league phase match is still to be played
+func (b *Bracket) phaseUnfinished() bool {
+	for _, result := range b.phase {
+		if !result.Finished {
+			return true
+		}
+	}
+	return false
+}
+
+// playRound plays the known ties among the pool, pairs the rest and returns the winners
+func (b *Bracket) playRound(rng *rand.Rand, model *goalModel, round BracketRound, pool []entrant) []entrant {
+	inPool := make(map[int]bool, len(pool))
+	for _, e := range pool {
+		inPool[e.teamID] = true
+	}
+
+	var winners []entrant
+	for _, tie := range round.Ties {
+		if !inPool[tie.TeamA.ID] || !inPool[tie.TeamB.ID] {
+			continue
+		}
+		delete(inPool, tie.TeamA.ID)
+		delete(inPool, tie.TeamB.ID)
+		winners = append(winners, entrant{teamID: model.playTie(rng, tie, b.Rules)})
+	}
+
+	var seeded, unseeded []int
+	for _, e := range pool {
+		if !inPool[e.teamID] {
+			continue
+		}
+		if e.seeded {
+			seeded = append(seeded, e.teamID)
+		} else {
+			unseeded = append(unseeded, e.teamID)
+		}
+	}
+	rng.Shuffle(len(seeded), func(i, j int) { seeded[i], seeded[j] = seeded[j], seeded[i] })
+	rng.Shuffle(len(unseeded), func(i, j int) { unseeded[i], unseeded[j] = unseeded[j], unseeded[i] })
+
+	// Seeded teams face unseeded ones and host the second leg
+	var pairs [][2]int
+	for len(seeded) > 0 && len(unseeded) > 0 {
+		pairs = append(pairs, [2]int{unseeded[0], seeded[0]})
+		seeded, unseeded = seeded[1:], unseeded[1:]
+	}
+	rest := append(seeded, unseeded...)
+	for len(rest) >= 2 {
+		pairs = append(pairs, [2]int{rest[0], rest[1]})
+		rest = rest[2:]
+	}
+	// An odd team out gets a bye
+	for _, id := range rest {
+		winners = append(winners, entrant{teamID: id})
+	}
+
+	for _, p := range pairs {
+		tie := Tie{
+			Stage:     round.Stage,
+			TeamA:     Team{ID: p[0]},
+			TeamB:     Team{ID: p[1]},
+			TotalLegs: round.Legs,
+		}
+		winners = append(winners, entrant{teamID: model.playTie(rng, tie, b.Rules)})
+	}
+
+	return winners
+}
+
+// playTie returns the winner of a tie, simulating any legs still to be
+// played followed by extra time and penalties if needed
+func (m *goalModel) playTie(rng *rand.Rand, tie Tie, rules KnockoutRules) int {
+	if tie.WinnerID != 0 {
+		return tie.WinnerID
+	}
+
+	a, b := tie.TeamA.ID, tie.TeamB.ID
+	neutral := tie.Stage == "FINAL" && tie.TotalLegs == 1
+
+	var goalsA, goalsB, awayA, awayB int
+	lastHome, lastAway := a, b
+	for leg := 0; leg < tie.TotalLegs; leg++ {
+		home, away := a, b
+		if leg%2 == 1 {
+			home, away = b, a
+		}
+
+		var homeGoals, awayGoals int
+		if leg < len(tie.Legs) && tie.Legs[leg].Finished {
+			played := tie.Legs[leg]
+			home, away = played.HomeTeam.ID, played.AwayTeam.ID
+			homeGoals, awayGoals = played.HomeGoals, played.AwayGoals
+		} else {
+			homeMean, awayMean := m.legMeans(home, away, neutral)
+			homeGoals, awayGoals = poisson(rng, homeMean), poisson(rng, awayMean)
+		}
+
+		if home == a {
+			goalsA += homeGoals
+			goalsB += awayGoals
+			awayB += awayGoals
+		} else {
+			goalsA += awayGoals
+			goalsB += homeGoals
+			awayA += awayGoals
+		}
+		lastHome, lastAway = home, away
+	}
+
+	switch {
+	case goalsA > goalsB:
+		return a
+	case goalsB > goalsA:
+		return b
+	case rules.AwayGoals && tie.TotalLegs == 2 && awayA != awayB:
+		if awayA > awayB {
+			return a
+		}
+		return b
+	}
+
+	// Extra time lasts a third of a match
+	homeMean, awayMean := m.legMeans(lastHome, lastAway, neutral)
+	homeExtra, awayExtra := poisson(rng, homeMean/3), poisson(rng, awayMean/3)
+	switch {
+	case homeExtra > awayExtra:
+		return lastHome
+	case awayExtra > homeExtra:
+		return lastAway
+	}
+
+	// Penalties are treated as a coin toss
+	if rng.IntN(2) == 0 {
+		return a
+	}
+	return b
+}
+
+// legMeans returns the Poisson means for a leg, averaging both orientations at a neutral venue
+func (m *goalModel) legMeans(homeID, awayID int, neutral bool) (float64, float64) {
+	homeMean, awayMean := m.expectedGoals(homeID, awayID)
+	if !neutral {
+		return homeMean, awayMean
+	}
+	reverseAway, reverseHome := m.expectedGoals(awayID, homeID)
+	return (homeMean + reverseHome) / 2, (awayMean + reverseAway) / 2
+}
+
+// BracketSimulator builds and simulates cup brackets from stored matches
+type BracketSimulator struct {
+	standings *StandingsCalculator
+}
+
+// NewBracketSimulator creates a new bracket simulator
+func NewBracketSimulator(db *sql.DB) *BracketSimulator {
+	return &BracketSimulator{standings: NewStandingsCalculator(db)}
+}
+
+// GetBracket builds the bracket for a competition's current season
+func (s *BracketSimulator) GetBracket(ctx context.Context, competitionID int) (*Bracket, error) {
+	comp, err := s.standings.repo.GetCompetition(ctx, competitionID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	seasonID, err := s.standings.seasonAt(ctx, competitionID, now)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := s.standings.loadResults(ctx, competitionID, seasonID, now)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := KnockoutRulesFor(comp.Code, seasonStart(comp, seasonID))
+	bracket := BuildBracket(results, rules, RulesForCompetition(comp.Code))
+	bracket.CompetitionID = competitionID
+	bracket.SeasonID = seasonID
+
+	return bracket, nil
+}
+
+// Simulate runs a tournament simulation for a competition's current season
+func (s *BracketSimulator) Simulate(ctx context.Context, competitionID int, opts SimulationOptions) (*BracketSimulation, error) {
+	bracket, err := s.GetBracket(ctx, competitionID)
+	if err != nil {
+		return nil, err
+	}
+
+	teams, err := SimulateBracket(bracket, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	stages := make([]string, 0, len(bracket.Rounds)+1)
+	for _, round := range bracket.Rounds {
+		stages = append(stages, round.Stage)
+	}
+	stages = append(stages, StageWinner)
+
+	return &BracketSimulation{
+		CompetitionID: competitionID,
+		SeasonID:      bracket.SeasonID,
+		Format:        bracket.Format,
+		Iterations:    simulationIterations(opts.Iterations),
+		Stages:        stages,
+		Teams:         teams,
+		GeneratedAt:   time.Now(),
+	}, nil
+}
+
+// seasonStart returns the start date of a competition season, if known
+func seasonStart(comp *Competition, seasonID int) time.Time {
+	seasons := append([]Season{comp.CurrentSeason}, comp.Seasons...)
+	for _, season := range seasons {
+		if season.ID != seasonID {
+			continue
+		}
+		start, err := time.Parse(time.DateOnly, season.StartDate)
+		if err == nil {
+			return start
+		}
+	}
+	return time.Time{}
+}
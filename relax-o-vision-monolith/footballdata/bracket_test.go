@@ -0,0 +1,261 @@
+package footballdata
+
+import (
+	"math"
+	"testing"
+)
+
+// newTestLeg creates a finished knockout leg
+func newTestLeg(day int, stage string, home, away Team, homeGoals, awayGoals int, winner, duration string) MatchResult {
+	leg := newTestResult(day, home, away, homeGoals, awayGoals)
+	leg.Stage = stage
+	leg.Status = "FINISHED"
+	leg.Winner = winner
+	leg.Duration = duration
+	return leg
+}
+
+func TestBuildTies_Resolution(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		rules         KnockoutRules
+		legs          []MatchResult
+		wantWinner    int
+		wantDecidedBy string
+	}{
+		{
+			name:  "aggregate",
+			rules: KnockoutRules{TwoLegged: true},
+			legs: []MatchResult{
+				newTestLeg(1, "LAST_16", testTeamA, testTeamB, 2, 0, "HOME_TEAM", "REGULAR"),
+				newTestLeg(8, "LAST_16", testTeamB, testTeamA, 1, 0, "HOME_TEAM", "REGULAR"),
+			},
+			wantWinner:    testTeamA.ID,
+			wantDecidedBy: DecidedAggregate,
+		},
+		{
+			name:  "away goals",
+			rules: KnockoutRules{TwoLegged: true, AwayGoals: true},
+			legs: []MatchResult{
+				newTestLeg(1, "LAST_16", testTeamA, testTeamB, 2, 1, "HOME_TEAM", "REGULAR"),
+				newTestLeg(8, "LAST_16", testTeamB, testTeamA, 1, 0, "HOME_TEAM", "REGULAR"),
+			},
+			wantWinner:    testTeamB.ID,
+			wantDecidedBy: DecidedAwayGoals,
+		},
+		{
+			name:  "extra time in the second leg",
+			rules: KnockoutRules{TwoLegged: true},
+			legs: []MatchResult{
+				newTestLeg(1, "LAST_16", testTeamA, testTeamB, 1, 0, "HOME_TEAM", "REGULAR"),
+				newTestLeg(8, "LAST_16", testTeamB, testTeamA, 2, 0, "HOME_TEAM", "EXTRA_TIME"),
+			},
+			wantWinner:    testTeamB.ID,
+			wantDecidedBy: DecidedExtraTime,
+		},
+		{
+			name:  "penalties",
+			rules: KnockoutRules{TwoLegged: true},
+			legs: []MatchResult{
+				newTestLeg(1, "LAST_16", testTeamA, testTeamB, 1, 0, "HOME_TEAM", "REGULAR"),
+				newTestLeg(8, "LAST_16", testTeamB, testTeamA, 1, 0, "AWAY_TEAM", "PENALTY_SHOOTOUT"),
+			},
+			wantWinner:    testTeamA.ID,
+			wantDecidedBy: DecidedPenalties,
+		},
+		{
+			name:  "second leg to play",
+			rules: KnockoutRules{TwoLegged: true},
+			legs: []MatchResult{
+				newTestLeg(1, "LAST_16", testTeamA, testTeamB, 3, 0, "HOME_TEAM", "REGULAR"),
+			},
+		},
+		{
+			name:  "single-leg final",
+			rules: KnockoutRules{TwoLegged: true},
+			legs: []MatchResult{
+				newTestLeg(1, "FINAL", testTeamA, testTeamB, 0, 1, "AWAY_TEAM", "REGULAR"),
+			},
+			wantWinner:    testTeamB.ID,
+			wantDecidedBy: DecidedRegularTime,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			stage := tt.legs[0].Stage
+			ties := buildTies(stage, tt.legs, tt.rules.legsFor(stage), tt.rules)
+			if len(ties) != 1 {
+				t.Fatalf("got %d ties, want 1", len(ties))
+			}
+			tie := ties[0]
+			if tie.WinnerID != tt.wantWinner || tie.DecidedBy != tt.wantDecidedBy {
+				t.Errorf("winner = %d by %q, want %d by %q", tie.WinnerID, tie.DecidedBy, tt.wantWinner, tt.wantDecidedBy)
+			}
+			if tie.TeamA.ID != testTeamA.ID {
+				t.Errorf("TeamA = %d, want the first leg home side %d", tie.TeamA.ID, testTeamA.ID)
+			}
+		})
+	}
+}
+
+func TestSimulateBracket_Knockout(t *testing.T) {
+	t.Parallel()
+
+	// Alpha and Charlie are through to a final still to be played
+	final := newTestFixture(22, testTeamA, testTeamC)
+	final.Stage = "FINAL"
+	results := []MatchResult{
+		newTestLeg(1, "SEMI_FINALS", testTeamA, testTeamB, 2, 0, "HOME_TEAM", "REGULAR"),
+		newTestLeg(1, "SEMI_FINALS", testTeamC, testTeamD, 1, 1, "HOME_TEAM", "PENALTY_SHOOTOUT"),
+		final,
+	}
+
+	bracket := BuildBracket(results, KnockoutRules{}, DefaultStandingsRules)
+	if bracket.Format != FormatKnockout || len(bracket.Rounds) != 2 {
+		t.Fatalf("bracket = %s with %d rounds, want KNOCKOUT with 2", bracket.Format, len(bracket.Rounds))
+	}
+
+	odds, err := SimulateBracket(bracket, SimulationOptions{Iterations: 1000, Seed: 3})
+	if err != nil {
+		t.Fatalf("SimulateBracket() error = %v", err)
+	}
+
+	var total float64
+	for _, o := range odds {
+		total += o.WinProb
+		if o.ReachProbabilities["SEMI_FINALS"] != 1 {
+			t.Errorf("%s semi-final prob = %v, want 1", o.Team.Name, o.ReachProbabilities["SEMI_FINALS"])
+		}
+
+		finalist := o.Team.ID == testTeamA.ID || o.Team.ID == testTeamC.ID
+		if got := o.ReachProbabilities["FINAL"]; (finalist && got != 1) || (!finalist && got != 0) {
+			t.Errorf("%s final prob = %v", o.Team.Name, got)
+		}
+		if !finalist && o.WinProb != 0 {
+			t.Errorf("%s win prob = %v, want 0", o.Team.Name, o.WinProb)
+		}
+	}
+	if math.Abs(total-1) > 1e-9 {
+		t.Errorf("win probabilities sum to %v, want 1", total)
+	}
+}
+
+func TestSimulateBracket_GroupStage(t *testing.T) {
+	t.Parallel()
+
+	groupA, groupB := "GROUP_A", "GROUP_B"
+	teamE, teamF := Team{ID: 5, Name: "Echo"}, Team{ID: 6, Name: "Foxtrot"}
+	group := func(result MatchResult, name *string) MatchResult {
+		result.Stage, result.Group = "GROUP_STAGE", name
+		return result
+	}
+
+	// Three-team groups with one match left in group B
+	last := newTestFixture(15, testTeamD, teamF)
+	results := []MatchResult{
+		group(newTestResult(1, testTeamA, testTeamB, 1, 0), &groupA),
+		group(newTestResult(8, testTeamB, testTeamC, 2, 0), &groupA),
+		group(newTestResult(15, testTeamC, testTeamA, 0, 3), &groupA),
+		group(newTestResult(1, testTeamD, teamE, 0, 0), &groupB),
+		group(newTestResult(8, teamE, teamF, 1, 0), &groupB),
+		group(last, &groupB),
+	}
+
+	bracket := BuildBracket(results, KnockoutRules{GroupQualifiers: 2}, DefaultStandingsRules)
+	if bracket.Format != FormatGroups || len(bracket.Tables) != 2 {
+		t.Fatalf("bracket = %s with %d tables, want GROUPS with 2", bracket.Format, len(bracket.Tables))
+	}
+	if bracket.Rounds[0].Stage != "SEMI_FINALS" {
+		t.Fatalf("first round = %s, want SEMI_FINALS", bracket.Rounds[0].Stage)
+	}
+
+	odds, err := SimulateBracket(bracket, SimulationOptions{Iterations: 2000, Seed: 11})
+	if err != nil {
+		t.Fatalf("SimulateBracket() error = %v", err)
+	}
+
+	reach := make(map[int]float64)
+	var semiTotal float64
+	for _, o := range odds {
+		reach[o.Team.ID] = o.ReachProbabilities["SEMI_FINALS"]
+		semiTotal += o.ReachProbabilities["SEMI_FINALS"]
+	}
+
+	// Group A is settled; group B depends on the last match
+	if reach[testTeamA.ID] != 1 || reach[testTeamB.ID] != 1 || reach[testTeamC.ID] != 0 {
+		t.Errorf("group A semi-final odds = %v, %v, %v, want 1, 1, 0", reach[testTeamA.ID], reach[testTeamB.ID], reach[testTeamC.ID])
+	}
+	if reach[teamE.ID] != 1 {
+		t.Errorf("Echo semi-final prob = %v, want 1", reach[teamE.ID])
+	}
+	if reach[testTeamD.ID] == 0 || reach[teamF.ID] == 0 {
+		t.Errorf("Delta and Foxtrot should both have a chance: %v, %v", reach[testTeamD.ID], reach[teamF.ID])
+	}
+	if math.Abs(semiTotal-4) > 1e-9 {
+		t.Errorf("semi-final places sum to %v, want 4", semiTotal)
+	}
+}
+
+func TestSimulateBracket_HalfDrawnRound(t *testing.T) {
+	t.Parallel()
+
+	// Alpha is through to the final; its opponent comes from a semi-final
+	// still to be played, and the final's other side is not drawn yet
+	semi := newTestFixture(8, testTeamC, testTeamD)
+	semi.Stage = "SEMI_FINALS"
+	final := newTestFixture(22, testTeamA, Team{})
+	final.Stage = "FINAL"
+	tbd := newTestFixture(22, Team{}, Team{})
+	tbd.Stage = "FINAL"
+	results := []MatchResult{
+		newTestLeg(1, "SEMI_FINALS", testTeamA, testTeamB, 2, 0, "HOME_TEAM", "REGULAR"),
+		semi,
+		final,
+		tbd,
+	}
+
+	bracket := BuildBracket(results, KnockoutRules{}, DefaultStandingsRules)
+	if len(bracket.Rounds) != 2 {
+		t.Fatalf("got %d rounds, want 2", len(bracket.Rounds))
+	}
+	if ties := bracket.Rounds[1].Ties; len(ties) != 0 {
+		t.Errorf("final ties = %+v, want none until both sides are known", ties)
+	}
+	for _, entrants := range bracket.directEntrants() {
+		for _, e := range entrants {
+			if e.teamID == 0 {
+				t.Errorf("an undrawn team enters the bracket")
+			}
+		}
+	}
+
+	odds, err := SimulateBracket(bracket, SimulationOptions{Iterations: 1000, Seed: 5})
+	if err != nil {
+		t.Fatalf("SimulateBracket() error = %v", err)
+	}
+	if len(odds) != 4 {
+		t.Fatalf("got odds for %d teams, want 4", len(odds))
+	}
+
+	reach := make(map[int]float64)
+	var finalists, total float64
+	for _, o := range odds {
+		if o.Team.ID == 0 {
+			t.Errorf("odds for an undrawn team: %+v", o)
+		}
+		reach[o.Team.ID] = o.ReachProbabilities["FINAL"]
+		finalists += o.ReachProbabilities["FINAL"]
+		total += o.WinProb
+	}
+	if reach[testTeamA.ID] != 1 || reach[testTeamB.ID] != 0 {
+		t.Errorf("final odds of Alpha and Bravo = %v, %v, want 1 and 0", reach[testTeamA.ID], reach[testTeamB.ID])
+	}
+	if math.Abs(finalists-2) > 1e-9 || math.Abs(total-1) > 1e-9 {
+		t.Errorf("final places sum to %v and titles to %v, want 2 and 1", finalists, total)
+	}
+}
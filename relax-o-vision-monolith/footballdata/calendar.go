@@ -0,0 +1,267 @@
+package footballdata
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// calendarLookback keeps recently played matches in feeds so they don't vanish from calendars
+	calendarLookback = 7 * 24 * time.Hour
+	// calendarMatchDuration is the event length used for a match
+	calendarMatchDuration = 2 * time.Hour
+	calendarProductID     = "-//relax-o-vision//Fixtures//EN"
+	calendarUIDDomain     = "relaxovision"
+	icalTimeFormat        = "20060102T150405Z"
+	icalLineLimit         = 75
+)
+
+// CalendarMatch is a match as listed in an iCalendar feed
+type CalendarMatch struct {
+	ID          int
+	UTCDate     time.Time
+	Status      string
+	Matchday    int
+	Stage       string
+	Competition string
+	HomeTeam    string
+	AwayTeam    string
+	Venue       string // home team's venue
+	HomeGoals   *int
+	AwayGoals   *int
+	Sequence    int
+}
+
+// CalendarFilter selects the matches of a feed: a team's or a competition's
+type CalendarFilter struct {
+	TeamID        int
+	CompetitionID int
+}
+
+// CalendarBuilder builds iCalendar fixture feeds with prediction probabilities
+type CalendarBuilder struct {
+	repo          *Repository
+	probabilities MatchProbabilitySource
+}
+
+// NewCalendarBuilder creates a new calendar builder. probabilities may be nil.
+func NewCalendarBuilder(repo *Repository, probabilities MatchProbabilitySource) *CalendarBuilder {
+	return &CalendarBuilder{repo: repo, probabilities: probabilities}
+}
+
+// TeamCalendar writes the fixture calendar of a team
+func (b *CalendarBuilder) TeamCalendar(ctx context.Context, w io.Writer, teamID int, now time.Time) error {
+	team, err := b.repo.GetTeam(ctx, teamID)
+	if err != nil {
+		return err
+	}
+	return b.write(ctx, w, team.Name, CalendarFilter{TeamID: teamID}, now)
+}
+
+// CompetitionCalendar writes the fixture calendar of a competition
+func (b *CalendarBuilder) CompetitionCalendar(ctx context.Context, w io.Writer, competitionID int, now time.Time) error {
+	comp, err := b.repo.GetCompetition(ctx, competitionID)
+	if err != nil {
+		return err
+	}
+	return b.write(ctx, w, comp.Name, CalendarFilter{CompetitionID: competitionID}, now)
+}
+
+// write loads the matches of a feed with their latest probabilities and writes the calendar
+func (b *CalendarBuilder) write(ctx context.Context, w io.Writer, name string, filter CalendarFilter, now time.Time) error {
+	matches, err := b.repo.CalendarMatches(ctx, filter, now)
+	if err != nil {
+		return err
+	}
+
+	var probabilities map[int]MatchProbabilities
+	if b.probabilities != nil && len(matches) > 0 {
+		ids := make([]int, len(matches))
+		for i, m := range matches {
+			ids[i] = m.ID
+		}
+		probabilities, err = b.probabilities.MatchProbabilities(ctx, ids)
+		if err != nil {
+			// The feed is still useful without predictions
+			slog.Warn("Failed to load calendar probabilities", "error", err)
+			probabilities = nil
+		}
+	}
+
+	return WriteICalendar(w, name+" fixtures", matches, probabilities, now)
+}
+
+// CalendarMatches lists upcoming and recently played matches for a calendar feed
+func (r *Repository) CalendarMatches(ctx context.Context, filter CalendarFilter, now time.Time) ([]CalendarMatch, error) {
+	var where whereBuilder
+	where.add("m.utc_date >= %s", now.Add(-calendarLookback))
+	if filter.TeamID != 0 {
+		where.add("(m.home_team_id = %s OR m.away_team_id = %s)", filter.TeamID, filter.TeamID)
+	}
+	if filter.CompetitionID != 0 {
+		where.add("m.competition_id = %s", filter.CompetitionID)
+	}
+
+	query := `
+		SELECT m.id, m.utc_date, m.status, COALESCE(m.matchday, 0), COALESCE(m.stage, ''), COALESCE(c.name, ''),
+		       COALESCE(ht.name, m.home_team->>'name', ''), COALESCE(awt.name, m.away_team->>'name', ''),
+		       COALESCE(ht.venue, ''), m.home_score_ft, m.away_score_ft, m.schedule_sequence
+		FROM matches m
+		LEFT JOIN competitions c ON c.id = m.competition_id
+		LEFT JOIN teams ht ON ht.id = m.home_team_id
+		LEFT JOIN teams awt ON awt.id = m.away_team_id` + where.String() + `
+		ORDER BY m.utc_date, m.id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, where.args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query calendar matches: %w", err)
+	}
+	defer rows.Close()
+
+	var matches []CalendarMatch
+	for rows.Next() {
+		var m CalendarMatch
+		err := rows.Scan(&m.ID, &m.UTCDate, &m.Status, &m.Matchday, &m.Stage, &m.Competition,
+			&m.HomeTeam, &m.AwayTeam, &m.Venue, &m.HomeGoals, &m.AwayGoals, &m.Sequence)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan calendar match: %w", err)
+		}
+		matches = append(matches, m)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate calendar matches: %w", err)
+	}
+
+	return matches, nil
+}
+
+// WriteICalendar writes an RFC 5545 calendar with one event per match.
+// Events keep a stable UID per match and use the match's schedule sequence,
+// so calendar apps replace an event when its kickoff changes. Probabilities
+// are added to the description of matches that have a prediction.
+func WriteICalendar(w io.Writer, name string, matches []CalendarMatch, probabilities map[int]MatchProbabilities, now time.Time) error {
+	cw := &calendarWriter{w: bufio.NewWriter(w)}
+
+	cw.line("BEGIN:VCALENDAR")
+	cw.line("VERSION:2.0")
+	cw.line("PRODID:" + calendarProductID)
+	cw.line("CALSCALE:GREGORIAN")
+	cw.line("METHOD:PUBLISH")
+	cw.line("X-WR-CALNAME:" + escapeICalText(name))
+	cw.line("X-PUBLISHED-TTL:PT1H")
+	cw.line("REFRESH-INTERVAL;VALUE=DURATION:PT1H")
+
+	for _, m := range matches {
+		cw.line("BEGIN:VEVENT")
+		cw.line(fmt.Sprintf("UID:match-%d@%s", m.ID, calendarUIDDomain))
+		cw.line(fmt.Sprintf("SEQUENCE:%d", m.Sequence))
+		cw.line("DTSTAMP:" + now.UTC().Format(icalTimeFormat))
+		cw.line("DTSTART:" + m.UTCDate.UTC().Format(icalTimeFormat))
+		cw.line("DTEND:" + m.UTCDate.Add(calendarMatchDuration).UTC().Format(icalTimeFormat))
+		cw.line("SUMMARY:" + escapeICalText(calendarSummary(m)))
+		if m.Venue != "" {
+			cw.line("LOCATION:" + escapeICalText(m.Venue))
+		}
+		probs, hasPrediction := probabilities[m.ID]
+		var p *MatchProbabilities
+		if hasPrediction {
+			p = &probs
+		}
+		cw.line("DESCRIPTION:" + escapeICalText(calendarDescription(m, p)))
+		cw.line("STATUS:" + calendarStatus(m.Status))
+		cw.line("END:VEVENT")
+	}
+
+	cw.line("END:VCALENDAR")
+
+	if cw.err != nil {
+		return cw.err
+	}
+	return cw.w.Flush()
+}
+
+// calendarSummary returns the event title, with the score once played
+func calendarSummary(m CalendarMatch) string {
+	summary := m.HomeTeam + " vs " + m.AwayTeam
+	if m.Status == "FINISHED" && m.HomeGoals != nil && m.AwayGoals != nil {
+		summary = fmt.Sprintf("%s %d-%d %s", m.HomeTeam, *m.HomeGoals, *m.AwayGoals, m.AwayTeam)
+	}
+	switch m.Status {
+	case "POSTPONED":
+		summary += " (postponed)"
+	case "CANCELLED":
+		summary += " (cancelled)"
+	}
+	return summary
+}
+
+// calendarDescription describes the competition round and the prediction
+func calendarDescription(m CalendarMatch, probs *MatchProbabilities) string {
+	lines := []string{m.Competition}
+	switch {
+	case m.Stage != "" && m.Stage != "REGULAR_SEASON":
+		lines = append(lines, strings.ReplaceAll(m.Stage, "_", " "))
+	case m.Matchday > 0:
+		lines = append(lines, fmt.Sprintf("Matchday %d", m.Matchday))
+	}
+	if m.Status == "SCHEDULED" {
+		lines = append(lines, "Kickoff time to be confirmed")
+	}
+	if probs != nil {
+		lines = append(lines, fmt.Sprintf("Prediction: %s %.0f%%, draw %.0f%%, %s %.0f%%",
+			m.HomeTeam, probs.HomeWin*100, probs.Draw*100, m.AwayTeam, probs.AwayWin*100))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// calendarStatus maps a match status to an event status
+func calendarStatus(status string) string {
+	switch status {
+	case "CANCELLED":
+		return "CANCELLED"
+	case "SCHEDULED", "POSTPONED":
+		return "TENTATIVE"
+	default:
+		return "CONFIRMED"
+	}
+}
+
+// escapeICalText escapes a TEXT property value
+func escapeICalText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// calendarWriter writes content lines with CRLF endings, folded at 75 octets
+type calendarWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+// line writes one content line, folding it without splitting UTF-8 characters
+func (cw *calendarWriter) line(s string) {
+	if cw.err != nil {
+		return
+	}
+
+	limit := icalLineLimit
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		if _, cw.err = cw.w.WriteString(s[:cut] + "\r\n "); cw.err != nil {
+			return
+		}
+		s = s[cut:]
+		limit = icalLineLimit - 1 // continuation lines start with a space
+	}
+	_, cw.err = cw.w.WriteString(s + "\r\n")
+}
@@ -0,0 +1,149 @@
+package footballdata
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteICalendar(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 3, 20, 9, 0, 0, 0, time.UTC)
+	paris := time.FixedZone("CET", 3600)
+	home, away := 2, 1
+
+	matches := []CalendarMatch{
+		{
+			ID: 101, UTCDate: time.Date(2024, 3, 30, 16, 0, 0, 0, paris), Status: "TIMED", Matchday: 29,
+			Competition: "Premier League", HomeTeam: "Arsenal FC", AwayTeam: "Manchester City FC",
+			Venue: "Emirates Stadium", Sequence: 2,
+		},
+		{
+			ID: 102, UTCDate: time.Date(2024, 3, 16, 12, 30, 0, 0, time.UTC), Status: "FINISHED", Stage: "QUARTER_FINALS",
+			Competition: "FA Cup", HomeTeam: "Brighton & Hove Albion FC", AwayTeam: "Arsenal FC",
+			HomeGoals: &home, AwayGoals: &away,
+		},
+		{
+			ID: 103, UTCDate: time.Date(2024, 4, 6, 14, 0, 0, 0, time.UTC), Status: "CANCELLED",
+			Competition: "Premier League", HomeTeam: "Arsenal FC", AwayTeam: "Luton Town FC",
+		},
+	}
+	probabilities := map[int]MatchProbabilities{101: {HomeWin: 0.42, Draw: 0.27, AwayWin: 0.31}}
+
+	var buf bytes.Buffer
+	if err := WriteICalendar(&buf, "Arsenal FC fixtures", matches, probabilities, now); err != nil {
+		t.Fatalf("WriteICalendar: %v", err)
+	}
+	out := buf.String()
+
+	if strings.Contains(strings.ReplaceAll(out, "\r\n", ""), "\n") {
+		t.Error("calendar contains bare LF line endings")
+	}
+
+	// Unfold continuation lines before checking properties
+	unfolded := strings.ReplaceAll(out, "\r\n ", "")
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\n",
+		"X-WR-CALNAME:Arsenal FC fixtures\r\n",
+		"UID:match-101@relaxovision\r\nSEQUENCE:2\r\nDTSTAMP:20240320T090000Z\r\n",
+		"DTSTART:20240330T150000Z\r\nDTEND:20240330T170000Z\r\n",
+		"SUMMARY:Arsenal FC vs Manchester City FC\r\n",
+		"LOCATION:Emirates Stadium\r\n",
+		`DESCRIPTION:Premier League\nMatchday 29\nPrediction: Arsenal FC 42%\, draw 27%\, Manchester City FC 31%` + "\r\n",
+		"UID:match-102@relaxovision\r\nSEQUENCE:0\r\n",
+		"SUMMARY:Brighton & Hove Albion FC 2-1 Arsenal FC\r\n",
+		`DESCRIPTION:FA Cup\nQUARTER FINALS` + "\r\n",
+		"SUMMARY:Arsenal FC vs Luton Town FC (cancelled)\r\n",
+		"STATUS:CANCELLED\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(unfolded, want) {
+			t.Errorf("calendar missing %q", want)
+		}
+	}
+
+	if n := strings.Count(out, "BEGIN:VEVENT"); n != 3 {
+		t.Errorf("calendar has %d events, want 3", n)
+	}
+}
+
+func TestCalendarWriterFolding(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		line string
+	}{
+		{name: "short line", line: "SUMMARY:Arsenal vs Chelsea"},
+		{name: "exactly 75 octets", line: "DESCRIPTION:" + strings.Repeat("a", 63)},
+		{name: "long ascii", line: "DESCRIPTION:" + strings.Repeat("abcdefghij", 20)},
+		{name: "multibyte", line: "LOCATION:" + strings.Repeat("Estádio José Alvalade ", 10)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			cw := &calendarWriter{w: bufio.NewWriter(&buf)}
+			cw.line(tt.line)
+			if err := cw.w.Flush(); err != nil {
+				t.Fatalf("Flush: %v", err)
+			}
+
+			out := buf.String()
+			for _, l := range strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n") {
+				if len(l) > icalLineLimit {
+					t.Errorf("line of %d octets exceeds %d: %q", len(l), icalLineLimit, l)
+				}
+			}
+			if got := strings.ReplaceAll(strings.TrimSuffix(out, "\r\n"), "\r\n ", ""); got != tt.line {
+				t.Errorf("unfolded = %q, want %q", got, tt.line)
+			}
+		})
+	}
+}
+
+func TestEscapeICalText(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"Arsenal vs Chelsea", "Arsenal vs Chelsea"},
+		{"Brighton, Hove", `Brighton\, Hove`},
+		{"a;b", `a\;b`},
+		{`back\slash`, `back\\slash`},
+		{"line1\nline2", `line1\nline2`},
+		{"line1\r\nline2", `line1\nline2`},
+	}
+
+	for _, tt := range tests {
+		if got := escapeICalText(tt.in); got != tt.want {
+			t.Errorf("escapeICalText(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCalendarStatus(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]string{
+		"SCHEDULED": "TENTATIVE",
+		"POSTPONED": "TENTATIVE",
+		"TIMED":     "CONFIRMED",
+		"IN_PLAY":   "CONFIRMED",
+		"FINISHED":  "CONFIRMED",
+		"CANCELLED": "CANCELLED",
+	}
+
+	for status, want := range tests {
+		if got := calendarStatus(status); got != want {
+			t.Errorf("calendarStatus(%q) = %q, want %q", status, got, want)
+		}
+	}
+}
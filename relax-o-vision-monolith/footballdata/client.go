@@ -6,12 +6,14 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 )
 
 const (
-	baseURL            = "https://api.football-data.org/v4"
+	// DefaultBaseURL is the production football-data.org API endpoint
+	DefaultBaseURL     = "https://api.football-data.org/v4"
 	requestsPerMinute  = 10
 	rateLimitDuration  = time.Minute
 )
@@ -19,6 +21,7 @@ const (
 // Client represents the football-data.org API client
 type Client struct {
 	apiKey      string
+	baseURL     string
 	httpClient  *http.Client
 	lastRequest time.Time
 	mu          sync.Mutex // Protects lastRequest
@@ -26,8 +29,18 @@ type Client struct {
 
 // NewClient creates a new football-data.org API client
 func NewClient(apiKey string) *Client {
+	return NewClientWithBaseURL(apiKey, DefaultBaseURL)
+}
+
+// NewClientWithBaseURL creates a client against an alternative API endpoint,
+// such as the local fake server used for development and tests
+func NewClientWithBaseURL(apiKey, baseURL string) *Client {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
 	return &Client{
-		apiKey: apiKey,
+		apiKey:  apiKey,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
@@ -51,7 +64,7 @@ func (c *Client) doRequest(ctx context.Context, endpoint string) ([]byte, error)
 	c.lastRequest = time.Now()
 	c.mu.Unlock()
 
-	url := fmt.Sprintf("%s%s", baseURL, endpoint)
+	url := fmt.Sprintf("%s%s", c.baseURL, endpoint)
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
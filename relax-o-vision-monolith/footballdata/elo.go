@@ -0,0 +1,145 @@
+package footballdata
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+const (
+	// EloInitialRating is the rating of a team without earlier results
+	EloInitialRating = 1500.0
+	eloK             = 20.0
+	eloHomeAdvantage = 60.0
+	// eloLookback bounds the results replayed to compute ratings
+	eloLookback = 4 * 365 * 24 * time.Hour
+)
+
+// EloCalculator rates team strength from stored results across all competitions
+type EloCalculator struct {
+	db *sql.DB
+}
+
+// NewEloCalculator creates a new Elo calculator
+func NewEloCalculator(db *sql.DB) *EloCalculator {
+	return &EloCalculator{db: db}
+}
+
+// RatingsAt returns every team's Elo rating from results before the given time
+func (e *EloCalculator) RatingsAt(ctx context.Context, asOf time.Time) (map[int]float64, error) {
+	query := `
+		SELECT id, utc_date, home_team_id, away_team_id, home_score_ft, away_score_ft
+		FROM matches
+		WHERE status = 'FINISHED'
+		  AND home_score_ft IS NOT NULL
+		  AND away_score_ft IS NOT NULL
+		  AND utc_date >= $1
+		  AND utc_date < $2
+		ORDER BY utc_date, id
+	`
+
+	rows, err := e.db.QueryContext(ctx, query, asOf.Add(-eloLookback), asOf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query results for ratings: %w", err)
+	}
+	defer rows.Close()
+
+	var results []MatchResult
+	for rows.Next() {
+		r := MatchResult{Finished: true}
+		if err := rows.Scan(&r.MatchID, &r.UTCDate, &r.HomeTeam.ID, &r.AwayTeam.ID, &r.HomeGoals, &r.AwayGoals); err != nil {
+			return nil, fmt.Errorf("failed to scan result for ratings: %w", err)
+		}
+		results = append(results, r)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate results for ratings: %w", err)
+	}
+
+	return ComputeEloRatings(results), nil
+}
+
+// RatingsVersion identifies the results RatingsAt would replay for the given
+// time: it changes whenever one of them is added, removed or corrected
+func (e *EloCalculator) RatingsVersion(ctx context.Context, asOf time.Time) (string, error) {
+	query := `
+		SELECT concat_ws(':', COUNT(*), MAX(result_updated_at))
+		FROM matches
+		WHERE status = 'FINISHED'
+		  AND home_score_ft IS NOT NULL
+		  AND away_score_ft IS NOT NULL
+		  AND utc_date >= $1
+		  AND utc_date < $2
+	`
+
+	var version string
+	if err := e.db.QueryRowContext(ctx, query, asOf.Add(-eloLookback), asOf).Scan(&version); err != nil {
+		return "", fmt.Errorf("failed to get ratings version: %w", err)
+	}
+
+	return version, nil
+}
+
+// ComputeEloRatings replays finished results in date order. Wins by larger
+// margins move ratings further, as in the World Football Elo ratings.
+func ComputeEloRatings(results []MatchResult) map[int]float64 {
+	played := make([]MatchResult, 0, len(results))
+	for _, r := range results {
+		if r.Finished {
+			played = append(played, r)
+		}
+	}
+	sort.SliceStable(played, func(i, j int) bool { return played[i].UTCDate.Before(played[j].UTCDate) })
+
+	ratings := make(map[int]float64)
+	rating := func(teamID int) float64 {
+		if r, ok := ratings[teamID]; ok {
+			return r
+		}
+		return EloInitialRating
+	}
+
+	for _, r := range played {
+		home, away := rating(r.HomeTeam.ID), rating(r.AwayTeam.ID)
+		expected := EloExpectedScore(home, away)
+
+		actual := 0.5
+		switch {
+		case r.HomeGoals > r.AwayGoals:
+			actual = 1
+		case r.HomeGoals < r.AwayGoals:
+			actual = 0
+		}
+
+		delta := eloK * eloMarginMultiplier(r.HomeGoals-r.AwayGoals) * (actual - expected)
+		ratings[r.HomeTeam.ID] = home + delta
+		ratings[r.AwayTeam.ID] = away - delta
+	}
+
+	return ratings
+}
+
+// EloExpectedScore returns the home side's expected score (win = 1, draw = 0.5)
+// including home advantage
+func EloExpectedScore(home, away float64) float64 {
+	return 1 / (1 + math.Pow(10, (away-home-eloHomeAdvantage)/400))
+}
+
+// eloMarginMultiplier scales rating changes by goal difference
+func eloMarginMultiplier(goalDifference int) float64 {
+	if goalDifference < 0 {
+		goalDifference = -goalDifference
+	}
+	switch goalDifference {
+	case 0, 1:
+		return 1
+	case 2:
+		return 1.5
+	default:
+		return (11 + float64(goalDifference)) / 8
+	}
+}
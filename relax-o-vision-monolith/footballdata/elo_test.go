@@ -0,0 +1,58 @@
+package footballdata
+
+import "testing"
+
+func TestComputeEloRatings(t *testing.T) {
+	t.Parallel()
+
+	results := []MatchResult{
+		newTestResult(8, testTeamA, testTeamC, 4, 0),
+		newTestResult(1, testTeamA, testTeamB, 1, 0),
+		newTestResult(15, testTeamB, testTeamC, 1, 1),
+		newTestFixture(22, testTeamC, testTeamA),
+	}
+
+	ratings := ComputeEloRatings(results)
+
+	if len(ratings) != 3 {
+		t.Fatalf("got %d ratings, want 3 (fixtures are ignored)", len(ratings))
+	}
+
+	var total float64
+	for _, r := range ratings {
+		total += r
+	}
+	if !almostEqual(total, 3*EloInitialRating) {
+		t.Errorf("ratings sum to %v, want %v", total, 3*EloInitialRating)
+	}
+
+	if !(ratings[testTeamA.ID] > ratings[testTeamB.ID] && ratings[testTeamB.ID] > ratings[testTeamC.ID]) {
+		t.Errorf("ratings = %v, want Alpha > Bravo > Charlie", ratings)
+	}
+
+	// Bravo lost away and only drew at home to the lowest rated side
+	if ratings[testTeamB.ID]-EloInitialRating >= 0 {
+		t.Errorf("Bravo rating = %v, want below initial after a loss and a home draw", ratings[testTeamB.ID])
+	}
+}
+
+func TestEloMarginMultiplier(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		goalDifference int
+		want           float64
+	}{
+		{0, 1},
+		{1, 1},
+		{-2, 1.5},
+		{3, 1.75},
+		{5, 2},
+	}
+
+	for _, tt := range tests {
+		if got := eloMarginMultiplier(tt.goalDifference); got != tt.want {
+			t.Errorf("eloMarginMultiplier(%d) = %v, want %v", tt.goalDifference, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package fakeserver
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock provides simulated time so fixtures can progress through a season
+// faster (or slower) than wall-clock time
+type Clock struct {
+	mu       sync.RWMutex
+	simStart time.Time
+	anchor   time.Time
+	speed    float64
+	now      func() time.Time
+}
+
+// NewClock creates a clock that starts at start and advances speed times
+// faster than real time. A speed of 0 freezes the clock until Advance is called.
+func NewClock(start time.Time, speed float64) *Clock {
+	if speed < 0 {
+		speed = 0
+	}
+	return &Clock{
+		simStart: start.UTC(),
+		anchor:   time.Now(),
+		speed:    speed,
+		now:      time.Now,
+	}
+}
+
+// Now returns the current simulated time
+func (c *Clock) Now() time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	elapsed := c.now().Sub(c.anchor)
+	return c.simStart.Add(time.Duration(float64(elapsed) * c.speed))
+}
+
+// Set moves the simulated time to t
+func (c *Clock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.simStart = t.UTC()
+	c.anchor = c.now()
+}
+
+// Advance moves the simulated time forward by d
+func (c *Clock) Advance(d time.Duration) {
+	c.Set(c.Now().Add(d))
+}
+
+// Speed returns the clock's speed multiplier
+func (c *Clock) Speed() float64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.speed
+}
@@ -0,0 +1,141 @@
+package fakeserver
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/edd/relaxovisionmonolith/footballdata"
+)
+
+//go:embed fixtures
+var defaultFixtures embed.FS
+
+// Fixtures holds the static data served by the fake server
+//
+// A fixture directory contains:
+//   - competitions.json: a competitions response ({"competitions": [...]})
+//   - teams.json (optional): {"teams": [...]} with full team details
+//   - matches/<CODE>.json: a matches response ({"matches": [...]}) per competition code
+type Fixtures struct {
+	Competitions []footballdata.Competition
+	Teams        map[int]footballdata.Team
+	Matches      map[string][]footballdata.Match // keyed by competition code
+}
+
+// teamsFile wraps the optional teams fixture
+type teamsFile struct {
+	Teams []footballdata.Team `json:"teams"`
+}
+
+// DefaultFixtures returns the fixtures bundled with the fake server
+func DefaultFixtures() (*Fixtures, error) {
+	sub, err := fs.Sub(defaultFixtures, "fixtures")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bundled fixtures: %w", err)
+	}
+	return LoadFixtures(sub)
+}
+
+// LoadFixturesDir loads fixtures from a directory on disk
+func LoadFixturesDir(dir string) (*Fixtures, error) {
+	return LoadFixtures(os.DirFS(dir))
+}
+
+// LoadFixtures loads fixtures from the given filesystem
+func LoadFixtures(fsys fs.FS) (*Fixtures, error) {
+	fixtures := &Fixtures{
+		Teams:   make(map[int]footballdata.Team),
+		Matches: make(map[string][]footballdata.Match),
+	}
+
+	var competitions footballdata.CompetitionsResponse
+	if err := readJSON(fsys, "competitions.json", &competitions); err != nil {
+		return nil, err
+	}
+	fixtures.Competitions = competitions.Competitions
+
+	var teams teamsFile
+	if err := readJSON(fsys, "teams.json", &teams); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	for _, team := range teams.Teams {
+		fixtures.Teams[team.ID] = team
+	}
+
+	for _, comp := range fixtures.Competitions {
+		var matches footballdata.MatchesResponse
+		err := readJSON(fsys, path.Join("matches", comp.Code+".json"), &matches)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range matches.Matches {
+			match := &matches.Matches[i]
+			match.Competition = summarizeCompetition(comp)
+			if match.Season.ID == 0 {
+				match.Season = comp.CurrentSeason
+			}
+			fixtures.addTeam(match.HomeTeam)
+			fixtures.addTeam(match.AwayTeam)
+		}
+
+		sort.SliceStable(matches.Matches, func(i, j int) bool {
+			return matches.Matches[i].UTCDate.Before(matches.Matches[j].UTCDate)
+		})
+		fixtures.Matches[comp.Code] = matches.Matches
+	}
+
+	return fixtures, nil
+}
+
+// Competition finds a competition by code or numeric ID
+func (f *Fixtures) Competition(codeOrID string) (*footballdata.Competition, bool) {
+	for i := range f.Competitions {
+		comp := &f.Competitions[i]
+		if strings.EqualFold(comp.Code, codeOrID) || fmt.Sprint(comp.ID) == codeOrID {
+			return comp, true
+		}
+	}
+	return nil, false
+}
+
+// addTeam registers a team seen in a match unless full details are already known
+func (f *Fixtures) addTeam(team footballdata.Team) {
+	if _, ok := f.Teams[team.ID]; ok || team.ID == 0 {
+		return
+	}
+	f.Teams[team.ID] = team
+}
+
+// summarizeCompetition returns the reduced competition object embedded in matches
+func summarizeCompetition(comp footballdata.Competition) footballdata.Competition {
+	return footballdata.Competition{
+		ID:     comp.ID,
+		Name:   comp.Name,
+		Code:   comp.Code,
+		Type:   comp.Type,
+		Emblem: comp.Emblem,
+	}
+}
+
+// readJSON decodes a JSON fixture file
+func readJSON(fsys fs.FS, name string, v any) error {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return fmt.Errorf("failed to read fixture %s: %w", name, err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to parse fixture %s: %w", name, err)
+	}
+	return nil
+}
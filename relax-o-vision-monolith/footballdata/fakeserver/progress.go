@@ -0,0 +1,211 @@
+package fakeserver
+
+import (
+	"math"
+	"math/rand/v2"
+	"sort"
+	"time"
+
+	"github.com/edd/relaxovisionmonolith/footballdata"
+)
+
+// Match timeline relative to kickoff
+const (
+	firstHalfLength  = 45 * time.Minute
+	halfTimeBreak    = 15 * time.Minute
+	secondHalfLength = 50 * time.Minute // includes stoppage time
+	secondHalfStart  = firstHalfLength + halfTimeBreak
+	fullTimeAfter    = secondHalfStart + secondHalfLength
+)
+
+// Match statuses used by football-data.org
+const (
+	StatusScheduled = "SCHEDULED"
+	StatusInPlay    = "IN_PLAY"
+	StatusPaused    = "PAUSED"
+	StatusFinished  = "FINISHED"
+)
+
+// matchAt returns the state of a fixture match at the given simulated time.
+// Postponed, suspended and cancelled matches are returned unchanged.
+func matchAt(match footballdata.Match, now time.Time) footballdata.Match {
+	switch match.Status {
+	case "POSTPONED", "SUSPENDED", "CANCELLED", "AWARDED":
+		return match
+	}
+
+	elapsed := now.Sub(match.UTCDate)
+	if elapsed < 0 {
+		match.Status = StatusScheduled
+		match.Score = footballdata.Score{}
+		return match
+	}
+
+	homeGoals, awayGoals := goalTimeline(match)
+
+	var minute int
+	switch {
+	case elapsed < firstHalfLength:
+		match.Status = StatusInPlay
+		minute = int(elapsed/time.Minute) + 1
+	case elapsed < secondHalfStart:
+		match.Status = StatusPaused
+		minute = 45
+	case elapsed < fullTimeAfter:
+		match.Status = StatusInPlay
+		minute = min(45+int((elapsed-secondHalfStart)/time.Minute)+1, 90)
+	default:
+		match.Status = StatusFinished
+		minute = 90
+	}
+
+	home := goalsBy(homeGoals, minute)
+	away := goalsBy(awayGoals, minute)
+	match.Score = footballdata.Score{
+		Duration: "REGULAR",
+		FullTime: footballdata.ScoreData{Home: &home, Away: &away},
+	}
+	if minute >= 45 {
+		htHome := goalsBy(homeGoals, 45)
+		htAway := goalsBy(awayGoals, 45)
+		match.Score.HalfTime = footballdata.ScoreData{Home: &htHome, Away: &htAway}
+	}
+	if match.Status == StatusFinished {
+		match.Score.Winner = winnerOf(home, away)
+	}
+	match.LastUpdated = now
+
+	return match
+}
+
+// goalTimeline returns the minutes in which each side scores. Scores from the
+// fixture are honoured; matches without one get a deterministic result seeded
+// by the match ID so every request sees the same game.
+func goalTimeline(match footballdata.Match) (home, away []int) {
+	rng := rand.New(rand.NewPCG(uint64(match.ID), 0x5eed))
+
+	ft := match.Score.FullTime
+	ht := match.Score.HalfTime
+
+	var homeTotal, awayTotal int
+	if ft.Home != nil && ft.Away != nil {
+		homeTotal, awayTotal = *ft.Home, *ft.Away
+	} else {
+		homeTotal = poisson(rng, 1.5)
+		awayTotal = poisson(rng, 1.1)
+	}
+
+	homeFirstHalf, awayFirstHalf := -1, -1
+	if ht.Home != nil && ht.Away != nil {
+		homeFirstHalf, awayFirstHalf = *ht.Home, *ht.Away
+	}
+
+	return goalMinutes(rng, homeTotal, homeFirstHalf), goalMinutes(rng, awayTotal, awayFirstHalf)
+}
+
+// goalMinutes spreads total goals over 90 minutes. When firstHalf is known,
+// exactly that many goals land in the first 45 minutes.
+func goalMinutes(rng *rand.Rand, total, firstHalf int) []int {
+	minutes := make([]int, 0, total)
+	for i := 0; i < total; i++ {
+		switch {
+		case firstHalf < 0:
+			minutes = append(minutes, rng.IntN(90)+1)
+		case i < firstHalf:
+			minutes = append(minutes, rng.IntN(45)+1)
+		default:
+			minutes = append(minutes, rng.IntN(45)+46)
+		}
+	}
+	sort.Ints(minutes)
+	return minutes
+}
+
+// goalsBy counts goals scored up to and including the given minute
+func goalsBy(minutes []int, minute int) int {
+	count := 0
+	for _, m := range minutes {
+		if m <= minute {
+			count++
+		}
+	}
+	return count
+}
+
+// poisson draws from a Poisson distribution using Knuth's algorithm
+func poisson(rng *rand.Rand, lambda float64) int {
+	limit := math.Exp(-lambda)
+	k := 0
+	p := 1.0
+	for {
+		p *= rng.Float64()
+		if p <= limit {
+			return k
+		}
+		k++
+	}
+}
+
+// winnerOf returns the football-data.org winner value for a score
+func winnerOf(home, away int) string {
+	switch {
+	case home > away:
+		return "HOME_TEAM"
+	case away > home:
+		return "AWAY_TEAM"
+	default:
+		return "DRAW"
+	}
+}
+
+// currentMatchday returns the first matchday that still has unfinished matches
+func currentMatchday(matches []footballdata.Match) int {
+	current := 0
+	for _, match := range matches {
+		if match.Status != StatusFinished && match.Matchday > 0 {
+			if current == 0 || match.Matchday < current {
+				current = match.Matchday
+			}
+		}
+	}
+	if current == 0 {
+		for _, match := range matches {
+			current = max(current, match.Matchday)
+		}
+	}
+	return current
+}
+
+// buildStandings computes TOTAL standings tables from finished matches, one
+// table per group (or a single table for league competitions)
+func buildStandings(matches []footballdata.Match, teams map[int]footballdata.Team) []footballdata.StandingTable {
+	results := make([]footballdata.MatchResult, 0, len(matches))
+	for _, match := range matches {
+		result := footballdata.MatchResult{
+			MatchID:  match.ID,
+			UTCDate:  match.UTCDate,
+			Status:   match.Status,
+			Stage:    match.Stage,
+			Group:    match.Group,
+			HomeTeam: match.HomeTeam,
+			AwayTeam: match.AwayTeam,
+		}
+		if full, ok := teams[match.HomeTeam.ID]; ok {
+			result.HomeTeam = full
+		}
+		if full, ok := teams[match.AwayTeam.ID]; ok {
+			result.AwayTeam = full
+		}
+		if result.Group != nil && result.Stage == "" {
+			result.Stage = "GROUP_STAGE"
+		}
+		if match.Status == StatusFinished && match.Score.FullTime.Home != nil && match.Score.FullTime.Away != nil {
+			result.Finished = true
+			result.HomeGoals = *match.Score.FullTime.Home
+			result.AwayGoals = *match.Score.FullTime.Away
+		}
+		results = append(results, result)
+	}
+
+	return footballdata.BuildStandingTables(results, footballdata.TableOptions{Type: footballdata.TableTotal})
+}
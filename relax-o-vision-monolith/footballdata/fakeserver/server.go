@@ -0,0 +1,387 @@
+// Package fakeserver provides a stand-in for the football-data.org v4 API.
+//
+// It serves competitions, teams, matches and standings from fixture files,
+// enforces a per-token request budget with the same headers and 429 responses
+// as the real API, and moves matches through SCHEDULED, IN_PLAY and FINISHED
+// as its simulated clock advances.
+package fakeserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/edd/relaxovisionmonolith/footballdata"
+)
+
+// DefaultRequestsPerMinute matches the football-data.org free tier
+const DefaultRequestsPerMinute = 10
+
+// Config holds fake server configuration
+type Config struct {
+	// APIKey, when set, must be sent in X-Auth-Token by every request
+	APIKey string
+	// RequestsPerMinute is the per-token budget; zero or less disables limiting
+	RequestsPerMinute int
+	// Clock drives match progression; defaults to real time
+	Clock *Clock
+}
+
+// Server emulates the football-data.org API
+type Server struct {
+	fixtures *Fixtures
+	config   Config
+	clock    *Clock
+	mux      *http.ServeMux
+	now      func() time.Time // wall clock used for rate limiting
+
+	mu      sync.Mutex
+	windows map[string]*rateWindow
+}
+
+// rateWindow tracks requests made by a token within the current minute
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+// apiError mirrors the error body returned by football-data.org
+type apiError struct {
+	Message   string `json:"message"`
+	ErrorCode int    `json:"errorCode"`
+}
+
+// New creates a fake server backed by the given fixtures
+func New(fixtures *Fixtures, config Config) *Server {
+	clock := config.Clock
+	if clock == nil {
+		clock = NewClock(time.Now(), 1)
+	}
+
+	s := &Server{
+		fixtures: fixtures,
+		config:   config,
+		clock:    clock,
+		mux:      http.NewServeMux(),
+		now:      time.Now,
+		windows:  make(map[string]*rateWindow),
+	}
+
+	s.mux.HandleFunc("GET /v4/competitions", s.api(s.handleCompetitions))
+	s.mux.HandleFunc("GET /v4/competitions/{code}", s.api(s.handleCompetition))
+	s.mux.HandleFunc("GET /v4/competitions/{code}/matches", s.api(s.handleCompetitionMatches))
+	s.mux.HandleFunc("GET /v4/competitions/{code}/standings", s.api(s.handleStandings))
+	s.mux.HandleFunc("GET /v4/teams/{id}", s.api(s.handleTeam))
+	s.mux.HandleFunc("GET /v4/matches/{id}", s.api(s.handleMatch))
+
+	// Control endpoints are not rate limited
+	s.mux.HandleFunc("GET /_fake/clock", s.handleGetClock)
+	s.mux.HandleFunc("POST /_fake/clock", s.handleSetClock)
+
+	return s
+}
+
+// Clock returns the server's simulated clock
+func (s *Server) Clock() *Clock {
+	return s.clock
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// api wraps an API handler with authentication and rate limiting
+func (s *Server) api(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := r.Header.Get("X-Auth-Token")
+		if s.config.APIKey != "" && token != s.config.APIKey {
+			writeError(w, http.StatusForbidden, "The resource you are looking for is restricted. Please pass a valid API token and check your subscription for permission.")
+			return
+		}
+
+		w.Header().Set("X-API-Version", "v4")
+		if s.config.RequestsPerMinute > 0 {
+			remaining, reset, ok := s.take(token)
+			w.Header().Set("X-Requests-Available-Minute", strconv.Itoa(remaining))
+			w.Header().Set("X-RequestCounter-Reset", strconv.Itoa(reset))
+			if !ok {
+				slog.Debug("Fake football-data rate limit hit", "token", token, "reset", reset)
+				writeError(w, http.StatusTooManyRequests, fmt.Sprintf("You reached your request limit. Wait %d seconds.", reset))
+				return
+			}
+		}
+
+		next(w, r)
+	}
+}
+
+// take consumes one request from the token's budget. It returns the remaining
+// requests, the seconds until the counter resets and whether the request is allowed.
+func (s *Server) take(token string) (int, int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	window, ok := s.windows[token]
+	if !ok || now.Sub(window.start) >= time.Minute {
+		window = &rateWindow{start: now}
+		s.windows[token] = window
+	}
+
+	reset := int((time.Minute - now.Sub(window.start) + time.Second - 1) / time.Second)
+	if window.count >= s.config.RequestsPerMinute {
+		return 0, reset, false
+	}
+
+	window.count++
+	return s.config.RequestsPerMinute - window.count, reset, true
+}
+
+// handleCompetitions handles GET /v4/competitions
+func (s *Server) handleCompetitions(w http.ResponseWriter, r *http.Request) {
+	competitions := make([]footballdata.Competition, 0, len(s.fixtures.Competitions))
+	for _, comp := range s.fixtures.Competitions {
+		competitions = append(competitions, s.competitionAt(comp))
+	}
+
+	writeJSON(w, http.StatusOK, footballdata.CompetitionsResponse{
+		Count:        len(competitions),
+		Competitions: competitions,
+	})
+}
+
+// handleCompetition handles GET /v4/competitions/{code}
+func (s *Server) handleCompetition(w http.ResponseWriter, r *http.Request) {
+	comp, ok := s.fixtures.Competition(r.PathValue("code"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "The resource you are looking for does not exist.")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, s.competitionAt(*comp))
+}
+
+// handleCompetitionMatches handles GET /v4/competitions/{code}/matches
+func (s *Server) handleCompetitionMatches(w http.ResponseWriter, r *http.Request) {
+	comp, ok := s.fixtures.Competition(r.PathValue("code"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "The resource you are looking for does not exist.")
+		return
+	}
+
+	filter, err := parseMatchFilter(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var matches []footballdata.Match
+	for _, match := range s.matchesAt(comp.Code) {
+		if filter.matches(match) {
+			matches = append(matches, match)
+		}
+	}
+	if matches == nil {
+		matches = []footballdata.Match{}
+	}
+
+	writeJSON(w, http.StatusOK, footballdata.MatchesResponse{
+		Count:   len(matches),
+		Matches: matches,
+	})
+}
+
+// handleStandings handles GET /v4/competitions/{code}/standings
+func (s *Server) handleStandings(w http.ResponseWriter, r *http.Request) {
+	comp, ok := s.fixtures.Competition(r.PathValue("code"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "The resource you are looking for does not exist.")
+		return
+	}
+
+	current := s.competitionAt(*comp)
+	writeJSON(w, http.StatusOK, footballdata.Standing{
+		Competition: summarizeCompetition(current),
+		Season:      current.CurrentSeason,
+		Standings:   buildStandings(s.matchesAt(comp.Code), s.fixtures.Teams),
+	})
+}
+
+// handleTeam handles GET /v4/teams/{id}
+func (s *Server) handleTeam(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid team ID")
+		return
+	}
+
+	team, ok := s.fixtures.Teams[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, "The resource you are looking for does not exist.")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, team)
+}
+
+// handleMatch handles GET /v4/matches/{id}
+func (s *Server) handleMatch(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid match ID")
+		return
+	}
+
+	for code := range s.fixtures.Matches {
+		for _, match := range s.matchesAt(code) {
+			if match.ID == id {
+				writeJSON(w, http.StatusOK, match)
+				return
+			}
+		}
+	}
+
+	writeError(w, http.StatusNotFound, "The resource you are looking for does not exist.")
+}
+
+// handleGetClock handles GET /_fake/clock
+func (s *Server) handleGetClock(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"now":   s.clock.Now(),
+		"speed": s.clock.Speed(),
+	})
+}
+
+// handleSetClock handles POST /_fake/clock?set=<RFC3339> or ?advance=<duration>
+func (s *Server) handleSetClock(w http.ResponseWriter, r *http.Request) {
+	if value := r.URL.Query().Get("set"); value != "" {
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "set must be an RFC 3339 timestamp")
+			return
+		}
+		s.clock.Set(t)
+	}
+
+	if value := r.URL.Query().Get("advance"); value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "advance must be a duration such as 90m or 24h")
+			return
+		}
+		s.clock.Advance(d)
+	}
+
+	s.handleGetClock(w, r)
+}
+
+// matchesAt returns a competition's matches as they stand at the simulated time
+func (s *Server) matchesAt(code string) []footballdata.Match {
+	now := s.clock.Now()
+	fixtures := s.fixtures.Matches[code]
+
+	matches := make([]footballdata.Match, len(fixtures))
+	for i, match := range fixtures {
+		matches[i] = matchAt(match, now)
+	}
+	return matches
+}
+
+// competitionAt returns a competition with its current matchday brought up to date
+func (s *Server) competitionAt(comp footballdata.Competition) footballdata.Competition {
+	if matches := s.matchesAt(comp.Code); len(matches) > 0 {
+		comp.CurrentSeason.CurrentMatchday = currentMatchday(matches)
+	}
+	return comp
+}
+
+// matchFilter holds the supported /matches query filters
+type matchFilter struct {
+	statuses map[string]bool
+	matchday int
+	dateFrom time.Time
+	dateTo   time.Time
+}
+
+// parseMatchFilter parses status, matchday, dateFrom and dateTo query parameters
+func parseMatchFilter(r *http.Request) (*matchFilter, error) {
+	query := r.URL.Query()
+	filter := &matchFilter{}
+
+	if status := query.Get("status"); status != "" {
+		filter.statuses = make(map[string]bool)
+		for _, s := range strings.Split(status, ",") {
+			s = strings.ToUpper(strings.TrimSpace(s))
+			if s == "LIVE" {
+				// LIVE is shorthand for matches currently being played
+				filter.statuses[StatusInPlay] = true
+				filter.statuses[StatusPaused] = true
+				continue
+			}
+			filter.statuses[s] = true
+		}
+	}
+
+	if matchday := query.Get("matchday"); matchday != "" {
+		md, err := strconv.Atoi(matchday)
+		if err != nil {
+			return nil, fmt.Errorf("matchday must be a number")
+		}
+		filter.matchday = md
+	}
+
+	if dateFrom := query.Get("dateFrom"); dateFrom != "" {
+		t, err := time.Parse(time.DateOnly, dateFrom)
+		if err != nil {
+			return nil, fmt.Errorf("dateFrom must be formatted as YYYY-MM-DD")
+		}
+		filter.dateFrom = t
+	}
+
+	if dateTo := query.Get("dateTo"); dateTo != "" {
+		t, err := time.Parse(time.DateOnly, dateTo)
+		if err != nil {
+			return nil, fmt.Errorf("dateTo must be formatted as YYYY-MM-DD")
+		}
+		filter.dateTo = t.Add(24 * time.Hour)
+	}
+
+	return filter, nil
+}
+
+// matches reports whether a match passes the filter
+func (f *matchFilter) matches(match footballdata.Match) bool {
+	if f.statuses != nil && !f.statuses[match.Status] {
+		return false
+	}
+	if f.matchday != 0 && match.Matchday != f.matchday {
+		return false
+	}
+	if !f.dateFrom.IsZero() && match.UTCDate.Before(f.dateFrom) {
+		return false
+	}
+	if !f.dateTo.IsZero() && !match.UTCDate.Before(f.dateTo) {
+		return false
+	}
+	return true
+}
+
+// writeJSON writes a JSON response
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Error("Failed to encode fake football-data response", "error", err)
+	}
+}
+
+// writeError writes a football-data.org style error response
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, apiError{Message: message, ErrorCode: status})
+}
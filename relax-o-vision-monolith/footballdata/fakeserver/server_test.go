@@ -0,0 +1,223 @@
+package fakeserver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/edd/relaxovisionmonolith/footballdata"
+)
+
+var testStart = time.Date(2024, 9, 14, 12, 0, 0, 0, time.UTC)
+
+// newTestServer starts a fake server over the bundled fixtures with a frozen clock
+func newTestServer(t *testing.T, config Config) (*Server, *httptest.Server) {
+	t.Helper()
+
+	fixtures, err := DefaultFixtures()
+	if err != nil {
+		t.Fatalf("DefaultFixtures() error = %v", err)
+	}
+
+	if config.Clock == nil {
+		config.Clock = NewClock(testStart, 0)
+	}
+	fake := New(fixtures, config)
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	return fake, server
+}
+
+func TestServer_ClientRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	_, server := newTestServer(t, Config{})
+	client := footballdata.NewClientWithBaseURL("test-key", server.URL+"/v4")
+
+	competitions, err := client.GetCompetitions(context.Background())
+	if err != nil {
+		t.Fatalf("GetCompetitions() error = %v", err)
+	}
+	if len(competitions) != 1 || competitions[0].Code != "PL" {
+		t.Fatalf("GetCompetitions() = %+v, want the bundled PL competition", competitions)
+	}
+	if competitions[0].CurrentSeason.CurrentMatchday != 4 {
+		t.Errorf("CurrentMatchday = %d, want 4", competitions[0].CurrentSeason.CurrentMatchday)
+	}
+}
+
+func TestServer_MatchProgression(t *testing.T) {
+	t.Parallel()
+
+	fake, server := newTestServer(t, Config{})
+	ctx := context.Background()
+
+	// First match of matchday 4 kicks off at 14:00 UTC
+	steps := []struct {
+		at     time.Time
+		status string
+	}{
+		{at: time.Date(2024, 9, 14, 13, 0, 0, 0, time.UTC), status: StatusScheduled},
+		{at: time.Date(2024, 9, 14, 14, 30, 0, 0, time.UTC), status: StatusInPlay},
+		{at: time.Date(2024, 9, 14, 14, 50, 0, 0, time.UTC), status: StatusPaused},
+		{at: time.Date(2024, 9, 14, 15, 30, 0, 0, time.UTC), status: StatusInPlay},
+		{at: time.Date(2024, 9, 14, 16, 0, 0, 0, time.UTC), status: StatusFinished},
+	}
+
+	var lastHome, lastAway int
+	for _, step := range steps {
+		fake.Clock().Set(step.at)
+
+		// A fresh client per step avoids the client's own request throttling
+		client := footballdata.NewClientWithBaseURL("test-key", server.URL+"/v4")
+		matches, err := client.GetMatches(ctx, "PL")
+		if err != nil {
+			t.Fatalf("GetMatches() error = %v", err)
+		}
+
+		var match *footballdata.Match
+		for i := range matches {
+			if matches[i].Matchday == 4 && matches[i].UTCDate.Hour() == 14 {
+				match = &matches[i]
+			}
+		}
+		if match == nil {
+			t.Fatal("matchday 4 early kickoff not found")
+		}
+
+		if match.Status != step.status {
+			t.Errorf("at %s status = %s, want %s", step.at.Format(time.Kitchen), match.Status, step.status)
+		}
+
+		if step.status == StatusScheduled {
+			if match.Score.FullTime.Home != nil {
+				t.Errorf("scheduled match has a score")
+			}
+			continue
+		}
+
+		home, away := *match.Score.FullTime.Home, *match.Score.FullTime.Away
+		if home < lastHome || away < lastAway {
+			t.Errorf("score went backwards: %d-%d after %d-%d", home, away, lastHome, lastAway)
+		}
+		lastHome, lastAway = home, away
+
+		if step.status == StatusFinished && match.Score.Winner == "" {
+			t.Error("finished match has no winner")
+		}
+	}
+}
+
+func TestServer_RateLimit(t *testing.T) {
+	t.Parallel()
+
+	fake, server := newTestServer(t, Config{RequestsPerMinute: 2})
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	fake.now = func() time.Time { return now }
+
+	get := func() *http.Response {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+"/v4/competitions", nil)
+		req.Header.Set("X-Auth-Token", "test-key")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request error = %v", err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	for want := 1; want >= 0; want-- {
+		resp := get()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("status = %d, want 200", resp.StatusCode)
+		}
+		if got := resp.Header.Get("X-Requests-Available-Minute"); got != strconv.Itoa(want) {
+			t.Errorf("X-Requests-Available-Minute = %s, want %d", got, want)
+		}
+	}
+
+	resp := get()
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want 429", resp.StatusCode)
+	}
+	if got := resp.Header.Get("X-RequestCounter-Reset"); got != "60" {
+		t.Errorf("X-RequestCounter-Reset = %s, want 60", got)
+	}
+
+	now = now.Add(time.Minute)
+	if resp := get(); resp.StatusCode != http.StatusOK {
+		t.Errorf("status after reset = %d, want 200", resp.StatusCode)
+	}
+}
+
+func TestServer_RejectsWrongAPIKey(t *testing.T) {
+	t.Parallel()
+
+	_, server := newTestServer(t, Config{APIKey: "secret"})
+
+	_, err := footballdata.NewClientWithBaseURL("wrong", server.URL+"/v4").GetCompetitions(context.Background())
+	if err == nil {
+		t.Error("GetCompetitions() with wrong key succeeded, want error")
+	}
+
+	_, err = footballdata.NewClientWithBaseURL("secret", server.URL+"/v4").GetCompetitions(context.Background())
+	if err != nil {
+		t.Errorf("GetCompetitions() with correct key error = %v", err)
+	}
+}
+
+func TestServer_Standings(t *testing.T) {
+	t.Parallel()
+
+	_, server := newTestServer(t, Config{})
+	client := footballdata.NewClientWithBaseURL("test-key", server.URL+"/v4")
+
+	standing, err := client.GetStandings(context.Background(), "PL")
+	if err != nil {
+		t.Fatalf("GetStandings() error = %v", err)
+	}
+	if len(standing.Standings) != 1 {
+		t.Fatalf("got %d tables, want 1", len(standing.Standings))
+	}
+
+	want := []struct {
+		tla    string
+		points int
+	}{
+		{"ARS", 7}, {"MCI", 5}, {"LIV", 4}, {"CHE", 0},
+	}
+	table := standing.Standings[0].Table
+	for i, w := range want {
+		if table[i].Team.TLA != w.tla || table[i].Points != w.points {
+			t.Errorf("position %d = %s (%d pts), want %s (%d pts)", i+1, table[i].Team.TLA, table[i].Points, w.tla, w.points)
+		}
+		if table[i].PlayedGames != 3 {
+			t.Errorf("%s played %d games, want 3", table[i].Team.TLA, table[i].PlayedGames)
+		}
+	}
+}
+
+func TestGoalTimeline_HonoursFixtureScore(t *testing.T) {
+	t.Parallel()
+
+	fullHome, fullAway, halfHome, halfAway := 3, 1, 1, 1
+	match := footballdata.Match{
+		ID: 42,
+		Score: footballdata.Score{
+			FullTime: footballdata.ScoreData{Home: &fullHome, Away: &fullAway},
+			HalfTime: footballdata.ScoreData{Home: &halfHome, Away: &halfAway},
+		},
+	}
+
+	home, away := goalTimeline(match)
+	if len(home) != 3 || len(away) != 1 {
+		t.Fatalf("goalTimeline() = %v, %v, want 3 and 1 goals", home, away)
+	}
+	if goalsBy(home, 45) != 1 || goalsBy(away, 45) != 1 {
+		t.Errorf("half-time goals = %d-%d, want 1-1", goalsBy(home, 45), goalsBy(away, 45))
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"time"
 )
 
 // TeamForm represents recent form analysis for a team
@@ -30,37 +31,164 @@ func NewFormAnalyzer(db *sql.DB) *FormAnalyzer {
 	return &FormAnalyzer{db: db}
 }
 
+// formMatchLimit is the number of recent matches considered for form analysis
+const formMatchLimit = 10
+
+// teamResult is a finished match seen from one team's perspective
+type teamResult struct {
+	Home         bool
+	GoalsFor     int
+	GoalsAgainst int
+}
+
 // AnalyzeTeamForm analyzes recent form for a team
 func (f *FormAnalyzer) AnalyzeTeamForm(ctx context.Context, teamID int) (*TeamForm, error) {
-	form := &TeamForm{
-		TeamID: teamID,
-	}
+	return f.AnalyzeTeamFormAt(ctx, teamID, time.Now())
+}
 
-	// Get team name
-	err := f.db.QueryRowContext(ctx, `
-		SELECT (data->>'name')::text
-		FROM teams
-		WHERE id = $1
-	`, teamID).Scan(&form.TeamName)
+// AnalyzeTeamFormAt analyzes a team's form from matches played before the given time
+func (f *FormAnalyzer) AnalyzeTeamFormAt(ctx context.Context, teamID int, before time.Time) (*TeamForm, error) {
+	var teamName string
+	err := f.db.QueryRowContext(ctx, `SELECT name FROM teams WHERE id = $1`, teamID).Scan(&teamName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get team name: %w", err)
 	}
 
-	// Get last 5 matches (simplified - in real implementation would parse JSON)
-	// TODO: Implement actual form calculation from match history
-	// For now, return placeholder data
-	form.Last5Results = []string{"W", "D", "W", "L", "W"}
-	form.Last5GoalsFor = 8
-	form.Last5GoalsAgainst = 5
-	form.HomeForm = 2.1
-	form.AwayForm = 1.5
-	form.GoalScoringTrend = 0.3  // Increasing
-	form.DefensiveTrend = -0.1   // Slightly improving
-	form.FormScore = 0.72
+	// Most recent finished matches first; served by the home/away team date indexes
+	query := `
+		SELECT home_team_id, home_score_ft, away_score_ft
+		FROM matches
+		WHERE
+			status = 'FINISHED' AND
+			home_score_ft IS NOT NULL AND
+			away_score_ft IS NOT NULL AND
+			(home_team_id = $1 OR away_team_id = $1) AND
+			utc_date < $3
+		ORDER BY utc_date DESC
+		LIMIT $2
+	`
+
+	rows, err := f.db.QueryContext(ctx, query, teamID, formMatchLimit, before)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query recent matches: %w", err)
+	}
+	defer rows.Close()
+
+	var recent []teamResult
+	for rows.Next() {
+		var homeTeamID, homeScore, awayScore int
+		if err := rows.Scan(&homeTeamID, &homeScore, &awayScore); err != nil {
+			return nil, fmt.Errorf("failed to scan match: %w", err)
+		}
+
+		result := teamResult{Home: homeTeamID == teamID, GoalsFor: homeScore, GoalsAgainst: awayScore}
+		if !result.Home {
+			result.GoalsFor, result.GoalsAgainst = awayScore, homeScore
+		}
+		recent = append(recent, result)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read recent matches: %w", err)
+	}
+
+	// Reverse into chronological order (oldest first)
+	for i, j := 0, len(recent)-1; i < j; i, j = i+1, j-1 {
+		recent[i], recent[j] = recent[j], recent[i]
+	}
+
+	form := f.buildTeamForm(recent)
+	form.TeamID = teamID
+	form.TeamName = teamName
 
 	return form, nil
 }
 
+// buildTeamForm computes form metrics from results in chronological order
+func (f *FormAnalyzer) buildTeamForm(results []teamResult) *TeamForm {
+	form := &TeamForm{
+		Last5Results: []string{},
+	}
+
+	last5 := results
+	if len(last5) > 5 {
+		last5 = results[len(results)-5:]
+	}
+	previous := results[:len(results)-len(last5)]
+
+	for _, r := range last5 {
+		form.Last5Results = append(form.Last5Results, resultCode(r))
+		form.Last5GoalsFor += r.GoalsFor
+		form.Last5GoalsAgainst += r.GoalsAgainst
+	}
+
+	var homePoints, awayPoints, homeGames, awayGames int
+	for _, r := range results {
+		if r.Home {
+			homePoints += resultPoints(r)
+			homeGames++
+		} else {
+			awayPoints += resultPoints(r)
+			awayGames++
+		}
+	}
+	if homeGames > 0 {
+		form.HomeForm = float64(homePoints) / float64(homeGames)
+	}
+	if awayGames > 0 {
+		form.AwayForm = float64(awayPoints) / float64(awayGames)
+	}
+
+	// Trends compare the last five matches with the ones before them.
+	// A positive scoring trend and a negative defensive trend are improvements.
+	if len(last5) > 0 && len(previous) > 0 {
+		recentFor, recentAgainst := averageGoals(last5)
+		olderFor, olderAgainst := averageGoals(previous)
+		form.GoalScoringTrend = recentFor - olderFor
+		form.DefensiveTrend = recentAgainst - olderAgainst
+	}
+
+	form.FormScore = f.CalculateFormScore(form.Last5Results)
+	return form
+}
+
+// resultCode returns W, D or L for a result
+func resultCode(r teamResult) string {
+	switch {
+	case r.GoalsFor > r.GoalsAgainst:
+		return "W"
+	case r.GoalsFor == r.GoalsAgainst:
+		return "D"
+	default:
+		return "L"
+	}
+}
+
+// resultPoints returns league points earned by a result
+func resultPoints(r teamResult) int {
+	switch resultCode(r) {
+	case "W":
+		return 3
+	case "D":
+		return 1
+	default:
+		return 0
+	}
+}
+
+// averageGoals returns average goals scored and conceded per match
+func averageGoals(results []teamResult) (float64, float64) {
+	if len(results) == 0 {
+		return 0, 0
+	}
+	var goalsFor, goalsAgainst int
+	for _, r := range results {
+		goalsFor += r.GoalsFor
+		goalsAgainst += r.GoalsAgainst
+	}
+	n := float64(len(results))
+	return float64(goalsFor) / n, float64(goalsAgainst) / n
+}
+
 // CalculateFormScore calculates a weighted form score
 func (f *FormAnalyzer) CalculateFormScore(results []string) float64 {
 	if len(results) == 0 {
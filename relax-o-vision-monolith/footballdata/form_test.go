@@ -0,0 +1,68 @@
+package footballdata
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestFormAnalyzer_buildTeamForm(t *testing.T) {
+	t.Parallel()
+
+	analyzer := NewFormAnalyzer(nil)
+
+	// Chronological order, oldest first
+	results := []teamResult{
+		{Home: true, GoalsFor: 0, GoalsAgainst: 2},  // L
+		{Home: false, GoalsFor: 1, GoalsAgainst: 1}, // D
+		{Home: true, GoalsFor: 3, GoalsAgainst: 0},  // W
+		{Home: false, GoalsFor: 2, GoalsAgainst: 1}, // W
+		{Home: true, GoalsFor: 1, GoalsAgainst: 1},  // D
+		{Home: false, GoalsFor: 0, GoalsAgainst: 1}, // L
+		{Home: true, GoalsFor: 2, GoalsAgainst: 0},  // W
+	}
+
+	form := analyzer.buildTeamForm(results)
+
+	wantResults := []string{"W", "W", "D", "L", "W"}
+	if !reflect.DeepEqual(form.Last5Results, wantResults) {
+		t.Errorf("Last5Results = %v, want %v", form.Last5Results, wantResults)
+	}
+	if form.Last5GoalsFor != 8 || form.Last5GoalsAgainst != 3 {
+		t.Errorf("Last5 goals = %d-%d, want 8-3", form.Last5GoalsFor, form.Last5GoalsAgainst)
+	}
+
+	// Home: L, W, D, W = 7 points in 4 games; away: D, W, L = 4 points in 3 games
+	if !almostEqual(form.HomeForm, 7.0/4.0) {
+		t.Errorf("HomeForm = %v, want %v", form.HomeForm, 7.0/4.0)
+	}
+	if !almostEqual(form.AwayForm, 4.0/3.0) {
+		t.Errorf("AwayForm = %v, want %v", form.AwayForm, 4.0/3.0)
+	}
+
+	// Last five average 1.6 scored / 0.6 conceded vs 0.5 / 1.5 before
+	if !almostEqual(form.GoalScoringTrend, 1.1) {
+		t.Errorf("GoalScoringTrend = %v, want 1.1", form.GoalScoringTrend)
+	}
+	if !almostEqual(form.DefensiveTrend, -0.9) {
+		t.Errorf("DefensiveTrend = %v, want -0.9", form.DefensiveTrend)
+	}
+
+	if want := analyzer.CalculateFormScore(wantResults); form.FormScore != want {
+		t.Errorf("FormScore = %v, want %v", form.FormScore, want)
+	}
+}
+
+func TestFormAnalyzer_buildTeamForm_NoMatches(t *testing.T) {
+	t.Parallel()
+
+	form := NewFormAnalyzer(nil).buildTeamForm(nil)
+
+	if len(form.Last5Results) != 0 || form.FormScore != 0 || form.HomeForm != 0 || form.GoalScoringTrend != 0 {
+		t.Errorf("buildTeamForm(nil) = %+v, want zero form", form)
+	}
+}
+
+func almostEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
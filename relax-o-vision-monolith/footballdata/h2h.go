@@ -3,7 +3,6 @@ package footballdata
 import (
 	"context"
 	"database/sql"
-	"encoding/json"
 	"fmt"
 	"time"
 )
@@ -48,121 +47,87 @@ func NewH2HAnalyzer(db *sql.DB) *H2HAnalyzer {
 
 // AnalyzeHeadToHead analyzes head-to-head record between two teams
 func (h *H2HAnalyzer) AnalyzeHeadToHead(ctx context.Context, team1ID, team2ID int) (*HeadToHead, error) {
+	return h.AnalyzeHeadToHeadAt(ctx, team1ID, team2ID, time.Now())
+}
+
+// AnalyzeHeadToHeadAt analyzes the head-to-head record from matches played before the given time
+func (h *H2HAnalyzer) AnalyzeHeadToHeadAt(ctx context.Context, team1ID, team2ID int, before time.Time) (*HeadToHead, error) {
 	h2h := &HeadToHead{
 		Team1ID: team1ID,
 		Team2ID: team2ID,
 	}
 
 	// Get team names
-	var team1Data, team2Data []byte
-	err := h.db.QueryRowContext(ctx, `SELECT data FROM teams WHERE id = $1`, team1ID).Scan(&team1Data)
+	err := h.db.QueryRowContext(ctx, `SELECT name FROM teams WHERE id = $1`, team1ID).Scan(&h2h.Team1Name)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get team1: %w", err)
 	}
 
-	err = h.db.QueryRowContext(ctx, `SELECT data FROM teams WHERE id = $1`, team2ID).Scan(&team2Data)
+	err = h.db.QueryRowContext(ctx, `SELECT name FROM teams WHERE id = $1`, team2ID).Scan(&h2h.Team2Name)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get team2: %w", err)
 	}
 
-	var team1Map, team2Map map[string]interface{}
-	json.Unmarshal(team1Data, &team1Map)
-	json.Unmarshal(team2Data, &team2Map)
-
-	if name, ok := team1Map["name"].(string); ok {
-		h2h.Team1Name = name
-	}
-	if name, ok := team2Map["name"].(string); ok {
-		h2h.Team2Name = name
-	}
-
 	// Query historical matches between these teams
 	query := `
-		SELECT 
-			id, 
-			utc_date,
-			home_team,
-			away_team,
-			score,
-			competition
-		FROM matches
-		WHERE 
-			status = 'FINISHED' AND
+		SELECT
+			m.utc_date,
+			m.home_team_id,
+			m.away_team_id,
+			m.home_score_ft,
+			m.away_score_ft,
+			COALESCE(c.name, '')
+		FROM matches m
+		LEFT JOIN competitions c ON c.id = m.competition_id
+		WHERE
+			m.status = 'FINISHED' AND
+			m.home_score_ft IS NOT NULL AND
+			m.away_score_ft IS NOT NULL AND
 			(
-				(home_team->>'id')::int = $1 AND (away_team->>'id')::int = $2
+				(m.home_team_id = $1 AND m.away_team_id = $2)
 				OR
-				(home_team->>'id')::int = $2 AND (away_team->>'id')::int = $1
-			)
-		ORDER BY utc_date DESC
+				(m.home_team_id = $2 AND m.away_team_id = $1)
+			) AND
+			m.utc_date < $3
+		ORDER BY m.utc_date DESC
 		LIMIT 10
 	`
 
-	rows, err := h.db.QueryContext(ctx, query, team1ID, team2ID)
+	rows, err := h.db.QueryContext(ctx, query, team1ID, team2ID, before)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query h2h matches: %w", err)
 	}
 	defer rows.Close()
 
 	for rows.Next() {
-		var matchID int
-		var utcDate time.Time
-		var homeTeamJSON, awayTeamJSON, scoreJSON, competitionJSON []byte
-
-		err := rows.Scan(&matchID, &utcDate, &homeTeamJSON, &awayTeamJSON, &scoreJSON, &competitionJSON)
+		var summary MatchSummary
+		err := rows.Scan(
+			&summary.Date,
+			&summary.HomeTeamID,
+			&summary.AwayTeamID,
+			&summary.HomeScore,
+			&summary.AwayScore,
+			&summary.Competition,
+		)
 		if err != nil {
 			continue
 		}
 
-		var homeTeam, awayTeam, score, competition map[string]interface{}
-		json.Unmarshal(homeTeamJSON, &homeTeam)
-		json.Unmarshal(awayTeamJSON, &awayTeam)
-		json.Unmarshal(scoreJSON, &score)
-		json.Unmarshal(competitionJSON, &competition)
-
-		homeTeamID := int(homeTeam["id"].(float64))
-		awayTeamID := int(awayTeam["id"].(float64))
-
-		// Extract scores
-		var homeScore, awayScore int
-		if fullTime, ok := score["fullTime"].(map[string]interface{}); ok {
-			if hs, ok := fullTime["home"].(float64); ok {
-				homeScore = int(hs)
-			}
-			if as, ok := fullTime["away"].(float64); ok {
-				awayScore = int(as)
-			}
-		}
-
-		winner := "draw"
-		if homeScore > awayScore {
-			winner = "home"
-		} else if awayScore > homeScore {
-			winner = "away"
-		}
-
-		compName := ""
-		if name, ok := competition["name"].(string); ok {
-			compName = name
-		}
-
-		summary := MatchSummary{
-			Date:        utcDate,
-			HomeTeamID:  homeTeamID,
-			AwayTeamID:  awayTeamID,
-			HomeScore:   homeScore,
-			AwayScore:   awayScore,
-			Winner:      winner,
-			Competition: compName,
+		summary.Winner = "draw"
+		if summary.HomeScore > summary.AwayScore {
+			summary.Winner = "home"
+		} else if summary.AwayScore > summary.HomeScore {
+			summary.Winner = "away"
 		}
 
 		h2h.RecentMatches = append(h2h.RecentMatches, summary)
 		h2h.TotalMatches++
 
 		// Count wins/goals from team1's perspective
-		if homeTeamID == team1ID {
-			h2h.Team1Goals += homeScore
-			h2h.Team2Goals += awayScore
-			switch winner {
+		if summary.HomeTeamID == team1ID {
+			h2h.Team1Goals += summary.HomeScore
+			h2h.Team2Goals += summary.AwayScore
+			switch summary.Winner {
 			case "home":
 				h2h.Team1Wins++
 			case "away":
@@ -171,9 +136,9 @@ func (h *H2HAnalyzer) AnalyzeHeadToHead(ctx context.Context, team1ID, team2ID in
 				h2h.Draws++
 			}
 		} else {
-			h2h.Team1Goals += awayScore
-			h2h.Team2Goals += homeScore
-			switch winner {
+			h2h.Team1Goals += summary.AwayScore
+			h2h.Team2Goals += summary.HomeScore
+			switch summary.Winner {
 			case "away":
 				h2h.Team1Wins++
 			case "home":
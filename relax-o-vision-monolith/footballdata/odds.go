@@ -0,0 +1,315 @@
+package footballdata
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// FootballDataBookmaker names the odds delivered with football-data.org matches
+const FootballDataBookmaker = "football-data.org"
+
+// OddsSnapshot is one bookmaker's 1X2 decimal odds for a match at a point in time
+type OddsSnapshot struct {
+	MatchID   int    `json:"matchId"`
+	Bookmaker string `json:"bookmaker"`
+	Odds
+	CapturedAt time.Time `json:"capturedAt"`
+	Source     string    `json:"source,omitempty"`
+}
+
+// MarketProbabilities are outcome probabilities implied by bookmaker odds,
+// normalised to remove the margin
+type MarketProbabilities struct {
+	HomeWin    float64 `json:"homeWin"`
+	Draw       float64 `json:"draw"`
+	AwayWin    float64 `json:"awayWin"`
+	Margin     float64 `json:"margin"`               // bookmaker overround, e.g. 0.05 for 105%
+	Bookmakers int     `json:"bookmakers,omitempty"` // bookmakers averaged into a consensus
+}
+
+// BookmakerOdds holds a bookmaker's opening and closing odds for a match
+type BookmakerOdds struct {
+	Bookmaker string       `json:"bookmaker"`
+	Opening   OddsSnapshot `json:"opening"`
+	Closing   OddsSnapshot `json:"closing"` // latest snapshot before kickoff
+	Snapshots int          `json:"snapshots"`
+}
+
+// OddsHistory summarises a match's pre-kickoff odds across bookmakers
+type OddsHistory struct {
+	MatchID    int                  `json:"matchId"`
+	Kickoff    time.Time            `json:"kickoff"`
+	Bookmakers []BookmakerOdds      `json:"bookmakers"`
+	Opening    *MarketProbabilities `json:"opening,omitempty"` // consensus of opening odds
+	Closing    *MarketProbabilities `json:"closing,omitempty"` // consensus of closing odds
+}
+
+// Valid reports whether all three prices are usable decimal odds
+func (o Odds) Valid() bool {
+	return o.HomeWin > 1 && o.Draw > 1 && o.AwayWin > 1
+}
+
+// Implied converts decimal odds to margin-free probabilities, or nil for invalid odds
+func (o Odds) Implied() *MarketProbabilities {
+	if !o.Valid() {
+		return nil
+	}
+
+	home, draw, away := 1/o.HomeWin, 1/o.Draw, 1/o.AwayWin
+	total := home + draw + away
+	return &MarketProbabilities{
+		HomeWin:    home / total,
+		Draw:       draw / total,
+		AwayWin:    away / total,
+		Margin:     total - 1,
+		Bookmakers: 1,
+	}
+}
+
+// Probabilities returns the outcome probabilities without the margin
+func (m *MarketProbabilities) Probabilities() MatchProbabilities {
+	return MatchProbabilities{HomeWin: m.HomeWin, Draw: m.Draw, AwayWin: m.AwayWin}
+}
+
+// ConsensusProbabilities averages the implied probabilities of several bookmakers' odds
+func ConsensusProbabilities(snapshots []OddsSnapshot) *MarketProbabilities {
+	var consensus MarketProbabilities
+	for _, s := range snapshots {
+		implied := s.Implied()
+		if implied == nil {
+			continue
+		}
+		consensus.HomeWin += implied.HomeWin
+		consensus.Draw += implied.Draw
+		consensus.AwayWin += implied.AwayWin
+		consensus.Margin += implied.Margin
+		consensus.Bookmakers++
+	}
+	if consensus.Bookmakers == 0 {
+		return nil
+	}
+
+	n := float64(consensus.Bookmakers)
+	consensus.HomeWin /= n
+	consensus.Draw /= n
+	consensus.AwayWin /= n
+	consensus.Margin /= n
+	return &consensus
+}
+
+// summarizeOdds builds a match's odds history from its snapshots, ignoring
+// snapshots taken at or after kickoff
+func summarizeOdds(matchID int, kickoff time.Time, snapshots []OddsSnapshot) *OddsHistory {
+	history := &OddsHistory{MatchID: matchID, Kickoff: kickoff, Bookmakers: []BookmakerOdds{}}
+
+	index := make(map[string]int)
+	for _, s := range snapshots {
+		if !s.CapturedAt.Before(kickoff) || !s.Valid() {
+			continue
+		}
+
+		i, ok := index[s.Bookmaker]
+		if !ok {
+			index[s.Bookmaker] = len(history.Bookmakers)
+			history.Bookmakers = append(history.Bookmakers, BookmakerOdds{Bookmaker: s.Bookmaker, Opening: s, Closing: s, Snapshots: 1})
+			continue
+		}
+
+		b := &history.Bookmakers[i]
+		if s.CapturedAt.Before(b.Opening.CapturedAt) {
+			b.Opening = s
+		}
+		if !s.CapturedAt.Before(b.Closing.CapturedAt) {
+			b.Closing = s
+		}
+		b.Snapshots++
+	}
+
+	slices.SortFunc(history.Bookmakers, func(a, b BookmakerOdds) int {
+		return cmp.Compare(a.Bookmaker, b.Bookmaker)
+	})
+
+	opening := make([]OddsSnapshot, len(history.Bookmakers))
+	closing := make([]OddsSnapshot, len(history.Bookmakers))
+	for i, b := range history.Bookmakers {
+		opening[i], closing[i] = b.Opening, b.Closing
+	}
+	history.Opening = ConsensusProbabilities(opening)
+	history.Closing = ConsensusProbabilities(closing)
+
+	return history
+}
+
+// SaveOddsSnapshot stores a bookmaker's odds snapshot, replacing one taken at the same time
+func (r *Repository) SaveOddsSnapshot(ctx context.Context, s *OddsSnapshot) error {
+	query := `
+		INSERT INTO odds_snapshots (match_id, bookmaker, home_win, draw, away_win, captured_at, source)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (match_id, bookmaker, captured_at) DO UPDATE SET
+			home_win = EXCLUDED.home_win,
+			draw = EXCLUDED.draw,
+			away_win = EXCLUDED.away_win,
+			source = EXCLUDED.source
+	`
+
+	_, err := r.db.ExecContext(ctx, query,
+		s.MatchID,
+		s.Bookmaker,
+		s.HomeWin,
+		s.Draw,
+		s.AwayWin,
+		s.CapturedAt,
+		sql.NullString{String: s.Source, Valid: s.Source != ""},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save odds snapshot: %w", err)
+	}
+
+	return nil
+}
+
+// saveMatchOdds records the odds delivered with a match as a snapshot when
+// they have changed since the last one. Odds seen after kickoff are in-play
+// prices and are not recorded.
+func saveMatchOdds(ctx context.Context, tx *sql.Tx, match *Match, now time.Time) error {
+	if match.Odds == nil || !match.Odds.Valid() || !now.Before(match.UTCDate) {
+		return nil
+	}
+
+	query := `
+		INSERT INTO odds_snapshots (match_id, bookmaker, home_win, draw, away_win, captured_at, source)
+		SELECT $1, $2::varchar, $3::float8, $4::float8, $5::float8, $6, $2::varchar
+		WHERE NOT EXISTS (
+			SELECT 1 FROM (
+				SELECT home_win, draw, away_win FROM odds_snapshots
+				WHERE match_id = $1 AND bookmaker = $2::varchar
+				ORDER BY captured_at DESC
+				LIMIT 1
+			) latest
+			WHERE latest.home_win = $3::float8 AND latest.draw = $4::float8 AND latest.away_win = $5::float8
+		)
+		ON CONFLICT (match_id, bookmaker, captured_at) DO NOTHING
+	`
+
+	_, err := tx.ExecContext(ctx, query,
+		match.ID, FootballDataBookmaker, match.Odds.HomeWin, match.Odds.Draw, match.Odds.AwayWin, now)
+	if err != nil {
+		return fmt.Errorf("failed to save match odds: %w", err)
+	}
+
+	return nil
+}
+
+// GetOddsSnapshots returns all odds snapshots of a match in capture order
+func (r *Repository) GetOddsSnapshots(ctx context.Context, matchID int) ([]OddsSnapshot, error) {
+	query := `
+		SELECT match_id, bookmaker, home_win, draw, away_win, captured_at, COALESCE(source, '')
+		FROM odds_snapshots
+		WHERE match_id = $1
+		ORDER BY captured_at, bookmaker
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, matchID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query odds snapshots: %w", err)
+	}
+	defer rows.Close()
+
+	snapshots := []OddsSnapshot{}
+	for rows.Next() {
+		var s OddsSnapshot
+		if err := rows.Scan(&s.MatchID, &s.Bookmaker, &s.HomeWin, &s.Draw, &s.AwayWin, &s.CapturedAt, &s.Source); err != nil {
+			return nil, fmt.Errorf("failed to scan odds snapshot: %w", err)
+		}
+		snapshots = append(snapshots, s)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate odds snapshots: %w", err)
+	}
+
+	return snapshots, nil
+}
+
+// GetOddsHistory returns a match's opening and closing odds per bookmaker
+// together with the market consensus
+func (r *Repository) GetOddsHistory(ctx context.Context, matchID int) (*OddsHistory, error) {
+	var kickoff time.Time
+	err := r.db.QueryRowContext(ctx, `SELECT utc_date FROM matches WHERE id = $1`, matchID).Scan(&kickoff)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("match not found")
+		}
+		return nil, fmt.Errorf("failed to get match: %w", err)
+	}
+
+	snapshots, err := r.GetOddsSnapshots(ctx, matchID)
+	if err != nil {
+		return nil, err
+	}
+
+	return summarizeOdds(matchID, kickoff, snapshots), nil
+}
+
+// ClosingOdds returns each bookmaker's last odds before kickoff, for the
+// matches that have odds. For upcoming matches these are the current prices.
+func (r *Repository) ClosingOdds(ctx context.Context, matchIDs []int) (map[int][]OddsSnapshot, error) {
+	query := `
+		SELECT DISTINCT ON (o.match_id, o.bookmaker)
+		       o.match_id, o.bookmaker, o.home_win, o.draw, o.away_win, o.captured_at, COALESCE(o.source, '')
+		FROM odds_snapshots o
+		JOIN matches m ON m.id = o.match_id
+		WHERE o.match_id = ANY($1) AND o.captured_at < m.utc_date
+		ORDER BY o.match_id, o.bookmaker, o.captured_at DESC
+	`
+
+	ids := make([]int64, len(matchIDs))
+	for i, id := range matchIDs {
+		ids[i] = int64(id)
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query closing odds: %w", err)
+	}
+	defer rows.Close()
+
+	closing := make(map[int][]OddsSnapshot)
+	for rows.Next() {
+		var s OddsSnapshot
+		if err := rows.Scan(&s.MatchID, &s.Bookmaker, &s.HomeWin, &s.Draw, &s.AwayWin, &s.CapturedAt, &s.Source); err != nil {
+			return nil, fmt.Errorf("failed to scan closing odds: %w", err)
+		}
+		closing[s.MatchID] = append(closing[s.MatchID], s)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate closing odds: %w", err)
+	}
+
+	return closing, nil
+}
+
+// ClosingProbabilities returns the market consensus of each bookmaker's last
+// odds before kickoff, for the matches that have odds
+func (r *Repository) ClosingProbabilities(ctx context.Context, matchIDs []int) (map[int]MarketProbabilities, error) {
+	closing, err := r.ClosingOdds(ctx, matchIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	probabilities := make(map[int]MarketProbabilities, len(closing))
+	for matchID, snapshots := range closing {
+		if consensus := ConsensusProbabilities(snapshots); consensus != nil {
+			probabilities[matchID] = *consensus
+		}
+	}
+
+	return probabilities, nil
+}
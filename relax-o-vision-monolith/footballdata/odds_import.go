@@ -0,0 +1,141 @@
+package footballdata
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// oddsCSVColumns are the columns an odds CSV must have; source is optional
+var oddsCSVColumns = []string{"match_id", "bookmaker", "home_win", "draw", "away_win", "captured_at"}
+
+// ParseOddsJSON parses a JSON array of odds snapshots. Snapshots without a
+// source use defaultSource and snapshots without a capture time use now.
+func ParseOddsJSON(r io.Reader, defaultSource string, now time.Time) ([]OddsSnapshot, error) {
+	var snapshots []OddsSnapshot
+	if err := json.NewDecoder(r).Decode(&snapshots); err != nil {
+		return nil, fmt.Errorf("failed to decode odds: %w", err)
+	}
+
+	for i := range snapshots {
+		s := &snapshots[i]
+		if s.Source == "" {
+			s.Source = defaultSource
+		}
+		if s.CapturedAt.IsZero() {
+			s.CapturedAt = now
+		}
+		if err := validateOddsSnapshot(s); err != nil {
+			return nil, fmt.Errorf("record %d: %w", i+1, err)
+		}
+	}
+
+	return snapshots, nil
+}
+
+// ParseOddsCSV parses odds snapshots from CSV with a header row of
+// match_id, bookmaker, home_win, draw, away_win, captured_at (RFC 3339) and
+// an optional source column
+func ParseOddsCSV(r io.Reader, defaultSource string) ([]OddsSnapshot, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name != "source" && !slices.Contains(oddsCSVColumns, name) {
+			return nil, fmt.Errorf("unknown CSV column %q", name)
+		}
+		columns[name] = i
+	}
+	for _, name := range oddsCSVColumns {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("CSV is missing the %s column", name)
+		}
+	}
+
+	var snapshots []OddsSnapshot
+	for line := 2; ; line++ {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		cell := func(name string) string { return strings.TrimSpace(row[columns[name]]) }
+
+		s := OddsSnapshot{Bookmaker: cell("bookmaker"), Source: defaultSource}
+		if s.MatchID, err = strconv.Atoi(cell("match_id")); err != nil {
+			return nil, fmt.Errorf("line %d: invalid match_id %q", line, cell("match_id"))
+		}
+		for _, price := range []struct {
+			column string
+			target *float64
+		}{
+			{"home_win", &s.HomeWin},
+			{"draw", &s.Draw},
+			{"away_win", &s.AwayWin},
+		} {
+			if *price.target, err = strconv.ParseFloat(cell(price.column), 64); err != nil {
+				return nil, fmt.Errorf("line %d: invalid %s %q", line, price.column, cell(price.column))
+			}
+		}
+		if s.CapturedAt, err = time.Parse(time.RFC3339, cell("captured_at")); err != nil {
+			return nil, fmt.Errorf("line %d: invalid captured_at %q", line, cell("captured_at"))
+		}
+		if _, ok := columns["source"]; ok && cell("source") != "" {
+			s.Source = cell("source")
+		}
+		if err := validateOddsSnapshot(&s); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		snapshots = append(snapshots, s)
+	}
+
+	return snapshots, nil
+}
+
+// validateOddsSnapshot checks an imported snapshot is complete
+func validateOddsSnapshot(s *OddsSnapshot) error {
+	switch {
+	case s.MatchID == 0:
+		return fmt.Errorf("matchId is required")
+	case s.Bookmaker == "":
+		return fmt.Errorf("bookmaker is required")
+	case !s.Valid():
+		return fmt.Errorf("odds must all be greater than 1")
+	}
+	return nil
+}
+
+// ImportOddsSnapshots saves imported odds snapshots, continuing past records
+// that fail (such as unknown matches)
+func (r *Repository) ImportOddsSnapshots(ctx context.Context, snapshots []OddsSnapshot) *ImportSummary {
+	summary := &ImportSummary{}
+	for i := range snapshots {
+		if err := r.SaveOddsSnapshot(ctx, &snapshots[i]); err != nil {
+			slog.Error("Failed to import odds snapshot", "matchId", snapshots[i].MatchID, "bookmaker", snapshots[i].Bookmaker, "error", err)
+			summary.Failed++
+			summary.Errors = append(summary.Errors, fmt.Sprintf("match %d (%s): %v", snapshots[i].MatchID, snapshots[i].Bookmaker, err))
+			continue
+		}
+		summary.Imported++
+	}
+
+	slog.Info("Imported odds snapshots", "imported", summary.Imported, "failed", summary.Failed)
+	return summary
+}
@@ -0,0 +1,234 @@
+package footballdata
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOddsImplied(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		odds Odds
+		want *MarketProbabilities
+	}{
+		{name: "no odds", odds: Odds{}},
+		{name: "invalid odds", odds: Odds{HomeWin: 2, Draw: 0, AwayWin: 3}},
+		{
+			name: "fair book",
+			odds: Odds{HomeWin: 2, Draw: 4, AwayWin: 4},
+			want: &MarketProbabilities{HomeWin: 0.5, Draw: 0.25, AwayWin: 0.25},
+		},
+		{
+			name: "overround removed",
+			odds: Odds{HomeWin: 1.8, Draw: 3.6, AwayWin: 4.5},
+			want: &MarketProbabilities{HomeWin: 10.0 / 19, Draw: 5.0 / 19, AwayWin: 4.0 / 19, Margin: 1.0 / 18},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := tt.odds.Implied()
+			if tt.want == nil {
+				if got != nil {
+					t.Errorf("Implied() = %+v, want nil", got)
+				}
+				return
+			}
+			if got == nil {
+				t.Fatal("Implied() = nil")
+			}
+			assertMarketProbabilities(t, got, tt.want)
+			if got.Bookmakers != 1 {
+				t.Errorf("Bookmakers = %d, want 1", got.Bookmakers)
+			}
+		})
+	}
+}
+
+func TestSummarizeOdds(t *testing.T) {
+	t.Parallel()
+
+	kickoff := time.Date(2024, 3, 30, 15, 0, 0, 0, time.UTC)
+	snapshot := func(bookmaker string, hoursBefore int, home, draw, away float64) OddsSnapshot {
+		return OddsSnapshot{
+			MatchID:    7,
+			Bookmaker:  bookmaker,
+			Odds:       Odds{HomeWin: home, Draw: draw, AwayWin: away},
+			CapturedAt: kickoff.Add(-time.Duration(hoursBefore) * time.Hour),
+		}
+	}
+
+	history := summarizeOdds(7, kickoff, []OddsSnapshot{
+		snapshot("bet365", 72, 2.5, 3.4, 2.9),
+		snapshot("pinnacle", 48, 2, 4, 4),
+		snapshot("bet365", 24, 2, 4, 4),
+		snapshot("pinnacle", 2, 2.5, 5, 2.5),
+		snapshot("bet365", 0, 1.5, 4, 7),    // in play
+		snapshot("unibet", 12, 1.1, 0, 1.1), // invalid
+	})
+
+	if len(history.Bookmakers) != 2 {
+		t.Fatalf("got %d bookmakers, want 2: %+v", len(history.Bookmakers), history.Bookmakers)
+	}
+
+	bet365 := history.Bookmakers[0]
+	if bet365.Bookmaker != "bet365" || bet365.Snapshots != 2 {
+		t.Errorf("first bookmaker = %s with %d snapshots, want bet365 with 2", bet365.Bookmaker, bet365.Snapshots)
+	}
+	if bet365.Opening.HomeWin != 2.5 || bet365.Closing.HomeWin != 2 {
+		t.Errorf("bet365 opening/closing home = %v/%v, want 2.5/2", bet365.Opening.HomeWin, bet365.Closing.HomeWin)
+	}
+
+	// Closing: bet365 0.5/0.25/0.25 and pinnacle 0.4/0.2/0.4, both fair books
+	if history.Closing == nil {
+		t.Fatal("Closing = nil")
+	}
+	assertMarketProbabilities(t, history.Closing, &MarketProbabilities{HomeWin: 0.45, Draw: 0.225, AwayWin: 0.325})
+	if history.Closing.Bookmakers != 2 {
+		t.Errorf("Closing.Bookmakers = %d, want 2", history.Closing.Bookmakers)
+	}
+
+	if history.Opening == nil || history.Opening.HomeWin >= history.Closing.HomeWin {
+		t.Errorf("Opening = %+v, want a lower home probability than closing", history.Opening)
+	}
+}
+
+func TestSummarizeOddsWithoutSnapshots(t *testing.T) {
+	t.Parallel()
+
+	history := summarizeOdds(7, time.Now(), nil)
+	if len(history.Bookmakers) != 0 || history.Opening != nil || history.Closing != nil {
+		t.Errorf("summarizeOdds(nil) = %+v, want an empty history", history)
+	}
+}
+
+func TestParseOddsCSV(t *testing.T) {
+	t.Parallel()
+
+	input := `match_id,bookmaker,home_win,draw,away_win,captured_at,source
+101,bet365,2.10,3.40,3.60,2024-03-29T18:00:00Z,
+101,pinnacle,2.15,3.50,3.55,2024-03-30T14:00:00Z,oddsportal
+`
+
+	snapshots, err := ParseOddsCSV(strings.NewReader(input), "import")
+	if err != nil {
+		t.Fatalf("ParseOddsCSV: %v", err)
+	}
+	if len(snapshots) != 2 {
+		t.Fatalf("got %d snapshots, want 2", len(snapshots))
+	}
+
+	first := snapshots[0]
+	if first.MatchID != 101 || first.Bookmaker != "bet365" || first.Source != "import" {
+		t.Errorf("first snapshot = %+v", first)
+	}
+	if first.HomeWin != 2.10 || first.Draw != 3.40 || first.AwayWin != 3.60 {
+		t.Errorf("first odds = %+v, want 2.10/3.40/3.60", first.Odds)
+	}
+	if want := time.Date(2024, 3, 29, 18, 0, 0, 0, time.UTC); !first.CapturedAt.Equal(want) {
+		t.Errorf("CapturedAt = %v, want %v", first.CapturedAt, want)
+	}
+	if snapshots[1].Source != "oddsportal" {
+		t.Errorf("second source = %q, want oddsportal", snapshots[1].Source)
+	}
+}
+
+func TestParseOddsCSVErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		input   string
+		wantErr string
+	}{
+		{
+			name:    "missing column",
+			input:   "match_id,bookmaker,home_win,draw,away_win\n1,bet365,2,3,4\n",
+			wantErr: "missing the captured_at column",
+		},
+		{
+			name:    "unknown column",
+			input:   "match_id,bookmaker,home_win,draw,away_win,captured_at,over_2_5\n",
+			wantErr: `unknown CSV column "over_2_5"`,
+		},
+		{
+			name:    "invalid time",
+			input:   "match_id,bookmaker,home_win,draw,away_win,captured_at\n1,bet365,2,3,4,yesterday\n",
+			wantErr: `line 2: invalid captured_at "yesterday"`,
+		},
+		{
+			name:    "odds below one",
+			input:   "match_id,bookmaker,home_win,draw,away_win,captured_at\n1,bet365,2,0.9,4,2024-03-29T18:00:00Z\n",
+			wantErr: "line 2: odds must all be greater than 1",
+		},
+		{
+			name:    "missing bookmaker",
+			input:   "match_id,bookmaker,home_win,draw,away_win,captured_at\n1,,2,3,4,2024-03-29T18:00:00Z\n",
+			wantErr: "line 2: bookmaker is required",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := ParseOddsCSV(strings.NewReader(tt.input), "import")
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ParseOddsCSV() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseOddsJSON(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 3, 30, 12, 0, 0, 0, time.UTC)
+	input := `[
+		{"matchId": 101, "bookmaker": "bet365", "homeWin": 2.1, "draw": 3.4, "awayWin": 3.6},
+		{"matchId": 102, "bookmaker": "pinnacle", "homeWin": 1.5, "draw": 4.2, "awayWin": 6.5,
+		 "capturedAt": "2024-03-29T10:00:00Z", "source": "oddsportal"}
+	]`
+
+	snapshots, err := ParseOddsJSON(strings.NewReader(input), "import", now)
+	if err != nil {
+		t.Fatalf("ParseOddsJSON: %v", err)
+	}
+	if len(snapshots) != 2 {
+		t.Fatalf("got %d snapshots, want 2", len(snapshots))
+	}
+	if !snapshots[0].CapturedAt.Equal(now) || snapshots[0].Source != "import" || snapshots[0].HomeWin != 2.1 {
+		t.Errorf("first snapshot = %+v, want defaults applied", snapshots[0])
+	}
+	if snapshots[1].Source != "oddsportal" || snapshots[1].CapturedAt.Equal(now) {
+		t.Errorf("second snapshot = %+v, want its own source and time", snapshots[1])
+	}
+
+	if _, err := ParseOddsJSON(strings.NewReader(`[{"bookmaker": "bet365", "homeWin": 2, "draw": 3, "awayWin": 4}]`), "import", now); err == nil {
+		t.Error("ParseOddsJSON() without matchId succeeded")
+	}
+}
+
+func assertMarketProbabilities(t *testing.T, got, want *MarketProbabilities) {
+	t.Helper()
+
+	for _, c := range []struct {
+		name      string
+		got, want float64
+	}{
+		{"home win", got.HomeWin, want.HomeWin},
+		{"draw", got.Draw, want.Draw},
+		{"away win", got.AwayWin, want.AwayWin},
+		{"margin", got.Margin, want.Margin},
+	} {
+		if math.Abs(c.got-c.want) > 1e-9 {
+			t.Errorf("%s = %v, want %v", c.name, c.got, c.want)
+		}
+	}
+}
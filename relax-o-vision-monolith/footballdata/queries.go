@@ -0,0 +1,306 @@
+package footballdata
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	// DefaultPageSize is the number of items returned when no limit is given
+	DefaultPageSize = 50
+	// MaxPageSize caps the limit of a list query
+	MaxPageSize = 200
+)
+
+// Match list sort orders
+const (
+	SortDateAsc  = "utcDate"
+	SortDateDesc = "-utcDate"
+)
+
+// ErrInvalidCursor is returned for a cursor that was not issued by a list query
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Page is one page of a list query. NextCursor is empty on the last page.
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// MatchFilter selects matches to list. Zero values don't filter.
+type MatchFilter struct {
+	CompetitionID int
+	TeamID        int // home or away
+	Status        []string
+	Matchday      int
+	SeasonID      int
+	From          time.Time // inclusive
+	To            time.Time // exclusive
+	Sort          string    // SortDateAsc (default) or SortDateDesc
+	Limit         int
+	Cursor        string
+}
+
+// TeamFilter selects teams to list, ordered by name
+type TeamFilter struct {
+	CompetitionID int // teams with a stored match in the competition
+	NamePrefix    string
+	Limit         int
+	Cursor        string
+}
+
+// CompetitionFilter selects competitions to list, ordered by name
+type CompetitionFilter struct {
+	Type     string // LEAGUE or CUP
+	AreaCode string
+	Limit    int
+	Cursor   string
+}
+
+// cursor is the keyset position after the last item of a page
+type cursor struct {
+	Value string `json:"v"`
+	ID    int    `json:"id"`
+}
+
+// encodeCursor returns an opaque cursor for the item with the given sort value and ID
+func encodeCursor(value string, id int) string {
+	data, _ := json.Marshal(cursor{Value: value, ID: id})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor parses a cursor returned by encodeCursor
+func decodeCursor(s string) (*cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c cursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// pageLimit clamps a requested page size
+func pageLimit(limit int) int {
+	if limit <= 0 {
+		return DefaultPageSize
+	}
+	return min(limit, MaxPageSize)
+}
+
+// whereBuilder collects SQL conditions with numbered placeholders
+type whereBuilder struct {
+	conditions []string
+	args       []any
+}
+
+// add appends a condition; each %s in it is replaced by the next placeholder
+func (w *whereBuilder) add(condition string, args ...any) {
+	placeholders := make([]any, len(args))
+	for i, arg := range args {
+		w.args = append(w.args, arg)
+		placeholders[i] = fmt.Sprintf("$%d", len(w.args))
+	}
+	w.conditions = append(w.conditions, fmt.Sprintf(condition, placeholders...))
+}
+
+// String returns the WHERE clause, or an empty string without conditions
+func (w *whereBuilder) String() string {
+	if len(w.conditions) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(w.conditions, " AND ")
+}
+
+// ListMatches lists stored matches matching a filter, ordered by kickoff
+func (r *Repository) ListMatches(ctx context.Context, filter MatchFilter) (*Page[Match], error) {
+	var where whereBuilder
+	if filter.CompetitionID != 0 {
+		where.add("competition_id = %s", filter.CompetitionID)
+	}
+	if filter.TeamID != 0 {
+		where.add("(home_team_id = %s OR away_team_id = %s)", filter.TeamID, filter.TeamID)
+	}
+	if len(filter.Status) > 0 {
+		where.add("status = ANY(%s)", pq.Array(filter.Status))
+	}
+	if filter.Matchday != 0 {
+		where.add("matchday = %s", filter.Matchday)
+	}
+	if filter.SeasonID != 0 {
+		where.add("season_id = %s", filter.SeasonID)
+	}
+	if !filter.From.IsZero() {
+		where.add("utc_date >= %s", filter.From)
+	}
+	if !filter.To.IsZero() {
+		where.add("utc_date < %s", filter.To)
+	}
+
+	order, comparison := "ASC", ">"
+	switch filter.Sort {
+	case "", SortDateAsc:
+	case SortDateDesc:
+		order, comparison = "DESC", "<"
+	default:
+		return nil, fmt.Errorf("sort must be %s or %s", SortDateAsc, SortDateDesc)
+	}
+
+	if filter.Cursor != "" {
+		c, err := decodeCursor(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		after, err := time.Parse(time.RFC3339Nano, c.Value)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		where.add("(utc_date, id) "+comparison+" (%s, %s)", after, c.ID)
+	}
+
+	limit := pageLimit(filter.Limit)
+	query := `SELECT ` + matchColumns + ` FROM matches` + where.String() +
+		fmt.Sprintf(" ORDER BY utc_date %s, id %s LIMIT %d", order, order, limit+1)
+
+	rows, err := r.db.QueryContext(ctx, query, where.args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list matches: %w", err)
+	}
+	defer rows.Close()
+
+	page := &Page[Match]{Items: []Match{}}
+	for rows.Next() {
+		match, err := scanMatch(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan match: %w", err)
+		}
+		page.Items = append(page.Items, *match)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate matches: %w", err)
+	}
+
+	if len(page.Items) > limit {
+		page.Items = page.Items[:limit]
+		last := page.Items[limit-1]
+		page.NextCursor = encodeCursor(last.UTCDate.Format(time.RFC3339Nano), last.ID)
+	}
+
+	return page, nil
+}
+
+// ListTeams lists stored teams matching a filter, ordered by name
+func (r *Repository) ListTeams(ctx context.Context, filter TeamFilter) (*Page[Team], error) {
+	var where whereBuilder
+	if filter.CompetitionID != 0 {
+		where.add(`EXISTS (
+			SELECT 1 FROM matches m
+			WHERE m.competition_id = %s AND (m.home_team_id = teams.id OR m.away_team_id = teams.id)
+		)`, filter.CompetitionID)
+	}
+	if filter.NamePrefix != "" {
+		where.add("(name ILIKE %s OR short_name ILIKE %s)", likePrefix(filter.NamePrefix), likePrefix(filter.NamePrefix))
+	}
+	if filter.Cursor != "" {
+		c, err := decodeCursor(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		where.add("(COALESCE(name, ''), id) > (%s, %s)", c.Value, c.ID)
+	}
+
+	limit := pageLimit(filter.Limit)
+	query := `SELECT ` + teamColumns + ` FROM teams` + where.String() +
+		fmt.Sprintf(" ORDER BY COALESCE(name, ''), id LIMIT %d", limit+1)
+
+	rows, err := r.db.QueryContext(ctx, query, where.args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list teams: %w", err)
+	}
+	defer rows.Close()
+
+	page := &Page[Team]{Items: []Team{}}
+	for rows.Next() {
+		team, err := scanTeam(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan team: %w", err)
+		}
+		page.Items = append(page.Items, *team)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate teams: %w", err)
+	}
+
+	if len(page.Items) > limit {
+		page.Items = page.Items[:limit]
+		last := page.Items[limit-1]
+		page.NextCursor = encodeCursor(last.Name, last.ID)
+	}
+
+	return page, nil
+}
+
+// ListCompetitions lists stored competitions matching a filter, ordered by name
+func (r *Repository) ListCompetitions(ctx context.Context, filter CompetitionFilter) (*Page[Competition], error) {
+	var where whereBuilder
+	if filter.Type != "" {
+		where.add("type = %s", filter.Type)
+	}
+	if filter.AreaCode != "" {
+		where.add("area->>'code' = %s", filter.AreaCode)
+	}
+	if filter.Cursor != "" {
+		c, err := decodeCursor(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		where.add("(name, id) > (%s, %s)", c.Value, c.ID)
+	}
+
+	limit := pageLimit(filter.Limit)
+	query := `SELECT ` + competitionColumns + ` FROM competitions` + where.String() +
+		fmt.Sprintf(" ORDER BY name, id LIMIT %d", limit+1)
+
+	rows, err := r.db.QueryContext(ctx, query, where.args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list competitions: %w", err)
+	}
+	defer rows.Close()
+
+	page := &Page[Competition]{Items: []Competition{}}
+	for rows.Next() {
+		comp, err := scanCompetition(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan competition: %w", err)
+		}
+		page.Items = append(page.Items, *comp)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate competitions: %w", err)
+	}
+
+	if len(page.Items) > limit {
+		page.Items = page.Items[:limit]
+		last := page.Items[limit-1]
+		page.NextCursor = encodeCursor(last.Name, last.ID)
+	}
+
+	return page, nil
+}
+
+// likePrefix escapes LIKE wildcards in a prefix and appends %
+func likePrefix(prefix string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(prefix) + "%"
+}
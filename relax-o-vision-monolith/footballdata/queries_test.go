@@ -0,0 +1,74 @@
+package footballdata
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	encoded := encodeCursor("2024-08-17T14:00:00Z", 497401)
+
+	c, err := decodeCursor(encoded)
+	if err != nil {
+		t.Fatalf("decodeCursor() error = %v", err)
+	}
+	if c.Value != "2024-08-17T14:00:00Z" || c.ID != 497401 {
+		t.Errorf("decodeCursor() = %+v", c)
+	}
+
+	for _, invalid := range []string{"not base64!", "bm90IGpzb24"} {
+		if _, err := decodeCursor(invalid); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("decodeCursor(%q) error = %v, want ErrInvalidCursor", invalid, err)
+		}
+	}
+}
+
+func TestWhereBuilder(t *testing.T) {
+	t.Parallel()
+
+	var empty whereBuilder
+	if got := empty.String(); got != "" {
+		t.Errorf("empty String() = %q, want empty", got)
+	}
+
+	var where whereBuilder
+	where.add("competition_id = %s", 2021)
+	where.add("(home_team_id = %s OR away_team_id = %s)", 57, 57)
+
+	want := " WHERE competition_id = $1 AND (home_team_id = $2 OR away_team_id = $3)"
+	if got := where.String(); got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+	if len(where.args) != 3 {
+		t.Errorf("got %d args, want 3", len(where.args))
+	}
+}
+
+func TestPageLimit(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		limit, want int
+	}{
+		{0, DefaultPageSize},
+		{-5, DefaultPageSize},
+		{20, 20},
+		{MaxPageSize + 1, MaxPageSize},
+	}
+
+	for _, tt := range tests {
+		if got := pageLimit(tt.limit); got != tt.want {
+			t.Errorf("pageLimit(%d) = %d, want %d", tt.limit, got, tt.want)
+		}
+	}
+}
+
+func TestLikePrefix(t *testing.T) {
+	t.Parallel()
+
+	if got, want := likePrefix(`Man_100%\`), `Man\_100\%\\%`; got != want {
+		t.Errorf("likePrefix() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,226 @@
+package footballdata
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// RefereeTypeMain is the referee type of the match referee (as opposed to assistants and VAR)
+const RefereeTypeMain = "REFEREE"
+
+// RefereeProfile aggregates the finished matches a referee officiated.
+// Card figures only cover matches whose imported statistics have card counts.
+type RefereeProfile struct {
+	Referee            Referee   `json:"referee"`
+	AsOf               time.Time `json:"asOf"`
+	Matches            int       `json:"matches"`
+	HomeWins           int       `json:"homeWins"`
+	Draws              int       `json:"draws"`
+	AwayWins           int       `json:"awayWins"`
+	HomeWinRate        float64   `json:"homeWinRate"`
+	DrawRate           float64   `json:"drawRate"`
+	AwayWinRate        float64   `json:"awayWinRate"`
+	GoalsPerGame       float64   `json:"goalsPerGame"`
+	CardMatches        int       `json:"cardMatches"`
+	YellowCardsPerGame float64   `json:"yellowCardsPerGame"`
+	RedCardsPerGame    float64   `json:"redCardsPerGame"`
+}
+
+// refereeMatch is one finished match used for a referee profile
+type refereeMatch struct {
+	HomeGoals   int
+	AwayGoals   int
+	HasCards    bool
+	YellowCards float64 // both teams
+	RedCards    float64
+}
+
+// saveMatchReferees upserts a match's officials into referees and match_referees
+// within the transaction saving the match
+func saveMatchReferees(ctx context.Context, tx *sql.Tx, match *Match) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM match_referees WHERE match_id = $1`, match.ID); err != nil {
+		return fmt.Errorf("failed to clear match referees: %w", err)
+	}
+
+	for _, referee := range match.Referees {
+		if referee.ID == 0 {
+			continue
+		}
+
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO referees (id, name, nationality, updated_at)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (id) DO UPDATE SET
+				name = EXCLUDED.name,
+				nationality = COALESCE(EXCLUDED.nationality, referees.nationality),
+				updated_at = EXCLUDED.updated_at
+		`, referee.ID, referee.Name, sql.NullString{String: referee.Nationality, Valid: referee.Nationality != ""}, time.Now())
+		if err != nil {
+			return fmt.Errorf("failed to save referee: %w", err)
+		}
+
+		refereeType := referee.Type
+		if refereeType == "" {
+			refereeType = RefereeTypeMain
+		}
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO match_referees (match_id, referee_id, type)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (match_id, referee_id) DO UPDATE SET type = EXCLUDED.type
+		`, match.ID, referee.ID, refereeType)
+		if err != nil {
+			return fmt.Errorf("failed to save match referee: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// GetReferee retrieves a referee by ID
+func (r *Repository) GetReferee(ctx context.Context, id int) (*Referee, error) {
+	query := `SELECT id, name, COALESCE(nationality, '') FROM referees WHERE id = $1`
+
+	var referee Referee
+	err := r.db.QueryRowContext(ctx, query, id).Scan(&referee.ID, &referee.Name, &referee.Nationality)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("referee not found")
+		}
+		return nil, fmt.Errorf("failed to get referee: %w", err)
+	}
+
+	return &referee, nil
+}
+
+// GetMatchReferee retrieves the main referee assigned to a match
+func (r *Repository) GetMatchReferee(ctx context.Context, matchID int) (*Referee, error) {
+	query := `
+		SELECT rf.id, rf.name, COALESCE(rf.nationality, ''), mr.type
+		FROM match_referees mr
+		JOIN referees rf ON rf.id = mr.referee_id
+		WHERE mr.match_id = $1 AND mr.type = $2
+	`
+
+	var referee Referee
+	err := r.db.QueryRowContext(ctx, query, matchID, RefereeTypeMain).Scan(
+		&referee.ID, &referee.Name, &referee.Nationality, &referee.Type,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("match referee not found")
+		}
+		return nil, fmt.Errorf("failed to get match referee: %w", err)
+	}
+
+	return &referee, nil
+}
+
+// GetRefereeProfile aggregates the finished matches a referee officiated
+// as main referee that kicked off before the given time
+func (r *Repository) GetRefereeProfile(ctx context.Context, refereeID int, before time.Time) (*RefereeProfile, error) {
+	referee, err := r.GetReferee(ctx, refereeID)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT m.home_score_ft, m.away_score_ft, COALESCE(` + hasCardsColumn + `, FALSE),
+		       COALESCE(s.home_yellow_cards + s.away_yellow_cards, 0),
+		       COALESCE(s.home_red_cards + s.away_red_cards, 0)
+		FROM match_referees mr
+		JOIN matches m ON m.id = mr.match_id
+		LEFT JOIN match_statistics s ON s.match_id = m.id
+		WHERE mr.referee_id = $1
+		  AND mr.type = $2
+		  AND m.status = 'FINISHED'
+		  AND m.home_score_ft IS NOT NULL
+		  AND m.away_score_ft IS NOT NULL
+		  AND m.utc_date < $3
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, refereeID, RefereeTypeMain, before)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query referee matches: %w", err)
+	}
+	defer rows.Close()
+
+	var matches []refereeMatch
+	for rows.Next() {
+		var m refereeMatch
+		if err := rows.Scan(&m.HomeGoals, &m.AwayGoals, &m.HasCards, &m.YellowCards, &m.RedCards); err != nil {
+			return nil, fmt.Errorf("failed to scan referee match: %w", err)
+		}
+		matches = append(matches, m)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate referee matches: %w", err)
+	}
+
+	profile := aggregateRefereeProfile(*referee, matches)
+	profile.AsOf = before
+	return profile, nil
+}
+
+// GetMatchRefereeProfile returns the profile of a match's referee as it stood before kickoff
+func (r *Repository) GetMatchRefereeProfile(ctx context.Context, matchID int) (*RefereeProfile, error) {
+	referee, err := r.GetMatchReferee(ctx, matchID)
+	if err != nil {
+		return nil, err
+	}
+
+	var kickoff time.Time
+	if err := r.db.QueryRowContext(ctx, `SELECT utc_date FROM matches WHERE id = $1`, matchID).Scan(&kickoff); err != nil {
+		return nil, fmt.Errorf("failed to get match date: %w", err)
+	}
+
+	profile, err := r.GetRefereeProfile(ctx, referee.ID, kickoff)
+	if err != nil {
+		return nil, err
+	}
+	profile.Referee.Type = referee.Type
+	return profile, nil
+}
+
+// aggregateRefereeProfile computes outcome rates, scoring and cards per game
+func aggregateRefereeProfile(referee Referee, matches []refereeMatch) *RefereeProfile {
+	profile := &RefereeProfile{Referee: referee, Matches: len(matches)}
+	if len(matches) == 0 {
+		return profile
+	}
+
+	var goals int
+	var yellow, red float64
+	for _, m := range matches {
+		goals += m.HomeGoals + m.AwayGoals
+		switch {
+		case m.HomeGoals > m.AwayGoals:
+			profile.HomeWins++
+		case m.HomeGoals < m.AwayGoals:
+			profile.AwayWins++
+		default:
+			profile.Draws++
+		}
+
+		if m.HasCards {
+			profile.CardMatches++
+			yellow += m.YellowCards
+			red += m.RedCards
+		}
+	}
+
+	n := float64(len(matches))
+	profile.HomeWinRate = float64(profile.HomeWins) / n
+	profile.DrawRate = float64(profile.Draws) / n
+	profile.AwayWinRate = float64(profile.AwayWins) / n
+	profile.GoalsPerGame = float64(goals) / n
+
+	if profile.CardMatches > 0 {
+		profile.YellowCardsPerGame = yellow / float64(profile.CardMatches)
+		profile.RedCardsPerGame = red / float64(profile.CardMatches)
+	}
+
+	return profile
+}
@@ -0,0 +1,105 @@
+package footballdata
+
+import "testing"
+
+func TestAggregateRefereeProfile(t *testing.T) {
+	t.Parallel()
+
+	referee := Referee{ID: 11, Name: "Anthony Taylor"}
+	matches := []refereeMatch{
+		{HomeGoals: 2, AwayGoals: 1, HasCards: true, YellowCards: 5, RedCards: 1},
+		{HomeGoals: 0, AwayGoals: 0, HasCards: true, YellowCards: 3},
+		{HomeGoals: 1, AwayGoals: 3},
+		{HomeGoals: 1, AwayGoals: 0},
+	}
+
+	profile := aggregateRefereeProfile(referee, matches)
+
+	if profile.Matches != 4 || profile.HomeWins != 2 || profile.Draws != 1 || profile.AwayWins != 1 {
+		t.Errorf("record = %d matches %d-%d-%d, want 4 matches 2-1-1", profile.Matches, profile.HomeWins, profile.Draws, profile.AwayWins)
+	}
+
+	checks := []struct {
+		name      string
+		got, want float64
+	}{
+		{"home win rate", profile.HomeWinRate, 0.5},
+		{"draw rate", profile.DrawRate, 0.25},
+		{"away win rate", profile.AwayWinRate, 0.25},
+		{"goals per game", profile.GoalsPerGame, 2},
+		{"yellow cards per game", profile.YellowCardsPerGame, 4},
+		{"red cards per game", profile.RedCardsPerGame, 0.5},
+	}
+	for _, c := range checks {
+		if !almostEqual(c.got, c.want) {
+			t.Errorf("%s = %v, want %v", c.name, c.got, c.want)
+		}
+	}
+	if profile.CardMatches != 2 {
+		t.Errorf("card matches = %d, want 2", profile.CardMatches)
+	}
+
+	if empty := aggregateRefereeProfile(referee, nil); empty.Matches != 0 || empty.HomeWinRate != 0 || empty.Referee.ID != referee.ID {
+		t.Errorf("empty profile = %+v", empty)
+	}
+}
+
+func TestAggregateRefereeProfile_Cards(t *testing.T) {
+	t.Parallel()
+
+	referee := Referee{ID: 11, Name: "Anthony Taylor"}
+
+	tests := []struct {
+		name                string
+		matches             []refereeMatch
+		wantCardMatches     int
+		wantYellow, wantRed float64
+	}{
+		{
+			name: "mixed card data",
+			matches: []refereeMatch{
+				{HomeGoals: 1, AwayGoals: 0, HasCards: true, YellowCards: 6, RedCards: 1},
+				{HomeGoals: 2, AwayGoals: 2},
+				{HomeGoals: 0, AwayGoals: 1, HasCards: true, YellowCards: 2},
+				{HomeGoals: 3, AwayGoals: 1},
+			},
+			wantCardMatches: 2,
+			wantYellow:      4,
+			wantRed:         0.5,
+		},
+		{
+			// A match with no cards shown counts, unlike one without card data
+			name: "no cards shown",
+			matches: []refereeMatch{
+				{HomeGoals: 1, AwayGoals: 1, HasCards: true},
+				{HomeGoals: 0, AwayGoals: 0, HasCards: true, YellowCards: 4},
+				{HomeGoals: 2, AwayGoals: 0},
+			},
+			wantCardMatches: 2,
+			wantYellow:      2,
+		},
+		{
+			name: "no card data",
+			matches: []refereeMatch{
+				{HomeGoals: 1, AwayGoals: 0},
+				{HomeGoals: 1, AwayGoals: 2},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			profile := aggregateRefereeProfile(referee, tt.matches)
+			if profile.Matches != len(tt.matches) || profile.CardMatches != tt.wantCardMatches {
+				t.Errorf("matches = %d with cards in %d, want %d with cards in %d",
+					profile.Matches, profile.CardMatches, len(tt.matches), tt.wantCardMatches)
+			}
+			if !almostEqual(profile.YellowCardsPerGame, tt.wantYellow) || !almostEqual(profile.RedCardsPerGame, tt.wantRed) {
+				t.Errorf("cards per game = %v yellow, %v red, want %v and %v",
+					profile.YellowCardsPerGame, profile.RedCardsPerGame, tt.wantYellow, tt.wantRed)
+			}
+		})
+	}
+}
@@ -74,16 +74,28 @@ func (r *Repository) SaveCompetition(ctx context.Context, comp *Competition) err
 
 // GetCompetition retrieves a competition by ID
 func (r *Repository) GetCompetition(ctx context.Context, id int) (*Competition, error) {
-	query := `
-		SELECT id, code, name, type, emblem, area, current_season, seasons
-		FROM competitions
-		WHERE id = $1
-	`
+	query := `SELECT ` + competitionColumns + ` FROM competitions WHERE id = $1`
+
+	comp, err := scanCompetition(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("competition not found")
+		}
+		return nil, fmt.Errorf("failed to get competition: %w", err)
+	}
 
+	return comp, nil
+}
+
+// competitionColumns lists the competitions columns read by scanCompetition
+const competitionColumns = `id, code, name, type, emblem, area, current_season, seasons`
+
+// scanCompetition scans a row of competitionColumns and decodes its JSONB columns
+func scanCompetition(row rowScanner) (*Competition, error) {
 	var comp Competition
 	var areaJSON, currentSeasonJSON, seasonsJSON []byte
 
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
+	err := row.Scan(
 		&comp.ID,
 		&comp.Code,
 		&comp.Name,
@@ -94,10 +106,7 @@ func (r *Repository) GetCompetition(ctx context.Context, id int) (*Competition,
 		&seasonsJSON,
 	)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("competition not found")
-		}
-		return nil, fmt.Errorf("failed to get competition: %w", err)
+		return nil, err
 	}
 
 	if err := json.Unmarshal(areaJSON, &comp.Area); err != nil {
@@ -166,14 +175,28 @@ func (r *Repository) SaveTeam(ctx context.Context, team *Team) error {
 
 // GetTeam retrieves a team by ID
 func (r *Repository) GetTeam(ctx context.Context, id int) (*Team, error) {
-	query := `
-		SELECT id, name, short_name, tla, crest, address, website, founded, club_colors, venue
-		FROM teams
-		WHERE id = $1
-	`
+	query := `SELECT ` + teamColumns + ` FROM teams WHERE id = $1`
+
+	team, err := scanTeam(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("team not found")
+		}
+		return nil, fmt.Errorf("failed to get team: %w", err)
+	}
 
+	return team, nil
+}
+
+// teamColumns lists the teams columns read by scanTeam
+// Teams backfilled from match payloads only have their name, short name, TLA and crest.
+const teamColumns = `id, COALESCE(name, ''), COALESCE(short_name, ''), COALESCE(tla, ''), COALESCE(crest, ''),
+	COALESCE(address, ''), COALESCE(website, ''), COALESCE(founded, 0), COALESCE(club_colors, ''), COALESCE(venue, '')`
+
+// scanTeam scans a row of teamColumns
+func scanTeam(row rowScanner) (*Team, error) {
 	var team Team
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
+	err := row.Scan(
 		&team.ID,
 		&team.Name,
 		&team.ShortName,
@@ -186,12 +209,8 @@ func (r *Repository) GetTeam(ctx context.Context, id int) (*Team, error) {
 		&team.Venue,
 	)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("team not found")
-		}
-		return nil, fmt.Errorf("failed to get team: %w", err)
+		return nil, err
 	}
-
 	return &team, nil
 }
 
@@ -223,9 +242,28 @@ func (r *Repository) SaveMatch(ctx context.Context, match *Match) error {
 	}
 
 	query := `
-		INSERT INTO matches (id, competition_id, season_id, matchday, status, utc_date, home_team, away_team, score, odds, referees, updated_at, cached_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		INSERT INTO matches (
+			id, competition_id, season_id, matchday, status, utc_date, home_team, away_team, score, odds, referees,
+			home_team_id, away_team_id, home_score_ft, away_score_ft, home_score_ht, away_score_ht, winner, stage,
+			group_name, updated_at, cached_at, result_updated_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $21)
 		ON CONFLICT (id) DO UPDATE SET
+			result_updated_at = CASE
+				WHEN (matches.status = 'FINISHED' OR EXCLUDED.status = 'FINISHED')
+				  AND (matches.status IS DISTINCT FROM EXCLUDED.status
+				       OR matches.home_score_ft IS DISTINCT FROM EXCLUDED.home_score_ft
+				       OR matches.away_score_ft IS DISTINCT FROM EXCLUDED.away_score_ft)
+				THEN EXCLUDED.result_updated_at
+				ELSE matches.result_updated_at
+			END,
+			schedule_sequence = CASE
+				WHEN matches.utc_date IS DISTINCT FROM EXCLUDED.utc_date
+				  OR (matches.status IS DISTINCT FROM EXCLUDED.status
+				      AND (matches.status IN ('POSTPONED', 'CANCELLED') OR EXCLUDED.status IN ('POSTPONED', 'CANCELLED')))
+				THEN matches.schedule_sequence + 1
+				ELSE matches.schedule_sequence
+			END,
 			competition_id = EXCLUDED.competition_id,
 			season_id = EXCLUDED.season_id,
 			matchday = EXCLUDED.matchday,
@@ -236,12 +274,27 @@ func (r *Repository) SaveMatch(ctx context.Context, match *Match) error {
 			score = EXCLUDED.score,
 			odds = EXCLUDED.odds,
 			referees = EXCLUDED.referees,
+			home_team_id = EXCLUDED.home_team_id,
+			away_team_id = EXCLUDED.away_team_id,
+			home_score_ft = EXCLUDED.home_score_ft,
+			away_score_ft = EXCLUDED.away_score_ft,
+			home_score_ht = EXCLUDED.home_score_ht,
+			away_score_ht = EXCLUDED.away_score_ht,
+			winner = EXCLUDED.winner,
+			stage = EXCLUDED.stage,
+			group_name = EXCLUDED.group_name,
 			updated_at = EXCLUDED.updated_at,
 			cached_at = EXCLUDED.cached_at
 	`
 
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
 	now := time.Now()
-	_, err = r.db.ExecContext(ctx, query,
+	_, err = tx.ExecContext(ctx, query,
 		match.ID,
 		match.Competition.ID,
 		match.Season.ID,
@@ -253,6 +306,15 @@ func (r *Repository) SaveMatch(ctx context.Context, match *Match) error {
 		scoreJSON,
 		oddsJSON,
 		refereesJSON,
+		teamID(match.HomeTeam.ID),
+		teamID(match.AwayTeam.ID),
+		match.Score.FullTime.Home,
+		match.Score.FullTime.Away,
+		match.Score.HalfTime.Home,
+		match.Score.HalfTime.Away,
+		sql.NullString{String: match.Score.Winner, Valid: match.Score.Winner != ""},
+		sql.NullString{String: match.Stage, Valid: match.Stage != ""},
+		match.Group,
 		now,
 		now, // cached_at
 	)
@@ -260,21 +322,53 @@ func (r *Repository) SaveMatch(ctx context.Context, match *Match) error {
 		return fmt.Errorf("failed to save match: %w", err)
 	}
 
-	return nil
+	if err := saveMatchOdds(ctx, tx, match, now); err != nil {
+		return err
+	}
+
+	if err := saveMatchReferees(ctx, tx, match); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// teamID is a team's ID column, NULL while football-data has not drawn the
+// team (ID 0)
+func teamID(id int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(id), Valid: id != 0}
 }
 
 // GetMatch retrieves a match by ID
 func (r *Repository) GetMatch(ctx context.Context, id int) (*Match, error) {
-	query := `
-		SELECT id, competition_id, season_id, matchday, status, utc_date, home_team, away_team, score, odds, referees
-		FROM matches
-		WHERE id = $1
-	`
+	query := `SELECT ` + matchColumns + ` FROM matches WHERE id = $1`
+
+	match, err := scanMatch(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("match not found")
+		}
+		return nil, fmt.Errorf("failed to get match: %w", err)
+	}
+
+	return match, nil
+}
+
+// matchColumns lists the matches columns read by scanMatch
+const matchColumns = `id, competition_id, season_id, matchday, status, utc_date, home_team, away_team, score, odds, referees, stage, group_name`
 
+// rowScanner is implemented by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanMatch scans a row of matchColumns and decodes its JSONB columns
+func scanMatch(row rowScanner) (*Match, error) {
 	var match Match
 	var homeTeamJSON, awayTeamJSON, scoreJSON, oddsJSON, refereesJSON []byte
+	var stage, group sql.NullString
 
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
+	err := row.Scan(
 		&match.ID,
 		&match.CompetitionID,
 		&match.Season.ID,
@@ -286,12 +380,16 @@ func (r *Repository) GetMatch(ctx context.Context, id int) (*Match, error) {
 		&scoreJSON,
 		&oddsJSON,
 		&refereesJSON,
+		&stage,
+		&group,
 	)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("match not found")
-		}
-		return nil, fmt.Errorf("failed to get match: %w", err)
+		return nil, err
+	}
+
+	match.Stage = stage.String
+	if group.Valid {
+		match.Group = &group.String
 	}
 
 	if err := json.Unmarshal(homeTeamJSON, &match.HomeTeam); err != nil {
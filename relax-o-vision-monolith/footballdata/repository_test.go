@@ -1,6 +1,7 @@
 package footballdata
 
 import (
+	"database/sql"
 	"testing"
 )
 
@@ -54,3 +55,30 @@ func TestRepository_CachedAtUpdate(t *testing.T) {
 func TestRepository_JSONBHandling(t *testing.T) {
 	t.Skip("Integration test - requires PostgreSQL database - test JSONB columns")
 }
+
+func TestTeamID(t *testing.T) {
+	t.Parallel()
+
+	// A knockout fixture whose away side comes from a tie still to be played
+	match := NewTestMatch(1, NewTestTeam(10, "Arsenal"), &Team{})
+	match.Stage = "SEMI_FINALS"
+
+	tests := []struct {
+		name string
+		team Team
+		want sql.NullInt64
+	}{
+		{"drawn", match.HomeTeam, sql.NullInt64{Int64: 10, Valid: true}},
+		{"to be decided", match.AwayTeam, sql.NullInt64{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := teamID(tt.team.ID); got != tt.want {
+				t.Errorf("teamID(%d) = %+v, want %+v", tt.team.ID, got, tt.want)
+			}
+		})
+	}
+}
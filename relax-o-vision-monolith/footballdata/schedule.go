@@ -0,0 +1,276 @@
+package footballdata
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	// scheduleLookback bounds how far back the previous match is searched for
+	scheduleLookback = 90 * 24 * time.Hour
+	// bigMatchWindow is how far ahead upcoming big matches are counted
+	bigMatchWindow = 7 * 24 * time.Hour
+	earthRadiusKm  = 6371.0
+)
+
+// bigMatchCompetitions are competitions whose matches count as big regardless of stage
+var bigMatchCompetitions = map[string]bool{
+	"CL": true,
+	"EL": true,
+	"EC": true,
+	"WC": true,
+}
+
+// ScheduleFeatures describes a team's fixture load before a match
+type ScheduleFeatures struct {
+	MatchID            int       `json:"matchId"`
+	TeamID             int       `json:"teamId"`
+	DaysSinceLastMatch *float64  `json:"daysSinceLastMatch"` // nil when the team has no earlier match
+	MatchesLast7Days   int       `json:"matchesLast7Days"`
+	MatchesLast14Days  int       `json:"matchesLast14Days"`
+	MatchesLast30Days  int       `json:"matchesLast30Days"`
+	UpcomingBigMatches int       `json:"upcomingBigMatches"` // within 7 days after the match
+	TravelKm           *float64  `json:"travelKm"`           // from the previous match's venue, when both are known
+	ComputedAt         time.Time `json:"-"`                  // stored with the row, kept out of prompts and snapshots
+}
+
+// MatchSchedule holds both teams' schedule features for a match
+type MatchSchedule struct {
+	MatchID int              `json:"matchId"`
+	Home    ScheduleFeatures `json:"home"`
+	Away    ScheduleFeatures `json:"away"`
+}
+
+// Coordinates is a venue location in decimal degrees
+type Coordinates struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// scheduledMatch is a match in a team's schedule, located at the home team's venue
+type scheduledMatch struct {
+	ID              int
+	UTCDate         time.Time
+	Status          string
+	Stage           string
+	CompetitionCode string
+	HomeTeamID      int
+	AwayTeamID      int
+	Venue           *Coordinates
+}
+
+// ScheduleCalculator computes and stores fixture congestion features
+type ScheduleCalculator struct {
+	db *sql.DB
+}
+
+// NewScheduleCalculator creates a new schedule calculator
+func NewScheduleCalculator(db *sql.DB) *ScheduleCalculator {
+	return &ScheduleCalculator{db: db}
+}
+
+// ComputeMatchSchedule computes both teams' schedule features before a match and stores them
+func (c *ScheduleCalculator) ComputeMatchSchedule(ctx context.Context, matchID int) (*MatchSchedule, error) {
+	match, err := c.loadMatch(ctx, matchID)
+	if err != nil {
+		return nil, err
+	}
+
+	schedule := &MatchSchedule{MatchID: matchID}
+	for _, side := range []struct {
+		teamID   int
+		features *ScheduleFeatures
+	}{
+		{match.HomeTeamID, &schedule.Home},
+		{match.AwayTeamID, &schedule.Away},
+	} {
+		history, err := c.loadTeamSchedule(ctx, side.teamID, match.UTCDate)
+		if err != nil {
+			return nil, err
+		}
+
+		*side.features = computeScheduleFeatures(side.teamID, match, history)
+		if err := c.save(ctx, side.features); err != nil {
+			return nil, err
+		}
+	}
+
+	return schedule, nil
+}
+
+// UpdateTeamVenueCoordinates sets the coordinates of a team's home venue
+func (r *Repository) UpdateTeamVenueCoordinates(ctx context.Context, teamID int, coords Coordinates) error {
+	query := `UPDATE teams SET venue_latitude = $1, venue_longitude = $2, updated_at = $3 WHERE id = $4`
+	result, err := r.db.ExecContext(ctx, query, coords.Latitude, coords.Longitude, time.Now(), teamID)
+	if err != nil {
+		return fmt.Errorf("failed to update venue coordinates: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("team not found")
+	}
+	return nil
+}
+
+// scheduledMatchQuery selects matches with the home venue's coordinates
+const scheduledMatchQuery = `
+	SELECT m.id, m.utc_date, m.status, COALESCE(m.stage, ''), COALESCE(c.code, ''),
+	       COALESCE(m.home_team_id, 0), COALESCE(m.away_team_id, 0), ht.venue_latitude, ht.venue_longitude
+	FROM matches m
+	LEFT JOIN competitions c ON c.id = m.competition_id
+	LEFT JOIN teams ht ON ht.id = m.home_team_id
+`
+
+// scanScheduledMatch scans a row selected by scheduledMatchQuery
+func scanScheduledMatch(row rowScanner) (scheduledMatch, error) {
+	var m scheduledMatch
+	var lat, lng sql.NullFloat64
+	err := row.Scan(&m.ID, &m.UTCDate, &m.Status, &m.Stage, &m.CompetitionCode, &m.HomeTeamID, &m.AwayTeamID, &lat, &lng)
+	if lat.Valid && lng.Valid {
+		m.Venue = &Coordinates{Latitude: lat.Float64, Longitude: lng.Float64}
+	}
+	return m, err
+}
+
+// loadMatch loads the match features are computed for
+func (c *ScheduleCalculator) loadMatch(ctx context.Context, matchID int) (scheduledMatch, error) {
+	match, err := scanScheduledMatch(c.db.QueryRowContext(ctx, scheduledMatchQuery+`WHERE m.id = $1`, matchID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return match, fmt.Errorf("match not found")
+		}
+		return match, fmt.Errorf("failed to get match: %w", err)
+	}
+	return match, nil
+}
+
+// loadTeamSchedule loads a team's matches in every competition around a kickoff
+func (c *ScheduleCalculator) loadTeamSchedule(ctx context.Context, teamID int, kickoff time.Time) ([]scheduledMatch, error) {
+	query := scheduledMatchQuery + `
+		WHERE (m.home_team_id = $1 OR m.away_team_id = $1)
+		  AND m.utc_date >= $2
+		  AND m.utc_date <= $3
+		  AND m.status NOT IN ('POSTPONED', 'CANCELLED', 'SUSPENDED')
+		ORDER BY m.utc_date
+	`
+
+	rows, err := c.db.QueryContext(ctx, query, teamID, kickoff.Add(-scheduleLookback), kickoff.Add(bigMatchWindow))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query team schedule: %w", err)
+	}
+	defer rows.Close()
+
+	var matches []scheduledMatch
+	for rows.Next() {
+		m, err := scanScheduledMatch(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan team schedule: %w", err)
+		}
+		matches = append(matches, m)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate team schedule: %w", err)
+	}
+
+	return matches, nil
+}
+
+// save upserts a team's schedule features for a match
+func (c *ScheduleCalculator) save(ctx context.Context, f *ScheduleFeatures) error {
+	query := `
+		INSERT INTO match_schedule_features (
+			match_id, team_id, days_since_last_match, matches_last_7_days, matches_last_14_days,
+			matches_last_30_days, upcoming_big_matches, travel_km, computed_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (match_id, team_id) DO UPDATE SET
+			days_since_last_match = EXCLUDED.days_since_last_match,
+			matches_last_7_days = EXCLUDED.matches_last_7_days,
+			matches_last_14_days = EXCLUDED.matches_last_14_days,
+			matches_last_30_days = EXCLUDED.matches_last_30_days,
+			upcoming_big_matches = EXCLUDED.upcoming_big_matches,
+			travel_km = EXCLUDED.travel_km,
+			computed_at = EXCLUDED.computed_at
+	`
+
+	_, err := c.db.ExecContext(ctx, query,
+		f.MatchID,
+		f.TeamID,
+		f.DaysSinceLastMatch,
+		f.MatchesLast7Days,
+		f.MatchesLast14Days,
+		f.MatchesLast30Days,
+		f.UpcomingBigMatches,
+		f.TravelKm,
+		f.ComputedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save schedule features: %w", err)
+	}
+
+	return nil
+}
+
+// computeScheduleFeatures derives a team's schedule load before a match from
+// its surrounding matches in every competition
+func computeScheduleFeatures(teamID int, match scheduledMatch, schedule []scheduledMatch) ScheduleFeatures {
+	features := ScheduleFeatures{MatchID: match.ID, TeamID: teamID, ComputedAt: time.Now()}
+
+	var previous *scheduledMatch
+	for i := range schedule {
+		m := &schedule[i]
+		if m.ID == match.ID {
+			continue
+		}
+
+		gap := match.UTCDate.Sub(m.UTCDate)
+		switch {
+		case gap > 0:
+			if previous == nil || m.UTCDate.After(previous.UTCDate) {
+				previous = m
+			}
+			if gap <= 7*24*time.Hour {
+				features.MatchesLast7Days++
+			}
+			if gap <= 14*24*time.Hour {
+				features.MatchesLast14Days++
+			}
+			if gap <= 30*24*time.Hour {
+				features.MatchesLast30Days++
+			}
+		case -gap <= bigMatchWindow && isBigMatch(*m):
+			features.UpcomingBigMatches++
+		}
+	}
+
+	if previous != nil {
+		days := math.Round(match.UTCDate.Sub(previous.UTCDate).Hours()/24*100) / 100
+		features.DaysSinceLastMatch = &days
+
+		if previous.Venue != nil && match.Venue != nil {
+			km := math.Round(haversineKm(*previous.Venue, *match.Venue)*10) / 10
+			features.TravelKm = &km
+		}
+	}
+
+	return features
+}
+
+// isBigMatch reports whether a match is in a continental or international
+// competition or a knockout round
+func isBigMatch(m scheduledMatch) bool {
+	return bigMatchCompetitions[m.CompetitionCode] || stageIndex(m.Stage) >= 0
+}
+
+// haversineKm returns the great-circle distance between two points
+func haversineKm(a, b Coordinates) float64 {
+	lat1, lat2 := a.Latitude*math.Pi/180, b.Latitude*math.Pi/180
+	dLat := lat2 - lat1
+	dLng := (b.Longitude - a.Longitude) * math.Pi / 180
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(h))
+}
@@ -0,0 +1,61 @@
+package footballdata
+
+import (
+	"testing"
+	"time"
+)
+
+func TestComputeScheduleFeatures(t *testing.T) {
+	t.Parallel()
+
+	kickoff := time.Date(2024, 3, 30, 15, 0, 0, 0, time.UTC)
+	london := &Coordinates{Latitude: 51.5549, Longitude: -0.1084}
+	manchester := &Coordinates{Latitude: 53.4631, Longitude: -2.2913}
+	day := func(d int) time.Time { return kickoff.AddDate(0, 0, d) }
+
+	match := scheduledMatch{ID: 10, UTCDate: kickoff, CompetitionCode: "PL", HomeTeamID: 1, AwayTeamID: 2, Venue: london}
+	schedule := []scheduledMatch{
+		{ID: 1, UTCDate: day(-40), CompetitionCode: "PL", HomeTeamID: 2},
+		{ID: 2, UTCDate: day(-20), CompetitionCode: "PL", HomeTeamID: 2},
+		{ID: 3, UTCDate: day(-10), CompetitionCode: "FAC", HomeTeamID: 2},
+		{ID: 4, UTCDate: day(-6), CompetitionCode: "PL", HomeTeamID: 2},
+		{ID: 5, UTCDate: kickoff.Add(-75 * time.Hour), CompetitionCode: "CL", HomeTeamID: 2, Venue: manchester},
+		match,
+		{ID: 6, UTCDate: day(3), CompetitionCode: "CL", Stage: "QUARTER_FINALS", AwayTeamID: 2},
+		{ID: 7, UTCDate: day(6), CompetitionCode: "PL", HomeTeamID: 2},
+		{ID: 8, UTCDate: day(7), CompetitionCode: "FAC", Stage: "SEMI_FINALS", HomeTeamID: 2},
+	}
+
+	features := computeScheduleFeatures(2, match, schedule)
+
+	if features.MatchID != 10 || features.TeamID != 2 {
+		t.Errorf("features for match %d team %d, want match 10 team 2", features.MatchID, features.TeamID)
+	}
+	if features.DaysSinceLastMatch == nil || *features.DaysSinceLastMatch != 3.13 {
+		t.Errorf("DaysSinceLastMatch = %v, want 3.13", features.DaysSinceLastMatch)
+	}
+	if features.MatchesLast7Days != 2 || features.MatchesLast14Days != 3 || features.MatchesLast30Days != 4 {
+		t.Errorf("matches in 7/14/30 days = %d/%d/%d, want 2/3/4",
+			features.MatchesLast7Days, features.MatchesLast14Days, features.MatchesLast30Days)
+	}
+	if features.UpcomingBigMatches != 2 {
+		t.Errorf("UpcomingBigMatches = %d, want 2", features.UpcomingBigMatches)
+	}
+	if features.TravelKm == nil || *features.TravelKm < 255 || *features.TravelKm > 265 {
+		t.Errorf("TravelKm = %v, want about 260", features.TravelKm)
+	}
+}
+
+func TestComputeScheduleFeatures_NoHistory(t *testing.T) {
+	t.Parallel()
+
+	match := scheduledMatch{ID: 1, UTCDate: time.Date(2024, 8, 17, 14, 0, 0, 0, time.UTC)}
+	features := computeScheduleFeatures(5, match, []scheduledMatch{match})
+
+	if features.DaysSinceLastMatch != nil || features.TravelKm != nil {
+		t.Errorf("DaysSinceLastMatch = %v, TravelKm = %v, want both nil", features.DaysSinceLastMatch, features.TravelKm)
+	}
+	if features.MatchesLast30Days != 0 || features.UpcomingBigMatches != 0 {
+		t.Errorf("features = %+v, want no matches counted", features)
+	}
+}
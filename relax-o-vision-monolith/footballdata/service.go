@@ -6,10 +6,14 @@ import (
 	"log/slog"
 )
 
+// SyncHook is called after a competition's matches have been synced
+type SyncHook func(ctx context.Context, competitionID int)
+
 // Service handles business logic for football data
 type Service struct {
-	client *Client
-	repo   *Repository
+	client    *Client
+	repo      *Repository
+	syncHooks []SyncHook
 }
 
 // NewService creates a new service instance
@@ -70,9 +74,21 @@ func (s *Service) SyncCompetitionMatches(ctx context.Context, competitionCode st
 	}
 
 	slog.Info("Completed matches sync", "competition", competitionCode, "count", len(matches))
+
+	if len(matches) > 0 {
+		for _, hook := range s.syncHooks {
+			hook(ctx, matches[0].Competition.ID)
+		}
+	}
+
 	return nil
 }
 
+// OnMatchesSynced registers a hook to run after each competition matches sync
+func (s *Service) OnMatchesSynced(hook SyncHook) {
+	s.syncHooks = append(s.syncHooks, hook)
+}
+
 // GetCompetition retrieves a competition by ID
 func (s *Service) GetCompetition(ctx context.Context, id int) (*Competition, error) {
 	return s.repo.GetCompetition(ctx, id)
@@ -88,6 +104,21 @@ func (s *Service) GetMatch(ctx context.Context, id int) (*Match, error) {
 	return s.repo.GetMatch(ctx, id)
 }
 
+// ListMatches lists stored matches matching a filter
+func (s *Service) ListMatches(ctx context.Context, filter MatchFilter) (*Page[Match], error) {
+	return s.repo.ListMatches(ctx, filter)
+}
+
+// ListTeams lists stored teams matching a filter
+func (s *Service) ListTeams(ctx context.Context, filter TeamFilter) (*Page[Team], error) {
+	return s.repo.ListTeams(ctx, filter)
+}
+
+// ListCompetitions lists stored competitions matching a filter
+func (s *Service) ListCompetitions(ctx context.Context, filter CompetitionFilter) (*Page[Competition], error) {
+	return s.repo.ListCompetitions(ctx, filter)
+}
+
 // GetAllCompetitions fetches all competitions from the API
 func (s *Service) GetAllCompetitions(ctx context.Context) ([]Competition, error) {
 	slog.Info("Fetching all competitions from API")
@@ -0,0 +1,525 @@
+package footballdata
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"math/rand/v2"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/edd/relaxovisionmonolith/cache"
+)
+
+const (
+	// DefaultSimulationIterations is the number of seasons simulated per request
+	DefaultSimulationIterations = 10000
+	// MaxSimulationIterations caps caller-supplied iteration counts
+	MaxSimulationIterations = 100000
+
+	simulationCacheTTL = 24 * time.Hour
+
+	// priorGames shrinks team strengths towards the league average early in a season
+	priorGames = 5.0
+)
+
+// MatchProbabilities holds outcome probabilities for a single match
+type MatchProbabilities struct {
+	HomeWin float64 `json:"homeWin"`
+	Draw    float64 `json:"draw"`
+	AwayWin float64 `json:"awayWin"`
+}
+
+// MatchProbabilitySource supplies outcome probabilities for upcoming matches,
+// e.g. from stored predictions. Matches missing from the result fall back to
+// the simulator's statistical model.
+type MatchProbabilitySource interface {
+	MatchProbabilities(ctx context.Context, matchIDs []int) (map[int]MatchProbabilities, error)
+}
+
+// FixedResult pins a match to a given score in a what-if simulation
+type FixedResult struct {
+	MatchID   int `json:"matchId"`
+	HomeGoals int `json:"homeGoals"`
+	AwayGoals int `json:"awayGoals"`
+}
+
+// SimulationZones describes the table places reported by the simulator
+type SimulationZones struct {
+	Relegation int `json:"relegation"`
+}
+
+// competitionZones holds zones that differ from the default three relegation places
+var competitionZones = map[string]SimulationZones{
+	"BL1": {Relegation: 2}, // 16th goes to a play-off
+	"FL1": {Relegation: 2},
+}
+
+// ZonesForCompetition returns the simulation zones for a competition code
+func ZonesForCompetition(code string) SimulationZones {
+	if zones, ok := competitionZones[code]; ok {
+		return zones
+	}
+	return SimulationZones{Relegation: 3}
+}
+
+// SimulationOptions controls a season simulation
+type SimulationOptions struct {
+	Iterations   int
+	Seed         uint64 // zero seeds from the clock
+	Rules        StandingsRules
+	Zones        SimulationZones
+	FixedResults []FixedResult
+}
+
+// TeamSimulation summarises a team's simulated final positions
+type TeamSimulation struct {
+	Team                  Team      `json:"team"`
+	CurrentPosition       int       `json:"currentPosition"`
+	CurrentPoints         int       `json:"currentPoints"`
+	ExpectedPoints        float64   `json:"expectedPoints"`
+	ExpectedPosition      float64   `json:"expectedPosition"`
+	PositionProbabilities []float64 `json:"positionProbabilities"` // index 0 is first place
+	TitleProb             float64   `json:"titleProb"`
+	Top4Prob              float64   `json:"top4Prob"`
+	RelegationProb        float64   `json:"relegationProb"`
+}
+
+// SimulationResult is the outcome of a Monte Carlo season simulation
+type SimulationResult struct {
+	CompetitionID    int              `json:"competitionId"`
+	SeasonID         int              `json:"seasonId"`
+	Iterations       int              `json:"iterations"`
+	RemainingMatches int              `json:"remainingMatches"`
+	PredictedMatches int              `json:"predictedMatches"` // remaining matches drawn from stored probabilities
+	Zones            SimulationZones  `json:"zones"`
+	FixedResults     []FixedResult    `json:"fixedResults,omitempty"`
+	Teams            []TeamSimulation `json:"teams"`
+	GeneratedAt      time.Time        `json:"generatedAt"`
+}
+
+// SeasonSimulator runs Monte Carlo simulations of the rest of a season
+type SeasonSimulator struct {
+	standings     *StandingsCalculator
+	cache         cache.Cache
+	probabilities MatchProbabilitySource
+}
+
+// NewSeasonSimulator creates a new season simulator. The cache and probability
+// source are optional.
+func NewSeasonSimulator(db *sql.DB, c cache.Cache, probabilities MatchProbabilitySource) *SeasonSimulator {
+	return &SeasonSimulator{
+		standings:     NewStandingsCalculator(db),
+		cache:         c,
+		probabilities: probabilities,
+	}
+}
+
+// GetSimulation returns the cached simulation for a competition, running one if needed
+func (s *SeasonSimulator) GetSimulation(ctx context.Context, competitionID int) (*SimulationResult, error) {
+	if s.cache != nil {
+		data, err := s.cache.Get(ctx, simulationCacheKey(competitionID))
+		if err == nil && data != nil {
+			var result SimulationResult
+			if err := json.Unmarshal(data, &result); err == nil {
+				return &result, nil
+			}
+		}
+	}
+
+	return s.Refresh(ctx, competitionID)
+}
+
+// Refresh runs a fresh simulation for a competition and caches it
+func (s *SeasonSimulator) Refresh(ctx context.Context, competitionID int) (*SimulationResult, error) {
+	result, err := s.Simulate(ctx, competitionID, SimulationOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	if s.cache != nil {
+		data, err := json.Marshal(result)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal simulation: %w", err)
+		}
+		if err := s.cache.Set(ctx, simulationCacheKey(competitionID), data, simulationCacheTTL); err != nil {
+			slog.Warn("Failed to cache simulation", "competitionId", competitionID, "error", err)
+		}
+	}
+
+	return result, nil
+}
+
+// Simulate runs a simulation of the current season. Results are never cached,
+// so it also serves what-if requests with fixed results.
+func (s *SeasonSimulator) Simulate(ctx context.Context, competitionID int, opts SimulationOptions) (*SimulationResult, error) {
+	comp, err := s.standings.repo.GetCompetition(ctx, competitionID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	seasonID, err := s.standings.seasonAt(ctx, competitionID, now)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := s.standings.loadResults(ctx, competitionID, seasonID, now)
+	if err != nil {
+		return nil, err
+	}
+
+	var remaining []int
+	for _, result := range results {
+		if isRemaining(result) {
+			remaining = append(remaining, result.MatchID)
+		}
+	}
+
+	probabilities := map[int]MatchProbabilities{}
+	if s.probabilities != nil && len(remaining) > 0 {
+		probabilities, err = s.probabilities.MatchProbabilities(ctx, remaining)
+		if err != nil {
+			slog.Warn("Failed to load match probabilities, using statistical model", "competitionId", competitionID, "error", err)
+			probabilities = map[int]MatchProbabilities{}
+		}
+	}
+
+	opts.Rules = RulesForCompetition(comp.Code)
+	opts.Zones = ZonesForCompetition(comp.Code)
+
+	teams, err := SimulateSeason(results, probabilities, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	predicted := 0
+	for _, id := range remaining {
+		if _, ok := probabilities[id]; ok {
+			predicted++
+		}
+	}
+
+	return &SimulationResult{
+		CompetitionID:    competitionID,
+		SeasonID:         seasonID,
+		Iterations:       simulationIterations(opts.Iterations),
+		RemainingMatches: len(remaining),
+		PredictedMatches: predicted,
+		Zones:            opts.Zones,
+		FixedResults:     opts.FixedResults,
+		Teams:            teams,
+		GeneratedAt:      now,
+	}, nil
+}
+
+// simulationCacheKey returns the cache key for a competition's simulation
+func simulationCacheKey(competitionID int) string {
+	return fmt.Sprintf("football:simulation:%d", competitionID)
+}
+
+// simulationIterations clamps a requested iteration count
+func simulationIterations(n int) int {
+	switch {
+	case n <= 0:
+		return DefaultSimulationIterations
+	case n > MaxSimulationIterations:
+		return MaxSimulationIterations
+	default:
+		return n
+	}
+}
+
+// isRemaining reports whether a match is still to be played or finished:
+// scheduled, in play or suspended, or postponed to a later date
+func isRemaining(result MatchResult) bool {
+	return !result.Finished && result.Status != "FINISHED" && result.Status != "CANCELLED"
+}
+
+// SimulateSeason plays out the remaining matches of a single-table season
+// many times and aggregates each team's final positions
+func SimulateSeason(results []MatchResult, probabilities map[int]MatchProbabilities, opts SimulationOptions) ([]TeamSimulation, error) {
+	iterations := simulationIterations(opts.Iterations)
+
+	fixed := make(map[int]FixedResult, len(opts.FixedResults))
+	for _, f := range opts.FixedResults {
+		fixed[f.MatchID] = f
+	}
+
+	// Fixed results are treated as already played
+	base := make([]MatchResult, len(results))
+	copy(base, results)
+	var remaining []int
+	for i := range base {
+		if f, ok := fixed[base[i].MatchID]; ok && !base[i].Finished {
+			base[i].Finished = true
+			base[i].HomeGoals = f.HomeGoals
+			base[i].AwayGoals = f.AwayGoals
+			delete(fixed, base[i].MatchID)
+			continue
+		}
+		if isRemaining(base[i]) {
+			remaining = append(remaining, i)
+		}
+	}
+	for id := range fixed {
+		return nil, fmt.Errorf("match %d is not a remaining fixture", id)
+	}
+
+	tableOpts := TableOptions{Type: TableTotal, Rules: opts.Rules}
+	current := BuildStandingTables(base, tableOpts)
+	if len(current) != 1 {
+		return nil, fmt.Errorf("simulation supports single-table competitions only")
+	}
+
+	model := newGoalModel(base)
+	table := current[0].Table
+	index := make(map[int]int, len(table))
+	for i, row := range table {
+		index[row.Team.ID] = i
+	}
+
+	seed := opts.Seed
+	if seed == 0 {
+		seed = uint64(time.Now().UnixNano())
+	}
+
+	workers := runtime.GOMAXPROCS(0)
+	if workers > iterations {
+		workers = iterations
+	}
+
+	// positions[team][place] counts how often a team finished in a place
+	positions := make([][]int, len(table))
+	for i := range positions {
+		positions[i] = make([]int, len(table))
+	}
+	points := make([]int, len(table))
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		runs := iterations / workers
+		if w < iterations%workers {
+			runs++
+		}
+
+		wg.Add(1)
+		go func(worker uint64, runs int) {
+			defer wg.Done()
+
+			rng := rand.New(rand.NewPCG(seed, worker))
+			season := make([]MatchResult, len(base))
+			localPositions := make([][]int, len(table))
+			for i := range localPositions {
+				localPositions[i] = make([]int, len(table))
+			}
+			localPoints := make([]int, len(table))
+
+			for run := 0; run < runs; run++ {
+				copy(season, base)
+				for _, i := range remaining {
+					match := &season[i]
+					probs, ok := probabilities[match.MatchID]
+					if ok {
+						match.HomeGoals, match.AwayGoals = model.sampleOutcome(rng, match.HomeTeam.ID, match.AwayTeam.ID, probs)
+					} else {
+						match.HomeGoals, match.AwayGoals = model.sampleScore(rng, match.HomeTeam.ID, match.AwayTeam.ID)
+					}
+					match.Finished = true
+				}
+
+				final := BuildStandingTables(season, tableOpts)[0].Table
+				for place, row := range final {
+					t := index[row.Team.ID]
+					localPositions[t][place]++
+					localPoints[t] += row.Points
+				}
+			}
+
+			mu.Lock()
+			for t := range localPositions {
+				for place, n := range localPositions[t] {
+					positions[t][place] += n
+				}
+				points[t] += localPoints[t]
+			}
+			mu.Unlock()
+		}(uint64(w), runs)
+	}
+	wg.Wait()
+
+	n := float64(iterations)
+	teams := make([]TeamSimulation, len(table))
+	for t, row := range table {
+		sim := TeamSimulation{
+			Team:                  row.Team,
+			CurrentPosition:       row.Position,
+			CurrentPoints:         row.Points,
+			ExpectedPoints:        float64(points[t]) / n,
+			PositionProbabilities: make([]float64, len(table)),
+		}
+		for place, count := range positions[t] {
+			p := float64(count) / n
+			sim.PositionProbabilities[place] = p
+			sim.ExpectedPosition += float64(place+1) * p
+			if place == 0 {
+				sim.TitleProb += p
+			}
+			if place < 4 {
+				sim.Top4Prob += p
+			}
+			if place >= len(table)-opts.Zones.Relegation {
+				sim.RelegationProb += p
+			}
+		}
+		teams[t] = sim
+	}
+
+	return teams, nil
+}
+
+// teamStrength holds a team's attack and defence ratings relative to the league
+type teamStrength struct {
+	homeAttack  float64
+	homeDefence float64
+	awayAttack  float64
+	awayDefence float64
+}
+
+// goalModel is an independent Poisson model fitted to the season so far
+type goalModel struct {
+	homeGoals float64 // league average goals per match for the home side
+	awayGoals float64
+	teams     map[int]teamStrength
+}
+
+// newGoalModel fits attack and defence ratings from finished results,
+// shrunk towards the league average by priorGames
+func newGoalModel(results []MatchResult) *goalModel {
+	type tally struct {
+		homeGames, homeFor, homeAgainst float64
+		awayGames, awayFor, awayAgainst float64
+	}
+
+	tallies := make(map[int]*tally)
+	var games, homeTotal, awayTotal float64
+	for _, result := range results {
+		for _, id := range []int{result.HomeTeam.ID, result.AwayTeam.ID} {
+			if tallies[id] == nil {
+				tallies[id] = &tally{}
+			}
+		}
+		if !result.Finished {
+			continue
+		}
+
+		home, away := tallies[result.HomeTeam.ID], tallies[result.AwayTeam.ID]
+		home.homeGames++
+		home.homeFor += float64(result.HomeGoals)
+		home.homeAgainst += float64(result.AwayGoals)
+		away.awayGames++
+		away.awayFor += float64(result.AwayGoals)
+		away.awayAgainst += float64(result.HomeGoals)
+
+		games++
+		homeTotal += float64(result.HomeGoals)
+		awayTotal += float64(result.AwayGoals)
+	}
+
+	// Typical top-flight averages until there are results to learn from
+	model := &goalModel{homeGoals: 1.5, awayGoals: 1.2, teams: make(map[int]teamStrength, len(tallies))}
+	if games > 0 {
+		model.homeGoals = (homeTotal + priorGames*model.homeGoals) / (games + priorGames)
+		model.awayGoals = (awayTotal + priorGames*model.awayGoals) / (games + priorGames)
+	}
+
+	rating := func(goals, played, average float64) float64 {
+		return (goals + priorGames*average) / (played + priorGames) / average
+	}
+	for id, t := range tallies {
+		model.teams[id] = teamStrength{
+			homeAttack:  rating(t.homeFor, t.homeGames, model.homeGoals),
+			homeDefence: rating(t.homeAgainst, t.homeGames, model.awayGoals),
+			awayAttack:  rating(t.awayFor, t.awayGames, model.awayGoals),
+			awayDefence: rating(t.awayAgainst, t.awayGames, model.homeGoals),
+		}
+	}
+
+	return model
+}
+
+// expectedGoals returns the Poisson means for a fixture
+func (m *goalModel) expectedGoals(homeID, awayID int) (float64, float64) {
+	home, away := m.teams[homeID], m.teams[awayID]
+	return m.homeGoals * home.homeAttack * away.awayDefence,
+		m.awayGoals * away.awayAttack * home.homeDefence
+}
+
+// sampleScore draws a scoreline from the Poisson model
+func (m *goalModel) sampleScore(rng *rand.Rand, homeID, awayID int) (int, int) {
+	homeMean, awayMean := m.expectedGoals(homeID, awayID)
+	return poisson(rng, homeMean), poisson(rng, awayMean)
+}
+
+// sampleOutcome draws an outcome from the given probabilities, then a
+// scoreline consistent with it so goal difference stays realistic
+func (m *goalModel) sampleOutcome(rng *rand.Rand, homeID, awayID int, probs MatchProbabilities) (int, int) {
+	total := probs.HomeWin + probs.Draw + probs.AwayWin
+	if total <= 0 {
+		return m.sampleScore(rng, homeID, awayID)
+	}
+
+	r := rng.Float64() * total
+	want := 0 // goal difference sign
+	switch {
+	case r < probs.HomeWin:
+		want = 1
+	case r >= probs.HomeWin+probs.Draw:
+		want = -1
+	}
+
+	for attempt := 0; attempt < 20; attempt++ {
+		home, away := m.sampleScore(rng, homeID, awayID)
+		if sign(home-away) == want {
+			return home, away
+		}
+	}
+
+	switch want {
+	case 1:
+		return 1, 0
+	case -1:
+		return 0, 1
+	default:
+		return 1, 1
+	}
+}
+
+// poisson draws from a Poisson distribution using Knuth's method
+func poisson(rng *rand.Rand, lambda float64) int {
+	limit := math.Exp(-lambda)
+	k := 0
+	p := rng.Float64()
+	for p > limit {
+		k++
+		p *= rng.Float64()
+	}
+	return k
+}
+
+// sign returns -1, 0 or 1
+func sign(n int) int {
+	switch {
+	case n > 0:
+		return 1
+	case n < 0:
+		return -1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,165 @@
+package footballdata
+
+import (
+	"math"
+	"math/rand/v2"
+	"testing"
+)
+
+// newTestFixture creates an unplayed fixture on the given day of September 2024
+func newTestFixture(day int, home, away Team) MatchResult {
+	result := newTestResult(day, home, away, 0, 0)
+	result.Finished = false
+	result.Status = "TIMED"
+	return result
+}
+
+func newTestSeason() []MatchResult {
+	return []MatchResult{
+		// Alpha have won everything and are out of reach
+		newTestResult(1, testTeamA, testTeamB, 3, 0),
+		newTestResult(1, testTeamC, testTeamD, 1, 1),
+		newTestResult(8, testTeamA, testTeamC, 2, 0),
+		newTestResult(8, testTeamB, testTeamD, 0, 0),
+		newTestResult(15, testTeamA, testTeamD, 4, 1),
+		newTestResult(15, testTeamB, testTeamC, 1, 1),
+		newTestFixture(22, testTeamB, testTeamA),
+		newTestFixture(22, testTeamD, testTeamC),
+	}
+}
+
+func TestSimulateSeason(t *testing.T) {
+	t.Parallel()
+
+	teams, err := SimulateSeason(newTestSeason(), nil, SimulationOptions{
+		Iterations: 2000,
+		Seed:       42,
+		Rules:      DefaultStandingsRules,
+		Zones:      SimulationZones{Relegation: 1},
+	})
+	if err != nil {
+		t.Fatalf("SimulateSeason() error = %v", err)
+	}
+	if len(teams) != 4 {
+		t.Fatalf("got %d teams, want 4", len(teams))
+	}
+
+	var relegation float64
+	for _, team := range teams {
+		var total float64
+		for _, p := range team.PositionProbabilities {
+			total += p
+		}
+		if math.Abs(total-1) > 1e-9 {
+			t.Errorf("%s position probabilities sum to %v, want 1", team.Team.Name, total)
+		}
+		if team.ExpectedPoints < float64(team.CurrentPoints) || team.ExpectedPoints > float64(team.CurrentPoints+3) {
+			t.Errorf("%s expected points %v outside [%d, %d]", team.Team.Name, team.ExpectedPoints, team.CurrentPoints, team.CurrentPoints+3)
+		}
+		relegation += team.RelegationProb
+	}
+
+	if teams[0].Team.ID != testTeamA.ID || teams[0].TitleProb != 1 {
+		t.Errorf("leader = %s with title prob %v, want Alpha certain", teams[0].Team.Name, teams[0].TitleProb)
+	}
+	if math.Abs(relegation-1) > 1e-9 {
+		t.Errorf("relegation probabilities sum to %v, want 1", relegation)
+	}
+}
+
+func TestSimulateSeason_FixedResults(t *testing.T) {
+	t.Parallel()
+
+	season := newTestSeason()
+	opts := SimulationOptions{
+		Iterations: 500,
+		Seed:       7,
+		Rules:      DefaultStandingsRules,
+		Zones:      SimulationZones{Relegation: 1},
+		// Delta beat Charlie, Bravo lose to Alpha: Bravo finish bottom
+		FixedResults: []FixedResult{
+			{MatchID: season[6].MatchID, HomeGoals: 0, AwayGoals: 2},
+			{MatchID: season[7].MatchID, HomeGoals: 1, AwayGoals: 0},
+		},
+	}
+
+	teams, err := SimulateSeason(season, nil, opts)
+	if err != nil {
+		t.Fatalf("SimulateSeason() error = %v", err)
+	}
+	for _, team := range teams {
+		want := 0.0
+		if team.Team.ID == testTeamB.ID {
+			want = 1
+		}
+		if team.RelegationProb != want {
+			t.Errorf("%s relegation prob = %v, want %v", team.Team.Name, team.RelegationProb, want)
+		}
+	}
+
+	opts.FixedResults = []FixedResult{{MatchID: season[0].MatchID, HomeGoals: 0, AwayGoals: 5}}
+	if _, err := SimulateSeason(season, nil, opts); err == nil {
+		t.Error("SimulateSeason() fixing a played match succeeded, want error")
+	}
+}
+
+func TestSimulateSeason_MatchdayInProgress(t *testing.T) {
+	t.Parallel()
+
+	// The last matchday is under way: every team still plays one match
+	statuses := []string{"IN_PLAY", "PAUSED", "SUSPENDED", "POSTPONED"}
+	for _, status := range statuses {
+		t.Run(status, func(t *testing.T) {
+			t.Parallel()
+
+			season := newTestSeason()
+			season[6].Status = status
+			season[7].Status = "CANCELLED"
+
+			teams, err := SimulateSeason(season, nil, SimulationOptions{Iterations: 500, Seed: 3, Rules: DefaultStandingsRules})
+			if err != nil {
+				t.Fatalf("SimulateSeason() error = %v", err)
+			}
+			for _, team := range teams {
+				played := team.Team.ID == testTeamA.ID || team.Team.ID == testTeamB.ID
+				gained := team.ExpectedPoints - float64(team.CurrentPoints)
+				if played && gained <= 0 {
+					t.Errorf("%s gains %v expected points from the %s match, want some", team.Team.Name, gained, status)
+				}
+				if !played && gained != 0 {
+					t.Errorf("%s gains %v expected points from a cancelled match, want none", team.Team.Name, gained)
+				}
+			}
+		})
+	}
+
+	if !isRemaining(newTestFixture(1, testTeamA, testTeamB)) || isRemaining(newTestResult(1, testTeamA, testTeamB, 1, 0)) {
+		t.Error("isRemaining() = a timed fixture not remaining or a result remaining")
+	}
+}
+
+func TestGoalModel_SampleOutcome(t *testing.T) {
+	t.Parallel()
+
+	model := newGoalModel(newTestSeason())
+	rng := rand.New(rand.NewPCG(1, 2))
+
+	tests := []struct {
+		name  string
+		probs MatchProbabilities
+		want  int
+	}{
+		{name: "home win", probs: MatchProbabilities{HomeWin: 1}, want: 1},
+		{name: "draw", probs: MatchProbabilities{Draw: 1}, want: 0},
+		{name: "away win", probs: MatchProbabilities{AwayWin: 1}, want: -1},
+	}
+
+	for _, tt := range tests {
+		for i := 0; i < 100; i++ {
+			home, away := model.sampleOutcome(rng, testTeamD.ID, testTeamA.ID, tt.probs)
+			if sign(home-away) != tt.want {
+				t.Fatalf("%s: sampled %d-%d", tt.name, home, away)
+			}
+		}
+	}
+}
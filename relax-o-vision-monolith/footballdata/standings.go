@@ -0,0 +1,381 @@
+package footballdata
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// TableType selects which matches count towards a table
+type TableType string
+
+const (
+	TableTotal TableType = "TOTAL"
+	TableHome  TableType = "HOME"
+	TableAway  TableType = "AWAY"
+)
+
+// Tiebreaker is a single criterion used to order teams in a table
+type Tiebreaker string
+
+const (
+	TiebreakPoints          Tiebreaker = "POINTS"
+	TiebreakGoalDifference  Tiebreaker = "GOAL_DIFFERENCE"
+	TiebreakGoalsFor        Tiebreaker = "GOALS_FOR"
+	TiebreakWins            Tiebreaker = "WINS"
+	TiebreakAwayGoalsFor    Tiebreaker = "AWAY_GOALS_FOR"
+	TiebreakH2HPoints       Tiebreaker = "H2H_POINTS"
+	TiebreakH2HGoalDiff     Tiebreaker = "H2H_GOAL_DIFFERENCE"
+	TiebreakH2HGoalsFor     Tiebreaker = "H2H_GOALS_FOR"
+	TiebreakH2HAwayGoalsFor Tiebreaker = "H2H_AWAY_GOALS_FOR"
+)
+
+// StandingsRules describes how a competition awards points and breaks ties
+type StandingsRules struct {
+	PointsForWin  int          `json:"pointsForWin"`
+	PointsForDraw int          `json:"pointsForDraw"`
+	Tiebreakers   []Tiebreaker `json:"tiebreakers"`
+}
+
+// DefaultStandingsRules orders teams by points, goal difference, goals scored
+// and finally the head-to-head record
+var DefaultStandingsRules = StandingsRules{
+	PointsForWin:  3,
+	PointsForDraw: 1,
+	Tiebreakers: []Tiebreaker{
+		TiebreakPoints,
+		TiebreakGoalDifference,
+		TiebreakGoalsFor,
+		TiebreakH2HPoints,
+		TiebreakH2HGoalDiff,
+	},
+}
+
+// competitionRules holds tiebreak rules that differ from the defaults, keyed by competition code
+var competitionRules = map[string]StandingsRules{
+	// La Liga and Serie A decide ties on the head-to-head record first
+	"PD": {PointsForWin: 3, PointsForDraw: 1, Tiebreakers: []Tiebreaker{
+		TiebreakPoints, TiebreakH2HPoints, TiebreakH2HGoalDiff, TiebreakGoalDifference, TiebreakGoalsFor,
+	}},
+	"SA": {PointsForWin: 3, PointsForDraw: 1, Tiebreakers: []Tiebreaker{
+		TiebreakPoints, TiebreakH2HPoints, TiebreakH2HGoalDiff, TiebreakGoalDifference, TiebreakGoalsFor,
+	}},
+	// UEFA group stages use head-to-head before overall goal difference
+	"CL": {PointsForWin: 3, PointsForDraw: 1, Tiebreakers: []Tiebreaker{
+		TiebreakPoints, TiebreakH2HPoints, TiebreakH2HGoalDiff, TiebreakH2HGoalsFor,
+		TiebreakGoalDifference, TiebreakGoalsFor, TiebreakAwayGoalsFor, TiebreakWins,
+	}},
+	"EC": {PointsForWin: 3, PointsForDraw: 1, Tiebreakers: []Tiebreaker{
+		TiebreakPoints, TiebreakH2HPoints, TiebreakH2HGoalDiff, TiebreakH2HGoalsFor,
+		TiebreakGoalDifference, TiebreakGoalsFor, TiebreakWins,
+	}},
+}
+
+// RulesForCompetition returns the standings rules for a competition code
+func RulesForCompetition(code string) StandingsRules {
+	if rules, ok := competitionRules[code]; ok {
+		return rules
+	}
+	return DefaultStandingsRules
+}
+
+// MatchResult is the minimal view of a match needed to build tables
+type MatchResult struct {
+	MatchID   int       `json:"matchId"`
+	UTCDate   time.Time `json:"utcDate"`
+	Status    string    `json:"status"`
+	Stage     string    `json:"stage"`
+	Group     *string   `json:"group"`
+	HomeTeam  Team      `json:"homeTeam"`
+	AwayTeam  Team      `json:"awayTeam"`
+	Finished  bool      `json:"finished"`
+	HomeGoals int       `json:"homeGoals"`
+	AwayGoals int       `json:"awayGoals"`
+	Winner    string    `json:"winner,omitempty"`   // HOME_TEAM, AWAY_TEAM or DRAW
+	Duration  string    `json:"duration,omitempty"` // REGULAR, EXTRA_TIME or PENALTY_SHOOTOUT
+}
+
+// TableOptions controls how standings tables are built
+type TableOptions struct {
+	Type  TableType
+	LastN int // when positive, only each team's last N results count
+	Rules StandingsRules
+}
+
+// tableStages are the stages whose matches make up a league table
+var tableStages = map[string]bool{
+	"":               true,
+	"REGULAR_SEASON": true,
+	"LEAGUE_STAGE":   true,
+	"GROUP_STAGE":    true,
+}
+
+// BuildStandingTables builds one table per group (or a single table when
+// matches have no group) from results in chronological order. Unfinished
+// matches only contribute their teams so every participant is listed.
+// Knockout matches are ignored.
+func BuildStandingTables(results []MatchResult, opts TableOptions) []StandingTable {
+	if opts.Type == "" {
+		opts.Type = TableTotal
+	}
+	if opts.Rules.PointsForWin == 0 && len(opts.Rules.Tiebreakers) == 0 {
+		opts.Rules = DefaultStandingsRules
+	}
+
+	type groupTable struct {
+		stage   string
+		group   *string
+		teams   map[int]Team
+		results []MatchResult
+	}
+
+	groups := make(map[string]*groupTable)
+	var keys []string
+
+	for _, result := range results {
+		if result.Group == nil && !tableStages[result.Stage] {
+			continue
+		}
+
+		key := ""
+		if result.Group != nil {
+			key = *result.Group
+		}
+		g, ok := groups[key]
+		if !ok {
+			stage := result.Stage
+			if stage == "" {
+				stage = "REGULAR_SEASON"
+			}
+			g = &groupTable{stage: stage, group: result.Group, teams: make(map[int]Team)}
+			groups[key] = g
+			keys = append(keys, key)
+		}
+
+		g.teams[result.HomeTeam.ID] = result.HomeTeam
+		g.teams[result.AwayTeam.ID] = result.AwayTeam
+		if result.Finished {
+			g.results = append(g.results, result)
+		}
+	}
+
+	sort.Strings(keys)
+	tables := make([]StandingTable, 0, len(keys))
+	for _, key := range keys {
+		g := groups[key]
+		tables = append(tables, StandingTable{
+			Stage: g.stage,
+			Type:  string(opts.Type),
+			Group: g.group,
+			Table: buildTable(g.teams, g.results, opts),
+		})
+	}
+
+	return tables
+}
+
+// appearance is one team's side of a finished match
+type appearance struct {
+	TeamID       int
+	OpponentID   int
+	Away         bool
+	GoalsFor     int
+	GoalsAgainst int
+}
+
+// tableRow accumulates a team's record while a table is built
+type tableRow struct {
+	standing     TeamStanding
+	awayGoalsFor int
+	recent       []string // results, oldest first
+}
+
+// buildTable computes and orders a single table
+func buildTable(teams map[int]Team, results []MatchResult, opts TableOptions) []TeamStanding {
+	rows := make(map[int]*tableRow, len(teams))
+	for id, team := range teams {
+		rows[id] = &tableRow{standing: TeamStanding{Team: team}}
+	}
+
+	counted := countedAppearances(results, opts)
+	for _, a := range counted {
+		rows[a.TeamID].record(a, opts.Rules)
+	}
+
+	ordered := make([]*tableRow, 0, len(rows))
+	for _, row := range rows {
+		ordered = append(ordered, row)
+	}
+	// Start from a deterministic order so ties that survive every rule are stable
+	sort.Slice(ordered, func(i, j int) bool {
+		return ordered[i].standing.Team.Name < ordered[j].standing.Team.Name
+	})
+	ordered = rankRows(ordered, opts.Rules.Tiebreakers, counted, opts.Rules)
+
+	table := make([]TeamStanding, len(ordered))
+	for i, row := range ordered {
+		row.standing.Position = i + 1
+		if len(row.recent) > 0 {
+			// Most recent result first, as returned by football-data.org
+			recent := make([]string, 0, 5)
+			for j := len(row.recent) - 1; j >= 0 && len(recent) < 5; j-- {
+				recent = append(recent, row.recent[j])
+			}
+			form := strings.Join(recent, ",")
+			row.standing.Form = &form
+		}
+		table[i] = row.standing
+	}
+
+	return table
+}
+
+// countedAppearances splits results into the team appearances that count for
+// the table type, keeping only each team's last N when requested
+func countedAppearances(results []MatchResult, opts TableOptions) []appearance {
+	var all []appearance
+	for _, result := range results {
+		if opts.Type != TableAway {
+			all = append(all, appearance{
+				TeamID:       result.HomeTeam.ID,
+				OpponentID:   result.AwayTeam.ID,
+				GoalsFor:     result.HomeGoals,
+				GoalsAgainst: result.AwayGoals,
+			})
+		}
+		if opts.Type != TableHome {
+			all = append(all, appearance{
+				TeamID:       result.AwayTeam.ID,
+				OpponentID:   result.HomeTeam.ID,
+				Away:         true,
+				GoalsFor:     result.AwayGoals,
+				GoalsAgainst: result.HomeGoals,
+			})
+		}
+	}
+
+	if opts.LastN <= 0 {
+		return all
+	}
+
+	// Walk backwards so each team keeps its most recent appearances
+	seen := make(map[int]int)
+	keep := make([]bool, len(all))
+	for i := len(all) - 1; i >= 0; i-- {
+		if seen[all[i].TeamID] < opts.LastN {
+			seen[all[i].TeamID]++
+			keep[i] = true
+		}
+	}
+
+	counted := make([]appearance, 0, len(all))
+	for i, a := range all {
+		if keep[i] {
+			counted = append(counted, a)
+		}
+	}
+	return counted
+}
+
+// record adds an appearance to the row
+func (r *tableRow) record(a appearance, rules StandingsRules) {
+	s := &r.standing
+	s.PlayedGames++
+	s.GoalsFor += a.GoalsFor
+	s.GoalsAgainst += a.GoalsAgainst
+	s.GoalDifference = s.GoalsFor - s.GoalsAgainst
+	if a.Away {
+		r.awayGoalsFor += a.GoalsFor
+	}
+
+	switch {
+	case a.GoalsFor > a.GoalsAgainst:
+		s.Won++
+		s.Points += rules.PointsForWin
+		r.recent = append(r.recent, "W")
+	case a.GoalsFor == a.GoalsAgainst:
+		s.Draw++
+		s.Points += rules.PointsForDraw
+		r.recent = append(r.recent, "D")
+	default:
+		s.Lost++
+		r.recent = append(r.recent, "L")
+	}
+}
+
+// rankRows orders rows by applying tiebreakers in turn to each group of teams
+// still level. Head-to-head criteria are computed among the tied teams only.
+func rankRows(rows []*tableRow, tiebreakers []Tiebreaker, counted []appearance, rules StandingsRules) []*tableRow {
+	if len(rows) <= 1 || len(tiebreakers) == 0 {
+		return rows
+	}
+
+	values := criterionValues(rows, tiebreakers[0], counted, rules)
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		return values[rows[i].standing.Team.ID] > values[rows[j].standing.Team.ID]
+	})
+
+	ranked := make([]*tableRow, 0, len(rows))
+	for start := 0; start < len(rows); {
+		end := start + 1
+		for end < len(rows) && values[rows[end].standing.Team.ID] == values[rows[start].standing.Team.ID] {
+			end++
+		}
+		ranked = append(ranked, rankRows(rows[start:end], tiebreakers[1:], counted, rules)...)
+		start = end
+	}
+
+	return ranked
+}
+
+// criterionValues returns each row's value for a tiebreaker; higher ranks first
+func criterionValues(rows []*tableRow, criterion Tiebreaker, counted []appearance, rules StandingsRules) map[int]int {
+	values := make(map[int]int, len(rows))
+
+	switch criterion {
+	case TiebreakH2HPoints, TiebreakH2HGoalDiff, TiebreakH2HGoalsFor, TiebreakH2HAwayGoalsFor:
+		// Build a mini-table from matches between the tied teams only
+		mini := make(map[int]*tableRow, len(rows))
+		for _, row := range rows {
+			mini[row.standing.Team.ID] = &tableRow{}
+		}
+		for _, a := range counted {
+			if mini[a.TeamID] == nil || mini[a.OpponentID] == nil {
+				continue
+			}
+			mini[a.TeamID].record(a, rules)
+		}
+
+		for id, row := range mini {
+			switch criterion {
+			case TiebreakH2HPoints:
+				values[id] = row.standing.Points
+			case TiebreakH2HGoalDiff:
+				values[id] = row.standing.GoalDifference
+			case TiebreakH2HGoalsFor:
+				values[id] = row.standing.GoalsFor
+			case TiebreakH2HAwayGoalsFor:
+				values[id] = row.awayGoalsFor
+			}
+		}
+	default:
+		for _, row := range rows {
+			s := row.standing
+			switch criterion {
+			case TiebreakPoints:
+				values[s.Team.ID] = s.Points
+			case TiebreakGoalDifference:
+				values[s.Team.ID] = s.GoalDifference
+			case TiebreakGoalsFor:
+				values[s.Team.ID] = s.GoalsFor
+			case TiebreakWins:
+				values[s.Team.ID] = s.Won
+			case TiebreakAwayGoalsFor:
+				values[s.Team.ID] = row.awayGoalsFor
+			}
+		}
+	}
+
+	return values
+}
@@ -0,0 +1,204 @@
+package footballdata
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// StandingsOptions selects the table computed by the standings calculator
+type StandingsOptions struct {
+	AsOf     time.Time // only matches kicking off before this instant count; zero means now
+	SeasonID int       // zero picks the season in progress at AsOf
+	Type     TableType
+	LastN    int
+}
+
+// ComputedStandings is a standings snapshot computed from stored matches
+type ComputedStandings struct {
+	Standing
+	AsOf  time.Time      `json:"asOf"`
+	Rules StandingsRules `json:"rules"`
+}
+
+// StandingsCalculator computes league tables from stored match results
+type StandingsCalculator struct {
+	db   *sql.DB
+	repo *Repository
+}
+
+// NewStandingsCalculator creates a new standings calculator
+func NewStandingsCalculator(db *sql.DB) *StandingsCalculator {
+	return &StandingsCalculator{db: db, repo: NewRepository(db)}
+}
+
+// ComputeStandings builds the competition's tables as they stood at opts.AsOf
+func (s *StandingsCalculator) ComputeStandings(ctx context.Context, competitionID int, opts StandingsOptions) (*ComputedStandings, error) {
+	if opts.AsOf.IsZero() {
+		opts.AsOf = time.Now()
+	}
+
+	comp, err := s.repo.GetCompetition(ctx, competitionID)
+	if err != nil {
+		return nil, err
+	}
+
+	seasonID := opts.SeasonID
+	if seasonID == 0 {
+		seasonID, err = s.seasonAt(ctx, competitionID, opts.AsOf)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	results, err := s.loadResults(ctx, competitionID, seasonID, opts.AsOf)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := RulesForCompetition(comp.Code)
+	tables := BuildStandingTables(results, TableOptions{
+		Type:  opts.Type,
+		LastN: opts.LastN,
+		Rules: rules,
+	})
+
+	season := Season{ID: seasonID}
+	for _, candidate := range comp.Seasons {
+		if candidate.ID == seasonID {
+			season = candidate
+			break
+		}
+	}
+	if season.ID == comp.CurrentSeason.ID {
+		season = comp.CurrentSeason
+	}
+
+	return &ComputedStandings{
+		Standing: Standing{
+			Competition: *comp,
+			Season:      season,
+			Standings:   tables,
+		},
+		AsOf:  opts.AsOf,
+		Rules: rules,
+	}, nil
+}
+
+// TeamStandingAt returns a team's row in the table as it stood at asOf
+func (s *StandingsCalculator) TeamStandingAt(ctx context.Context, competitionID, teamID int, asOf time.Time) (*TeamStanding, error) {
+	standings, err := s.ComputeStandings(ctx, competitionID, StandingsOptions{AsOf: asOf})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, table := range standings.Standings {
+		for i := range table.Table {
+			if table.Table[i].Team.ID == teamID {
+				return &table.Table[i], nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("team not found in standings")
+}
+
+// seasonAt finds the season in progress at the given time, falling back to
+// the next season when the cut-off is before its first match
+func (s *StandingsCalculator) seasonAt(ctx context.Context, competitionID int, asOf time.Time) (int, error) {
+	var seasonID int
+	err := s.db.QueryRowContext(ctx, `
+		SELECT season_id FROM matches
+		WHERE competition_id = $1 AND utc_date < $2
+		ORDER BY utc_date DESC
+		LIMIT 1
+	`, competitionID, asOf).Scan(&seasonID)
+	if err == sql.ErrNoRows {
+		err = s.db.QueryRowContext(ctx, `
+			SELECT season_id FROM matches
+			WHERE competition_id = $1
+			ORDER BY utc_date ASC
+			LIMIT 1
+		`, competitionID).Scan(&seasonID)
+	}
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, fmt.Errorf("no matches found for competition")
+		}
+		return 0, fmt.Errorf("failed to resolve season: %w", err)
+	}
+
+	return seasonID, nil
+}
+
+// loadResults loads a season's matches in chronological order, marking those
+// finished before the cut-off
+func (s *StandingsCalculator) loadResults(ctx context.Context, competitionID, seasonID int, asOf time.Time) ([]MatchResult, error) {
+	query := `
+		SELECT m.id, m.utc_date, m.status, COALESCE(m.stage, ''), m.group_name,
+		       m.home_team_id, COALESCE(ht.name, ''), COALESCE(ht.short_name, ''), COALESCE(ht.tla, ''), COALESCE(ht.crest, ''),
+		       m.away_team_id, COALESCE(at.name, ''), COALESCE(at.short_name, ''), COALESCE(at.tla, ''), COALESCE(at.crest, ''),
+		       m.home_score_ft, m.away_score_ft, COALESCE(m.winner, ''), COALESCE(m.score->>'duration', '')
+		FROM matches m
+		LEFT JOIN teams ht ON ht.id = m.home_team_id
+		LEFT JOIN teams at ON at.id = m.away_team_id
+		WHERE m.competition_id = $1
+		  AND m.season_id = $2
+		  AND m.home_team_id IS NOT NULL
+		  AND m.away_team_id IS NOT NULL
+		ORDER BY m.utc_date, m.id
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, competitionID, seasonID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query matches: %w", err)
+	}
+	defer rows.Close()
+
+	var results []MatchResult
+	for rows.Next() {
+		var result MatchResult
+		var group sql.NullString
+		var homeGoals, awayGoals sql.NullInt64
+
+		if err := rows.Scan(
+			&result.MatchID,
+			&result.UTCDate,
+			&result.Status,
+			&result.Stage,
+			&group,
+			&result.HomeTeam.ID,
+			&result.HomeTeam.Name,
+			&result.HomeTeam.ShortName,
+			&result.HomeTeam.TLA,
+			&result.HomeTeam.Crest,
+			&result.AwayTeam.ID,
+			&result.AwayTeam.Name,
+			&result.AwayTeam.ShortName,
+			&result.AwayTeam.TLA,
+			&result.AwayTeam.Crest,
+			&homeGoals,
+			&awayGoals,
+			&result.Winner,
+			&result.Duration,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan match: %w", err)
+		}
+
+		if group.Valid {
+			result.Group = &group.String
+		}
+		result.Finished = result.Status == "FINISHED" && homeGoals.Valid && awayGoals.Valid && result.UTCDate.Before(asOf)
+		result.HomeGoals = int(homeGoals.Int64)
+		result.AwayGoals = int(awayGoals.Int64)
+
+		results = append(results, result)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate matches: %w", err)
+	}
+
+	return results, nil
+}
@@ -0,0 +1,214 @@
+package footballdata
+
+import (
+	"testing"
+	"time"
+)
+
+var (
+	testTeamA = Team{ID: 1, Name: "Alpha"}
+	testTeamB = Team{ID: 2, Name: "Bravo"}
+	testTeamC = Team{ID: 3, Name: "Charlie"}
+	testTeamD = Team{ID: 4, Name: "Delta"}
+)
+
+// newTestResult creates a finished result played on the given day of September 2024
+func newTestResult(day int, home, away Team, homeGoals, awayGoals int) MatchResult {
+	return MatchResult{
+		MatchID:   day*100 + home.ID*10 + away.ID,
+		UTCDate:   time.Date(2024, 9, day, 15, 0, 0, 0, time.UTC),
+		HomeTeam:  home,
+		AwayTeam:  away,
+		Finished:  true,
+		HomeGoals: homeGoals,
+		AwayGoals: awayGoals,
+	}
+}
+
+// tableOrder returns the team IDs of a table in position order
+func tableOrder(table []TeamStanding) []int {
+	ids := make([]int, len(table))
+	for i, row := range table {
+		ids[i] = row.Team.ID
+	}
+	return ids
+}
+
+func equalIDs(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestBuildStandingTables(t *testing.T) {
+	t.Parallel()
+
+	results := []MatchResult{
+		newTestResult(1, testTeamA, testTeamB, 2, 0),
+		newTestResult(1, testTeamC, testTeamD, 1, 1),
+		newTestResult(8, testTeamB, testTeamC, 3, 1),
+		newTestResult(8, testTeamD, testTeamA, 0, 1),
+		newTestResult(15, testTeamA, testTeamC, 0, 2),
+		newTestResult(15, testTeamB, testTeamD, 2, 2),
+	}
+	upcoming := newTestResult(22, testTeamC, testTeamA, 0, 0)
+	upcoming.Finished = false
+	results = append(results, upcoming)
+
+	tests := []struct {
+		name      string
+		opts      TableOptions
+		wantOrder []int
+		wantForm  map[int]string
+	}{
+		{
+			name:      "total table",
+			opts:      TableOptions{Type: TableTotal},
+			wantOrder: []int{1, 2, 3, 4},
+			wantForm:  map[int]string{1: "L,W,W", 4: "D,L,D"},
+		},
+		{
+			name:      "home table",
+			opts:      TableOptions{Type: TableHome},
+			wantOrder: []int{2, 1, 3, 4},
+		},
+		{
+			name:      "away table",
+			opts:      TableOptions{Type: TableAway},
+			wantOrder: []int{1, 3, 4, 2},
+		},
+		{
+			name:      "last two matches",
+			opts:      TableOptions{LastN: 2},
+			wantOrder: []int{2, 3, 1, 4},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			tables := BuildStandingTables(results, tt.opts)
+			if len(tables) != 1 {
+				t.Fatalf("got %d tables, want 1", len(tables))
+			}
+			table := tables[0].Table
+
+			if got := tableOrder(table); !equalIDs(got, tt.wantOrder) {
+				t.Errorf("order = %v, want %v", got, tt.wantOrder)
+			}
+			for i, row := range table {
+				if row.Position != i+1 {
+					t.Errorf("row %d has position %d", i, row.Position)
+				}
+				if want, ok := tt.wantForm[row.Team.ID]; ok && (row.Form == nil || *row.Form != want) {
+					t.Errorf("team %d form = %v, want %s", row.Team.ID, row.Form, want)
+				}
+			}
+		})
+	}
+}
+
+func TestBuildStandingTables_ListsTeamsWithoutResults(t *testing.T) {
+	t.Parallel()
+
+	upcoming := newTestResult(1, testTeamA, testTeamB, 0, 0)
+	upcoming.Finished = false
+
+	tables := BuildStandingTables([]MatchResult{upcoming}, TableOptions{})
+	if len(tables) != 1 || len(tables[0].Table) != 2 {
+		t.Fatalf("tables = %+v, want one table with two teams", tables)
+	}
+	for _, row := range tables[0].Table {
+		if row.PlayedGames != 0 || row.Points != 0 || row.Form != nil {
+			t.Errorf("team %d has results before kickoff: %+v", row.Team.ID, row)
+		}
+	}
+}
+
+func TestBuildStandingTables_Tiebreakers(t *testing.T) {
+	t.Parallel()
+
+	// All three teams finish on three points with level goal difference;
+	// Charlie scored most, and Bravo won the meeting with Alpha
+	results := []MatchResult{
+		newTestResult(1, testTeamA, testTeamB, 0, 1),
+		newTestResult(8, testTeamA, testTeamC, 2, 1),
+		newTestResult(15, testTeamB, testTeamC, 1, 2),
+	}
+
+	tests := []struct {
+		name      string
+		rules     StandingsRules
+		wantOrder []int
+	}{
+		{
+			name:      "head-to-head decides after goal difference",
+			rules:     DefaultStandingsRules,
+			wantOrder: []int{3, 2, 1},
+		},
+		{
+			name: "goals scored without head-to-head",
+			rules: StandingsRules{PointsForWin: 3, PointsForDraw: 1, Tiebreakers: []Tiebreaker{
+				TiebreakPoints, TiebreakGoalDifference, TiebreakGoalsFor,
+			}},
+			// Alpha and Bravo stay level and fall back to name order
+			wantOrder: []int{3, 1, 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			tables := BuildStandingTables(results, TableOptions{Rules: tt.rules})
+			if got := tableOrder(tables[0].Table); !equalIDs(got, tt.wantOrder) {
+				t.Errorf("order = %v, want %v", got, tt.wantOrder)
+			}
+		})
+	}
+}
+
+func TestBuildStandingTables_Groups(t *testing.T) {
+	t.Parallel()
+
+	groupA, groupB := "GROUP_A", "GROUP_B"
+	first := newTestResult(1, testTeamA, testTeamB, 1, 0)
+	first.Stage, first.Group = "GROUP_STAGE", &groupA
+	second := newTestResult(1, testTeamC, testTeamD, 0, 2)
+	second.Stage, second.Group = "GROUP_STAGE", &groupB
+	final := newTestResult(20, testTeamA, testTeamD, 1, 1)
+	final.Stage = "FINAL"
+
+	tables := BuildStandingTables([]MatchResult{second, first, final}, TableOptions{})
+	if len(tables) != 2 {
+		t.Fatalf("got %d tables, want 2", len(tables))
+	}
+	if *tables[0].Group != groupA || *tables[1].Group != groupB {
+		t.Errorf("groups = %s, %s, want %s, %s", *tables[0].Group, *tables[1].Group, groupA, groupB)
+	}
+	if tables[0].Stage != "GROUP_STAGE" {
+		t.Errorf("stage = %s, want GROUP_STAGE", tables[0].Stage)
+	}
+	// The final is a knockout match and must not count
+	if got := tables[1].Table[0]; got.Team.ID != testTeamD.ID || got.PlayedGames != 1 {
+		t.Errorf("group B leader = %+v, want Delta with one game", got)
+	}
+}
+
+func TestRulesForCompetition(t *testing.T) {
+	t.Parallel()
+
+	if got := RulesForCompetition("PL").Tiebreakers[1]; got != TiebreakGoalDifference {
+		t.Errorf("PL second tiebreaker = %s, want %s", got, TiebreakGoalDifference)
+	}
+	if got := RulesForCompetition("PD").Tiebreakers[1]; got != TiebreakH2HPoints {
+		t.Errorf("PD second tiebreaker = %s, want %s", got, TiebreakH2HPoints)
+	}
+}
@@ -1,5 +1,12 @@
 package footballdata
 
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
 // ExtendedMatchStats represents extended match statistics
 type ExtendedMatchStats struct {
 	Possession    TeamStatPair `json:"possession"`
@@ -19,3 +26,340 @@ type TeamStatPair struct {
 	Home float64 `json:"home"`
 	Away float64 `json:"away"`
 }
+
+// StatAvailability records which optional stats a match's source provided.
+// Missing stats are stored as NULL and read as zero
+type StatAvailability struct {
+	HasShots         bool `json:"hasShots"`
+	HasShotsOnTarget bool `json:"hasShotsOnTarget"`
+	HasPossession    bool `json:"hasPossession"`
+	HasPassAccuracy  bool `json:"hasPassAccuracy"`
+	HasExpectedGoals bool `json:"hasXG"`
+	HasCards         bool `json:"hasCards"` // yellow and red
+}
+
+// MatchStatistics is the stored extended statistics for a match
+type MatchStatistics struct {
+	MatchID int    `json:"matchId"`
+	Source  string `json:"source"`
+	ExtendedMatchStats
+	StatAvailability
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// TeamStatsAggregate summarises a team's underlying performance over recent matches.
+// Per-match figures are averages over the matches that have the stat; xG figures
+// only cover matches with xG, and shot figures matches with shots.
+type TeamStatsAggregate struct {
+	TeamID               int     `json:"teamId"`
+	Matches              int     `json:"matches"`
+	XGMatches            int     `json:"xgMatches"`
+	ShotMatches          int     `json:"shotMatches"`
+	XGFor                float64 `json:"xgFor"`
+	XGAgainst            float64 `json:"xgAgainst"`
+	GoalsMinusXG         float64 `json:"goalsMinusXg"` // positive means finishing above expectation
+	ShotsFor             float64 `json:"shotsFor"`
+	ShotsAgainst         float64 `json:"shotsAgainst"`
+	ShotsOnTargetFor     float64 `json:"shotsOnTargetFor"`
+	ShotsOnTargetAgainst float64 `json:"shotsOnTargetAgainst"`
+	ShotConversion       float64 `json:"shotConversion"`     // goals per shot
+	OnTargetConversion   float64 `json:"onTargetConversion"` // goals per shot on target
+	Possession           float64 `json:"possession"`
+	PassAccuracy         float64 `json:"passAccuracy"`
+}
+
+// DefaultStatsWindow is the number of recent matches used for rolling aggregates
+const DefaultStatsWindow = 10
+
+// matchStatisticsColumns lists the stat columns in scan order
+const matchStatisticsColumns = `
+	COALESCE(s.home_possession, 0), COALESCE(s.away_possession, 0),
+	COALESCE(s.home_shots, 0), COALESCE(s.away_shots, 0),
+	COALESCE(s.home_shots_on_target, 0), COALESCE(s.away_shots_on_target, 0),
+	COALESCE(s.home_corners, 0), COALESCE(s.away_corners, 0),
+	COALESCE(s.home_fouls, 0), COALESCE(s.away_fouls, 0),
+	COALESCE(s.home_yellow_cards, 0), COALESCE(s.away_yellow_cards, 0),
+	COALESCE(s.home_red_cards, 0), COALESCE(s.away_red_cards, 0),
+	COALESCE(s.home_offsides, 0), COALESCE(s.away_offsides, 0),
+	COALESCE(s.home_pass_accuracy, 0), COALESCE(s.away_pass_accuracy, 0),
+	s.home_xg, s.away_xg,
+	s.home_shots IS NOT NULL AND s.away_shots IS NOT NULL,
+	s.home_shots_on_target IS NOT NULL AND s.away_shots_on_target IS NOT NULL,
+	s.home_possession IS NOT NULL AND s.away_possession IS NOT NULL,
+	s.home_pass_accuracy IS NOT NULL AND s.away_pass_accuracy IS NOT NULL,
+	` + hasCardsColumn
+
+// hasCardsColumn reports whether a match_statistics row s has card counts
+const hasCardsColumn = `(s.home_yellow_cards IS NOT NULL AND s.away_yellow_cards IS NOT NULL AND
+	 s.home_red_cards IS NOT NULL AND s.away_red_cards IS NOT NULL)`
+
+// statsScanTargets returns scan destinations matching matchStatisticsColumns
+func statsScanTargets(stats *ExtendedMatchStats, available *StatAvailability, homeXG, awayXG *sql.NullFloat64) []any {
+	return []any{
+		&stats.Possession.Home, &stats.Possession.Away,
+		&stats.Shots.Home, &stats.Shots.Away,
+		&stats.ShotsOnTarget.Home, &stats.ShotsOnTarget.Away,
+		&stats.Corners.Home, &stats.Corners.Away,
+		&stats.Fouls.Home, &stats.Fouls.Away,
+		&stats.YellowCards.Home, &stats.YellowCards.Away,
+		&stats.RedCards.Home, &stats.RedCards.Away,
+		&stats.Offsides.Home, &stats.Offsides.Away,
+		&stats.PassAccuracy.Home, &stats.PassAccuracy.Away,
+		homeXG, awayXG,
+		&available.HasShots,
+		&available.HasShotsOnTarget,
+		&available.HasPossession,
+		&available.HasPassAccuracy,
+		&available.HasCards,
+	}
+}
+
+// nullableStat returns a stat pair for storage, NULL when the source lacked it
+func nullableStat(pair TeamStatPair, available bool) (sql.NullFloat64, sql.NullFloat64) {
+	return sql.NullFloat64{Float64: pair.Home, Valid: available}, sql.NullFloat64{Float64: pair.Away, Valid: available}
+}
+
+// nullableCount returns a counted stat pair for storage, NULL when the source lacked it
+func nullableCount(pair TeamStatPair, available bool) (sql.NullInt64, sql.NullInt64) {
+	return sql.NullInt64{Int64: int64(pair.Home), Valid: available}, sql.NullInt64{Int64: int64(pair.Away), Valid: available}
+}
+
+// SaveMatchStatistics inserts or updates a match's extended statistics
+func (r *Repository) SaveMatchStatistics(ctx context.Context, stats *MatchStatistics) error {
+	query := `
+		INSERT INTO match_statistics (
+			match_id, source,
+			home_possession, away_possession, home_shots, away_shots,
+			home_shots_on_target, away_shots_on_target, home_corners, away_corners,
+			home_fouls, away_fouls, home_yellow_cards, away_yellow_cards,
+			home_red_cards, away_red_cards, home_offsides, away_offsides,
+			home_pass_accuracy, away_pass_accuracy, home_xg, away_xg, updated_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23)
+		ON CONFLICT (match_id) DO UPDATE SET
+			source = EXCLUDED.source,
+			home_possession = EXCLUDED.home_possession,
+			away_possession = EXCLUDED.away_possession,
+			home_shots = EXCLUDED.home_shots,
+			away_shots = EXCLUDED.away_shots,
+			home_shots_on_target = EXCLUDED.home_shots_on_target,
+			away_shots_on_target = EXCLUDED.away_shots_on_target,
+			home_corners = EXCLUDED.home_corners,
+			away_corners = EXCLUDED.away_corners,
+			home_fouls = EXCLUDED.home_fouls,
+			away_fouls = EXCLUDED.away_fouls,
+			home_yellow_cards = EXCLUDED.home_yellow_cards,
+			away_yellow_cards = EXCLUDED.away_yellow_cards,
+			home_red_cards = EXCLUDED.home_red_cards,
+			away_red_cards = EXCLUDED.away_red_cards,
+			home_offsides = EXCLUDED.home_offsides,
+			away_offsides = EXCLUDED.away_offsides,
+			home_pass_accuracy = EXCLUDED.home_pass_accuracy,
+			away_pass_accuracy = EXCLUDED.away_pass_accuracy,
+			home_xg = EXCLUDED.home_xg,
+			away_xg = EXCLUDED.away_xg,
+			updated_at = EXCLUDED.updated_at
+	`
+
+	homeXG, awayXG := nullableStat(stats.ExpectedGoals, stats.HasExpectedGoals)
+	homePossession, awayPossession := nullableStat(stats.Possession, stats.HasPossession)
+	homePassAccuracy, awayPassAccuracy := nullableStat(stats.PassAccuracy, stats.HasPassAccuracy)
+	homeShots, awayShots := nullableCount(stats.Shots, stats.HasShots)
+	homeOnTarget, awayOnTarget := nullableCount(stats.ShotsOnTarget, stats.HasShotsOnTarget)
+	homeYellow, awayYellow := nullableCount(stats.YellowCards, stats.HasCards)
+	homeRed, awayRed := nullableCount(stats.RedCards, stats.HasCards)
+
+	_, err := r.db.ExecContext(ctx, query,
+		stats.MatchID,
+		stats.Source,
+		homePossession, awayPossession,
+		homeShots, awayShots,
+		homeOnTarget, awayOnTarget,
+		int(stats.Corners.Home), int(stats.Corners.Away),
+		int(stats.Fouls.Home), int(stats.Fouls.Away),
+		homeYellow, awayYellow,
+		homeRed, awayRed,
+		int(stats.Offsides.Home), int(stats.Offsides.Away),
+		homePassAccuracy, awayPassAccuracy,
+		homeXG, awayXG,
+		time.Now(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save match statistics: %w", err)
+	}
+
+	return nil
+}
+
+// GetMatchStatistics retrieves a match's extended statistics
+func (r *Repository) GetMatchStatistics(ctx context.Context, matchID int) (*MatchStatistics, error) {
+	query := `
+		SELECT s.match_id, COALESCE(s.source, ''), s.updated_at,` + matchStatisticsColumns + `
+		FROM match_statistics s
+		WHERE s.match_id = $1
+	`
+
+	var stats MatchStatistics
+	var homeXG, awayXG sql.NullFloat64
+	targets := append([]any{&stats.MatchID, &stats.Source, &stats.UpdatedAt}, statsScanTargets(&stats.ExtendedMatchStats, &stats.StatAvailability, &homeXG, &awayXG)...)
+
+	err := r.db.QueryRowContext(ctx, query, matchID).Scan(targets...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("match statistics not found")
+		}
+		return nil, fmt.Errorf("failed to get match statistics: %w", err)
+	}
+
+	stats.HasExpectedGoals = homeXG.Valid && awayXG.Valid
+	stats.ExpectedGoals = TeamStatPair{Home: homeXG.Float64, Away: awayXG.Float64}
+
+	return &stats, nil
+}
+
+// GetTeamStatsAggregate computes rolling aggregates over a team's last finished
+// matches with statistics that kicked off before the given time
+func (r *Repository) GetTeamStatsAggregate(ctx context.Context, teamID int, before time.Time, limit int) (*TeamStatsAggregate, error) {
+	if limit <= 0 {
+		limit = DefaultStatsWindow
+	}
+
+	query := `
+		SELECT m.home_team_id = $1, m.home_score_ft, m.away_score_ft,` + matchStatisticsColumns + `
+		FROM match_statistics s
+		JOIN matches m ON m.id = s.match_id
+		WHERE (m.home_team_id = $1 OR m.away_team_id = $1)
+		  AND m.status = 'FINISHED'
+		  AND m.home_score_ft IS NOT NULL
+		  AND m.away_score_ft IS NOT NULL
+		  AND m.utc_date < $2
+		ORDER BY m.utc_date DESC
+		LIMIT $3
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, teamID, before, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query team statistics: %w", err)
+	}
+	defer rows.Close()
+
+	var matches []teamMatchStats
+	for rows.Next() {
+		var m teamMatchStats
+		var homeGoals, awayGoals int
+		var homeXG, awayXG sql.NullFloat64
+		targets := append([]any{&m.Home, &homeGoals, &awayGoals}, statsScanTargets(&m.Stats, &m.StatAvailability, &homeXG, &awayXG)...)
+		if err := rows.Scan(targets...); err != nil {
+			return nil, fmt.Errorf("failed to scan team statistics: %w", err)
+		}
+
+		m.GoalsFor, m.GoalsAgainst = homeGoals, awayGoals
+		if !m.Home {
+			m.GoalsFor, m.GoalsAgainst = awayGoals, homeGoals
+		}
+		m.HasExpectedGoals = homeXG.Valid && awayXG.Valid
+		m.Stats.ExpectedGoals = TeamStatPair{Home: homeXG.Float64, Away: awayXG.Float64}
+
+		matches = append(matches, m)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate team statistics: %w", err)
+	}
+
+	return aggregateTeamStats(teamID, matches), nil
+}
+
+// teamMatchStats is one match's statistics seen from a team's side
+type teamMatchStats struct {
+	Home         bool
+	GoalsFor     int
+	GoalsAgainst int
+	Stats        ExtendedMatchStats
+	StatAvailability
+}
+
+// forAgainst returns a stat from the team's perspective
+func (m teamMatchStats) forAgainst(pair TeamStatPair) (float64, float64) {
+	if m.Home {
+		return pair.Home, pair.Away
+	}
+	return pair.Away, pair.Home
+}
+
+// aggregateTeamStats averages a team's match statistics, each stat over the
+// matches that have it
+func aggregateTeamStats(teamID int, matches []teamMatchStats) *TeamStatsAggregate {
+	agg := &TeamStatsAggregate{TeamID: teamID, Matches: len(matches)}
+
+	var xgGoals, shotGoals, onTargetGoals, onTargetMatches, possessionMatches, passMatches int
+	var shots, onTarget float64
+	for _, m := range matches {
+		if m.HasShots {
+			shotsFor, shotsAgainst := m.forAgainst(m.Stats.Shots)
+			agg.ShotMatches++
+			agg.ShotsFor += shotsFor
+			agg.ShotsAgainst += shotsAgainst
+			shots += shotsFor
+			shotGoals += m.GoalsFor
+		}
+		if m.HasShotsOnTarget {
+			onTargetFor, onTargetAgainst := m.forAgainst(m.Stats.ShotsOnTarget)
+			onTargetMatches++
+			agg.ShotsOnTargetFor += onTargetFor
+			agg.ShotsOnTargetAgainst += onTargetAgainst
+			onTarget += onTargetFor
+			onTargetGoals += m.GoalsFor
+		}
+		if m.HasPossession {
+			possession, _ := m.forAgainst(m.Stats.Possession)
+			possessionMatches++
+			agg.Possession += possession
+		}
+		if m.HasPassAccuracy {
+			passAccuracy, _ := m.forAgainst(m.Stats.PassAccuracy)
+			passMatches++
+			agg.PassAccuracy += passAccuracy
+		}
+
+		if m.HasExpectedGoals {
+			xgFor, xgAgainst := m.forAgainst(m.Stats.ExpectedGoals)
+			agg.XGMatches++
+			agg.XGFor += xgFor
+			agg.XGAgainst += xgAgainst
+			xgGoals += m.GoalsFor
+		}
+	}
+
+	if agg.ShotMatches > 0 {
+		agg.ShotsFor /= float64(agg.ShotMatches)
+		agg.ShotsAgainst /= float64(agg.ShotMatches)
+	}
+	if onTargetMatches > 0 {
+		agg.ShotsOnTargetFor /= float64(onTargetMatches)
+		agg.ShotsOnTargetAgainst /= float64(onTargetMatches)
+	}
+	if possessionMatches > 0 {
+		agg.Possession /= float64(possessionMatches)
+	}
+	if passMatches > 0 {
+		agg.PassAccuracy /= float64(passMatches)
+	}
+
+	// Conversion only counts goals from matches with the shot data
+	if shots > 0 {
+		agg.ShotConversion = float64(shotGoals) / shots
+	}
+	if onTarget > 0 {
+		agg.OnTargetConversion = float64(onTargetGoals) / onTarget
+	}
+
+	if agg.XGMatches > 0 {
+		xgMatches := float64(agg.XGMatches)
+		agg.GoalsMinusXG = (float64(xgGoals) - agg.XGFor) / xgMatches
+		agg.XGFor /= xgMatches
+		agg.XGAgainst /= xgMatches
+	}
+
+	return agg
+}
@@ -0,0 +1,240 @@
+package footballdata
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// statsImportRecord is one entry of a JSON statistics import file. The optional
+// stats are pointers so files without them can be told apart from zeros.
+type statsImportRecord struct {
+	MatchID int    `json:"matchId"`
+	Source  string `json:"source"`
+	ExtendedMatchStats
+	Shots         *TeamStatPair `json:"shots"`
+	ShotsOnTarget *TeamStatPair `json:"shotsOnTarget"`
+	Possession    *TeamStatPair `json:"possession"`
+	PassAccuracy  *TeamStatPair `json:"passAccuracy"`
+	ExpectedGoals *TeamStatPair `json:"xG"`
+	YellowCards   *TeamStatPair `json:"yellowCards"`
+	RedCards      *TeamStatPair `json:"redCards"`
+}
+
+// optionalStat copies an optional stat from an import record, reporting whether it was given
+func optionalStat(dst *TeamStatPair, src *TeamStatPair) bool {
+	if src == nil {
+		return false
+	}
+	*dst = *src
+	return true
+}
+
+// statsCSVColumns maps CSV stat names (prefixed with home_ or away_) to their pair
+var statsCSVColumns = map[string]func(*ExtendedMatchStats) *TeamStatPair{
+	"possession":      func(s *ExtendedMatchStats) *TeamStatPair { return &s.Possession },
+	"shots":           func(s *ExtendedMatchStats) *TeamStatPair { return &s.Shots },
+	"shots_on_target": func(s *ExtendedMatchStats) *TeamStatPair { return &s.ShotsOnTarget },
+	"corners":         func(s *ExtendedMatchStats) *TeamStatPair { return &s.Corners },
+	"fouls":           func(s *ExtendedMatchStats) *TeamStatPair { return &s.Fouls },
+	"yellow_cards":    func(s *ExtendedMatchStats) *TeamStatPair { return &s.YellowCards },
+	"red_cards":       func(s *ExtendedMatchStats) *TeamStatPair { return &s.RedCards },
+	"offsides":        func(s *ExtendedMatchStats) *TeamStatPair { return &s.Offsides },
+	"pass_accuracy":   func(s *ExtendedMatchStats) *TeamStatPair { return &s.PassAccuracy },
+	"xg":              func(s *ExtendedMatchStats) *TeamStatPair { return &s.ExpectedGoals },
+}
+
+// ParseStatsJSON parses a JSON array of match statistics records. Records
+// without a source use defaultSource.
+func ParseStatsJSON(r io.Reader, defaultSource string) ([]MatchStatistics, error) {
+	var records []statsImportRecord
+	if err := json.NewDecoder(r).Decode(&records); err != nil {
+		return nil, fmt.Errorf("failed to decode statistics: %w", err)
+	}
+
+	stats := make([]MatchStatistics, 0, len(records))
+	for i, record := range records {
+		if record.MatchID == 0 {
+			return nil, fmt.Errorf("record %d: matchId is required", i+1)
+		}
+
+		s := MatchStatistics{
+			MatchID:            record.MatchID,
+			Source:             record.Source,
+			ExtendedMatchStats: record.ExtendedMatchStats,
+		}
+		if s.Source == "" {
+			s.Source = defaultSource
+		}
+		s.HasShots = optionalStat(&s.Shots, record.Shots)
+		s.HasShotsOnTarget = optionalStat(&s.ShotsOnTarget, record.ShotsOnTarget)
+		s.HasPossession = optionalStat(&s.Possession, record.Possession)
+		s.HasPassAccuracy = optionalStat(&s.PassAccuracy, record.PassAccuracy)
+		s.HasExpectedGoals = optionalStat(&s.ExpectedGoals, record.ExpectedGoals)
+		hasYellow := optionalStat(&s.YellowCards, record.YellowCards)
+		hasRed := optionalStat(&s.RedCards, record.RedCards)
+		s.HasCards = hasYellow && hasRed
+		stats = append(stats, s)
+	}
+
+	return stats, nil
+}
+
+// ParseStatsCSV parses match statistics from CSV with a header row. Columns
+// are match_id, an optional source, and home_/away_ pairs for any of the
+// stats in statsCSVColumns; empty cells are left at zero, and the optional
+// stats count as available only when both sides are given.
+func ParseStatsCSV(r io.Reader, defaultSource string) ([]MatchStatistics, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+
+	matchIDColumn, sourceColumn := -1, -1
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		header[i] = name
+
+		switch name {
+		case "match_id":
+			matchIDColumn = i
+		case "source":
+			sourceColumn = i
+		default:
+			stat, ok := strings.CutPrefix(name, "home_")
+			if !ok {
+				stat, ok = strings.CutPrefix(name, "away_")
+			}
+			if _, known := statsCSVColumns[stat]; !ok || !known {
+				return nil, fmt.Errorf("unknown CSV column %q", name)
+			}
+		}
+	}
+	if matchIDColumn < 0 {
+		return nil, fmt.Errorf("CSV is missing the match_id column")
+	}
+
+	var stats []MatchStatistics
+	for line := 2; ; line++ {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		s := MatchStatistics{Source: defaultSource}
+		s.MatchID, err = strconv.Atoi(strings.TrimSpace(row[matchIDColumn]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid match_id %q", line, row[matchIDColumn])
+		}
+		if sourceColumn >= 0 && strings.TrimSpace(row[sourceColumn]) != "" {
+			s.Source = strings.TrimSpace(row[sourceColumn])
+		}
+
+		// sides counts the sides given of each stat
+		sides := make(map[string]int)
+		for i, name := range header {
+			if i == matchIDColumn || i == sourceColumn {
+				continue
+			}
+			cell := strings.TrimSpace(row[i])
+			if cell == "" {
+				continue
+			}
+
+			value, err := strconv.ParseFloat(cell, 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid %s %q", line, name, cell)
+			}
+
+			if stat, ok := strings.CutPrefix(name, "home_"); ok {
+				statsCSVColumns[stat](&s.ExtendedMatchStats).Home = value
+				sides[stat]++
+			} else {
+				stat := strings.TrimPrefix(name, "away_")
+				statsCSVColumns[stat](&s.ExtendedMatchStats).Away = value
+				sides[stat]++
+			}
+		}
+		s.HasShots = sides["shots"] == 2
+		s.HasShotsOnTarget = sides["shots_on_target"] == 2
+		s.HasPossession = sides["possession"] == 2
+		s.HasPassAccuracy = sides["pass_accuracy"] == 2
+		s.HasExpectedGoals = sides["xg"] == 2
+		s.HasCards = sides["yellow_cards"] == 2 && sides["red_cards"] == 2
+
+		stats = append(stats, s)
+	}
+
+	return stats, nil
+}
+
+// ImportSummary reports the outcome of a statistics or odds import
+type ImportSummary struct {
+	Imported int      `json:"imported"`
+	Failed   int      `json:"failed"`
+	Errors   []string `json:"errors,omitempty"`
+}
+
+// StatsImporter stores extended match statistics from external sources
+type StatsImporter struct {
+	repo *Repository
+}
+
+// NewStatsImporter creates a new statistics importer
+func NewStatsImporter(repo *Repository) *StatsImporter {
+	return &StatsImporter{repo: repo}
+}
+
+// Import saves parsed statistics, continuing past records that fail (such as unknown matches)
+func (i *StatsImporter) Import(ctx context.Context, stats []MatchStatistics) *ImportSummary {
+	summary := &ImportSummary{}
+	for idx := range stats {
+		if err := i.repo.SaveMatchStatistics(ctx, &stats[idx]); err != nil {
+			slog.Error("Failed to import match statistics", "matchId", stats[idx].MatchID, "error", err)
+			summary.Failed++
+			summary.Errors = append(summary.Errors, fmt.Sprintf("match %d: %v", stats[idx].MatchID, err))
+			continue
+		}
+		summary.Imported++
+	}
+
+	slog.Info("Imported match statistics", "imported", summary.Imported, "failed", summary.Failed)
+	return summary
+}
+
+// ImportFile parses a .json or .csv statistics file and imports it
+func (i *StatsImporter) ImportFile(ctx context.Context, path, source string) (*ImportSummary, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open statistics file: %w", err)
+	}
+	defer file.Close()
+
+	var stats []MatchStatistics
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		stats, err = ParseStatsJSON(file, source)
+	case ".csv":
+		stats, err = ParseStatsCSV(file, source)
+	default:
+		return nil, fmt.Errorf("unsupported statistics file type %q", filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return i.Import(ctx, stats), nil
+}
@@ -0,0 +1,189 @@
+package footballdata
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseStatsCSV(t *testing.T) {
+	t.Parallel()
+
+	input := `match_id,source,home_possession,away_possession,home_shots,away_shots,home_xg,away_xg
+497401,fbref,58.5,41.5,14,9,1.8,0.7
+497402,,50,50,10,10,,
+`
+
+	stats, err := ParseStatsCSV(strings.NewReader(input), "import")
+	if err != nil {
+		t.Fatalf("ParseStatsCSV() error = %v", err)
+	}
+	if len(stats) != 2 {
+		t.Fatalf("got %d records, want 2", len(stats))
+	}
+
+	first := stats[0]
+	if first.MatchID != 497401 || first.Source != "fbref" {
+		t.Errorf("first record = %d from %q, want 497401 from fbref", first.MatchID, first.Source)
+	}
+	if first.Possession.Home != 58.5 || first.Shots.Away != 9 {
+		t.Errorf("first record stats = %+v", first.ExtendedMatchStats)
+	}
+	if !first.HasExpectedGoals || first.ExpectedGoals.Home != 1.8 {
+		t.Errorf("first record xG = %+v (has %v), want 1.8 home", first.ExpectedGoals, first.HasExpectedGoals)
+	}
+
+	second := stats[1]
+	if second.Source != "import" {
+		t.Errorf("second record source = %q, want the default", second.Source)
+	}
+	if second.HasExpectedGoals {
+		t.Error("second record has xG, want none for empty cells")
+	}
+	if !second.HasShots || !second.HasPossession || second.HasShotsOnTarget || second.HasPassAccuracy {
+		t.Errorf("second record availability = %+v, want shots and possession only", second.StatAvailability)
+	}
+
+	cards, err := ParseStatsCSV(strings.NewReader(`match_id,home_yellow_cards,away_yellow_cards,home_red_cards,away_red_cards
+1,2,3,0,1
+2,2,3,,
+`), "import")
+	if err != nil {
+		t.Fatalf("ParseStatsCSV() error = %v", err)
+	}
+	if !cards[0].HasCards || cards[1].HasCards {
+		t.Errorf("cards available = %v, %v, want only the first", cards[0].HasCards, cards[1].HasCards)
+	}
+}
+
+func TestParseStatsCSV_Errors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{name: "missing match_id", input: "home_shots,away_shots\n1,2\n"},
+		{name: "unknown column", input: "match_id,home_tackles\n1,2\n"},
+		{name: "invalid number", input: "match_id,home_shots\n1,many\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if _, err := ParseStatsCSV(strings.NewReader(tt.input), ""); err == nil {
+				t.Error("ParseStatsCSV() succeeded, want error")
+			}
+		})
+	}
+}
+
+func TestParseStatsJSON(t *testing.T) {
+	t.Parallel()
+
+	input := `[
+		{"matchId": 1, "shots": {"home": 12, "away": 4}, "xG": {"home": 0, "away": 0.3}},
+		{"matchId": 2, "source": "opta", "shots": {"home": 7, "away": 11}}
+	]`
+
+	stats, err := ParseStatsJSON(strings.NewReader(input), "import")
+	if err != nil {
+		t.Fatalf("ParseStatsJSON() error = %v", err)
+	}
+	if len(stats) != 2 {
+		t.Fatalf("got %d records, want 2", len(stats))
+	}
+	if !stats[0].HasExpectedGoals || stats[0].ExpectedGoals.Away != 0.3 || stats[0].Source != "import" {
+		t.Errorf("first record = %+v", stats[0])
+	}
+	if stats[1].HasExpectedGoals || stats[1].Source != "opta" {
+		t.Errorf("second record = %+v", stats[1])
+	}
+	if !stats[1].HasShots || stats[1].Shots.Away != 11 || stats[1].HasPossession {
+		t.Errorf("second record shots = %+v (available %+v)", stats[1].Shots, stats[1].StatAvailability)
+	}
+
+	if stats[0].HasCards || stats[1].HasCards {
+		t.Errorf("cards available = %v, %v, want none without card counts", stats[0].HasCards, stats[1].HasCards)
+	}
+
+	cards, err := ParseStatsJSON(strings.NewReader(`[
+		{"matchId": 3, "yellowCards": {"home": 0, "away": 0}, "redCards": {"home": 0, "away": 0}},
+		{"matchId": 4, "yellowCards": {"home": 2, "away": 3}}
+	]`), "import")
+	if err != nil {
+		t.Fatalf("ParseStatsJSON() error = %v", err)
+	}
+	if !cards[0].HasCards {
+		t.Error("a match without cards has no card counts, want counts of zero")
+	}
+	if cards[1].HasCards {
+		t.Error("a match without red cards has card counts, want none")
+	}
+
+	if _, err := ParseStatsJSON(strings.NewReader(`[{"shots": {"home": 1}}]`), ""); err == nil {
+		t.Error("ParseStatsJSON() without matchId succeeded, want error")
+	}
+}
+
+func TestAggregateTeamStats(t *testing.T) {
+	t.Parallel()
+
+	matches := []teamMatchStats{
+		{
+			Home: true, GoalsFor: 2, GoalsAgainst: 0,
+			Stats: ExtendedMatchStats{
+				Shots:         TeamStatPair{Home: 10, Away: 6},
+				ShotsOnTarget: TeamStatPair{Home: 5, Away: 2},
+				Possession:    TeamStatPair{Home: 60, Away: 40},
+				ExpectedGoals: TeamStatPair{Home: 1.5, Away: 0.5},
+			},
+			StatAvailability: StatAvailability{HasShots: true, HasShotsOnTarget: true, HasPossession: true, HasExpectedGoals: true},
+		},
+		{
+			Home: false, GoalsFor: 1, GoalsAgainst: 1,
+			Stats: ExtendedMatchStats{
+				Shots:         TeamStatPair{Home: 12, Away: 10},
+				ShotsOnTarget: TeamStatPair{Home: 4, Away: 3},
+				Possession:    TeamStatPair{Home: 55, Away: 45},
+			},
+			StatAvailability: StatAvailability{HasShots: true, HasShotsOnTarget: true, HasPossession: true},
+		},
+		{
+			// Without shot data its goals must not count towards conversion
+			Home: true, GoalsFor: 4, GoalsAgainst: 0,
+		},
+	}
+
+	agg := aggregateTeamStats(7, matches)
+
+	checks := []struct {
+		name      string
+		got, want float64
+	}{
+		{"shots for", agg.ShotsFor, 10},
+		{"shots against", agg.ShotsAgainst, 9},
+		{"shots on target for", agg.ShotsOnTargetFor, 4},
+		{"possession", agg.Possession, 52.5},
+		{"shot conversion", agg.ShotConversion, 3.0 / 20},
+		{"on-target conversion", agg.OnTargetConversion, 3.0 / 8},
+		{"xG for", agg.XGFor, 1.5},
+		{"xG against", agg.XGAgainst, 0.5},
+		{"goals minus xG", agg.GoalsMinusXG, 0.5},
+	}
+	for _, c := range checks {
+		if !almostEqual(c.got, c.want) {
+			t.Errorf("%s = %v, want %v", c.name, c.got, c.want)
+		}
+	}
+	if agg.Matches != 3 || agg.ShotMatches != 2 || agg.XGMatches != 1 {
+		t.Errorf("matches = %d (shots %d, xG %d), want 3 (shots 2, xG 1)", agg.Matches, agg.ShotMatches, agg.XGMatches)
+	}
+	if agg.PassAccuracy != 0 {
+		t.Errorf("pass accuracy = %v, want 0 without data", agg.PassAccuracy)
+	}
+
+	if empty := aggregateTeamStats(7, nil); empty.Matches != 0 || empty.ShotConversion != 0 {
+		t.Errorf("empty aggregate = %+v", empty)
+	}
+}
@@ -1,7 +1,11 @@
 package main
 
 import (
+	"log/slog"
+
 	"github.com/a-h/templ"
+	"github.com/edd/relaxovisionmonolith/betting"
+	"github.com/edd/relaxovisionmonolith/predictions"
 	"github.com/edd/relaxovisionmonolith/templates"
 	"github.com/edd/relaxovisionmonolith/templates/pages"
 	"github.com/gofiber/fiber/v2/middleware/adaptor"
@@ -35,6 +39,37 @@ func indexViewHandler(c *fiber.Ctx) error {
 
 }
 
+// accuracyViewHandler handles a view for the accuracy dashboard, with
+// upcoming value bets and a bankroll backtest of past predictions.
+func accuracyViewHandler(c *fiber.Ctx) error {
+	stats, err := predictions.NewAccuracyService(db).GetOverallStats(c.Context())
+	if err != nil {
+		slog.Error("Failed to get accuracy stats", "error", err)
+	}
+
+	valueBets, err := bettingService.ValueBets(c.Context(), betting.ValueBetFilter{MinEdge: betting.DefaultMinEdge})
+	if err != nil {
+		slog.Error("Failed to find value bets", "error", err)
+	}
+
+	backtest, err := bettingService.Backtest(c.Context(), betting.BacktestFilter{Options: betting.DefaultStakingOptions()})
+	if err != nil {
+		slog.Error("Failed to run bankroll backtest", "error", err)
+	}
+
+	metaTags := pages.MetaTags(
+		"football predictions, accuracy, value bets",              // define meta keywords
+		"Prediction accuracy, value bets and bankroll backtests.", // define meta description
+	)
+	bodyContent := pages.AccuracyDashboard(stats, valueBets, backtest)
+
+	templateHandler := templ.Handler(
+		templates.Layout("Prediction accuracy", metaTags, bodyContent),
+	)
+
+	return adaptor.HTTPHandler(templateHandler)(c)
+}
+
 // showContentAPIHandler handles an API endpoint to show content.
 func showContentAPIHandler(c *fiber.Ctx) error {
 	// Check, if the current request has a 'HX-Request' header.
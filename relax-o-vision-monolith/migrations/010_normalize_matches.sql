@@ -0,0 +1,43 @@
+-- Normalize matches: real team ID and score columns alongside the JSONB payloads
+
+-- Make sure every team referenced by a stored match exists before adding foreign keys
+INSERT INTO teams (id, name, short_name, tla, crest)
+SELECT DISTINCT ON ((t->>'id')::int)
+    (t->>'id')::int,
+    t->>'name',
+    t->>'shortName',
+    t->>'tla',
+    t->>'crest'
+FROM (
+    SELECT home_team AS t FROM matches WHERE home_team ? 'id'
+    UNION ALL
+    SELECT away_team AS t FROM matches WHERE away_team ? 'id'
+) referenced
+WHERE (t->>'id') IS NOT NULL
+ON CONFLICT (id) DO NOTHING;
+
+ALTER TABLE matches ADD COLUMN IF NOT EXISTS home_team_id INTEGER REFERENCES teams(id);
+ALTER TABLE matches ADD COLUMN IF NOT EXISTS away_team_id INTEGER REFERENCES teams(id);
+ALTER TABLE matches ADD COLUMN IF NOT EXISTS home_score_ft INTEGER;
+ALTER TABLE matches ADD COLUMN IF NOT EXISTS away_score_ft INTEGER;
+ALTER TABLE matches ADD COLUMN IF NOT EXISTS home_score_ht INTEGER;
+ALTER TABLE matches ADD COLUMN IF NOT EXISTS away_score_ht INTEGER;
+ALTER TABLE matches ADD COLUMN IF NOT EXISTS winner VARCHAR(20);  -- 'HOME_TEAM', 'AWAY_TEAM', 'DRAW'
+ALTER TABLE matches ADD COLUMN IF NOT EXISTS stage VARCHAR(50);
+
+-- Backfill from the existing JSONB columns
+UPDATE matches SET
+    home_team_id  = (home_team->>'id')::int,
+    away_team_id  = (away_team->>'id')::int,
+    home_score_ft = (score->'fullTime'->>'home')::int,
+    away_score_ft = (score->'fullTime'->>'away')::int,
+    home_score_ht = (score->'halfTime'->>'home')::int,
+    away_score_ht = (score->'halfTime'->>'away')::int,
+    winner        = NULLIF(score->>'winner', '')
+WHERE home_team_id IS NULL OR away_team_id IS NULL;
+
+-- Composite indexes for team history (form, head-to-head) and competition scans
+CREATE INDEX IF NOT EXISTS idx_matches_home_team_date ON matches(home_team_id, utc_date DESC);
+CREATE INDEX IF NOT EXISTS idx_matches_away_team_date ON matches(away_team_id, utc_date DESC);
+CREATE INDEX IF NOT EXISTS idx_matches_teams_pair ON matches(home_team_id, away_team_id, utc_date DESC);
+CREATE INDEX IF NOT EXISTS idx_matches_competition_status_date ON matches(competition_id, status, utc_date);
@@ -0,0 +1,4 @@
+-- Store the competition group so group-stage tables can be computed from matches
+ALTER TABLE matches ADD COLUMN IF NOT EXISTS group_name VARCHAR(50);
+
+CREATE INDEX IF NOT EXISTS idx_matches_competition_season_date ON matches(competition_id, season_id, utc_date);
@@ -0,0 +1,29 @@
+-- Extended per-match statistics imported from external sources
+CREATE TABLE IF NOT EXISTS match_statistics (
+    match_id INTEGER PRIMARY KEY REFERENCES matches(id) ON DELETE CASCADE,
+    source VARCHAR(50),
+    home_possession DECIMAL(5,2),
+    away_possession DECIMAL(5,2),
+    home_shots INTEGER,
+    away_shots INTEGER,
+    home_shots_on_target INTEGER,
+    away_shots_on_target INTEGER,
+    home_corners INTEGER,
+    away_corners INTEGER,
+    home_fouls INTEGER,
+    away_fouls INTEGER,
+    home_yellow_cards INTEGER,
+    away_yellow_cards INTEGER,
+    home_red_cards INTEGER,
+    away_red_cards INTEGER,
+    home_offsides INTEGER,
+    away_offsides INTEGER,
+    home_pass_accuracy DECIMAL(5,2),
+    away_pass_accuracy DECIMAL(5,2),
+    home_xg DECIMAL(5,2),  -- NULL when the source has no expected goals
+    away_xg DECIMAL(5,2),
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_match_statistics_source ON match_statistics(source);
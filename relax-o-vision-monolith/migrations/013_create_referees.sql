@@ -0,0 +1,36 @@
+-- Referees extracted from the match referees JSONB
+CREATE TABLE IF NOT EXISTS referees (
+    id INTEGER PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    nationality VARCHAR(100),
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS match_referees (
+    match_id INTEGER NOT NULL REFERENCES matches(id) ON DELETE CASCADE,
+    referee_id INTEGER NOT NULL REFERENCES referees(id),
+    type VARCHAR(50) NOT NULL,  -- 'REFEREE', 'ASSISTANT_REFEREE_N1', 'VIDEO_ASSISTANT_REFEREE', ...
+    PRIMARY KEY (match_id, referee_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_match_referees_referee_type ON match_referees(referee_id, type);
+
+-- Backfill from already synced matches
+INSERT INTO referees (id, name, nationality)
+SELECT DISTINCT ON ((r->>'id')::int)
+    (r->>'id')::int,
+    r->>'name',
+    NULLIF(r->>'nationality', '')
+FROM matches, jsonb_array_elements(CASE WHEN jsonb_typeof(referees) = 'array' THEN referees ELSE '[]'::jsonb END) r
+WHERE (r->>'id') IS NOT NULL
+ON CONFLICT (id) DO NOTHING;
+
+INSERT INTO match_referees (match_id, referee_id, type)
+SELECT DISTINCT ON (m.id, (r->>'id')::int)
+    m.id,
+    (r->>'id')::int,
+    COALESCE(NULLIF(r->>'type', ''), 'REFEREE')
+FROM matches m, jsonb_array_elements(CASE WHEN jsonb_typeof(m.referees) = 'array' THEN m.referees ELSE '[]'::jsonb END) r
+WHERE (r->>'id') IS NOT NULL
+ON CONFLICT (match_id, referee_id) DO NOTHING;
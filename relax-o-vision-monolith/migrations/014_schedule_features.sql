@@ -0,0 +1,17 @@
+-- Venue coordinates for travel distances (not provided by football-data.org)
+ALTER TABLE teams ADD COLUMN IF NOT EXISTS venue_latitude DOUBLE PRECISION;
+ALTER TABLE teams ADD COLUMN IF NOT EXISTS venue_longitude DOUBLE PRECISION;
+
+-- Schedule load of each team before a match
+CREATE TABLE IF NOT EXISTS match_schedule_features (
+    match_id INTEGER NOT NULL REFERENCES matches(id) ON DELETE CASCADE,
+    team_id INTEGER NOT NULL REFERENCES teams(id),
+    days_since_last_match DECIMAL(6,2),  -- NULL when the team has no earlier match
+    matches_last_7_days INTEGER NOT NULL DEFAULT 0,
+    matches_last_14_days INTEGER NOT NULL DEFAULT 0,
+    matches_last_30_days INTEGER NOT NULL DEFAULT 0,
+    upcoming_big_matches INTEGER NOT NULL DEFAULT 0,
+    travel_km DECIMAL(8,1),  -- NULL unless both venues have coordinates
+    computed_at TIMESTAMP DEFAULT NOW(),
+    PRIMARY KEY (match_id, team_id)
+);
@@ -0,0 +1,12 @@
+-- Point-in-time feature sets per match and feature-set version
+CREATE TABLE IF NOT EXISTS match_features (
+    match_id INTEGER NOT NULL REFERENCES matches(id) ON DELETE CASCADE,
+    version INTEGER NOT NULL,
+    features JSONB NOT NULL,
+    fingerprint VARCHAR(32) NOT NULL,  -- md5 of the inputs, compared to detect data changes
+    kickoff TIMESTAMP NOT NULL,
+    computed_at TIMESTAMP DEFAULT NOW(),
+    PRIMARY KEY (match_id, version)
+);
+
+CREATE INDEX IF NOT EXISTS idx_match_features_version_kickoff ON match_features(version, kickoff);
@@ -0,0 +1,2 @@
+-- Incremented whenever a match is rescheduled, used as the iCalendar SEQUENCE
+ALTER TABLE matches ADD COLUMN IF NOT EXISTS schedule_sequence INTEGER NOT NULL DEFAULT 0;
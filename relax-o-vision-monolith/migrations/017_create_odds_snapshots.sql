@@ -0,0 +1,38 @@
+-- Bookmaker 1X2 odds captured over time: the first snapshot of a bookmaker is
+-- its opening price, the last one before kickoff its closing price
+CREATE TABLE IF NOT EXISTS odds_snapshots (
+    id BIGSERIAL PRIMARY KEY,
+    match_id INTEGER NOT NULL REFERENCES matches(id) ON DELETE CASCADE,
+    bookmaker VARCHAR(100) NOT NULL,
+    home_win DOUBLE PRECISION NOT NULL,
+    draw DOUBLE PRECISION NOT NULL,
+    away_win DOUBLE PRECISION NOT NULL,
+    captured_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    source VARCHAR(50),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (match_id, bookmaker, captured_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_odds_snapshots_match ON odds_snapshots(match_id, captured_at);
+
+-- Seed snapshots from the odds already stored on matches
+INSERT INTO odds_snapshots (match_id, bookmaker, home_win, draw, away_win, captured_at, source)
+SELECT id, 'football-data.org', (odds->>'homeWin')::float8, (odds->>'draw')::float8, (odds->>'awayWin')::float8,
+       LEAST(COALESCE(updated_at, created_at, NOW()), utc_date - INTERVAL '1 minute'), 'football-data.org'
+FROM matches
+WHERE (odds->>'homeWin')::float8 > 1 AND (odds->>'draw')::float8 > 1 AND (odds->>'awayWin')::float8 > 1
+ON CONFLICT (match_id, bookmaker, captured_at) DO NOTHING;
+
+-- Brier scores and closing market probabilities of graded predictions, for the bookmaker baseline
+ALTER TABLE prediction_outcomes ADD COLUMN IF NOT EXISTS brier_score DECIMAL(6,5);
+ALTER TABLE prediction_outcomes ADD COLUMN IF NOT EXISTS market_home_prob DECIMAL(5,4);
+ALTER TABLE prediction_outcomes ADD COLUMN IF NOT EXISTS market_draw_prob DECIMAL(5,4);
+ALTER TABLE prediction_outcomes ADD COLUMN IF NOT EXISTS market_away_prob DECIMAL(5,4);
+ALTER TABLE prediction_outcomes ADD COLUMN IF NOT EXISTS market_correct BOOLEAN;
+ALTER TABLE prediction_outcomes ADD COLUMN IF NOT EXISTS market_brier_score DECIMAL(6,5);
+
+UPDATE prediction_outcomes SET brier_score =
+    POWER(home_win_prob - CASE WHEN actual_winner = 'home' THEN 1 ELSE 0 END, 2) +
+    POWER(draw_prob - CASE WHEN actual_winner = 'draw' THEN 1 ELSE 0 END, 2) +
+    POWER(away_win_prob - CASE WHEN actual_winner = 'away' THEN 1 ELSE 0 END, 2)
+WHERE brier_score IS NULL;
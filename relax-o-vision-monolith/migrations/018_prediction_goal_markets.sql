@@ -0,0 +1,8 @@
+-- Expected goals and the goal markets derived from them (over/under, both
+-- teams to score, double chance and exact scores)
+ALTER TABLE predictions ADD COLUMN IF NOT EXISTS home_expected_goals DOUBLE PRECISION;
+ALTER TABLE predictions ADD COLUMN IF NOT EXISTS away_expected_goals DOUBLE PRECISION;
+ALTER TABLE predictions ADD COLUMN IF NOT EXISTS markets JSONB;
+
+-- Goal market picks graded against the final score
+ALTER TABLE prediction_outcomes ADD COLUMN IF NOT EXISTS market_results JSONB;
@@ -0,0 +1,22 @@
+-- Fingerprint of the feature inputs a prediction was made from, compared to
+-- decide whether a scheduled re-prediction is needed
+ALTER TABLE predictions ADD COLUMN IF NOT EXISTS input_fingerprint VARCHAR(64);
+
+CREATE INDEX IF NOT EXISTS idx_predictions_match_created ON predictions(match_id, created_at DESC);
+
+-- Run history of the pre-kickoff prediction scheduler
+CREATE TABLE IF NOT EXISTS prediction_job_runs (
+    id BIGSERIAL PRIMARY KEY,
+    instance VARCHAR(255) NOT NULL,
+    status VARCHAR(20) NOT NULL,  -- running, completed or failed
+    candidates INTEGER NOT NULL DEFAULT 0,
+    created INTEGER NOT NULL DEFAULT 0,
+    repredicted INTEGER NOT NULL DEFAULT 0,
+    skipped INTEGER NOT NULL DEFAULT 0,
+    failed INTEGER NOT NULL DEFAULT 0,
+    error TEXT,
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    finished_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_prediction_job_runs_started ON prediction_job_runs(started_at DESC);
@@ -0,0 +1,25 @@
+-- Predictions of a match form a revision chain, each revision recording the
+-- analysis it was built from and the prompt and model versions behind it
+ALTER TABLE predictions ADD COLUMN IF NOT EXISTS revision INTEGER;
+ALTER TABLE predictions ADD COLUMN IF NOT EXISTS previous_id UUID REFERENCES predictions(id);
+ALTER TABLE predictions ADD COLUMN IF NOT EXISTS input_hash VARCHAR(64);
+ALTER TABLE predictions ADD COLUMN IF NOT EXISTS input_snapshot JSONB;
+ALTER TABLE predictions ADD COLUMN IF NOT EXISTS prompt_version INTEGER;
+ALTER TABLE predictions ADD COLUMN IF NOT EXISTS models JSONB;
+
+-- Number existing predictions in creation order
+WITH chain AS (
+    SELECT id,
+           ROW_NUMBER() OVER (PARTITION BY match_id ORDER BY created_at, id) AS revision,
+           LAG(id) OVER (PARTITION BY match_id ORDER BY created_at, id) AS previous_id
+    FROM predictions
+)
+UPDATE predictions p
+SET revision = chain.revision, previous_id = chain.previous_id
+FROM chain
+WHERE p.id = chain.id AND p.revision IS NULL;
+
+ALTER TABLE predictions ALTER COLUMN revision SET DEFAULT 1;
+ALTER TABLE predictions ALTER COLUMN revision SET NOT NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_predictions_match_revision ON predictions(match_id, revision);
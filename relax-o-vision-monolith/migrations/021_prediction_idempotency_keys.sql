@@ -0,0 +1,7 @@
+-- Idempotency keys of prediction requests, so a retried request returns the
+-- prediction it created instead of running the agents again
+CREATE TABLE IF NOT EXISTS prediction_idempotency_keys (
+    key VARCHAR(255) PRIMARY KEY,
+    prediction_id UUID NOT NULL REFERENCES predictions(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
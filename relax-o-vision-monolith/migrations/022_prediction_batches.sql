@@ -0,0 +1,25 @@
+-- Batches of predictions requested together, e.g. a competition's matchday
+CREATE TABLE IF NOT EXISTS prediction_batches (
+    id UUID PRIMARY KEY,
+    competition_id INTEGER REFERENCES competitions(id),
+    matchday INTEGER,
+    force BOOLEAN NOT NULL DEFAULT FALSE,
+    status VARCHAR(20) NOT NULL,  -- pending, running, completed or failed
+    total INTEGER NOT NULL,
+    completed INTEGER NOT NULL DEFAULT 0,
+    failed INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    finished_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Status of each match of a batch
+CREATE TABLE IF NOT EXISTS prediction_batch_matches (
+    batch_id UUID NOT NULL REFERENCES prediction_batches(id) ON DELETE CASCADE,
+    match_id INTEGER NOT NULL REFERENCES matches(id),
+    position INTEGER NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    prediction_id UUID REFERENCES predictions(id),
+    error TEXT,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (batch_id, match_id)
+);
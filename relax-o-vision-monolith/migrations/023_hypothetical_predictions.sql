@@ -0,0 +1,26 @@
+-- Predictions of matches that are not scheduled, e.g. a "dream final". Kept
+-- apart from predictions so they are never graded against results
+CREATE TABLE IF NOT EXISTS hypothetical_predictions (
+    id UUID PRIMARY KEY,
+    home_team_id INTEGER NOT NULL REFERENCES teams(id),
+    away_team_id INTEGER NOT NULL REFERENCES teams(id),
+    neutral BOOLEAN NOT NULL DEFAULT FALSE,
+    competition_id INTEGER REFERENCES competitions(id),
+    match_date TIMESTAMP WITH TIME ZONE NOT NULL,
+    home_win_prob DECIMAL(5,4),
+    draw_prob DECIMAL(5,4),
+    away_win_prob DECIMAL(5,4),
+    confidence DECIMAL(5,4),
+    reasoning JSONB,
+    agent_outputs JSONB,
+    home_expected_goals DOUBLE PRECISION,
+    away_expected_goals DOUBLE PRECISION,
+    markets JSONB,
+    input_hash VARCHAR(64) NOT NULL,
+    input_snapshot JSONB NOT NULL,
+    prompt_version INTEGER,
+    models JSONB,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_hypothetical_predictions_teams ON hypothetical_predictions(home_team_id, away_team_id);
@@ -0,0 +1,20 @@
+-- In-play outcome probabilities of a match, from its prediction conditioned
+-- on the live score and minute
+CREATE TABLE IF NOT EXISTS match_probability_timeline (
+    id BIGSERIAL PRIMARY KEY,
+    match_id INTEGER NOT NULL REFERENCES matches(id),
+    prediction_id UUID REFERENCES predictions(id) ON DELETE SET NULL,
+    minute INTEGER NOT NULL,
+    home_score INTEGER NOT NULL,
+    away_score INTEGER NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    home_win_prob DECIMAL(5,4) NOT NULL,
+    draw_prob DECIMAL(5,4) NOT NULL,
+    away_win_prob DECIMAL(5,4) NOT NULL,
+    home_remaining_goals DOUBLE PRECISION NOT NULL,
+    away_remaining_goals DOUBLE PRECISION NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_match_probability_timeline_point
+    ON match_probability_timeline(match_id, minute, home_score, away_score, status);
@@ -0,0 +1,31 @@
+-- Versioned calibrators fitted on graded predictions, per competition or
+-- for all competitions (competition_id 0). At most one is active for each
+CREATE TABLE IF NOT EXISTS prediction_calibrators (
+    id BIGSERIAL PRIMARY KEY,
+    competition_id INTEGER NOT NULL DEFAULT 0,
+    version INTEGER NOT NULL,
+    method VARCHAR(20) NOT NULL,
+    params JSONB NOT NULL,
+    samples INTEGER NOT NULL,
+    holdout INTEGER NOT NULL,
+    raw_brier_score DOUBLE PRECISION NOT NULL,
+    brier_score DOUBLE PRECISION NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT NOW(),
+    UNIQUE (competition_id, version)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_prediction_calibrators_active
+    ON prediction_calibrators(competition_id) WHERE active;
+
+-- The calibrator applied to a prediction and the aggregator's raw values
+ALTER TABLE predictions ADD COLUMN IF NOT EXISTS calibration JSONB;
+ALTER TABLE hypothetical_predictions ADD COLUMN IF NOT EXISTS calibration JSONB;
+
+-- Raw probabilities of calibrated predictions, graded alongside the calibrated ones
+ALTER TABLE prediction_outcomes ADD COLUMN IF NOT EXISTS raw_home_win_prob DECIMAL(5,4);
+ALTER TABLE prediction_outcomes ADD COLUMN IF NOT EXISTS raw_draw_prob DECIMAL(5,4);
+ALTER TABLE prediction_outcomes ADD COLUMN IF NOT EXISTS raw_away_win_prob DECIMAL(5,4);
+ALTER TABLE prediction_outcomes ADD COLUMN IF NOT EXISTS raw_correct BOOLEAN;
+ALTER TABLE prediction_outcomes ADD COLUMN IF NOT EXISTS raw_brier_score DOUBLE PRECISION;
+ALTER TABLE prediction_outcomes ADD COLUMN IF NOT EXISTS calibrator_id BIGINT REFERENCES prediction_calibrators(id);
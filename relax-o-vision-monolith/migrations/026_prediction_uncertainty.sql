@@ -0,0 +1,9 @@
+-- Spread of the agents' probabilities and the confidence derived from it
+ALTER TABLE predictions ADD COLUMN IF NOT EXISTS dispersion JSONB;
+ALTER TABLE predictions ADD COLUMN IF NOT EXISTS derived_confidence DECIMAL(5,4);
+ALTER TABLE hypothetical_predictions ADD COLUMN IF NOT EXISTS dispersion JSONB;
+ALTER TABLE hypothetical_predictions ADD COLUMN IF NOT EXISTS derived_confidence DECIMAL(5,4);
+
+-- Graded alongside the pick, to check whether disagreement predicts misses
+ALTER TABLE prediction_outcomes ADD COLUMN IF NOT EXISTS derived_confidence DECIMAL(5,4);
+ALTER TABLE prediction_outcomes ADD COLUMN IF NOT EXISTS disagreement DECIMAL(5,4);
@@ -0,0 +1,11 @@
+-- The agent pipeline a prediction ran, its agent calls, and the debate rounds
+ALTER TABLE predictions ADD COLUMN IF NOT EXISTS pipeline VARCHAR(20) NOT NULL DEFAULT 'standard';
+ALTER TABLE predictions ADD COLUMN IF NOT EXISTS llm_calls INTEGER;
+ALTER TABLE predictions ADD COLUMN IF NOT EXISTS debate JSONB;
+ALTER TABLE hypothetical_predictions ADD COLUMN IF NOT EXISTS pipeline VARCHAR(20) NOT NULL DEFAULT 'standard';
+ALTER TABLE hypothetical_predictions ADD COLUMN IF NOT EXISTS llm_calls INTEGER;
+ALTER TABLE hypothetical_predictions ADD COLUMN IF NOT EXISTS debate JSONB;
+
+-- Graded per pipeline, to weigh the debate's Brier score against its cost
+ALTER TABLE prediction_outcomes ADD COLUMN IF NOT EXISTS pipeline VARCHAR(20) NOT NULL DEFAULT 'standard';
+ALTER TABLE prediction_outcomes ADD COLUMN IF NOT EXISTS llm_calls INTEGER;
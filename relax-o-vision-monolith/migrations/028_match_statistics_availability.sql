@@ -0,0 +1,13 @@
+-- Stats a source did not provide used to be stored as zero; a pair of zeros
+-- cannot be a real value for these, so it is cleared to NULL
+UPDATE match_statistics SET home_shots = NULL, away_shots = NULL
+WHERE home_shots = 0 AND away_shots = 0;
+
+UPDATE match_statistics SET home_shots_on_target = NULL, away_shots_on_target = NULL
+WHERE home_shots IS NULL AND home_shots_on_target = 0 AND away_shots_on_target = 0;
+
+UPDATE match_statistics SET home_possession = NULL, away_possession = NULL
+WHERE home_possession = 0 AND away_possession = 0;
+
+UPDATE match_statistics SET home_pass_accuracy = NULL, away_pass_accuracy = NULL
+WHERE home_pass_accuracy = 0 AND away_pass_accuracy = 0;
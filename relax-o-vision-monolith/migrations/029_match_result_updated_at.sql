@@ -0,0 +1,6 @@
+-- Set whenever a match's finished result changes, so features derived from
+-- results (such as Elo ratings) are only recomputed when a result changes
+ALTER TABLE matches ADD COLUMN IF NOT EXISTS result_updated_at TIMESTAMP;
+
+UPDATE matches SET result_updated_at = updated_at
+WHERE status = 'FINISHED' AND result_updated_at IS NULL;
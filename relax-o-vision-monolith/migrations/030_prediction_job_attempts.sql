@@ -0,0 +1,4 @@
+-- Predictions a job run attempted, failed ones included, counted against the daily limit
+ALTER TABLE prediction_job_runs ADD COLUMN IF NOT EXISTS attempted INTEGER NOT NULL DEFAULT 0;
+
+UPDATE prediction_job_runs SET attempted = created + repredicted + failed WHERE attempted = 0;
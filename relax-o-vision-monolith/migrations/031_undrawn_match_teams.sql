@@ -0,0 +1,3 @@
+-- Teams football-data has not drawn yet (ID 0) are NULL, as the 010 backfill left them
+UPDATE matches SET home_team_id = NULL WHERE home_team_id = 0;
+UPDATE matches SET away_team_id = NULL WHERE away_team_id = 0;
@@ -0,0 +1,10 @@
+-- Grade each match on its latest prediction made before kickoff only
+DELETE FROM prediction_outcomes o
+WHERE o.prediction_id IS DISTINCT FROM (
+    SELECT p.id
+    FROM predictions p
+    JOIN matches m ON m.id = p.match_id
+    WHERE p.match_id = o.match_id AND p.created_at < m.utc_date
+    ORDER BY p.revision DESC
+    LIMIT 1
+);
@@ -0,0 +1,5 @@
+-- Card counts a source did not provide used to be stored as zero. A match
+-- without a single card is rare enough that all-zero counts are cleared to NULL
+UPDATE match_statistics
+SET home_yellow_cards = NULL, away_yellow_cards = NULL, home_red_cards = NULL, away_red_cards = NULL
+WHERE home_yellow_cards = 0 AND away_yellow_cards = 0 AND home_red_cards = 0 AND away_red_cards = 0;
@@ -0,0 +1,6 @@
+-- Running batches record a heartbeat, so an instance only fails the batches
+-- of stopped instances. Existing batches are as old as their last change
+ALTER TABLE prediction_batches ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP WITH TIME ZONE;
+UPDATE prediction_batches SET updated_at = COALESCE(finished_at, created_at) WHERE updated_at IS NULL;
+ALTER TABLE prediction_batches ALTER COLUMN updated_at SET DEFAULT NOW();
+ALTER TABLE prediction_batches ALTER COLUMN updated_at SET NOT NULL;
@@ -3,9 +3,18 @@ package predictions
 import (
 	"time"
 
+	"github.com/edd/relaxovisionmonolith/footballdata"
 	"github.com/google/uuid"
 )
 
+// Pseudo-providers reported alongside LLM providers
+const (
+	// EnsembleProvider covers outcomes of predictions without a single provider
+	EnsembleProvider = "ensemble"
+	// BookmakerProvider is the bookmaker baseline: picking the closing market favourite
+	BookmakerProvider = "bookmaker"
+)
+
 // PredictionOutcome represents the outcome of a prediction after a match completes
 type PredictionOutcome struct {
 	ID               uuid.UUID `json:"id"`
@@ -18,6 +27,7 @@ type PredictionOutcome struct {
 	HomeWinProb      float64   `json:"homeWinProb"`
 	DrawProb         float64   `json:"drawProb"`
 	AwayWinProb      float64   `json:"awayWinProb"`
+	BrierScore       float64   `json:"brierScore"`
 	ActualHomeScore  int       `json:"actualHomeScore"`
 	ActualAwayScore  int       `json:"actualAwayScore"`
 	CompetitionID    int       `json:"competitionId"`
@@ -25,6 +35,35 @@ type PredictionOutcome struct {
 	Provider         string    `json:"provider,omitempty"`
 	AgentType        string    `json:"agentType,omitempty"`
 	CreatedAt        time.Time `json:"createdAt"`
+
+	// Closing market consensus, when the match had odds
+	Market           *footballdata.MarketProbabilities `json:"market,omitempty"`
+	MarketCorrect    *bool                             `json:"marketCorrect,omitempty"`
+	MarketBrierScore *float64                          `json:"marketBrierScore,omitempty"`
+
+	// Goal market picks graded against the final score
+	MarketResults []MarketResult `json:"marketResults,omitempty"`
+
+	// The aggregator's raw probabilities graded the same way, when the prediction was calibrated
+	Raw *RawOutcome `json:"raw,omitempty"`
+
+	// Computed confidence and agent disagreement, when the prediction measured them
+	DerivedConfidence *float64 `json:"derivedConfidence,omitempty"`
+	Disagreement      *float64 `json:"disagreement,omitempty"`
+
+	// The agent pipeline the prediction ran and its agent calls, when recorded
+	Pipeline string `json:"pipeline"`
+	LLMCalls int    `json:"llmCalls,omitempty"`
+}
+
+// RawOutcome grades a calibrated prediction's raw probabilities
+type RawOutcome struct {
+	CalibratorID int64   `json:"calibratorId"`
+	HomeWinProb  float64 `json:"homeWinProb"`
+	DrawProb     float64 `json:"drawProb"`
+	AwayWinProb  float64 `json:"awayWinProb"`
+	WasCorrect   bool    `json:"wasCorrect"`
+	BrierScore   float64 `json:"brierScore"`
 }
 
 // AccuracyStats represents overall accuracy statistics
@@ -32,20 +71,30 @@ type AccuracyStats struct {
 	TotalPredictions    int                        `json:"totalPredictions"`
 	CorrectPredictions  int                        `json:"correctPredictions"`
 	AccuracyRate        float64                    `json:"accuracyRate"`
+	BrierScore          float64                    `json:"brierScore"`
+	MarketBaseline      *MarketBaseline            `json:"marketBaseline,omitempty"`
+	Calibration         *CalibrationStats          `json:"calibration,omitempty"`
 	ByCompetition       map[string]*CompetitionAcc `json:"byCompetition"`
 	ByConfidenceRange   map[string]*RangeAcc       `json:"byConfidenceRange"`
+	ByDerivedConfidence map[string]*RangeAcc       `json:"byDerivedConfidence"`
+	Disagreement        *DisagreementStats         `json:"disagreement,omitempty"`
 	ByProvider          map[string]*ProviderAcc    `json:"byProvider"`
 	ByAgent             map[string]*AgentAcc       `json:"byAgent"`
+	ByPipeline          map[string]*PipelineAcc    `json:"byPipeline"`
+	ByMarket            map[string]*GoalMarketAcc  `json:"byMarket"`
 	LastUpdated         time.Time                  `json:"lastUpdated"`
 }
 
 // CompetitionAcc represents accuracy for a competition
 type CompetitionAcc struct {
 	CompetitionID      int     `json:"competitionId"`
-	CompetitionName    string  `json:"competitionName"`
-	TotalPredictions   int     `json:"totalPredictions"`
-	CorrectPredictions int     `json:"correctPredictions"`
-	AccuracyRate       float64 `json:"accuracyRate"`
+	CompetitionName    string          `json:"competitionName"`
+	TotalPredictions   int             `json:"totalPredictions"`
+	CorrectPredictions int             `json:"correctPredictions"`
+	AccuracyRate       float64         `json:"accuracyRate"`
+	BrierScore         float64         `json:"brierScore"`
+	MarketBaseline     *MarketBaseline `json:"marketBaseline,omitempty"`
+	Calibration        *CalibrationStats `json:"calibration,omitempty"`
 }
 
 // RangeAcc represents accuracy for a confidence range
@@ -54,6 +103,7 @@ type RangeAcc struct {
 	TotalPredictions   int     `json:"totalPredictions"`
 	CorrectPredictions int     `json:"correctPredictions"`
 	AccuracyRate       float64 `json:"accuracyRate"`
+	BrierScore         float64 `json:"brierScore"`
 }
 
 // ProviderAcc represents accuracy for an LLM provider
@@ -62,6 +112,17 @@ type ProviderAcc struct {
 	TotalPredictions   int     `json:"totalPredictions"`
 	CorrectPredictions int     `json:"correctPredictions"`
 	AccuracyRate       float64 `json:"accuracyRate"`
+	BrierScore         float64 `json:"brierScore"`
+}
+
+// PipelineAcc represents accuracy for an agent pipeline, with its cost
+type PipelineAcc struct {
+	Pipeline           string  `json:"pipeline"`
+	TotalPredictions   int     `json:"totalPredictions"`
+	CorrectPredictions int     `json:"correctPredictions"`
+	AccuracyRate       float64 `json:"accuracyRate"`
+	BrierScore         float64 `json:"brierScore"`
+	AvgLLMCalls        float64 `json:"avgLlmCalls"` // over the predictions that recorded them
 }
 
 // AgentAcc represents accuracy for an agent type
@@ -72,12 +133,57 @@ type AgentAcc struct {
 	AccuracyRate       float64 `json:"accuracyRate"`
 }
 
+// GoalMarketAcc represents accuracy of the picks in a goal market
+type GoalMarketAcc struct {
+	Market             string  `json:"market"`
+	TotalPredictions   int     `json:"totalPredictions"`
+	CorrectPredictions int     `json:"correctPredictions"`
+	AccuracyRate       float64 `json:"accuracyRate"`
+	BrierScore         float64 `json:"brierScore"`
+}
+
 // LeaderboardEntry represents a leaderboard entry
 type LeaderboardEntry struct {
 	Name               string  `json:"name"`
-	Type               string  `json:"type"` // "provider" or "agent"
+	Type               string  `json:"type"` // "provider", "agent" or "pipeline"
 	TotalPredictions   int     `json:"totalPredictions"`
 	CorrectPredictions int     `json:"correctPredictions"`
 	AccuracyRate       float64 `json:"accuracyRate"`
+	BrierScore         float64 `json:"brierScore"`
+	AvgLLMCalls        float64 `json:"avgLlmCalls,omitempty"` // pipelines only
 	Rank               int     `json:"rank"`
 }
+
+// MarketBaseline compares predictions with the bookmaker closing odds over
+// the graded matches that had odds
+type MarketBaseline struct {
+	Matches           int     `json:"matches"`
+	AccuracyRate      float64 `json:"accuracyRate"` // picking the market favourite
+	BrierScore        float64 `json:"brierScore"`
+	ModelAccuracyRate float64 `json:"modelAccuracyRate"` // predictions over the same matches
+	ModelBrierScore   float64 `json:"modelBrierScore"`
+	AccuracyDelta     float64 `json:"accuracyDelta"` // model minus market; positive beats the market
+	BrierDelta        float64 `json:"brierDelta"`    // market minus model; positive beats the market
+}
+
+// CalibrationStats compares calibrated predictions with the aggregator's raw
+// probabilities over the graded matches that were calibrated
+type CalibrationStats struct {
+	Matches         int     `json:"matches"`
+	AccuracyRate    float64 `json:"accuracyRate"`
+	BrierScore      float64 `json:"brierScore"`
+	RawAccuracyRate float64 `json:"rawAccuracyRate"`
+	RawBrierScore   float64 `json:"rawBrierScore"`
+	// BrierImprovement is raw minus calibrated; positive when calibration helped
+	BrierImprovement float64 `json:"brierImprovement"`
+}
+
+// DisagreementStats checks whether disagreement between agents predicts
+// missed picks, over the graded predictions that measured it
+type DisagreementStats struct {
+	Matches int `json:"matches"`
+	// Mean disagreement of the picks that were right and of those that were wrong
+	CorrectMeanDisagreement float64              `json:"correctMeanDisagreement"`
+	MissedMeanDisagreement  float64              `json:"missedMeanDisagreement"`
+	ByRange                 map[string]*RangeAcc `json:"byRange"`
+}
@@ -3,22 +3,28 @@ package predictions
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"log/slog"
+	"math"
+	"sort"
 	"time"
 
+	"github.com/edd/relaxovisionmonolith/footballdata"
 	"github.com/google/uuid"
 )
 
 // AccuracyService handles prediction accuracy tracking and calculation
 type AccuracyService struct {
-	db *sql.DB
+	db   *sql.DB
+	odds *footballdata.Repository
 }
 
 // NewAccuracyService creates a new accuracy service
 func NewAccuracyService(db *sql.DB) *AccuracyService {
 	return &AccuracyService{
-		db: db,
+		db:   db,
+		odds: footballdata.NewRepository(db),
 	}
 }
 
@@ -27,16 +33,22 @@ func (s *AccuracyService) RecordOutcome(ctx context.Context, predictionID uuid.U
 	// Get the prediction
 	var homeWinProb, drawProb, awayWinProb, confidence float64
 	var competitionID int
+	var marketsJSON, calibrationJSON []byte
+	var derivedConfidence, disagreement sql.NullFloat64
+	var pipeline string
+	var llmCalls sql.NullInt64
 	
 	predQuery := `
-		SELECT p.home_win_prob, p.draw_prob, p.away_win_prob, p.confidence, m.competition_id
+		SELECT p.home_win_prob, p.draw_prob, p.away_win_prob, p.confidence, m.competition_id, p.markets, p.calibration,
+		       p.derived_confidence, (p.dispersion->>'disagreement')::float8, p.pipeline, p.llm_calls
 		FROM predictions p
 		JOIN matches m ON p.match_id = m.id
 		WHERE p.id = $1
 	`
 	
 	err := s.db.QueryRowContext(ctx, predQuery, predictionID).Scan(
-		&homeWinProb, &drawProb, &awayWinProb, &confidence, &competitionID,
+		&homeWinProb, &drawProb, &awayWinProb, &confidence, &competitionID, &marketsJSON, &calibrationJSON,
+		&derivedConfidence, &disagreement, &pipeline, &llmCalls,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to get prediction: %w", err)
@@ -47,12 +59,13 @@ func (s *AccuracyService) RecordOutcome(ctx context.Context, predictionID uuid.U
 	var competitionName string
 	
 	matchQuery := `
-		SELECT 
-			(score->'fullTime'->'home')::int,
-			(score->'fullTime'->'away')::int,
-			competition->>'name'
-		FROM matches
-		WHERE id = $1 AND status = 'FINISHED'
+		SELECT
+			m.home_score_ft,
+			m.away_score_ft,
+			COALESCE(c.name, '')
+		FROM matches m
+		LEFT JOIN competitions c ON c.id = m.competition_id
+		WHERE m.id = $1 AND m.status = 'FINISHED'
 	`
 	
 	err = s.db.QueryRowContext(ctx, matchQuery, matchID).Scan(&homeScore, &awayScore, &competitionName)
@@ -73,16 +86,7 @@ func (s *AccuracyService) RecordOutcome(ctx context.Context, predictionID uuid.U
 	}
 
 	// Determine predicted winner (highest probability)
-	predictedWinner := "draw"
-	maxProb := drawProb
-	if homeWinProb > maxProb {
-		predictedWinner = "home"
-		maxProb = homeWinProb
-	}
-	if awayWinProb > maxProb {
-		predictedWinner = "away"
-	}
-
+	predictedWinner := pickWinner(homeWinProb, drawProb, awayWinProb)
 	wasCorrect := predictedWinner == actualWinner
 
 	// Save outcome
@@ -97,19 +101,94 @@ func (s *AccuracyService) RecordOutcome(ctx context.Context, predictionID uuid.U
 		HomeWinProb:     homeWinProb,
 		DrawProb:        drawProb,
 		AwayWinProb:     awayWinProb,
+		BrierScore:      brierScore(homeWinProb, drawProb, awayWinProb, actualWinner),
 		ActualHomeScore: int(homeScore.Int64),
 		ActualAwayScore: int(awayScore.Int64),
 		CompetitionID:   competitionID,
 		CompetitionName: competitionName,
 		CreatedAt:       time.Now(),
 	}
+	outcome.Pipeline = pipeline
+	outcome.LLMCalls = int(llmCalls.Int64)
+	if derivedConfidence.Valid {
+		outcome.DerivedConfidence = &derivedConfidence.Float64
+	}
+	if disagreement.Valid {
+		outcome.Disagreement = &disagreement.Float64
+	}
+
+	// Grade the bookmaker baseline on the same match
+	market, err := s.odds.ClosingProbabilities(ctx, []int{matchID})
+	if err != nil {
+		slog.Warn("Failed to get closing odds", "matchId", matchID, "error", err)
+	} else if m, ok := market[matchID]; ok {
+		marketCorrect := pickWinner(m.HomeWin, m.Draw, m.AwayWin) == actualWinner
+		marketBrier := brierScore(m.HomeWin, m.Draw, m.AwayWin, actualWinner)
+		outcome.Market = &m
+		outcome.MarketCorrect = &marketCorrect
+		outcome.MarketBrierScore = &marketBrier
+	}
+
+	// Grade the goal markets when the prediction has them
+	var marketResultsJSON []byte
+	if len(marketsJSON) > 0 {
+		var markets Markets
+		if err := json.Unmarshal(marketsJSON, &markets); err != nil {
+			slog.Warn("Failed to unmarshal markets", "predictionId", predictionID, "error", err)
+		} else {
+			outcome.MarketResults = markets.Grade(outcome.ActualHomeScore, outcome.ActualAwayScore)
+			if marketResultsJSON, err = json.Marshal(outcome.MarketResults); err != nil {
+				return fmt.Errorf("failed to marshal market results: %w", err)
+			}
+		}
+	}
+
+	// Grade the raw probabilities too, to measure what calibration changed
+	if len(calibrationJSON) > 0 {
+		var calibration AppliedCalibration
+		if err := json.Unmarshal(calibrationJSON, &calibration); err != nil {
+			slog.Warn("Failed to unmarshal calibration", "predictionId", predictionID, "error", err)
+		} else {
+			outcome.Raw = &RawOutcome{
+				CalibratorID: calibration.CalibratorID,
+				HomeWinProb:  calibration.RawHomeWinProb,
+				DrawProb:     calibration.RawDrawProb,
+				AwayWinProb:  calibration.RawAwayWinProb,
+				WasCorrect:   pickWinner(calibration.RawHomeWinProb, calibration.RawDrawProb, calibration.RawAwayWinProb) == actualWinner,
+				BrierScore:   brierScore(calibration.RawHomeWinProb, calibration.RawDrawProb, calibration.RawAwayWinProb, actualWinner),
+			}
+		}
+	}
+
+	var rawHome, rawDraw, rawAway, rawBrier sql.NullFloat64
+	var rawCorrect sql.NullBool
+	var calibratorID sql.NullInt64
+	if outcome.Raw != nil {
+		rawHome = sql.NullFloat64{Float64: outcome.Raw.HomeWinProb, Valid: true}
+		rawDraw = sql.NullFloat64{Float64: outcome.Raw.DrawProb, Valid: true}
+		rawAway = sql.NullFloat64{Float64: outcome.Raw.AwayWinProb, Valid: true}
+		rawBrier = sql.NullFloat64{Float64: outcome.Raw.BrierScore, Valid: true}
+		rawCorrect = sql.NullBool{Bool: outcome.Raw.WasCorrect, Valid: true}
+		calibratorID = sql.NullInt64{Int64: outcome.Raw.CalibratorID, Valid: true}
+	}
+
+	var marketHome, marketDraw, marketAway sql.NullFloat64
+	if outcome.Market != nil {
+		marketHome = sql.NullFloat64{Float64: outcome.Market.HomeWin, Valid: true}
+		marketDraw = sql.NullFloat64{Float64: outcome.Market.Draw, Valid: true}
+		marketAway = sql.NullFloat64{Float64: outcome.Market.AwayWin, Valid: true}
+	}
 
 	insertQuery := `
 		INSERT INTO prediction_outcomes (
 			id, prediction_id, match_id, predicted_winner, actual_winner, 
 			was_correct, confidence_score, home_win_prob, draw_prob, away_win_prob,
-			actual_home_score, actual_away_score, competition_id, competition_name, created_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+			actual_home_score, actual_away_score, competition_id, competition_name, created_at,
+			brier_score, market_home_prob, market_draw_prob, market_away_prob, market_correct, market_brier_score,
+			market_results, raw_home_win_prob, raw_draw_prob, raw_away_win_prob, raw_correct, raw_brier_score, calibrator_id,
+			derived_confidence, disagreement, pipeline, llm_calls
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22,
+			$23, $24, $25, $26, $27, $28, $29, $30, $31, $32)
 	`
 
 	_, err = s.db.ExecContext(ctx, insertQuery,
@@ -118,6 +197,9 @@ func (s *AccuracyService) RecordOutcome(ctx context.Context, predictionID uuid.U
 		outcome.ConfidenceScore, outcome.HomeWinProb, outcome.DrawProb, outcome.AwayWinProb,
 		outcome.ActualHomeScore, outcome.ActualAwayScore,
 		outcome.CompetitionID, outcome.CompetitionName, outcome.CreatedAt,
+		outcome.BrierScore, marketHome, marketDraw, marketAway, outcome.MarketCorrect, outcome.MarketBrierScore,
+		marketResultsJSON, rawHome, rawDraw, rawAway, rawCorrect, rawBrier, calibratorID,
+		outcome.DerivedConfidence, outcome.Disagreement, outcome.Pipeline, llmCalls,
 	)
 
 	if err != nil {
@@ -136,36 +218,67 @@ func (s *AccuracyService) RecordOutcome(ctx context.Context, predictionID uuid.U
 // GetOverallStats calculates overall accuracy statistics
 func (s *AccuracyService) GetOverallStats(ctx context.Context) (*AccuracyStats, error) {
 	stats := &AccuracyStats{
-		ByCompetition:     make(map[string]*CompetitionAcc),
-		ByConfidenceRange: make(map[string]*RangeAcc),
-		ByProvider:        make(map[string]*ProviderAcc),
-		ByAgent:           make(map[string]*AgentAcc),
-		LastUpdated:       time.Now(),
+		ByCompetition:       make(map[string]*CompetitionAcc),
+		ByConfidenceRange:   make(map[string]*RangeAcc),
+		ByDerivedConfidence: make(map[string]*RangeAcc),
+		ByProvider:          make(map[string]*ProviderAcc),
+		ByAgent:             make(map[string]*AgentAcc),
+		ByPipeline:          make(map[string]*PipelineAcc),
+		ByMarket:            make(map[string]*GoalMarketAcc),
+		LastUpdated:         time.Now(),
 	}
 
 	// Overall stats
-	query := `
-		SELECT COUNT(*), SUM(CASE WHEN was_correct THEN 1 ELSE 0 END)
-		FROM prediction_outcomes
-	`
-	
-	err := s.db.QueryRowContext(ctx, query).Scan(&stats.TotalPredictions, &stats.CorrectPredictions)
+	query := `SELECT ` + outcomeAggregateColumns + ` FROM prediction_outcomes`
+
+	var overall outcomeAggregate
+	err := s.db.QueryRowContext(ctx, query).Scan(overall.scanTargets()...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get overall stats: %w", err)
 	}
 
-	if stats.TotalPredictions > 0 {
-		stats.AccuracyRate = float64(stats.CorrectPredictions) / float64(stats.TotalPredictions)
-	}
+	stats.TotalPredictions = overall.total
+	stats.CorrectPredictions = overall.correct
+	stats.AccuracyRate = overall.accuracyRate()
+	stats.BrierScore = overall.brier
+	stats.MarketBaseline = overall.baseline()
+	stats.Calibration = overall.calibration()
 
 	// By competition
 	if err := s.calculateCompetitionStats(ctx, stats); err != nil {
 		slog.Error("Failed to calculate competition stats", "error", err)
 	}
 
-	// By confidence range
-	if err := s.calculateConfidenceStats(ctx, stats); err != nil {
-		slog.Error("Failed to calculate confidence stats", "error", err)
+	// By confidence range, self-reported and derived
+	stats.ByConfidenceRange = s.calculateRangeStats(ctx, "confidence_score", confidenceRanges)
+	stats.ByDerivedConfidence = s.calculateRangeStats(ctx, "derived_confidence", confidenceRanges)
+
+	// By disagreement between the agents
+	if err := s.calculateDisagreementStats(ctx, stats); err != nil {
+		slog.Error("Failed to calculate disagreement stats", "error", err)
+	}
+
+	// By provider, with the bookmaker baseline as a pseudo-provider
+	if err := s.calculateProviderStats(ctx, stats); err != nil {
+		slog.Error("Failed to calculate provider stats", "error", err)
+	}
+	// By agent pipeline
+	if err := s.calculatePipelineStats(ctx, stats); err != nil {
+		slog.Error("Failed to calculate pipeline stats", "error", err)
+	}
+	// By goal market
+	if err := s.calculateGoalMarketStats(ctx, stats); err != nil {
+		slog.Error("Failed to calculate goal market stats", "error", err)
+	}
+
+	if baseline := stats.MarketBaseline; baseline != nil {
+		stats.ByProvider[BookmakerProvider] = &ProviderAcc{
+			ProviderName:       BookmakerProvider,
+			TotalPredictions:   baseline.Matches,
+			CorrectPredictions: overall.marketCorrect,
+			AccuracyRate:       baseline.AccuracyRate,
+			BrierScore:         baseline.BrierScore,
+		}
 	}
 
 	return stats, nil
@@ -177,8 +290,7 @@ func (s *AccuracyService) calculateCompetitionStats(ctx context.Context, stats *
 		SELECT 
 			competition_id,
 			competition_name,
-			COUNT(*) as total,
-			SUM(CASE WHEN was_correct THEN 1 ELSE 0 END) as correct
+			` + outcomeAggregateColumns + `
 		FROM prediction_outcomes
 		GROUP BY competition_id, competition_name
 	`
@@ -192,55 +304,162 @@ func (s *AccuracyService) calculateCompetitionStats(ctx context.Context, stats *
 	for rows.Next() {
 		var compID int
 		var compName string
-		var total, correct int
+		var agg outcomeAggregate
 
-		if err := rows.Scan(&compID, &compName, &total, &correct); err != nil {
+		if err := rows.Scan(append([]any{&compID, &compName}, agg.scanTargets()...)...); err != nil {
 			continue
 		}
 
-		acc := &CompetitionAcc{
-			CompetitionID:      compID,
-			CompetitionName:    compName,
-			TotalPredictions:   total,
-			CorrectPredictions: correct,
+		stats.ByCompetition[compName] = agg.competitionAcc(compID, compName)
+	}
+
+	return nil
+}
+
+// calculateProviderStats calculates accuracy by provider; outcomes without one
+// are the ensemble's
+func (s *AccuracyService) calculateProviderStats(ctx context.Context, stats *AccuracyStats) error {
+	query := `
+		SELECT 
+			COALESCE(provider, $1) as provider,
+			COUNT(*) as total,
+			SUM(CASE WHEN was_correct THEN 1 ELSE 0 END) as correct,
+			COALESCE(AVG(brier_score), 0) as brier
+		FROM prediction_outcomes
+		GROUP BY COALESCE(provider, $1)
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, EnsembleProvider)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		acc := &ProviderAcc{}
+		if err := rows.Scan(&acc.ProviderName, &acc.TotalPredictions, &acc.CorrectPredictions, &acc.BrierScore); err != nil {
+			continue
 		}
-		if total > 0 {
-			acc.AccuracyRate = float64(correct) / float64(total)
+		if acc.TotalPredictions > 0 {
+			acc.AccuracyRate = float64(acc.CorrectPredictions) / float64(acc.TotalPredictions)
 		}
 
-		stats.ByCompetition[compName] = acc
+		stats.ByProvider[acc.ProviderName] = acc
 	}
 
-	return nil
+	return rows.Err()
 }
 
-// calculateConfidenceStats calculates accuracy by confidence ranges
-func (s *AccuracyService) calculateConfidenceStats(ctx context.Context, stats *AccuracyStats) error {
-	ranges := []struct {
-		name string
-		min  float64
-		max  float64
-	}{
-		{"0.0-0.5", 0.0, 0.5},
-		{"0.5-0.6", 0.5, 0.6},
-		{"0.6-0.7", 0.6, 0.7},
-		{"0.7-0.8", 0.7, 0.8},
-		{"0.8-0.9", 0.8, 0.9},
-		{"0.9-1.0", 0.9, 1.0},
+// calculatePipelineStats calculates accuracy and agent calls by agent pipeline
+func (s *AccuracyService) calculatePipelineStats(ctx context.Context, stats *AccuracyStats) error {
+	query := `
+		SELECT 
+			pipeline,
+			COUNT(*) as total,
+			SUM(CASE WHEN was_correct THEN 1 ELSE 0 END) as correct,
+			COALESCE(AVG(brier_score), 0) as brier,
+			COALESCE(AVG(llm_calls), 0) as llm_calls
+		FROM prediction_outcomes
+		GROUP BY pipeline
+	`
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		acc := &PipelineAcc{}
+		if err := rows.Scan(&acc.Pipeline, &acc.TotalPredictions, &acc.CorrectPredictions, &acc.BrierScore, &acc.AvgLLMCalls); err != nil {
+			return fmt.Errorf("failed to scan pipeline stats: %w", err)
+		}
+		if acc.TotalPredictions > 0 {
+			acc.AccuracyRate = float64(acc.CorrectPredictions) / float64(acc.TotalPredictions)
+		}
+
+		stats.ByPipeline[acc.Pipeline] = acc
+	}
+
+	return rows.Err()
+}
+
+// calculateGoalMarketStats calculates accuracy of the graded goal market picks
+func (s *AccuracyService) calculateGoalMarketStats(ctx context.Context, stats *AccuracyStats) error {
+	query := `
+		SELECT 
+			r->>'market' as market,
+			COUNT(*) as total,
+			SUM(CASE WHEN (r->>'correct')::boolean THEN 1 ELSE 0 END) as correct,
+			COALESCE(AVG((r->>'brierScore')::float8), 0) as brier
+		FROM prediction_outcomes, jsonb_array_elements(market_results) r
+		WHERE market_results IS NOT NULL
+		GROUP BY r->>'market'
+	`
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		acc := &GoalMarketAcc{}
+		if err := rows.Scan(&acc.Market, &acc.TotalPredictions, &acc.CorrectPredictions, &acc.BrierScore); err != nil {
+			continue
+		}
+		if acc.TotalPredictions > 0 {
+			acc.AccuracyRate = float64(acc.CorrectPredictions) / float64(acc.TotalPredictions)
+		}
+
+		stats.ByMarket[acc.Market] = acc
 	}
 
+	return rows.Err()
+}
+
+// outcomeRange is a half-open range of a score such as confidence
+type outcomeRange struct {
+	name string
+	min  float64
+	max  float64
+}
+
+var confidenceRanges = []outcomeRange{
+	{"0.0-0.5", 0.0, 0.5},
+	{"0.5-0.6", 0.5, 0.6},
+	{"0.6-0.7", 0.6, 0.7},
+	{"0.7-0.8", 0.7, 0.8},
+	{"0.8-0.9", 0.8, 0.9},
+	{"0.9-1.0", 0.9, 1.0},
+}
+
+var disagreementRanges = []outcomeRange{
+	{"0.0-0.1", 0.0, 0.1},
+	{"0.1-0.2", 0.1, 0.2},
+	{"0.2-0.3", 0.2, 0.3},
+	{"0.3-1.0", 0.3, 1.0},
+}
+
+// calculateRangeStats calculates accuracy by ranges of an outcome column
+func (s *AccuracyService) calculateRangeStats(ctx context.Context, column string, ranges []outcomeRange) map[string]*RangeAcc {
+	byRange := make(map[string]*RangeAcc)
+
 	for _, r := range ranges {
 		query := `
 			SELECT 
 				COUNT(*) as total,
-				SUM(CASE WHEN was_correct THEN 1 ELSE 0 END) as correct
+				COALESCE(SUM(CASE WHEN was_correct THEN 1 ELSE 0 END), 0) as correct,
+				COALESCE(AVG(brier_score), 0) as brier
 			FROM prediction_outcomes
-			WHERE confidence_score >= $1 AND confidence_score < $2
+			WHERE ` + column + ` >= $1 AND ` + column + ` < $2
 		`
 
 		var total, correct int
-		err := s.db.QueryRowContext(ctx, query, r.min, r.max).Scan(&total, &correct)
+		var brier float64
+		err := s.db.QueryRowContext(ctx, query, r.min, r.max).Scan(&total, &correct, &brier)
 		if err != nil {
+			slog.Error("Failed to calculate range stats", "column", column, "range", r.name, "error", err)
 			continue
 		}
 
@@ -249,12 +468,39 @@ func (s *AccuracyService) calculateConfidenceStats(ctx context.Context, stats *A
 				Range:              r.name,
 				TotalPredictions:   total,
 				CorrectPredictions: correct,
+				BrierScore:         brier,
 			}
 			acc.AccuracyRate = float64(correct) / float64(total)
-			stats.ByConfidenceRange[r.name] = acc
+			byRange[r.name] = acc
 		}
 	}
 
+	return byRange
+}
+
+// calculateDisagreementStats compares the agents' disagreement on correct and
+// missed picks, for outcomes whose prediction measured it
+func (s *AccuracyService) calculateDisagreementStats(ctx context.Context, stats *AccuracyStats) error {
+	query := `
+		SELECT 
+			COUNT(*),
+			COALESCE(AVG(disagreement) FILTER (WHERE was_correct), 0),
+			COALESCE(AVG(disagreement) FILTER (WHERE NOT was_correct), 0)
+		FROM prediction_outcomes
+		WHERE disagreement IS NOT NULL
+	`
+
+	d := &DisagreementStats{}
+	err := s.db.QueryRowContext(ctx, query).Scan(&d.Matches, &d.CorrectMeanDisagreement, &d.MissedMeanDisagreement)
+	if err != nil {
+		return fmt.Errorf("failed to query disagreement stats: %w", err)
+	}
+	if d.Matches == 0 {
+		return nil
+	}
+
+	d.ByRange = s.calculateRangeStats(ctx, "disagreement", disagreementRanges)
+	stats.Disagreement = d
 	return nil
 }
 
@@ -264,20 +510,16 @@ func (s *AccuracyService) GetCompetitionStats(ctx context.Context, competitionID
 		SELECT 
 			competition_id,
 			competition_name,
-			COUNT(*) as total,
-			SUM(CASE WHEN was_correct THEN 1 ELSE 0 END) as correct
+			` + outcomeAggregateColumns + `
 		FROM prediction_outcomes
 		WHERE competition_id = $1
 		GROUP BY competition_id, competition_name
 	`
 
-	var acc CompetitionAcc
-	err := s.db.QueryRowContext(ctx, query, competitionID).Scan(
-		&acc.CompetitionID,
-		&acc.CompetitionName,
-		&acc.TotalPredictions,
-		&acc.CorrectPredictions,
-	)
+	var compID int
+	var compName string
+	var agg outcomeAggregate
+	err := s.db.QueryRowContext(ctx, query, competitionID).Scan(append([]any{&compID, &compName}, agg.scanTargets()...)...)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("no predictions found for competition")
@@ -285,50 +527,100 @@ func (s *AccuracyService) GetCompetitionStats(ctx context.Context, competitionID
 		return nil, err
 	}
 
-	if acc.TotalPredictions > 0 {
-		acc.AccuracyRate = float64(acc.CorrectPredictions) / float64(acc.TotalPredictions)
-	}
-
-	return &acc, nil
+	return agg.competitionAcc(compID, compName), nil
 }
 
-// GetLeaderboard gets a leaderboard of providers and agents
+// GetLeaderboard ranks providers, including the bookmaker baseline, and agent
+// pipelines by Brier score (lower is better). Agent outputs are not graded per
+// provider yet.
 func (s *AccuracyService) GetLeaderboard(ctx context.Context) ([]LeaderboardEntry, error) {
-	// For now, return empty as we need to extend the schema to track provider/agent per outcome
-	// This would require storing agent outputs with provider information
-	return []LeaderboardEntry{}, nil
+	stats, err := s.GetOverallStats(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	leaderboard := make([]LeaderboardEntry, 0, len(stats.ByProvider))
+	for _, acc := range stats.ByProvider {
+		leaderboard = append(leaderboard, LeaderboardEntry{
+			Name:               acc.ProviderName,
+			Type:               "provider",
+			TotalPredictions:   acc.TotalPredictions,
+			CorrectPredictions: acc.CorrectPredictions,
+			AccuracyRate:       acc.AccuracyRate,
+			BrierScore:         acc.BrierScore,
+		})
+	}
+
+	// Pipelines are ranked alongside, so the debate's Brier score can be
+	// weighed against its extra agent calls
+	for _, acc := range stats.ByPipeline {
+		leaderboard = append(leaderboard, LeaderboardEntry{
+			Name:               acc.Pipeline,
+			Type:               "pipeline",
+			TotalPredictions:   acc.TotalPredictions,
+			CorrectPredictions: acc.CorrectPredictions,
+			AccuracyRate:       acc.AccuracyRate,
+			BrierScore:         acc.BrierScore,
+			AvgLLMCalls:        acc.AvgLLMCalls,
+		})
+	}
+
+	sort.Slice(leaderboard, func(i, j int) bool {
+		if leaderboard[i].BrierScore != leaderboard[j].BrierScore {
+			return leaderboard[i].BrierScore < leaderboard[j].BrierScore
+		}
+		return leaderboard[i].AccuracyRate > leaderboard[j].AccuracyRate
+	})
+	for i := range leaderboard {
+		leaderboard[i].Rank = i + 1
+	}
+
+	return leaderboard, nil
 }
 
-// CheckCompletedMatches checks for completed matches and records outcomes
-func (s *AccuracyService) CheckCompletedMatches(ctx context.Context) error {
-	// Find predictions for completed matches that don't have outcomes yet
-	query := `
-		SELECT p.id, p.match_id
-		FROM predictions p
-		JOIN matches m ON p.match_id = m.id
-		LEFT JOIN prediction_outcomes po ON p.id = po.prediction_id
-		WHERE m.status = 'FINISHED' AND po.id IS NULL
+// completedPredictionsQuery selects the predictions of finished matches none
+// of whose predictions are graded yet. Only match predictions are read;
+// hypothetical ones are stored apart and never graded
+const completedPredictionsQuery = `
+	SELECT p.id, p.match_id, p.revision, p.created_at, m.utc_date
+	FROM predictions p
+	JOIN matches m ON p.match_id = m.id
+	WHERE p.match_id IN (
+		SELECT fm.id
+		FROM matches fm
+		WHERE fm.status = 'FINISHED'
+		  AND EXISTS (SELECT 1 FROM predictions pp WHERE pp.match_id = fm.id AND pp.created_at < fm.utc_date)
+		  AND NOT EXISTS (SELECT 1 FROM prediction_outcomes po WHERE po.match_id = fm.id)
 		LIMIT 100
-	`
+	)
+`
 
-	rows, err := s.db.QueryContext(ctx, query)
+// CheckCompletedMatches grades the predictions of finished matches, one
+// revision per match
+func (s *AccuracyService) CheckCompletedMatches(ctx context.Context) error {
+	rows, err := s.db.QueryContext(ctx, completedPredictionsQuery)
 	if err != nil {
 		return fmt.Errorf("failed to query completed matches: %w", err)
 	}
 	defer rows.Close()
 
-	count := 0
+	var candidates []gradeCandidate
 	for rows.Next() {
-		var predictionID uuid.UUID
-		var matchID int
-
-		if err := rows.Scan(&predictionID, &matchID); err != nil {
+		var c gradeCandidate
+		if err := rows.Scan(&c.predictionID, &c.matchID, &c.revision, &c.createdAt, &c.kickoff); err != nil {
 			slog.Error("Failed to scan prediction", "error", err)
 			continue
 		}
+		candidates = append(candidates, c)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to iterate completed matches: %w", err)
+	}
 
-		if err := s.RecordOutcome(ctx, predictionID, matchID); err != nil {
-			slog.Error("Failed to record outcome", "predictionId", predictionID, "error", err)
+	count := 0
+	for _, c := range gradedPredictions(candidates) {
+		if err := s.RecordOutcome(ctx, c.predictionID, c.matchID); err != nil {
+			slog.Error("Failed to record outcome", "predictionId", c.predictionID, "error", err)
 			continue
 		}
 
@@ -341,3 +633,166 @@ func (s *AccuracyService) CheckCompletedMatches(ctx context.Context) error {
 
 	return nil
 }
+
+// gradeCandidate is a prediction of a finished match
+type gradeCandidate struct {
+	predictionID uuid.UUID
+	matchID      int
+	revision     int
+	createdAt    time.Time
+	kickoff      time.Time
+}
+
+// gradedPredictions picks the prediction each match is graded on: its latest
+// revision made before kickoff, so re-predicted matches count once
+func gradedPredictions(candidates []gradeCandidate) []gradeCandidate {
+	latest := make(map[int]gradeCandidate)
+	var order []int
+	for _, c := range candidates {
+		if !c.createdAt.Before(c.kickoff) {
+			continue
+		}
+		current, ok := latest[c.matchID]
+		if !ok {
+			order = append(order, c.matchID)
+		}
+		if !ok || c.revision > current.revision {
+			latest[c.matchID] = c
+		}
+	}
+
+	graded := make([]gradeCandidate, len(order))
+	for i, matchID := range order {
+		graded[i] = latest[matchID]
+	}
+	return graded
+}
+
+// outcomeAggregateColumns aggregates graded outcomes overall, over the
+// outcomes that have a bookmaker baseline and over the calibrated ones, in
+// outcomeAggregate scan order
+const outcomeAggregateColumns = `
+	COUNT(*),
+	COALESCE(SUM(CASE WHEN was_correct THEN 1 ELSE 0 END), 0),
+	COALESCE(AVG(brier_score), 0),
+	COUNT(market_brier_score),
+	COALESCE(SUM(CASE WHEN market_brier_score IS NOT NULL AND was_correct THEN 1 ELSE 0 END), 0),
+	COALESCE(AVG(CASE WHEN market_brier_score IS NOT NULL THEN brier_score END), 0),
+	COALESCE(SUM(CASE WHEN market_correct THEN 1 ELSE 0 END), 0),
+	COALESCE(AVG(market_brier_score), 0),
+	COUNT(raw_brier_score),
+	COALESCE(SUM(CASE WHEN raw_brier_score IS NOT NULL AND was_correct THEN 1 ELSE 0 END), 0),
+	COALESCE(AVG(CASE WHEN raw_brier_score IS NOT NULL THEN brier_score END), 0),
+	COALESCE(SUM(CASE WHEN raw_correct THEN 1 ELSE 0 END), 0),
+	COALESCE(AVG(raw_brier_score), 0)`
+
+// outcomeAggregate holds the values selected by outcomeAggregateColumns
+type outcomeAggregate struct {
+	total, correct     int
+	brier              float64
+	marketMatches      int
+	modelCorrectMarket int // model picks right over the matches with odds
+	modelBrierMarket   float64
+	marketCorrect      int
+	marketBrier        float64
+	calibratedMatches  int
+	calibratedCorrect  int // calibrated picks right
+	calibratedBrier    float64
+	rawCorrect         int // raw picks right over the same matches
+	rawBrier           float64
+}
+
+// scanTargets returns scan destinations matching outcomeAggregateColumns
+func (a *outcomeAggregate) scanTargets() []any {
+	return []any{
+		&a.total, &a.correct, &a.brier,
+		&a.marketMatches, &a.modelCorrectMarket, &a.modelBrierMarket, &a.marketCorrect, &a.marketBrier,
+		&a.calibratedMatches, &a.calibratedCorrect, &a.calibratedBrier, &a.rawCorrect, &a.rawBrier,
+	}
+}
+
+// accuracyRate returns the share of correct predictions
+func (a *outcomeAggregate) accuracyRate() float64 {
+	if a.total == 0 {
+		return 0
+	}
+	return float64(a.correct) / float64(a.total)
+}
+
+// baseline compares the predictions with the market, or nil without graded odds
+func (a *outcomeAggregate) baseline() *MarketBaseline {
+	if a.marketMatches == 0 {
+		return nil
+	}
+
+	n := float64(a.marketMatches)
+	b := &MarketBaseline{
+		Matches:           a.marketMatches,
+		AccuracyRate:      float64(a.marketCorrect) / n,
+		BrierScore:        a.marketBrier,
+		ModelAccuracyRate: float64(a.modelCorrectMarket) / n,
+		ModelBrierScore:   a.modelBrierMarket,
+	}
+	b.AccuracyDelta = b.ModelAccuracyRate - b.AccuracyRate
+	b.BrierDelta = b.BrierScore - b.ModelBrierScore
+	return b
+}
+
+// calibration compares calibrated and raw probabilities, or nil without
+// graded calibrated predictions
+func (a *outcomeAggregate) calibration() *CalibrationStats {
+	if a.calibratedMatches == 0 {
+		return nil
+	}
+
+	n := float64(a.calibratedMatches)
+	return &CalibrationStats{
+		Matches:          a.calibratedMatches,
+		AccuracyRate:     float64(a.calibratedCorrect) / n,
+		BrierScore:       a.calibratedBrier,
+		RawAccuracyRate:  float64(a.rawCorrect) / n,
+		RawBrierScore:    a.rawBrier,
+		BrierImprovement: a.rawBrier - a.calibratedBrier,
+	}
+}
+
+// competitionAcc builds a competition's accuracy from its aggregate
+func (a *outcomeAggregate) competitionAcc(id int, name string) *CompetitionAcc {
+	return &CompetitionAcc{
+		CompetitionID:      id,
+		CompetitionName:    name,
+		TotalPredictions:   a.total,
+		CorrectPredictions: a.correct,
+		AccuracyRate:       a.accuracyRate(),
+		BrierScore:         a.brier,
+		MarketBaseline:     a.baseline(),
+		Calibration:        a.calibration(),
+	}
+}
+
+// pickWinner returns the outcome with the highest probability, preferring a
+// draw on ties as predictions always have
+func pickWinner(homeWin, draw, awayWin float64) string {
+	winner, maxProb := "draw", draw
+	if homeWin > maxProb {
+		winner, maxProb = "home", homeWin
+	}
+	if awayWin > maxProb {
+		winner = "away"
+	}
+	return winner
+}
+
+// brierScore returns the multi-class Brier score of 1X2 probabilities against
+// the actual winner: 0 is perfect, 2 is certain and wrong
+func brierScore(homeWin, draw, awayWin float64, actual string) float64 {
+	indicator := func(outcome string) float64 {
+		if outcome == actual {
+			return 1
+		}
+		return 0
+	}
+	return math.Pow(homeWin-indicator("home"), 2) +
+		math.Pow(draw-indicator("draw"), 2) +
+		math.Pow(awayWin-indicator("away"), 2)
+}
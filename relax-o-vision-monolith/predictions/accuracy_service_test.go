@@ -0,0 +1,72 @@
+package predictions
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestGradedPredictions(t *testing.T) {
+	t.Parallel()
+
+	kickoff := time.Date(2025, 3, 15, 15, 0, 0, 0, time.UTC)
+	candidate := func(matchID, revision int, before time.Duration) gradeCandidate {
+		return gradeCandidate{
+			predictionID: uuid.New(),
+			matchID:      matchID,
+			revision:     revision,
+			createdAt:    kickoff.Add(-before),
+			kickoff:      kickoff,
+		}
+	}
+
+	first, second := candidate(1, 1, 48*time.Hour), candidate(1, 2, time.Hour)
+	late := candidate(1, 3, -10*time.Minute)
+	single := candidate(2, 1, 24*time.Hour)
+	afterKickoff := candidate(3, 1, -time.Hour)
+
+	tests := []struct {
+		name       string
+		candidates []gradeCandidate
+		want       []gradeCandidate
+	}{
+		{
+			name:       "two revisions",
+			candidates: []gradeCandidate{second, first},
+			want:       []gradeCandidate{second},
+		},
+		{
+			name:       "revision after kickoff",
+			candidates: []gradeCandidate{first, second, late},
+			want:       []gradeCandidate{second},
+		},
+		{
+			name:       "several matches",
+			candidates: []gradeCandidate{first, single, second, afterKickoff},
+			want:       []gradeCandidate{second, single},
+		},
+		{
+			name:       "only predicted after kickoff",
+			candidates: []gradeCandidate{afterKickoff},
+			want:       []gradeCandidate{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := gradedPredictions(tt.candidates)
+			if len(got) != len(tt.want) {
+				t.Fatalf("gradedPredictions() = %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				if got[i].predictionID != tt.want[i].predictionID {
+					t.Errorf("graded %d = match %d revision %d, want match %d revision %d",
+						i, got[i].matchID, got[i].revision, tt.want[i].matchID, tt.want[i].revision)
+				}
+			}
+		})
+	}
+}
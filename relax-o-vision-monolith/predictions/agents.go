@@ -5,9 +5,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"strings"
 
 	"github.com/edd/relaxovisionmonolith/predictions/providers"
 	"github.com/sashabaranov/go-openai"
+	"golang.org/x/sync/errgroup"
 )
 
 const (
@@ -16,257 +18,490 @@ const (
 	AgentTypeForm         = "form"
 	AgentTypeHeadToHead   = "head-to-head"
 	AgentTypeAggregator   = "aggregator"
+	AgentTypeCritic       = "critic"
+	AgentTypePrompt       = "prompt"
+	AgentTypeElo          = "elo"
 )
 
+// PromptVersion identifies the agent prompts and response format, recorded
+// with each prediction revision. Bump it when a prompt changes
+const PromptVersion = 3
+
+// agentModel is the OpenAI model the agents run on
+const agentModel = openai.GPT4
+
+// Agent analyzes a match from one perspective. Agent types are registered
+// with a Registry and combined into pipelines by configuration
+type Agent interface {
+	// Type names the agent; its outputs carry it as their agent type
+	Type() string
+	// Model names what the agent runs on: an LLM, providers or a statistical model
+	Model() string
+	Analyze(ctx context.Context, analysis *MatchAnalysis) (*AgentOutput, error)
+}
+
+// Reviser is implemented by agents that can revise their output in a debate.
+// Agents without it keep their opening output through the rounds
+type Reviser interface {
+	Revise(ctx context.Context, analysis *MatchAnalysis, outputs []AgentOutput, own, round int) (*AgentOutput, error)
+}
+
+// Aggregator combines the agents' outputs into the final prediction
+type Aggregator interface {
+	Aggregate(ctx context.Context, outputs []AgentOutput) (*AgentOutput, error)
+}
+
 // providerResult holds result from a single provider analysis
 type providerResult struct {
-	provider string
-	result   *providers.AnalysisResult
+	provider  string
+	result    *providers.AnalysisResult
+	toolCalls []providers.ToolCall
+	// fallback is set when the agent had tools but the provider cannot call them
+	fallback bool
 	err      error
 }
 
-// Agent represents an AI agent for match prediction
-type Agent struct {
+// LLMAgent is an agent that asks an LLM for its analysis: OpenAI through its
+// own client, or the providers it was given
+type LLMAgent struct {
 	agentType string
 	client    *openai.Client
 	providers []providers.LLMProvider
 	weights   map[string]float64
+	// samples is how many answers an agent asking a single model averages
+	samples int
+	// tools lets the model query match data; toolProvider runs them for a single-provider agent
+	tools        *MatchTools
+	toolProvider providers.LLMProvider
 }
 
-// NewAgent creates a new AI agent with OpenAI (legacy compatibility)
-func NewAgent(agentType string, apiKey string) *Agent {
-	return &Agent{
-		agentType: agentType,
-		client:    openai.NewClient(apiKey),
+// NewLLMAgent creates a new LLM agent with OpenAI (legacy compatibility)
+func NewLLMAgent(agentType string, apiKey string) *LLMAgent {
+	return &LLMAgent{
+		agentType:    agentType,
+		client:       openai.NewClient(apiKey),
+		toolProvider: providers.NewOpenAIProvider(apiKey, agentModel),
 	}
 }
 
 // NewMultiProviderAgent creates a new agent with multiple LLM providers
-func NewMultiProviderAgent(agentType string, llmProviders []providers.LLMProvider, weights map[string]float64) *Agent {
-	return &Agent{
+func NewMultiProviderAgent(agentType string, llmProviders []providers.LLMProvider, weights map[string]float64) *LLMAgent {
+	return &LLMAgent{
 		agentType: agentType,
 		providers: llmProviders,
 		weights:   weights,
 	}
 }
 
-// StatisticalAgent analyzes historical statistics
-type StatisticalAgent struct {
-	*Agent
+// Type returns the agent's type
+func (a *LLMAgent) Type() string {
+	return a.agentType
 }
 
-// NewStatisticalAgent creates a new statistical analysis agent
-func NewStatisticalAgent(apiKey string) *StatisticalAgent {
-	return &StatisticalAgent{
-		Agent: NewAgent(AgentTypeStatistical, apiKey),
+// Model returns the OpenAI model, or the names of the agent's providers
+func (a *LLMAgent) Model() string {
+	if len(a.providers) == 0 {
+		return agentModel
+	}
+	names := make([]string, len(a.providers))
+	for i, p := range a.providers {
+		names[i] = p.Name()
 	}
+	return strings.Join(names, "+")
 }
 
-// Analyze performs statistical analysis on match data
-func (a *StatisticalAgent) Analyze(ctx context.Context, analysis *MatchAnalysis) (*AgentOutput, error) {
-	prompt := buildStatisticalPrompt(analysis)
-	
+// usesLLM marks agents whose analyses count as LLM calls
+func (a *LLMAgent) usesLLM() bool {
+	return true
+}
+
+// complete asks OpenAI for the agent's analysis. With more than one sample
+// the model answers n times at the temperature, and the answers are averaged
+// with their dispersion
+func (a *LLMAgent) complete(ctx context.Context, system, prompt string, temperature float32, n int) (*AgentOutput, error) {
 	resp, err := a.client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
-		Model: openai.GPT4,
+		Model: agentModel,
 		Messages: []openai.ChatCompletionMessage{
 			{
 				Role:    openai.ChatMessageRoleSystem,
-				Content: "You are an expert football analyst specializing in statistical analysis. Provide predictions based on team statistics.",
+				Content: system,
 			},
 			{
 				Role:    openai.ChatMessageRoleUser,
 				Content: prompt,
 			},
 		},
-		Temperature: 0.7,
+		Temperature: temperature,
+		N:           max(n, 1),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("no completion returned")
+	}
+
+	if len(resp.Choices) == 1 {
+		output, err := parseAgentResponse(a.agentType, resp.Choices[0].Message.Content)
+		if err != nil {
+			return nil, err
+		}
+		output.setUncertainty()
+		return output, nil
+	}
+
+	var samples []AgentOutput
+	for _, choice := range resp.Choices {
+		output, err := parseAgentResponse(a.agentType, choice.Message.Content)
+		if err != nil {
+			slog.Warn("Failed to parse agent sample", "agent", a.agentType, "error", err)
+			continue
+		}
+		samples = append(samples, *output)
+	}
+	if len(samples) == 0 {
+		return nil, fmt.Errorf("no sample could be parsed")
+	}
+
+	return combineSamples(a.agentType, samples), nil
+}
+
+// agentRequest is a question an agent puts to its model
+type agentRequest struct {
+	analysis *MatchAnalysis
+	system   string
+	// instructions are sent with the data and the expected response format
+	instructions string
+	data         any // the analysis when nil
+	temperature  float32
+	// tools lets the model query match data; nil to answer without them
+	tools *MatchTools
+}
+
+// ask puts the request to the agent's providers, or to OpenAI when it has
+// none. An agent asking a single model averages its samples; one with several
+// providers measures its dispersion across them instead
+func (a *LLMAgent) ask(ctx context.Context, req agentRequest) (*AgentOutput, error) {
+	if req.data == nil {
+		req.data = req.analysis
+	}
+	// OpenAI completions are sampled in one request, other questions once per sample
+	if a.samples > 1 && (len(a.providers) == 1 || len(a.providers) == 0 && req.tools != nil) {
+		return a.sample(ctx, req)
+	}
+	return a.askOnce(ctx, req, a.samples)
+}
+
+// askOnce puts the request to the agent's model once; a plain OpenAI
+// completion is sampled n times
+func (a *LLMAgent) askOnce(ctx context.Context, req agentRequest, n int) (*AgentOutput, error) {
+	if len(a.providers) > 0 {
+		return a.analyzeWithMultipleProviders(ctx, req)
+	}
+
+	prompt, err := providers.AnalysisPrompt(req.instructions, req.data)
+	if err != nil {
+		return nil, err
+	}
+	if req.tools == nil {
+		return a.complete(ctx, req.system, prompt, req.temperature, n)
+	}
+
+	caller, ok := a.toolProvider.(providers.ToolCaller)
+	if !ok {
+		output, err := a.complete(ctx, req.system, prompt, req.temperature, n)
+		if err != nil {
+			return nil, err
+		}
+		setToolMetadata(output, nil, []string{a.toolProvider.Name()})
+		return output, nil
+	}
+
+	result, calls, err := caller.AnalyzeWithTools(ctx, req.tools.request(req.analysis, req.system, prompt))
+	if err != nil {
+		return nil, err
+	}
+
+	output := newAgentOutput(a.agentType, result)
+	setToolMetadata(output, calls, nil)
+	output.setUncertainty()
+	return output, nil
+}
+
+// sample asks the agent's model the request once per sample, concurrently,
+// and averages the answers
+func (a *LLMAgent) sample(ctx context.Context, req agentRequest) (*AgentOutput, error) {
+	answers := make([]*AgentOutput, a.samples)
+	var g errgroup.Group
+	for i := range answers {
+		g.Go(func() error {
+			output, err := a.askOnce(ctx, req, 1)
+			if err != nil {
+				slog.Warn("Agent sample failed", "agent", a.agentType, "error", err)
+				return nil
+			}
+			answers[i] = output
+			return nil
+		})
+	}
+	g.Wait()
+
+	var samples []AgentOutput
+	var calls []providers.ToolCall
+	var fallback []string
+	for _, answer := range answers {
+		if answer == nil {
+			continue
+		}
+		samples = append(samples, *answer)
+		if c, ok := answer.Metadata["toolCalls"].([]providers.ToolCall); ok {
+			calls = append(calls, c...)
+		}
+		if f, ok := answer.Metadata["toolFallback"].([]string); ok {
+			fallback = f
+		}
+	}
+	if len(samples) == 0 {
+		return nil, fmt.Errorf("no sample succeeded")
+	}
+
+	output := combineSamples(a.agentType, samples)
+	if len(a.providers) == 1 {
+		output.ProviderOutputs = []ProviderOutput{{
+			Provider:    a.providers[0].Name(),
+			HomeWinProb: output.HomeWinProb,
+			DrawProb:    output.DrawProb,
+			AwayWinProb: output.AwayWinProb,
+		}}
+	}
+	if req.tools != nil {
+		setToolMetadata(output, calls, fallback)
+	}
+	return output, nil
+}
+
+// setToolMetadata records the tool calls behind an output, and the providers
+// that answered without tools as they lack function calling
+func setToolMetadata(output *AgentOutput, calls []providers.ToolCall, fallback []string) {
+	if output.Metadata == nil {
+		output.Metadata = make(map[string]any)
+	}
+	output.Metadata["toolCalls"] = append([]providers.ToolCall{}, calls...)
+	if len(fallback) > 0 {
+		output.Metadata["toolFallback"] = fallback
+	}
+}
+
+// newAgentOutput converts a provider's analysis into an agent output
+func newAgentOutput(agentType string, result *providers.AnalysisResult) *AgentOutput {
+	return &AgentOutput{
+		AgentType:         agentType,
+		HomeWinProb:       result.HomeWinProb,
+		DrawProb:          result.DrawProb,
+		AwayWinProb:       result.AwayWinProb,
+		Confidence:        result.Confidence,
+		Reasoning:         result.Reasoning,
+		KeyFactors:        result.KeyFactors,
+		HomeExpectedGoals: validExpectedGoals(result.HomeExpectedGoals),
+		AwayExpectedGoals: validExpectedGoals(result.AwayExpectedGoals),
+	}
+}
+
+// combineSamples averages an agent's sampled answers, keeping the first
+// answer's reasoning and the key factors most samples gave
+func combineSamples(agentType string, samples []AgentOutput) *AgentOutput {
+	output := &AgentOutput{
+		AgentType: agentType,
+		Reasoning: samples[0].Reasoning,
+		Metadata:  map[string]any{"samples": len(samples)},
+	}
+
+	forecasts := make([][3]float64, len(samples))
+	keyFactorCounts := make(map[string]int)
+	var homeGoals, awayGoals float64
+	goalSamples := 0
+	for i, sample := range samples {
+		forecasts[i] = forecastOf(sample)
+		output.HomeWinProb += sample.HomeWinProb
+		output.DrawProb += sample.DrawProb
+		output.AwayWinProb += sample.AwayWinProb
+		output.Confidence += sample.Confidence
+		if sample.HomeExpectedGoals != nil && sample.AwayExpectedGoals != nil {
+			homeGoals += *sample.HomeExpectedGoals
+			awayGoals += *sample.AwayExpectedGoals
+			goalSamples++
+		}
+		for _, factor := range sample.KeyFactors {
+			if keyFactorCounts[factor]++; keyFactorCounts[factor] == len(samples)/2+1 {
+				output.KeyFactors = append(output.KeyFactors, factor)
+			}
+		}
+	}
+
+	n := float64(len(samples))
+	output.HomeWinProb /= n
+	output.DrawProb /= n
+	output.AwayWinProb /= n
+	output.Confidence /= n
+	if goalSamples > 0 {
+		homeGoals /= float64(goalSamples)
+		awayGoals /= float64(goalSamples)
+		output.HomeExpectedGoals = &homeGoals
+		output.AwayExpectedGoals = &awayGoals
+	}
+
+	output.Dispersion = newDispersion(forecasts)
+	output.setUncertainty()
+	return output
+}
+
+// StatisticalAgent analyzes historical statistics
+type StatisticalAgent struct {
+	*LLMAgent
+}
+
+// NewStatisticalAgent creates a new statistical analysis agent
+func NewStatisticalAgent(apiKey string) *StatisticalAgent {
+	return &StatisticalAgent{
+		LLMAgent: NewLLMAgent(AgentTypeStatistical, apiKey),
+	}
+}
+
+// Analyze performs statistical analysis on match data
+func (a *StatisticalAgent) Analyze(ctx context.Context, analysis *MatchAnalysis) (*AgentOutput, error) {
+	output, err := a.ask(ctx, agentRequest{
+		analysis:     analysis,
+		system:       "You are an expert football analyst specializing in statistical analysis. Provide predictions based on team statistics.",
+		instructions: statisticalInstructions,
+		temperature:  0.7,
+		tools:        a.tools,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get statistical analysis: %w", err)
 	}
 
-	return parseAgentResponse(a.agentType, resp.Choices[0].Message.Content)
+	return output, nil
 }
 
 // FormAgent evaluates recent team form
 type FormAgent struct {
-	*Agent
+	*LLMAgent
 }
 
 // NewFormAgent creates a new form analysis agent
 func NewFormAgent(apiKey string) *FormAgent {
 	return &FormAgent{
-		Agent: NewAgent(AgentTypeForm, apiKey),
+		LLMAgent: NewLLMAgent(AgentTypeForm, apiKey),
 	}
 }
 
 // Analyze performs form analysis on match data
 func (a *FormAgent) Analyze(ctx context.Context, analysis *MatchAnalysis) (*AgentOutput, error) {
-	prompt := buildFormPrompt(analysis)
-	
-	resp, err := a.client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
-		Model: openai.GPT4,
-		Messages: []openai.ChatCompletionMessage{
-			{
-				Role:    openai.ChatMessageRoleSystem,
-				Content: "You are an expert football analyst specializing in recent team form. Focus on momentum and current performance trends.",
-			},
-			{
-				Role:    openai.ChatMessageRoleUser,
-				Content: prompt,
-			},
-		},
-		Temperature: 0.7,
+	output, err := a.ask(ctx, agentRequest{
+		analysis:     analysis,
+		system:       "You are an expert football analyst specializing in recent team form. Focus on momentum and current performance trends.",
+		instructions: formInstructions,
+		temperature:  0.7,
+		tools:        a.tools,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get form analysis: %w", err)
 	}
 
-	return parseAgentResponse(a.agentType, resp.Choices[0].Message.Content)
+	return output, nil
 }
 
 // HeadToHeadAgent analyzes head-to-head records
 type HeadToHeadAgent struct {
-	*Agent
+	*LLMAgent
 }
 
 // NewHeadToHeadAgent creates a new head-to-head analysis agent
 func NewHeadToHeadAgent(apiKey string) *HeadToHeadAgent {
 	return &HeadToHeadAgent{
-		Agent: NewAgent(AgentTypeHeadToHead, apiKey),
+		LLMAgent: NewLLMAgent(AgentTypeHeadToHead, apiKey),
 	}
 }
 
 // Analyze performs head-to-head analysis on match data
 func (a *HeadToHeadAgent) Analyze(ctx context.Context, analysis *MatchAnalysis) (*AgentOutput, error) {
-	prompt := buildHeadToHeadPrompt(analysis)
-	
-	resp, err := a.client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
-		Model: openai.GPT4,
-		Messages: []openai.ChatCompletionMessage{
-			{
-				Role:    openai.ChatMessageRoleSystem,
-				Content: "You are an expert football analyst specializing in head-to-head matchups. Analyze historical encounters between teams.",
-			},
-			{
-				Role:    openai.ChatMessageRoleUser,
-				Content: prompt,
-			},
-		},
-		Temperature: 0.7,
+	output, err := a.ask(ctx, agentRequest{
+		analysis:     analysis,
+		system:       "You are an expert football analyst specializing in head-to-head matchups. Analyze historical encounters between teams.",
+		instructions: headToHeadInstructions,
+		temperature:  0.7,
+		tools:        a.tools,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get head-to-head analysis: %w", err)
 	}
 
-	return parseAgentResponse(a.agentType, resp.Choices[0].Message.Content)
+	return output, nil
+}
+
+// PromptAgent analyzes a match with instructions from its pipeline configuration
+type PromptAgent struct {
+	*LLMAgent
+	system       string
+	instructions string
+}
+
+// Analyze runs the configured instructions on match data
+func (a *PromptAgent) Analyze(ctx context.Context, analysis *MatchAnalysis) (*AgentOutput, error) {
+	output, err := a.ask(ctx, agentRequest{
+		analysis:     analysis,
+		system:       a.system,
+		instructions: a.instructions,
+		temperature:  0.7,
+		tools:        a.tools,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s analysis: %w", a.agentType, err)
+	}
+
+	return output, nil
 }
 
 // AggregatorAgent combines insights from multiple agents
 type AggregatorAgent struct {
-	*Agent
+	*LLMAgent
 }
 
 // NewAggregatorAgent creates a new aggregator agent
 func NewAggregatorAgent(apiKey string) *AggregatorAgent {
 	return &AggregatorAgent{
-		Agent: NewAgent(AgentTypeAggregator, apiKey),
+		LLMAgent: NewLLMAgent(AgentTypeAggregator, apiKey),
 	}
 }
 
 // Aggregate combines outputs from multiple agents
 func (a *AggregatorAgent) Aggregate(ctx context.Context, outputs []AgentOutput) (*AgentOutput, error) {
-	prompt := buildAggregatorPrompt(outputs)
-	
-	resp, err := a.client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
-		Model: openai.GPT4,
-		Messages: []openai.ChatCompletionMessage{
-			{
-				Role:    openai.ChatMessageRoleSystem,
-				Content: "You are an expert football analyst who synthesizes multiple perspectives into a final prediction. Weight the different analyses and provide a consensus prediction.",
-			},
-			{
-				Role:    openai.ChatMessageRoleUser,
-				Content: prompt,
-			},
-		},
-		Temperature: 0.5,
+	output, err := a.ask(ctx, agentRequest{
+		system:       "You are an expert football analyst who synthesizes multiple perspectives into a final prediction. Weight the different analyses and provide a consensus prediction.",
+		instructions: "Synthesize the following agent predictions into a final consensus prediction:",
+		data:         outputs,
+		temperature:  0.5,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to aggregate predictions: %w", err)
 	}
 
-	return parseAgentResponse(a.agentType, resp.Choices[0].Message.Content)
-}
-
-// Helper functions for building prompts
-
-func buildStatisticalPrompt(analysis *MatchAnalysis) string {
-	data, _ := json.MarshalIndent(analysis, "", "  ")
-	return fmt.Sprintf(`Analyze the following match data and provide a prediction based on team statistics:
-
-%s
-
-Provide your analysis in JSON format:
-{
-  "homeWinProb": <0-1>,
-  "drawProb": <0-1>,
-  "awayWinProb": <0-1>,
-  "confidence": <0-1>,
-  "reasoning": "<explanation>",
-  "keyFactors": ["factor1", "factor2", ...]
-}`, string(data))
-}
-
-func buildFormPrompt(analysis *MatchAnalysis) string {
-	data, _ := json.MarshalIndent(analysis, "", "  ")
-	return fmt.Sprintf(`Analyze the following match data focusing on recent team form:
-
-%s
-
-Provide your analysis in JSON format:
-{
-  "homeWinProb": <0-1>,
-  "drawProb": <0-1>,
-  "awayWinProb": <0-1>,
-  "confidence": <0-1>,
-  "reasoning": "<explanation>",
-  "keyFactors": ["factor1", "factor2", ...]
-}`, string(data))
+	return output, nil
 }
 
-func buildHeadToHeadPrompt(analysis *MatchAnalysis) string {
-	data, _ := json.MarshalIndent(analysis, "", "  ")
-	return fmt.Sprintf(`Analyze the head-to-head history between these teams:
+// Instructions of the analyst agents
 
-%s
+const statisticalInstructions = `Analyze the following match data and provide a prediction based on team statistics.
+Where underlyingStats are present, weigh expected goals (xG) for and against and shot conversion
+alongside results: a team outperforming its xG is likely to regress towards it.`
 
-Provide your analysis in JSON format:
-{
-  "homeWinProb": <0-1>,
-  "drawProb": <0-1>,
-  "awayWinProb": <0-1>,
-  "confidence": <0-1>,
-  "reasoning": "<explanation>",
-  "keyFactors": ["factor1", "factor2", ...]
-}`, string(data))
-}
+const formInstructions = `Analyze the following match data focusing on recent team form.
+Account for each team's schedule: few rest days, a congested run of fixtures, long travel or a
+big match coming up can all mean a tired or rotated squad.`
 
-func buildAggregatorPrompt(outputs []AgentOutput) string {
-	data, _ := json.MarshalIndent(outputs, "", "  ")
-	return fmt.Sprintf(`Synthesize the following agent predictions into a final consensus prediction:
+const headToHeadInstructions = `Analyze the head-to-head history between these teams.`
 
-%s
-
-Provide your aggregated analysis in JSON format:
-{
-  "homeWinProb": <0-1>,
-  "drawProb": <0-1>,
-  "awayWinProb": <0-1>,
-  "confidence": <0-1>,
-  "reasoning": "<explanation>",
-  "keyFactors": ["factor1", "factor2", ...]
-}`, string(data))
-}
+// Helper functions for parsing responses
 
 func parseAgentResponse(agentType, response string) (*AgentOutput, error) {
 	var output struct {
@@ -276,6 +511,8 @@ func parseAgentResponse(agentType, response string) (*AgentOutput, error) {
 		Confidence  float64  `json:"confidence"`
 		Reasoning   string   `json:"reasoning"`
 		KeyFactors  []string `json:"keyFactors"`
+		HomeExpectedGoals *float64 `json:"homeExpectedGoals"`
+		AwayExpectedGoals *float64 `json:"awayExpectedGoals"`
 	}
 
 	if err := json.Unmarshal([]byte(response), &output); err != nil {
@@ -290,26 +527,22 @@ func parseAgentResponse(agentType, response string) (*AgentOutput, error) {
 		Confidence:  output.Confidence,
 		Reasoning:   output.Reasoning,
 		KeyFactors:  output.KeyFactors,
+		HomeExpectedGoals: validExpectedGoals(output.HomeExpectedGoals),
+		AwayExpectedGoals: validExpectedGoals(output.AwayExpectedGoals),
 	}, nil
 }
 
 // analyzeWithMultipleProviders runs analysis with multiple providers and aggregates results
-func (a *Agent) analyzeWithMultipleProviders(ctx context.Context, prompt string, analysis *MatchAnalysis) (*AgentOutput, error) {
+func (a *LLMAgent) analyzeWithMultipleProviders(ctx context.Context, req agentRequest) (*AgentOutput, error) {
 	if len(a.providers) == 0 {
 		return nil, fmt.Errorf("no providers configured")
 	}
-
 	results := make(chan providerResult, len(a.providers))
 
 	// Run all providers in parallel
 	for _, provider := range a.providers {
 		go func(p providers.LLMProvider) {
-			result, err := p.Analyze(ctx, prompt, analysis)
-			results <- providerResult{
-				provider: p.Name(),
-				result:   result,
-				err:      err,
-			}
+			results <- a.analyzeWithProvider(ctx, p, req)
 		}(provider)
 	}
 
@@ -329,15 +562,46 @@ func (a *Agent) analyzeWithMultipleProviders(ctx context.Context, prompt string,
 	}
 
 	// Aggregate results with weights
-	return a.aggregateProviderResults(validResults), nil
+	return a.aggregateProviderResults(validResults, req.tools != nil), nil
+}
+
+// analyzeWithProvider runs one provider's analysis, through its native
+// function calling when the agent has tools and the provider supports it.
+// Providers analyze with their own system prompt, so the agent's leads the instructions
+func (a *LLMAgent) analyzeWithProvider(ctx context.Context, p providers.LLMProvider, req agentRequest) providerResult {
+	res := providerResult{provider: p.Name()}
+	prompt := req.instructions
+	if req.system != "" {
+		prompt = req.system + "\n\n" + prompt
+	}
+
+	caller, ok := p.(providers.ToolCaller)
+	if req.tools == nil || !ok {
+		res.fallback = req.tools != nil
+		res.result, res.err = p.Analyze(ctx, prompt, req.data)
+		return res
+	}
+
+	fullPrompt, err := providers.AnalysisPrompt(prompt, req.data)
+	if err != nil {
+		res.err = err
+		return res
+	}
+	res.result, res.toolCalls, res.err = caller.AnalyzeWithTools(ctx, req.tools.request(req.analysis, providers.DefaultSystemPrompt, fullPrompt))
+	return res
 }
 
 // aggregateProviderResults aggregates results from multiple providers
-func (a *Agent) aggregateProviderResults(results []providerResult) *AgentOutput {
+func (a *LLMAgent) aggregateProviderResults(results []providerResult, tools bool) *AgentOutput {
 	var homeWinProb, drawProb, awayWinProb, confidence float64
 	var reasonings []string
+	var providerOutputs []ProviderOutput
+	var toolCalls []providers.ToolCall
+	var fallback []string
 	keyFactorsMap := make(map[string]int)
 
+	var homeGoals, awayGoals, goalsWeight float64
+
 	totalWeight := 0.0
 	for _, res := range results {
 		weight := 1.0
@@ -353,6 +617,25 @@ func (a *Agent) aggregateProviderResults(results []providerResult) *AgentOutput
 		awayWinProb += res.result.AwayWinProb * weight
 		confidence += res.result.Confidence * weight
 
+		resultHomeGoals, resultAwayGoals := validExpectedGoals(res.result.HomeExpectedGoals), validExpectedGoals(res.result.AwayExpectedGoals)
+		if resultHomeGoals != nil && resultAwayGoals != nil {
+			homeGoals += *resultHomeGoals * weight
+			awayGoals += *resultAwayGoals * weight
+			goalsWeight += weight
+		}
+
+		providerOutputs = append(providerOutputs, ProviderOutput{
+			Provider:    res.provider,
+			HomeWinProb: res.result.HomeWinProb,
+			DrawProb:    res.result.DrawProb,
+			AwayWinProb: res.result.AwayWinProb,
+		})
+
+		toolCalls = append(toolCalls, res.toolCalls...)
+		if res.fallback {
+			fallback = append(fallback, res.provider)
+		}
+
 		reasonings = append(reasonings, fmt.Sprintf("[%s]: %s", res.provider, res.result.Reasoning))
 		for _, factor := range res.result.KeyFactors {
 			keyFactorsMap[factor]++
@@ -384,7 +667,7 @@ func (a *Agent) aggregateProviderResults(results []providerResult) *AgentOutput
 		reasoning += r
 	}
 
-	return &AgentOutput{
+	output := &AgentOutput{
 		AgentType:   a.agentType,
 		HomeWinProb: homeWinProb,
 		DrawProb:    drawProb,
@@ -392,5 +675,28 @@ func (a *Agent) aggregateProviderResults(results []providerResult) *AgentOutput
 		Confidence:  confidence,
 		Reasoning:   reasoning,
 		KeyFactors:  keyFactors,
+		ProviderOutputs: providerOutputs,
+	}
+
+	// Expected goals are averaged over the providers that gave them
+	if goalsWeight > 0 {
+		homeGoals /= goalsWeight
+		awayGoals /= goalsWeight
+		output.HomeExpectedGoals = &homeGoals
+		output.AwayExpectedGoals = &awayGoals
 	}
+
+	if tools {
+		setToolMetadata(output, toolCalls, fallback)
+	}
+
+	// The spread between providers is kept as the agent's uncertainty
+	forecasts := make([][3]float64, len(providerOutputs))
+	for i, p := range providerOutputs {
+		forecasts[i] = [3]float64{p.HomeWinProb, p.DrawProb, p.AwayWinProb}
+	}
+	output.Dispersion = newDispersion(forecasts)
+	output.setUncertainty()
+
+	return output
 }
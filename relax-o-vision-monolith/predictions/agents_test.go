@@ -0,0 +1,110 @@
+package predictions
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/edd/relaxovisionmonolith/predictions/providers"
+)
+
+// sequenceProvider answers with its results in turn, failing past the last
+type sequenceProvider struct {
+	results []providers.AnalysisResult
+	calls   atomic.Int32
+}
+
+func (p *sequenceProvider) Name() string { return "fake" }
+
+func (p *sequenceProvider) Analyze(ctx context.Context, prompt string, data interface{}) (*providers.AnalysisResult, error) {
+	i := int(p.calls.Add(1)) - 1
+	if i >= len(p.results) {
+		return nil, fmt.Errorf("no answer %d", i)
+	}
+	result := p.results[i]
+	return &result, nil
+}
+
+func (p *sequenceProvider) GenerateEmbedding(ctx context.Context, text string) ([]float32, error) {
+	return nil, nil
+}
+
+func TestLLMAgent_Ask_SamplesSingleProvider(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		samples     int
+		results     []providers.AnalysisResult
+		wantCalls   int
+		wantSamples int
+		wantHome    float64
+	}{
+		{
+			name:    "unsampled",
+			samples: 0,
+			results: []providers.AnalysisResult{
+				{HomeWinProb: 0.5, DrawProb: 0.3, AwayWinProb: 0.2, Confidence: 0.6},
+			},
+			wantCalls: 1,
+			wantHome:  0.5,
+		},
+		{
+			name:    "sampled",
+			samples: 3,
+			results: []providers.AnalysisResult{
+				{HomeWinProb: 0.5, DrawProb: 0.3, AwayWinProb: 0.2, Confidence: 0.6},
+				{HomeWinProb: 0.6, DrawProb: 0.2, AwayWinProb: 0.2, Confidence: 0.6},
+				{HomeWinProb: 0.4, DrawProb: 0.3, AwayWinProb: 0.3, Confidence: 0.6},
+			},
+			wantCalls:   3,
+			wantSamples: 3,
+			wantHome:    0.5,
+		},
+		{
+			name:    "failed samples are left out",
+			samples: 3,
+			results: []providers.AnalysisResult{
+				{HomeWinProb: 0.5, DrawProb: 0.3, AwayWinProb: 0.2, Confidence: 0.6},
+				{HomeWinProb: 0.7, DrawProb: 0.2, AwayWinProb: 0.1, Confidence: 0.6},
+			},
+			wantCalls:   3,
+			wantSamples: 2,
+			wantHome:    0.6,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			provider := &sequenceProvider{results: tt.results}
+			agent := NewMultiProviderAgent("statistical", []providers.LLMProvider{provider}, nil)
+			agent.samples = tt.samples
+
+			output, err := agent.ask(context.Background(), agentRequest{instructions: "predict", data: map[string]any{}})
+			if err != nil {
+				t.Fatalf("ask() error = %v", err)
+			}
+			if got := int(provider.calls.Load()); got != tt.wantCalls {
+				t.Errorf("provider asked %d times, want %d", got, tt.wantCalls)
+			}
+			if !almostEqual(output.HomeWinProb, tt.wantHome) {
+				t.Errorf("home win = %v, want %v", output.HomeWinProb, tt.wantHome)
+			}
+			if tt.wantSamples == 0 {
+				return
+			}
+			if got := output.Metadata["samples"]; got != tt.wantSamples {
+				t.Errorf("samples = %v, want %d", got, tt.wantSamples)
+			}
+			if output.Dispersion == nil {
+				t.Error("dispersion not set across samples")
+			}
+			if len(output.ProviderOutputs) != 1 || output.ProviderOutputs[0].Provider != "fake" {
+				t.Errorf("provider outputs = %+v, want one for the provider", output.ProviderOutputs)
+			}
+		})
+	}
+}
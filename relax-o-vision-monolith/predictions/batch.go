@@ -0,0 +1,472 @@
+package predictions
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	// maxBatchMatches is the most matches a batch may predict
+	maxBatchMatches = 100
+	// batchConcurrency is how many predictions of a batch run at once
+	batchConcurrency = 2
+	// llmCallSpacing is how long the start of the next batch prediction waits
+	// per LLM call of the previous one, to stay within provider rate limits
+	llmCallSpacing = 750 * time.Millisecond
+	// batchHeartbeatInterval is how often a running batch records that it is alive
+	batchHeartbeatInterval = 30 * time.Second
+	// staleBatchAge is how long after its last heartbeat a batch counts as interrupted
+	staleBatchAge = 4 * batchHeartbeatInterval
+	// interruptedBatchError is the error of batch matches a restart interrupted
+	interruptedBatchError = "interrupted by a restart"
+)
+
+// Batch and batch match statuses
+const (
+	BatchPending   = "pending"
+	BatchRunning   = "running"
+	BatchCompleted = "completed"
+	BatchFailed    = "failed"
+)
+
+// ErrInvalidBatch is returned when a batch request selects no valid matches
+var ErrInvalidBatch = errors.New("invalid batch")
+
+// BatchRequest selects the matches of a batch: a competition's matchday or a list of match IDs
+type BatchRequest struct {
+	CompetitionID int   `json:"competitionId"`
+	Matchday      int   `json:"matchday"`
+	MatchIDs      []int `json:"matchIds"`
+	Force         bool  `json:"force"`
+}
+
+// Batch tracks the predictions of a batch of matches
+type Batch struct {
+	ID            string       `json:"id"`
+	CompetitionID int          `json:"competitionId,omitempty"`
+	Matchday      int          `json:"matchday,omitempty"`
+	Force         bool         `json:"force"`
+	Status        string       `json:"status"`
+	Total         int          `json:"total"`
+	Completed     int          `json:"completed"`
+	Failed        int          `json:"failed"`
+	CreatedAt     time.Time    `json:"createdAt"`
+	FinishedAt    *time.Time   `json:"finishedAt,omitempty"`
+	Matches       []BatchMatch `json:"matches"`
+}
+
+// BatchMatch is the status of one match of a batch
+type BatchMatch struct {
+	MatchID      int       `json:"matchId"`
+	Status       string    `json:"status"`
+	PredictionID string    `json:"predictionId,omitempty"`
+	Error        string    `json:"error,omitempty"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+}
+
+// BatchProgress reports a batch match finishing, or the batch itself when Match is nil
+type BatchProgress struct {
+	BatchID   string      `json:"batchId"`
+	Status    string      `json:"status"` // of the batch
+	Total     int         `json:"total"`
+	Completed int         `json:"completed"`
+	Failed    int         `json:"failed"`
+	Match     *BatchMatch `json:"match,omitempty"`
+}
+
+// BatchProgressHook is called as a batch progresses
+type BatchProgressHook func(progress BatchProgress)
+
+// OnBatchProgress registers a hook to run as batch matches finish
+func (s *Service) OnBatchProgress(hook BatchProgressHook) {
+	s.batchHooks = append(s.batchHooks, hook)
+}
+
+// StartBatch records a batch for the requested matches and predicts them in
+// the background, a few at a time
+func (s *Service) StartBatch(ctx context.Context, req BatchRequest) (*Batch, error) {
+	matchIDs, err := s.batchMatchIDs(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	batch := &Batch{
+		ID:            uuid.New().String(),
+		CompetitionID: req.CompetitionID,
+		Matchday:      req.Matchday,
+		Force:         req.Force,
+		Status:        BatchPending,
+		Total:         len(matchIDs),
+		CreatedAt:     now,
+	}
+	for _, matchID := range matchIDs {
+		batch.Matches = append(batch.Matches, BatchMatch{MatchID: matchID, Status: BatchPending, UpdatedAt: now})
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO prediction_batches (id, competition_id, matchday, force, status, total, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, batch.ID, sql.NullInt64{Int64: int64(req.CompetitionID), Valid: req.CompetitionID > 0},
+		sql.NullInt64{Int64: int64(req.Matchday), Valid: req.Matchday > 0}, batch.Force, batch.Status, batch.Total, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert batch: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO prediction_batch_matches (batch_id, match_id, position, status, updated_at)
+		SELECT $1, match_id, position, $3, $4
+		FROM unnest($2::int[]) WITH ORDINALITY AS m(match_id, position)
+	`, batch.ID, pq.Array(matchIDs), BatchPending, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert batch matches: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit batch: %w", err)
+	}
+
+	// The request's context is recycled once the response is sent, so the
+	// batch runs until it finishes or the service stops
+	s.background.Go(func() { s.runBatch(s.ctx, batch.ID, matchIDs, batch.Force) })
+
+	return batch, nil
+}
+
+// batchMatchIDs resolves the matches of a batch request: the given IDs, or the
+// scheduled matches of the competition's matchday in kickoff order
+func (s *Service) batchMatchIDs(ctx context.Context, req BatchRequest) ([]int, error) {
+	var rows *sql.Rows
+	var err error
+	if len(req.MatchIDs) > 0 {
+		rows, err = s.db.QueryContext(ctx, `SELECT id FROM matches WHERE id = ANY($1)`, pq.Array(req.MatchIDs))
+	} else {
+		rows, err = s.db.QueryContext(ctx, `
+			SELECT id
+			FROM matches
+			WHERE competition_id = $1 AND matchday = $2 AND status IN ('SCHEDULED', 'TIMED')
+			ORDER BY utc_date, id
+		`, req.CompetitionID, req.Matchday)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query batch matches: %w", err)
+	}
+	defer rows.Close()
+
+	var matchIDs []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan batch match: %w", err)
+		}
+		matchIDs = append(matchIDs, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate batch matches: %w", err)
+	}
+
+	return selectBatchMatches(req, matchIDs)
+}
+
+// selectBatchMatches checks the matches found for a batch request: requested
+// IDs are kept in their order without repeats and must all exist
+func selectBatchMatches(req BatchRequest, found []int) ([]int, error) {
+	matchIDs := found
+	if len(req.MatchIDs) > 0 {
+		matchIDs = nil
+		var unknown []int
+		for _, id := range req.MatchIDs {
+			switch {
+			case slices.Contains(matchIDs, id) || slices.Contains(unknown, id):
+			case slices.Contains(found, id):
+				matchIDs = append(matchIDs, id)
+			default:
+				unknown = append(unknown, id)
+			}
+		}
+		if len(unknown) > 0 {
+			return nil, fmt.Errorf("%w: unknown matches %v", ErrInvalidBatch, unknown)
+		}
+	}
+
+	switch {
+	case len(matchIDs) == 0:
+		return nil, fmt.Errorf("%w: no scheduled matches", ErrInvalidBatch)
+	case len(matchIDs) > maxBatchMatches:
+		return nil, fmt.Errorf("%w: more than %d matches", ErrInvalidBatch, maxBatchMatches)
+	}
+
+	return matchIDs, nil
+}
+
+// runBatch predicts the matches of a batch with bounded concurrency. When ctx
+// is cancelled the matches not yet predicted fail and the batch finishes
+func (s *Service) runBatch(ctx context.Context, batchID string, matchIDs []int, force bool) {
+	if _, err := s.db.ExecContext(ctx, `
+		UPDATE prediction_batches SET status = $2, updated_at = NOW() WHERE id = $1
+	`, batchID, BatchRunning); err != nil {
+		slog.Error("Failed to start batch", "batchId", batchID, "error", err)
+	}
+
+	heartbeatCtx, stopHeartbeat := context.WithCancel(ctx)
+	defer stopHeartbeat()
+	go s.batchHeartbeat(heartbeatCtx, batchID)
+
+	var g errgroup.Group
+	g.SetLimit(batchConcurrency)
+	for _, matchID := range matchIDs {
+		g.Go(func() error {
+			s.predictBatchMatch(ctx, batchID, matchID, force)
+			return nil
+		})
+	}
+	g.Wait()
+	stopHeartbeat()
+
+	// The outcome is recorded even when the service is stopping
+	ctx = context.WithoutCancel(ctx)
+	progress := BatchProgress{BatchID: batchID}
+	err := s.db.QueryRowContext(ctx, `
+		UPDATE prediction_batches
+		SET status = CASE WHEN failed = total THEN $2 ELSE $3 END, finished_at = NOW(), updated_at = NOW()
+		WHERE id = $1
+		RETURNING status, total, completed, failed
+	`, batchID, BatchFailed, BatchCompleted).Scan(&progress.Status, &progress.Total, &progress.Completed, &progress.Failed)
+	if err != nil {
+		slog.Error("Failed to finish batch", "batchId", batchID, "error", err)
+		return
+	}
+
+	slog.Info("Prediction batch finished", "batchId", batchID, "completed", progress.Completed, "failed", progress.Failed)
+	s.notifyBatchProgress(progress)
+}
+
+// batchHeartbeat records that a batch is still running until ctx is done, so
+// other instances do not take it for one a restart interrupted
+func (s *Service) batchHeartbeat(ctx context.Context, batchID string) {
+	ticker := time.NewTicker(batchHeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := s.db.ExecContext(ctx, `UPDATE prediction_batches SET updated_at = NOW() WHERE id = $1`, batchID); err != nil && ctx.Err() == nil {
+				slog.Warn("Failed to record batch heartbeat", "batchId", batchID, "error", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// predictBatchMatch predicts one match of a batch and records the outcome
+func (s *Service) predictBatchMatch(ctx context.Context, batchID string, matchID int, force bool) {
+	match := BatchMatch{MatchID: matchID, Status: BatchCompleted}
+
+	prediction, err := func() (*PredictionResult, error) {
+		p, err := s.matchPipeline(ctx, matchID)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.waitForPipelineSlot(ctx, p.llmCalls()); err != nil {
+			return nil, err
+		}
+		if _, err := s.db.ExecContext(ctx, `
+			UPDATE prediction_batch_matches SET status = $3, updated_at = NOW()
+			WHERE batch_id = $1 AND match_id = $2
+		`, batchID, matchID, BatchRunning); err != nil {
+			return nil, fmt.Errorf("failed to update batch match: %w", err)
+		}
+		return s.CreatePrediction(ctx, matchID, CreateOptions{Force: force})
+	}()
+	if err != nil {
+		slog.Warn("Batch prediction failed", "batchId", batchID, "matchId", matchID, "error", err)
+		match.Status = BatchFailed
+		match.Error = err.Error()
+	} else {
+		match.PredictionID = prediction.ID
+	}
+	match.UpdatedAt = time.Now()
+
+	// The outcome is recorded even when the service is stopping
+	ctx = context.WithoutCancel(ctx)
+	_, err = s.db.ExecContext(ctx, `
+		UPDATE prediction_batch_matches
+		SET status = $3, prediction_id = $4, error = $5, updated_at = $6
+		WHERE batch_id = $1 AND match_id = $2
+	`, batchID, matchID, match.Status, sql.NullString{String: match.PredictionID, Valid: match.PredictionID != ""},
+		sql.NullString{String: match.Error, Valid: match.Error != ""}, match.UpdatedAt)
+	if err != nil {
+		slog.Error("Failed to record batch match", "batchId", batchID, "matchId", matchID, "error", err)
+	}
+
+	progress := BatchProgress{BatchID: batchID, Match: &match}
+	completed, failed := batchMatchCounts(match.Status)
+	err = s.db.QueryRowContext(ctx, `
+		UPDATE prediction_batches
+		SET completed = completed + $2, failed = failed + $3, updated_at = NOW()
+		WHERE id = $1
+		RETURNING status, total, completed, failed
+	`, batchID, completed, failed).Scan(&progress.Status, &progress.Total, &progress.Completed, &progress.Failed)
+	if err != nil {
+		slog.Error("Failed to update batch progress", "batchId", batchID, "error", err)
+		return
+	}
+
+	s.notifyBatchProgress(progress)
+}
+
+// batchMatchCounts is what a finished batch match adds to its batch's
+// completed and failed counts
+func batchMatchCounts(status string) (completed, failed int) {
+	if status == BatchFailed {
+		return 0, 1
+	}
+	return 1, 0
+}
+
+// matchPipeline builds the pipeline that predicts a match
+func (s *Service) matchPipeline(ctx context.Context, matchID int) (*pipeline, error) {
+	var competitionCode string
+	err := s.db.QueryRowContext(ctx, `
+		SELECT COALESCE(c.code, '')
+		FROM matches m
+		LEFT JOIN competitions c ON c.id = m.competition_id
+		WHERE m.id = $1
+	`, matchID).Scan(&competitionCode)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("match not found")
+		}
+		return nil, fmt.Errorf("failed to get match competition: %w", err)
+	}
+
+	return s.pipelineFor(competitionCode)
+}
+
+// waitForPipelineSlot spaces out the starts of batch predictions across all
+// batches, by the LLM calls of the prediction started before
+func (s *Service) waitForPipelineSlot(ctx context.Context, llmCalls int) error {
+	start := s.reservePipelineSlot(time.Now(), llmCalls)
+
+	timer := time.NewTimer(time.Until(start))
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// reservePipelineSlot returns when a prediction making llmCalls LLM calls may
+// start, at now or once the previous prediction's spacing has passed, and
+// holds back the next one by its own calls
+func (s *Service) reservePipelineSlot(now time.Time, llmCalls int) time.Time {
+	s.pipelineMu.Lock()
+	defer s.pipelineMu.Unlock()
+
+	start := now
+	if s.nextPipelineStart.After(start) {
+		start = s.nextPipelineStart
+	}
+	s.nextPipelineStart = start.Add(time.Duration(llmCalls) * llmCallSpacing)
+	return start
+}
+
+// FailInterruptedBatches marks the pending or running batches whose last
+// heartbeat is older than staleBatchAge, left behind by a stopped instance,
+// and their unfinished matches, as failed. Batches other instances are still
+// running keep their heartbeat and are left alone
+func (s *Service) FailInterruptedBatches(ctx context.Context) (int, error) {
+	var n int
+	err := s.db.QueryRowContext(ctx, `
+		WITH stale AS (
+			UPDATE prediction_batches
+			SET failed = total - completed, status = CASE WHEN completed = 0 THEN $3 ELSE $4 END,
+			    finished_at = NOW(), updated_at = NOW()
+			WHERE status IN ($1, $2) AND updated_at < NOW() - $6 * INTERVAL '1 second'
+			RETURNING id
+		), stale_matches AS (
+			UPDATE prediction_batch_matches
+			SET status = $3, error = $5, updated_at = NOW()
+			WHERE status IN ($1, $2) AND batch_id IN (SELECT id FROM stale)
+		)
+		SELECT COUNT(*) FROM stale
+	`, BatchPending, BatchRunning, BatchFailed, BatchCompleted, interruptedBatchError,
+		int(staleBatchAge.Seconds())).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fail interrupted batches: %w", err)
+	}
+
+	return n, nil
+}
+
+// notifyBatchProgress runs the batch progress hooks
+func (s *Service) notifyBatchProgress(progress BatchProgress) {
+	for _, hook := range s.batchHooks {
+		hook(progress)
+	}
+}
+
+// GetBatch retrieves a batch with the status of each of its matches
+func (s *Service) GetBatch(ctx context.Context, id string) (*Batch, error) {
+	var batch Batch
+	var competitionID, matchday sql.NullInt64
+	var finishedAt sql.NullTime
+	err := s.db.QueryRowContext(ctx, `
+		SELECT id, competition_id, matchday, force, status, total, completed, failed, created_at, finished_at
+		FROM prediction_batches
+		WHERE id = $1
+	`, id).Scan(&batch.ID, &competitionID, &matchday, &batch.Force, &batch.Status, &batch.Total,
+		&batch.Completed, &batch.Failed, &batch.CreatedAt, &finishedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("batch not found")
+		}
+		return nil, fmt.Errorf("failed to get batch: %w", err)
+	}
+	batch.CompetitionID = int(competitionID.Int64)
+	batch.Matchday = int(matchday.Int64)
+	if finishedAt.Valid {
+		batch.FinishedAt = &finishedAt.Time
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT match_id, status, COALESCE(prediction_id::text, ''), COALESCE(error, ''), updated_at
+		FROM prediction_batch_matches
+		WHERE batch_id = $1
+		ORDER BY position
+	`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query batch matches: %w", err)
+	}
+	defer rows.Close()
+
+	batch.Matches = []BatchMatch{}
+	for rows.Next() {
+		var match BatchMatch
+		if err := rows.Scan(&match.MatchID, &match.Status, &match.PredictionID, &match.Error, &match.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan batch match: %w", err)
+		}
+		batch.Matches = append(batch.Matches, match)
+	}
+
+	return &batch, rows.Err()
+}
@@ -0,0 +1,139 @@
+package predictions
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestSelectBatchMatches(t *testing.T) {
+	t.Parallel()
+
+	tooMany := make([]int, maxBatchMatches+1)
+	for i := range tooMany {
+		tooMany[i] = i + 1
+	}
+
+	tests := []struct {
+		name    string
+		req     BatchRequest
+		found   []int
+		want    []int
+		wantErr bool
+	}{
+		{
+			name:  "matchday in kickoff order",
+			req:   BatchRequest{CompetitionID: 2021, Matchday: 30},
+			found: []int{7, 3, 5},
+			want:  []int{7, 3, 5},
+		},
+		{
+			name:  "requested order kept",
+			req:   BatchRequest{MatchIDs: []int{5, 3, 7}},
+			found: []int{3, 5, 7},
+			want:  []int{5, 3, 7},
+		},
+		{
+			name:  "repeats dropped",
+			req:   BatchRequest{MatchIDs: []int{5, 3, 5, 3}},
+			found: []int{3, 5},
+			want:  []int{5, 3},
+		},
+		{
+			name:    "unknown match",
+			req:     BatchRequest{MatchIDs: []int{5, 9}},
+			found:   []int{5},
+			wantErr: true,
+		},
+		{
+			name:    "no scheduled matches",
+			req:     BatchRequest{CompetitionID: 2021, Matchday: 38},
+			wantErr: true,
+		},
+		{
+			name:    "too many matches",
+			req:     BatchRequest{CompetitionID: 2021, Matchday: 1},
+			found:   tooMany,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := selectBatchMatches(tt.req, tt.found)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidBatch) {
+					t.Fatalf("error = %v, want ErrInvalidBatch", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("matches = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBatchMatchCounts(t *testing.T) {
+	t.Parallel()
+
+	statuses := []string{BatchCompleted, BatchFailed, BatchCompleted, BatchCompleted, BatchFailed}
+	var completed, failed int
+	for _, status := range statuses {
+		c, f := batchMatchCounts(status)
+		completed += c
+		failed += f
+	}
+
+	if completed != 3 || failed != 2 {
+		t.Errorf("completed, failed = %d, %d, want 3, 2", completed, failed)
+	}
+	if completed+failed != len(statuses) {
+		t.Errorf("counted %d matches, want %d", completed+failed, len(statuses))
+	}
+}
+
+func TestReservePipelineSlot(t *testing.T) {
+	t.Parallel()
+
+	s := &Service{}
+	now := time.Date(2025, 3, 15, 12, 0, 0, 0, time.UTC)
+
+	// The first prediction starts at once and holds back the next by its 6 calls
+	if got := s.reservePipelineSlot(now, 6); !got.Equal(now) {
+		t.Errorf("first start = %v, want %v", got, now)
+	}
+	second := now.Add(6 * llmCallSpacing)
+	if got := s.reservePipelineSlot(now.Add(time.Second), 2); !got.Equal(second) {
+		t.Errorf("second start = %v, want %v", got, second)
+	}
+	third := second.Add(2 * llmCallSpacing)
+	if got := s.reservePipelineSlot(now.Add(2*time.Second), 4); !got.Equal(third) {
+		t.Errorf("third start = %v, want %v", got, third)
+	}
+
+	// Once the spacing has passed, a prediction starts at once again
+	later := third.Add(time.Minute)
+	if got := s.reservePipelineSlot(later, 4); !got.Equal(later) {
+		t.Errorf("later start = %v, want %v", got, later)
+	}
+}
+
+func TestWaitForPipelineSlot_Cancelled(t *testing.T) {
+	t.Parallel()
+
+	s := &Service{nextPipelineStart: time.Now().Add(time.Hour)}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := s.waitForPipelineSlot(ctx, 1); !errors.Is(err, context.Canceled) {
+		t.Errorf("error = %v, want context.Canceled", err)
+	}
+}
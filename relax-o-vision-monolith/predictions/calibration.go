@@ -0,0 +1,588 @@
+package predictions
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+)
+
+// Calibration methods
+const (
+	CalibrationIsotonic    = "isotonic"
+	CalibrationPlatt       = "platt"
+	CalibrationTemperature = "temperature"
+	// CalibrationAuto fits every method and keeps the one with the lowest holdout Brier score
+	CalibrationAuto = "auto"
+)
+
+const (
+	// minCalibrationSamples is the fewest graded matches a calibrator is fitted on
+	minCalibrationSamples = 50
+	// calibrationHoldout is the share of the latest graded matches a fit is evaluated on
+	calibrationHoldout = 0.2
+	// calibrationLockClass namespaces the per-competition advisory locks that serialise versions
+	calibrationLockClass = 46
+	// minCalibratedProbability keeps calibrated probabilities and their logits finite
+	minCalibratedProbability = 1e-4
+)
+
+// outcomeNames are the 1X2 outcomes in the order calibrators index them
+var outcomeNames = [3]string{"home", "draw", "away"}
+
+// ErrInvalidCalibration is returned when a calibrator cannot be fitted
+var ErrInvalidCalibration = errors.New("invalid calibration")
+
+// Calibrator maps the aggregator's probabilities to calibrated ones. It is
+// fitted on the graded predictions of a competition, or of all competitions
+// when CompetitionID is zero
+type Calibrator struct {
+	ID            int64             `json:"id"`
+	CompetitionID int               `json:"competitionId"`
+	Version       int               `json:"version"`
+	Method        string            `json:"method"`
+	Params        CalibrationParams `json:"params"`
+	Samples       int               `json:"samples"` // graded matches fitted on
+	Holdout       int               `json:"holdout"` // latest graded matches evaluated on
+	// RawBrierScore and BrierScore are over the holdout, before and after calibration
+	RawBrierScore float64   `json:"rawBrierScore"`
+	BrierScore    float64   `json:"brierScore"`
+	Active        bool      `json:"active"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+// CalibrationParams are a fitted calibrator's parameters. Per-outcome
+// parameters are in home, draw, away order
+type CalibrationParams struct {
+	Temperature float64         `json:"temperature,omitempty"`
+	Platt       []PlattScaling  `json:"platt,omitempty"`
+	Isotonic    []IsotonicCurve `json:"isotonic,omitempty"`
+}
+
+// PlattScaling maps a probability p to sigmoid(A*logit(p) + B)
+type PlattScaling struct {
+	A float64 `json:"a"`
+	B float64 `json:"b"`
+}
+
+// IsotonicCurve is a non-decreasing map of probabilities, linear between its points
+type IsotonicCurve struct {
+	X []float64 `json:"x"`
+	Y []float64 `json:"y"`
+}
+
+// AppliedCalibration records the calibrator applied to a prediction and the
+// aggregator's raw values it replaced
+type AppliedCalibration struct {
+	CalibratorID   int64   `json:"calibratorId"`
+	CompetitionID  int     `json:"competitionId"`
+	Version        int     `json:"version"`
+	Method         string  `json:"method"`
+	RawHomeWinProb float64 `json:"rawHomeWinProb"`
+	RawDrawProb    float64 `json:"rawDrawProb"`
+	RawAwayWinProb float64 `json:"rawAwayWinProb"`
+	RawConfidence  float64 `json:"rawConfidence"`
+}
+
+// CalibrationRequest asks for a calibrator to be fitted
+type CalibrationRequest struct {
+	CompetitionID int    `json:"competitionId"` // zero for all competitions
+	Method        string `json:"method"`        // isotonic, platt, temperature or auto (the default)
+}
+
+// calibrationSample is a graded prediction's raw probabilities and the index of the actual outcome
+type calibrationSample struct {
+	probs  [3]float64
+	actual int
+}
+
+// Apply returns the calibrated probabilities, normalised to sum to one
+func (c *Calibrator) Apply(probs [3]float64) [3]float64 {
+	var calibrated [3]float64
+	switch c.Method {
+	case CalibrationTemperature:
+		for i, p := range probs {
+			calibrated[i] = math.Pow(clampProbability(p), 1/c.Params.Temperature)
+		}
+	case CalibrationPlatt:
+		for i, p := range probs {
+			calibrated[i] = c.Params.Platt[i].apply(p)
+		}
+	case CalibrationIsotonic:
+		for i, p := range probs {
+			calibrated[i] = c.Params.Isotonic[i].apply(p)
+		}
+	default:
+		return probs
+	}
+
+	total := 0.0
+	for i := range calibrated {
+		calibrated[i] = clampProbability(calibrated[i])
+		total += calibrated[i]
+	}
+	for i := range calibrated {
+		calibrated[i] /= total
+	}
+	return calibrated
+}
+
+// apply maps a probability through the Platt sigmoid
+func (p PlattScaling) apply(prob float64) float64 {
+	return sigmoid(p.A*logit(clampProbability(prob)) + p.B)
+}
+
+// apply maps a probability through the curve, flat beyond its ends
+func (c IsotonicCurve) apply(prob float64) float64 {
+	n := len(c.X)
+	switch {
+	case n == 0:
+		return prob
+	case prob <= c.X[0]:
+		return c.Y[0]
+	case prob >= c.X[n-1]:
+		return c.Y[n-1]
+	}
+
+	i, found := slices.BinarySearch(c.X, prob)
+	if found {
+		return c.Y[i]
+	}
+	t := (prob - c.X[i-1]) / (c.X[i] - c.X[i-1])
+	return c.Y[i-1] + t*(c.Y[i]-c.Y[i-1])
+}
+
+// FitCalibrator fits a new version of the competition's calibrator on its
+// graded predictions, evaluating it on the latest ones. It becomes the active
+// calibrator only if it lowers their Brier score
+func (s *Service) FitCalibrator(ctx context.Context, req CalibrationRequest) (*Calibrator, error) {
+	methods := []string{CalibrationIsotonic, CalibrationPlatt, CalibrationTemperature}
+	switch req.Method {
+	case "", CalibrationAuto:
+	case CalibrationIsotonic, CalibrationPlatt, CalibrationTemperature:
+		methods = []string{req.Method}
+	default:
+		return nil, fmt.Errorf("%w: method must be isotonic, platt, temperature or auto", ErrInvalidCalibration)
+	}
+
+	samples, err := s.calibrationSamples(ctx, req.CompetitionID)
+	if err != nil {
+		return nil, err
+	}
+	if len(samples) < minCalibrationSamples {
+		return nil, fmt.Errorf("%w: needs at least %d graded predictions, found %d",
+			ErrInvalidCalibration, minCalibrationSamples, len(samples))
+	}
+
+	best := fitBestCalibrator(req.CompetitionID, methods, samples)
+	if err := s.saveCalibrator(ctx, best); err != nil {
+		return nil, fmt.Errorf("failed to save calibrator: %w", err)
+	}
+
+	return best, nil
+}
+
+// fitBestCalibrator fits each method on all but the latest samples and keeps
+// the one with the lowest Brier score on those, active if it beats the raw one
+func fitBestCalibrator(competitionID int, methods []string, samples []calibrationSample) *Calibrator {
+	holdout := max(int(float64(len(samples))*calibrationHoldout), 1)
+	train, test := samples[:len(samples)-holdout], samples[len(samples)-holdout:]
+
+	var best *Calibrator
+	for _, method := range methods {
+		c := &Calibrator{
+			CompetitionID: competitionID,
+			Method:        method,
+			Params:        fitCalibration(method, train),
+			Samples:       len(train),
+			Holdout:       len(test),
+		}
+		c.RawBrierScore = meanBrier(test, func(p [3]float64) [3]float64 { return p })
+		c.BrierScore = meanBrier(test, c.Apply)
+		if best == nil || c.BrierScore < best.BrierScore {
+			best = c
+		}
+	}
+	best.Active = best.BrierScore < best.RawBrierScore
+	return best
+}
+
+// calibrationSamples returns the raw probabilities and results of the latest
+// graded prediction of each match, oldest match first
+func (s *Service) calibrationSamples(ctx context.Context, competitionID int) ([]calibrationSample, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT home, draw, away, actual_winner
+		FROM (
+			SELECT DISTINCT ON (o.match_id)
+			       COALESCE(o.raw_home_win_prob, o.home_win_prob) AS home,
+			       COALESCE(o.raw_draw_prob, o.draw_prob) AS draw,
+			       COALESCE(o.raw_away_win_prob, o.away_win_prob) AS away,
+			       o.actual_winner, m.utc_date
+			FROM prediction_outcomes o
+			JOIN predictions p ON p.id = o.prediction_id
+			JOIN matches m ON m.id = o.match_id
+			WHERE o.provider IS NULL AND o.agent_type IS NULL
+			  AND ($1 = 0 OR o.competition_id = $1)
+			ORDER BY o.match_id, p.revision DESC
+		) latest
+		ORDER BY utc_date
+	`, competitionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query graded predictions: %w", err)
+	}
+	defer rows.Close()
+
+	var samples []calibrationSample
+	for rows.Next() {
+		var sample calibrationSample
+		var actual string
+		if err := rows.Scan(&sample.probs[0], &sample.probs[1], &sample.probs[2], &actual); err != nil {
+			return nil, fmt.Errorf("failed to scan graded prediction: %w", err)
+		}
+		sample.actual = slices.Index(outcomeNames[:], actual)
+		if sample.actual < 0 {
+			continue
+		}
+		samples = append(samples, sample)
+	}
+
+	return samples, rows.Err()
+}
+
+// saveCalibrator stores the calibrator as the competition's next version,
+// replacing the active calibrator when it is active
+func (s *Service) saveCalibrator(ctx context.Context, c *Calibrator) error {
+	paramsJSON, err := json.Marshal(c.Params)
+	if err != nil {
+		return fmt.Errorf("failed to marshal calibration params: %w", err)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1, $2)`, calibrationLockClass, c.CompetitionID); err != nil {
+		return fmt.Errorf("failed to lock calibrators: %w", err)
+	}
+
+	if c.Active {
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE prediction_calibrators SET active = FALSE WHERE competition_id = $1 AND active
+		`, c.CompetitionID); err != nil {
+			return fmt.Errorf("failed to deactivate calibrator: %w", err)
+		}
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO prediction_calibrators (competition_id, version, method, params, samples, holdout,
+		                                    raw_brier_score, brier_score, active, created_at)
+		SELECT $1, COALESCE(MAX(version), 0) + 1, $2, $3, $4, $5, $6, $7, $8, NOW()
+		FROM prediction_calibrators
+		WHERE competition_id = $1
+		RETURNING id, version, created_at
+	`, c.CompetitionID, c.Method, paramsJSON, c.Samples, c.Holdout, c.RawBrierScore, c.BrierScore, c.Active,
+	).Scan(&c.ID, &c.Version, &c.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert calibrator: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// ActivateCalibrator makes a calibrator version its competition's active one,
+// e.g. to roll back to an earlier fit
+func (s *Service) ActivateCalibrator(ctx context.Context, id int64) (*Calibrator, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var competitionID int
+	err = tx.QueryRowContext(ctx, `SELECT competition_id FROM prediction_calibrators WHERE id = $1`, id).Scan(&competitionID)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("calibrator not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get calibrator: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1, $2)`, calibrationLockClass, competitionID); err != nil {
+		return nil, fmt.Errorf("failed to lock calibrators: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE prediction_calibrators SET active = (id = $2) WHERE competition_id = $1
+	`, competitionID, id); err != nil {
+		return nil, fmt.Errorf("failed to activate calibrator: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit calibrator: %w", err)
+	}
+
+	return s.getCalibrator(ctx, "id = $1", id)
+}
+
+// Calibrators lists every calibrator version, newest first within each competition
+func (s *Service) Calibrators(ctx context.Context) ([]Calibrator, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+calibratorColumns+`
+		FROM prediction_calibrators
+		ORDER BY competition_id, version DESC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query calibrators: %w", err)
+	}
+	defer rows.Close()
+
+	calibrators := []Calibrator{}
+	for rows.Next() {
+		c, err := scanCalibrator(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan calibrator: %w", err)
+		}
+		calibrators = append(calibrators, *c)
+	}
+
+	return calibrators, rows.Err()
+}
+
+// calibratorColumns are the prediction_calibrators columns read by scanCalibrator
+const calibratorColumns = `id, competition_id, version, method, params, samples, holdout,
+		       raw_brier_score, brier_score, active, created_at`
+
+// getCalibrator retrieves the calibrator matching the condition
+func (s *Service) getCalibrator(ctx context.Context, condition string, args ...any) (*Calibrator, error) {
+	c, err := scanCalibrator(s.db.QueryRowContext(ctx, `
+		SELECT `+calibratorColumns+`
+		FROM prediction_calibrators
+		WHERE `+condition, args...))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("calibrator not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get calibrator: %w", err)
+	}
+	return c, nil
+}
+
+// scanCalibrator scans a row of calibratorColumns
+func scanCalibrator(row rowScanner) (*Calibrator, error) {
+	var c Calibrator
+	var paramsJSON []byte
+	err := row.Scan(&c.ID, &c.CompetitionID, &c.Version, &c.Method, &paramsJSON, &c.Samples, &c.Holdout,
+		&c.RawBrierScore, &c.BrierScore, &c.Active, &c.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(paramsJSON, &c.Params); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal calibration params: %w", err)
+	}
+	return &c, nil
+}
+
+// calibrate replaces the prediction's probabilities with those of the
+// competition's active calibrator, or the all-competitions one, keeping the
+// raw values. Confidence becomes the calibrated probability of the pick
+func (s *Service) calibrate(ctx context.Context, competitionID int, p *PredictionResult) error {
+	c, err := scanCalibrator(s.db.QueryRowContext(ctx, `
+		SELECT `+calibratorColumns+`
+		FROM prediction_calibrators
+		WHERE active AND competition_id IN ($1, 0)
+		ORDER BY competition_id DESC
+		LIMIT 1
+	`, competitionID))
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get active calibrator: %w", err)
+	}
+
+	calibrated := c.Apply([3]float64{p.HomeWinProb, p.DrawProb, p.AwayWinProb})
+	p.Calibration = &AppliedCalibration{
+		CalibratorID:   c.ID,
+		CompetitionID:  c.CompetitionID,
+		Version:        c.Version,
+		Method:         c.Method,
+		RawHomeWinProb: p.HomeWinProb,
+		RawDrawProb:    p.DrawProb,
+		RawAwayWinProb: p.AwayWinProb,
+		RawConfidence:  p.Confidence,
+	}
+	p.HomeWinProb = round4(calibrated[0])
+	p.DrawProb = round4(calibrated[1])
+	p.AwayWinProb = round4(calibrated[2])
+	p.Confidence = max(p.HomeWinProb, p.DrawProb, p.AwayWinProb)
+	p.DerivedConfidence = derivedConfidence(calibrated, p.Dispersion)
+	return nil
+}
+
+// fitCalibration fits the method's parameters on the samples
+func fitCalibration(method string, samples []calibrationSample) CalibrationParams {
+	var params CalibrationParams
+	switch method {
+	case CalibrationTemperature:
+		params.Temperature = fitTemperature(samples)
+	case CalibrationPlatt:
+		for i := range outcomeNames {
+			params.Platt = append(params.Platt, fitPlatt(samples, i))
+		}
+	case CalibrationIsotonic:
+		for i := range outcomeNames {
+			params.Isotonic = append(params.Isotonic, fitIsotonic(samples, i))
+		}
+	}
+	return params
+}
+
+// fitTemperature finds the temperature minimising the log loss, by golden
+// section search over its logarithm
+func fitTemperature(samples []calibrationSample) float64 {
+	logLoss := func(logT float64) float64 {
+		c := &Calibrator{Method: CalibrationTemperature, Params: CalibrationParams{Temperature: math.Exp(logT)}}
+		loss := 0.0
+		for _, s := range samples {
+			loss -= math.Log(c.Apply(s.probs)[s.actual])
+		}
+		return loss
+	}
+
+	lo, hi := math.Log(0.2), math.Log(5.0)
+	ratio := (math.Sqrt(5) - 1) / 2
+	for range 60 {
+		a, b := hi-ratio*(hi-lo), lo+ratio*(hi-lo)
+		if logLoss(a) < logLoss(b) {
+			hi = b
+		} else {
+			lo = a
+		}
+	}
+	return math.Exp((lo + hi) / 2)
+}
+
+// fitPlatt fits a one-vs-rest logistic regression of the outcome on the logit
+// of its probability by Newton's method, with a little ridge for stability
+func fitPlatt(samples []calibrationSample, outcome int) PlattScaling {
+	const ridge = 1e-3
+	p := PlattScaling{A: 1}
+	for range 50 {
+		var gA, gB, hAA, hAB, hBB float64
+		for _, s := range samples {
+			x := logit(clampProbability(s.probs[outcome]))
+			q := sigmoid(p.A*x + p.B)
+			y := 0.0
+			if s.actual == outcome {
+				y = 1
+			}
+			w := q * (1 - q)
+			gA += (q - y) * x
+			gB += q - y
+			hAA += w * x * x
+			hAB += w * x
+			hBB += w
+		}
+		gA += ridge * (p.A - 1)
+		gB += ridge * p.B
+		hAA += ridge
+		hBB += ridge
+
+		det := hAA*hBB - hAB*hAB
+		if det <= 0 {
+			break
+		}
+		dA := (hBB*gA - hAB*gB) / det
+		dB := (hAA*gB - hAB*gA) / det
+		p.A -= dA
+		p.B -= dB
+		if math.Abs(dA) < 1e-8 && math.Abs(dB) < 1e-8 {
+			break
+		}
+	}
+	return p
+}
+
+// fitIsotonic fits a non-decreasing curve from the outcome's probability to
+// its observed frequency with the pool adjacent violators algorithm
+func fitIsotonic(samples []calibrationSample, outcome int) IsotonicCurve {
+	type block struct{ x, y, weight float64 }
+
+	sorted := slices.Clone(samples)
+	slices.SortFunc(sorted, func(a, b calibrationSample) int {
+		return cmp.Compare(a.probs[outcome], b.probs[outcome])
+	})
+
+	// Samples with the same probability are pooled first, so that the fit does
+	// not depend on their order
+	var points []block
+	for _, s := range sorted {
+		y := 0.0
+		if s.actual == outcome {
+			y = 1
+		}
+		if n := len(points); n > 0 && points[n-1].x == s.probs[outcome] {
+			last := &points[n-1]
+			last.y = (last.y*last.weight + y) / (last.weight + 1)
+			last.weight++
+			continue
+		}
+		points = append(points, block{x: s.probs[outcome], y: y, weight: 1})
+	}
+
+	var blocks []block
+	for _, point := range points {
+		blocks = append(blocks, point)
+		for n := len(blocks); n > 1 && blocks[n-2].y >= blocks[n-1].y; n = len(blocks) {
+			a, b := blocks[n-2], blocks[n-1]
+			weight := a.weight + b.weight
+			blocks[n-2] = block{
+				x:      (a.x*a.weight + b.x*b.weight) / weight,
+				y:      (a.y*a.weight + b.y*b.weight) / weight,
+				weight: weight,
+			}
+			blocks = blocks[:n-1]
+		}
+	}
+
+	var curve IsotonicCurve
+	for _, b := range blocks {
+		curve.X = append(curve.X, b.x)
+		curve.Y = append(curve.Y, b.y)
+	}
+	return curve
+}
+
+// meanBrier returns the mean Brier score of the samples' mapped probabilities
+func meanBrier(samples []calibrationSample, mapping func([3]float64) [3]float64) float64 {
+	if len(samples) == 0 {
+		return 0
+	}
+	total := 0.0
+	for _, s := range samples {
+		p := mapping(s.probs)
+		total += brierScore(p[0], p[1], p[2], outcomeNames[s.actual])
+	}
+	return total / float64(len(samples))
+}
+
+// clampProbability keeps a probability away from zero and one
+func clampProbability(p float64) float64 {
+	return min(max(p, minCalibratedProbability), 1-minCalibratedProbability)
+}
+
+// logit is the log odds of a probability
+func logit(p float64) float64 {
+	return math.Log(p / (1 - p))
+}
+
+// sigmoid is the probability of log odds
+func sigmoid(x float64) float64 {
+	return 1 / (1 + math.Exp(-x))
+}
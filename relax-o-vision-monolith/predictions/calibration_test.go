@@ -0,0 +1,335 @@
+package predictions
+
+import (
+	"math"
+	"testing"
+)
+
+// calibrationGroup is a number of samples with the same probabilities and
+// the count of each actual outcome among them
+type calibrationGroup struct {
+	probs  [3]float64
+	counts [3]int
+}
+
+// expandSamples expands groups into samples, repeating them in order
+func expandSamples(repeat int, groups ...calibrationGroup) []calibrationSample {
+	var samples []calibrationSample
+	for range repeat {
+		for _, g := range groups {
+			for actual, count := range g.counts {
+				for range count {
+					samples = append(samples, calibrationSample{probs: g.probs, actual: actual})
+				}
+			}
+		}
+	}
+	return samples
+}
+
+// calibratedGroups are probabilities that match the frequency of each outcome
+var calibratedGroups = []calibrationGroup{
+	{[3]float64{0.6, 0.25, 0.15}, [3]int{12, 5, 3}},
+	{[3]float64{0.45, 0.3, 0.25}, [3]int{9, 6, 5}},
+	{[3]float64{0.25, 0.3, 0.45}, [3]int{5, 6, 9}},
+	{[3]float64{0.15, 0.25, 0.6}, [3]int{3, 5, 12}},
+}
+
+// overconfidentGroups make the favourite far likelier than it turns out to be
+var overconfidentGroups = []calibrationGroup{
+	{[3]float64{0.8, 0.1, 0.1}, [3]int{5, 3, 2}},
+	{[3]float64{0.1, 0.1, 0.8}, [3]int{2, 3, 5}},
+}
+
+func TestFitIsotonic(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		samples []calibrationSample
+		// want maps probabilities to their calibrated value, when known
+		want map[float64]float64
+	}{
+		{
+			name:    "calibrated",
+			samples: expandSamples(1, calibratedGroups...),
+			want:    map[float64]float64{0.15: 0.15, 0.25: 0.25, 0.45: 0.45, 0.6: 0.6},
+		},
+		{
+			name:    "overconfident",
+			samples: expandSamples(1, overconfidentGroups...),
+			want:    map[float64]float64{0.1: 0.2, 0.8: 0.5},
+		},
+		{
+			// A higher probability with a lower frequency is pooled with the one before
+			name: "violator",
+			samples: expandSamples(1,
+				calibrationGroup{[3]float64{0.2, 0.4, 0.4}, [3]int{1, 2, 2}},
+				calibrationGroup{[3]float64{0.4, 0.3, 0.3}, [3]int{3, 1, 1}},
+				calibrationGroup{[3]float64{0.5, 0.25, 0.25}, [3]int{1, 2, 2}},
+				calibrationGroup{[3]float64{0.7, 0.15, 0.15}, [3]int{4, 0, 1}},
+			),
+			want: map[float64]float64{0.2: 0.2, 0.7: 0.8},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			curve := fitIsotonic(tt.samples, 0)
+			for i := 1; i < len(curve.X); i++ {
+				if curve.X[i] <= curve.X[i-1] || curve.Y[i] < curve.Y[i-1] {
+					t.Fatalf("curve is not increasing: %+v", curve)
+				}
+			}
+
+			previous := -1.0
+			for p := 0.0; p <= 1; p += 0.01 {
+				got := curve.apply(p)
+				if got < previous {
+					t.Fatalf("apply(%v) = %v, below apply of a lower probability %v", p, got, previous)
+				}
+				previous = got
+			}
+
+			for p, want := range tt.want {
+				if got := curve.apply(p); !almostEqual(got, want) {
+					t.Errorf("apply(%v) = %v, want %v", p, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestFitIsotonic_SampleOrder(t *testing.T) {
+	t.Parallel()
+
+	samples := expandSamples(1, calibratedGroups...)
+	reversed := make([]calibrationSample, len(samples))
+	for i, s := range samples {
+		reversed[len(samples)-1-i] = s
+	}
+
+	for outcome := range outcomeNames {
+		a, b := fitIsotonic(samples, outcome), fitIsotonic(reversed, outcome)
+		if len(a.X) != len(b.X) {
+			t.Fatalf("outcome %d: curves differ with sample order: %+v and %+v", outcome, a, b)
+		}
+		for i := range a.X {
+			if !almostEqual(a.X[i], b.X[i]) || !almostEqual(a.Y[i], b.Y[i]) {
+				t.Errorf("outcome %d: curves differ with sample order: %+v and %+v", outcome, a, b)
+			}
+		}
+	}
+}
+
+func TestFitPlatt(t *testing.T) {
+	t.Parallel()
+
+	t.Run("calibrated", func(t *testing.T) {
+		t.Parallel()
+
+		samples := expandSamples(1, calibratedGroups...)
+		for outcome := range outcomeNames {
+			p := fitPlatt(samples, outcome)
+			if math.Abs(p.A-1) > 1e-4 || math.Abs(p.B) > 1e-4 {
+				t.Errorf("outcome %d: fitPlatt() = %+v, want the identity A=1, B=0", outcome, p)
+			}
+		}
+	})
+
+	t.Run("overconfident", func(t *testing.T) {
+		t.Parallel()
+
+		p := fitPlatt(expandSamples(1, overconfidentGroups...), 0)
+		if p.A >= 1 {
+			t.Errorf("fitPlatt() = %+v, want A below 1 to soften the probabilities", p)
+		}
+		if got := p.apply(0.8); math.Abs(got-0.5) > 0.01 {
+			t.Errorf("apply(0.8) = %v, want about the observed 0.5", got)
+		}
+	})
+}
+
+func TestFitTemperature(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		samples []calibrationSample
+		check   func(t *testing.T, temperature float64)
+	}{
+		{
+			name:    "calibrated",
+			samples: expandSamples(1, calibratedGroups...),
+			check: func(t *testing.T, temperature float64) {
+				if math.Abs(temperature-1) > 1e-3 {
+					t.Errorf("temperature = %v, want 1", temperature)
+				}
+			},
+		},
+		{
+			name:    "overconfident",
+			samples: expandSamples(1, overconfidentGroups...),
+			check: func(t *testing.T, temperature float64) {
+				if temperature <= 1 {
+					t.Errorf("temperature = %v, want above 1", temperature)
+				}
+			},
+		},
+		{
+			name: "underconfident",
+			samples: expandSamples(1,
+				calibrationGroup{[3]float64{0.45, 0.3, 0.25}, [3]int{8, 1, 1}},
+				calibrationGroup{[3]float64{0.25, 0.3, 0.45}, [3]int{1, 1, 8}},
+			),
+			check: func(t *testing.T, temperature float64) {
+				if temperature >= 1 {
+					t.Errorf("temperature = %v, want below 1", temperature)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			tt.check(t, fitTemperature(tt.samples))
+		})
+	}
+}
+
+func TestCalibrator_Apply(t *testing.T) {
+	t.Parallel()
+
+	probs := [3]float64{0.5, 0.3, 0.2}
+
+	tests := []struct {
+		name       string
+		calibrator Calibrator
+		probs      [3]float64
+		want       [3]float64
+	}{
+		{
+			name:       "unknown method",
+			calibrator: Calibrator{Method: "none"},
+			probs:      probs,
+			want:       probs,
+		},
+		{
+			name:       "unit temperature",
+			calibrator: Calibrator{Method: CalibrationTemperature, Params: CalibrationParams{Temperature: 1}},
+			probs:      probs,
+			want:       probs,
+		},
+		{
+			name:       "temperature flattens",
+			calibrator: Calibrator{Method: CalibrationTemperature, Params: CalibrationParams{Temperature: 2}},
+			probs:      [3]float64{0.64, 0.2, 0.16},
+			want:       [3]float64{0.8 / 1.647214, 0.447214 / 1.647214, 0.4 / 1.647214},
+		},
+		{
+			name: "identity Platt",
+			calibrator: Calibrator{Method: CalibrationPlatt, Params: CalibrationParams{
+				Platt: []PlattScaling{{A: 1}, {A: 1}, {A: 1}},
+			}},
+			probs: probs,
+			want:  probs,
+		},
+		{
+			name: "isotonic",
+			calibrator: Calibrator{Method: CalibrationIsotonic, Params: CalibrationParams{
+				Isotonic: []IsotonicCurve{
+					{X: []float64{0.2, 0.6}, Y: []float64{0.3, 0.5}},
+					{X: []float64{0.3}, Y: []float64{0.3}},
+					{X: []float64{0.1, 0.3}, Y: []float64{0.1, 0.3}},
+				},
+			}},
+			probs: probs,
+			// 0.45 home between the curve's points, then normalised
+			want: [3]float64{0.45 / 0.95, 0.3 / 0.95, 0.2 / 0.95},
+		},
+		{
+			name:       "certain probabilities stay finite",
+			calibrator: Calibrator{Method: CalibrationTemperature, Params: CalibrationParams{Temperature: 0.5}},
+			probs:      [3]float64{1, 0, 0},
+			// Clamped away from zero before and after sharpening
+			want: [3]float64{1 - 2*minCalibratedProbability, minCalibratedProbability, minCalibratedProbability},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := tt.calibrator.Apply(tt.probs)
+			if sum := got[0] + got[1] + got[2]; !almostEqual(sum, 1) {
+				t.Errorf("Apply() sums to %v, want 1", sum)
+			}
+			for i := range got {
+				if !almostEqual(got[i], tt.want[i]) {
+					t.Errorf("Apply() = %v, want %v", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestFitBestCalibrator(t *testing.T) {
+	t.Parallel()
+
+	methods := []string{CalibrationIsotonic, CalibrationPlatt, CalibrationTemperature}
+
+	tests := []struct {
+		name       string
+		samples    []calibrationSample
+		wantActive bool
+	}{
+		{
+			name:       "holdout improves",
+			samples:    expandSamples(5, overconfidentGroups...),
+			wantActive: true,
+		},
+		{
+			// Fitted on overconfident predictions, evaluated on ones where the favourite always won
+			name: "holdout gets worse",
+			samples: append(expandSamples(4, overconfidentGroups...), expandSamples(1,
+				calibrationGroup{[3]float64{0.8, 0.1, 0.1}, [3]int{10, 0, 0}},
+				calibrationGroup{[3]float64{0.1, 0.1, 0.8}, [3]int{0, 0, 10}},
+			)...),
+			wantActive: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			c := fitBestCalibrator(7, methods, tt.samples)
+			if c.CompetitionID != 7 {
+				t.Errorf("competition = %d, want 7", c.CompetitionID)
+			}
+			if c.Samples != 80 || c.Holdout != 20 {
+				t.Errorf("fitted on %d and evaluated on %d samples, want 80 and 20", c.Samples, c.Holdout)
+			}
+			if c.Active != tt.wantActive {
+				t.Errorf("active = %v with Brier score %v against raw %v, want %v",
+					c.Active, c.BrierScore, c.RawBrierScore, tt.wantActive)
+			}
+			if c.Active != (c.BrierScore < c.RawBrierScore) {
+				t.Errorf("active = %v, but Brier score %v against raw %v", c.Active, c.BrierScore, c.RawBrierScore)
+			}
+
+			// The kept method has the lowest holdout Brier score
+			holdout := tt.samples[len(tt.samples)-c.Holdout:]
+			for _, method := range methods {
+				other := &Calibrator{Method: method, Params: fitCalibration(method, tt.samples[:c.Samples])}
+				if score := meanBrier(holdout, other.Apply); score < c.BrierScore-1e-12 {
+					t.Errorf("%s scores %v, below the kept %s's %v", method, score, c.Method, c.BrierScore)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package predictions
+
+import (
+	"context"
+	"fmt"
+)
+
+const (
+	// PipelineStandard runs the analysts once and aggregates their outputs
+	PipelineStandard = "standard"
+	// maxDebateRounds caps the revision rounds of the debate pipeline
+	maxDebateRounds = 5
+)
+
+// Debate records the rounds in which the analysts revised their outputs
+// after reading each other's, and the critic's challenge to the result
+type Debate struct {
+	Rounds   []DebateRound `json:"rounds"` // round 0 holds the opening analyses
+	Critique *AgentOutput  `json:"critique,omitempty"`
+}
+
+// DebateRound is the analysts' outputs after a round, and how far apart they are
+type DebateRound struct {
+	Round      int           `json:"round"`
+	Outputs    []AgentOutput `json:"outputs"`
+	Dispersion *Dispersion   `json:"dispersion,omitempty"`
+}
+
+// newDebateRound records the outputs of a round
+func newDebateRound(round int, outputs []AgentOutput) DebateRound {
+	forecasts := make([][3]float64, len(outputs))
+	for i, o := range outputs {
+		forecasts[i] = forecastOf(o)
+	}
+	return DebateRound{Round: round, Outputs: outputs, Dispersion: newDispersion(forecasts)}
+}
+
+// SetDebateRounds sets the revision rounds of the built-in pipeline, which
+// competitions without a configured pipeline run: n rounds switch it to the
+// debate pipeline, capped at maxDebateRounds, and 0 back to the standard one
+func (s *Service) SetDebateRounds(n int) {
+	s.debateRounds = min(max(n, 0), maxDebateRounds)
+}
+
+// Revise has the agent reconsider its output, at index own of outputs, after
+// reading the other analysts' latest outputs
+func (a *LLMAgent) Revise(ctx context.Context, analysis *MatchAnalysis, outputs []AgentOutput, own, round int) (*AgentOutput, error) {
+	others := make([]AgentOutput, 0, len(outputs)-1)
+	for i, o := range outputs {
+		if i != own {
+			others = append(others, o)
+		}
+	}
+
+	return a.ask(ctx, agentRequest{
+		analysis: analysis,
+		system:   "You are an expert football analyst debating a match prediction with other analysts.",
+		instructions: fmt.Sprintf(`You are the %s analyst on a panel predicting this match, in round %d of a debate.
+Read the other analysts' reasoning and probabilities. Revise yours where their arguments are
+convincing and keep them where you still disagree, and explain in your reasoning what changed and why.`,
+			a.agentType, round),
+		data: map[string]any{
+			"match":         analysis,
+			"yourAnalysis":  outputs[own],
+			"otherAnalyses": others,
+		},
+		temperature: 0.5,
+	})
+}
+
+// CriticAgent challenges the consensus the analysts reached in a debate
+type CriticAgent struct {
+	*LLMAgent
+}
+
+// NewCriticAgent creates a new critic agent
+func NewCriticAgent(apiKey string) *CriticAgent {
+	return &CriticAgent{
+		LLMAgent: NewLLMAgent(AgentTypeCritic, apiKey),
+	}
+}
+
+// Critique looks for weaknesses in the analysts' final outputs and gives the
+// probabilities the critic believes instead
+func (a *CriticAgent) Critique(ctx context.Context, analysis *MatchAnalysis, outputs []AgentOutput) (*AgentOutput, error) {
+	return a.ask(ctx, agentRequest{
+		analysis: analysis,
+		system:   "You are a sceptical football analyst who critiques other analysts' predictions.",
+		instructions: `A panel of analysts has debated this match. Challenge their consensus:
+look for overlooked factors, weak arguments, shared blind spots and overconfidence. Give the
+probabilities you believe are right, your critique as the reasoning and its main points as key factors.`,
+		data: map[string]any{
+			"match":    analysis,
+			"analyses": outputs,
+		},
+		temperature: 0.7,
+	})
+}
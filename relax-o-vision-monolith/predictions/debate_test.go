@@ -0,0 +1,303 @@
+package predictions
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/edd/relaxovisionmonolith/predictions/providers"
+)
+
+// recordingProvider answers with its result and records the prompt and data
+// of each question
+type recordingProvider struct {
+	result providers.AnalysisResult
+
+	mu      sync.Mutex
+	prompts []string
+	data    []any
+}
+
+func (p *recordingProvider) Name() string { return "fake" }
+
+func (p *recordingProvider) Analyze(ctx context.Context, prompt string, data interface{}) (*providers.AnalysisResult, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.prompts = append(p.prompts, prompt)
+	p.data = append(p.data, data)
+	result := p.result
+	return &result, nil
+}
+
+func (p *recordingProvider) GenerateEmbedding(ctx context.Context, text string) ([]float32, error) {
+	return nil, nil
+}
+
+// revision is what a debater read in a round
+type revision struct {
+	agent  string
+	round  int
+	own    AgentOutput
+	others []AgentOutput
+}
+
+// debateLog records the revisions of all debaters, which the workflow builds
+// afresh for each activity
+type debateLog struct {
+	mu        sync.Mutex
+	revisions []revision
+}
+
+// debater opens with its output from the table and in each round moves
+// halfway towards the mean of the others' outputs
+type debater struct {
+	name    string
+	opening map[string]AgentOutput
+	log     *debateLog
+}
+
+func (a *debater) Type() string  { return a.name }
+func (a *debater) Model() string { return "debater" }
+
+func (a *debater) Analyze(ctx context.Context, analysis *MatchAnalysis) (*AgentOutput, error) {
+	output, ok := a.opening[a.name]
+	if !ok {
+		return nil, fmt.Errorf("no output for %s", a.name)
+	}
+	output.AgentType = a.name
+	return &output, nil
+}
+
+func (a *debater) Revise(ctx context.Context, analysis *MatchAnalysis, outputs []AgentOutput, own, round int) (*AgentOutput, error) {
+	others := make([]AgentOutput, 0, len(outputs)-1)
+	var mean [3]float64
+	for i, o := range outputs {
+		if i == own {
+			continue
+		}
+		others = append(others, o)
+		mean[0] += o.HomeWinProb / float64(len(outputs)-1)
+		mean[1] += o.DrawProb / float64(len(outputs)-1)
+		mean[2] += o.AwayWinProb / float64(len(outputs)-1)
+	}
+
+	a.log.mu.Lock()
+	a.log.revisions = append(a.log.revisions, revision{agent: a.name, round: round, own: outputs[own], others: others})
+	a.log.mu.Unlock()
+
+	revised := outputs[own]
+	revised.AgentType = a.name
+	revised.HomeWinProb = (revised.HomeWinProb + mean[0]) / 2
+	revised.DrawProb = (revised.DrawProb + mean[1]) / 2
+	revised.AwayWinProb = (revised.AwayWinProb + mean[2]) / 2
+	return &revised, nil
+}
+
+func TestExecutePipeline_Debate(t *testing.T) {
+	t.Parallel()
+
+	opening := map[string]AgentOutput{
+		"first":  {HomeWinProb: 0.6, DrawProb: 0.2, AwayWinProb: 0.2, Confidence: 0.6},
+		"second": {HomeWinProb: 0.3, DrawProb: 0.3, AwayWinProb: 0.4, Confidence: 0.5},
+		"third":  {HomeWinProb: 0.2, DrawProb: 0.3, AwayWinProb: 0.5, Confidence: 0.4},
+	}
+	agents := []AgentConfig{{Type: "debater", Name: "first"}, {Type: "debater", Name: "second"}, {Type: "debater", Name: "third"}}
+
+	steps := map[string]func(s *Service, p *pipeline) pipelineSteps{
+		"local": func(s *Service, p *pipeline) pipelineSteps {
+			return &localSteps{ctx: context.Background(), pipeline: p, analysis: &MatchAnalysis{}}
+		},
+		"workflow": func(s *Service, p *pipeline) pipelineSteps {
+			return &workflowSteps{call: serviceActivities(s), input: PipelineInput{Pipeline: p.config}}
+		},
+	}
+
+	for _, rounds := range []int{1, 3} {
+		for stepsName, newSteps := range steps {
+			t.Run(fmt.Sprintf("%d rounds/%s", rounds, stepsName), func(t *testing.T) {
+				t.Parallel()
+
+				critic := &recordingProvider{result: providers.AnalysisResult{HomeWinProb: 0.4, DrawProb: 0.3, AwayWinProb: 0.3, Confidence: 0.5}}
+				log := &debateLog{}
+				s := NewService(nil, "key")
+				s.SetProviders([]providers.LLMProvider{critic})
+				s.Registry().RegisterAgent("debater", func(_ AgentDeps, cfg AgentConfig) (Agent, error) {
+					return &debater{name: cfg.name(), opening: opening, log: log}, nil
+				})
+
+				p, err := s.buildPipeline(PipelineConfig{
+					Name:         "debate",
+					Agents:       agents,
+					Aggregation:  AggregationMean,
+					DebateRounds: rounds,
+					Critic:       ModelConfig{Providers: []string{"fake"}},
+				})
+				if err != nil {
+					t.Fatalf("buildPipeline() error = %v", err)
+				}
+
+				run, err := executePipeline(p.config, newSteps(s, p))
+				if err != nil {
+					t.Fatalf("executePipeline() error = %v", err)
+				}
+
+				// The opening analyses, then one round per revision
+				if len(run.debate.Rounds) != rounds+1 {
+					t.Fatalf("got %d debate rounds, want %d", len(run.debate.Rounds), rounds+1)
+				}
+				if len(log.revisions) != rounds*len(agents) {
+					t.Errorf("got %d revisions, want %d", len(log.revisions), rounds*len(agents))
+				}
+
+				for i, round := range run.debate.Rounds {
+					if round.Round != i || len(round.Outputs) != len(agents) {
+						t.Fatalf("round %d = round %d with %d outputs, want %d", i, round.Round, len(round.Outputs), len(agents))
+					}
+
+					// Each round's dispersion is of that round's outputs, and narrows as the analysts converge
+					forecasts := make([][3]float64, len(round.Outputs))
+					for j, o := range round.Outputs {
+						forecasts[j] = forecastOf(o)
+					}
+					want := newDispersion(forecasts)
+					if round.Dispersion == nil || !almostEqual(round.Dispersion.HomeWin.StdDev, want.HomeWin.StdDev) ||
+						round.Dispersion.Disagreement != want.Disagreement {
+						t.Errorf("round %d dispersion = %+v, want %+v", i, round.Dispersion, want)
+					}
+					if i > 0 && round.Dispersion.Disagreement >= run.debate.Rounds[i-1].Dispersion.Disagreement {
+						t.Errorf("round %d disagreement %v, not below round %d's %v",
+							i, round.Dispersion.Disagreement, i-1, run.debate.Rounds[i-1].Dispersion.Disagreement)
+					}
+				}
+
+				// Each analyst revised its own output of the round before with the others' outputs of that round
+				for _, rev := range log.revisions {
+					previous := run.debate.Rounds[rev.round-1].Outputs
+					if rev.own.AgentType != rev.agent {
+						t.Errorf("%s round %d revised %s's output", rev.agent, rev.round, rev.own.AgentType)
+					}
+					if len(rev.others) != len(agents)-1 {
+						t.Fatalf("%s round %d read %d other outputs, want %d", rev.agent, rev.round, len(rev.others), len(agents)-1)
+					}
+					for _, o := range previous {
+						if o.AgentType == rev.agent {
+							if !almostEqual(rev.own.HomeWinProb, o.HomeWinProb) {
+								t.Errorf("%s round %d own home win = %v, want %v", rev.agent, rev.round, rev.own.HomeWinProb, o.HomeWinProb)
+							}
+							continue
+						}
+						found := false
+						for _, other := range rev.others {
+							if other.AgentType == o.AgentType {
+								found = true
+								if !almostEqual(other.HomeWinProb, o.HomeWinProb) {
+									t.Errorf("%s round %d read %s home win %v, want %v",
+										rev.agent, rev.round, o.AgentType, other.HomeWinProb, o.HomeWinProb)
+								}
+							}
+							if other.AgentType == rev.agent {
+								t.Errorf("%s round %d read its own output among the others", rev.agent, rev.round)
+							}
+						}
+						if !found {
+							t.Errorf("%s round %d did not read %s", rev.agent, rev.round, o.AgentType)
+						}
+					}
+				}
+
+				// The critic challenges the final round, which is also what the agents end on
+				final := run.debate.Rounds[rounds].Outputs
+				if len(critic.data) != 1 {
+					t.Fatalf("critic asked %d times, want once", len(critic.data))
+				}
+				data, _ := critic.data[0].(map[string]any)
+				seen, _ := data["analyses"].([]AgentOutput)
+				if len(seen) != len(final) {
+					t.Fatalf("critic saw %d analyses, want %d", len(seen), len(final))
+				}
+				for i := range final {
+					if seen[i].AgentType != final[i].AgentType || !almostEqual(seen[i].HomeWinProb, final[i].HomeWinProb) {
+						t.Errorf("critic saw %s at %v, want the final %s at %v",
+							seen[i].AgentType, seen[i].HomeWinProb, final[i].AgentType, final[i].HomeWinProb)
+					}
+					if !almostEqual(run.outputs[i].HomeWinProb, final[i].HomeWinProb) {
+						t.Errorf("output %d home win = %v, want the final round's %v", i, run.outputs[i].HomeWinProb, final[i].HomeWinProb)
+					}
+				}
+				if run.debate.Critique == nil || !almostEqual(run.debate.Critique.HomeWinProb, 0.4) {
+					t.Errorf("critique = %+v, want the critic's answer", run.debate.Critique)
+				}
+			})
+		}
+	}
+}
+
+func TestLLMAgent_Revise(t *testing.T) {
+	t.Parallel()
+
+	provider := &recordingProvider{result: providers.AnalysisResult{HomeWinProb: 0.45, DrawProb: 0.3, AwayWinProb: 0.25, Confidence: 0.6}}
+	agent := NewMultiProviderAgent(AgentTypeForm, []providers.LLMProvider{provider}, nil)
+	outputs := []AgentOutput{
+		{AgentType: AgentTypeStatistical, HomeWinProb: 0.5, DrawProb: 0.3, AwayWinProb: 0.2},
+		{AgentType: AgentTypeForm, HomeWinProb: 0.4, DrawProb: 0.3, AwayWinProb: 0.3},
+		{AgentType: AgentTypeHeadToHead, HomeWinProb: 0.3, DrawProb: 0.3, AwayWinProb: 0.4},
+	}
+
+	output, err := agent.Revise(context.Background(), &MatchAnalysis{MatchID: 1}, outputs, 1, 2)
+	if err != nil {
+		t.Fatalf("Revise() error = %v", err)
+	}
+	if !almostEqual(output.HomeWinProb, 0.45) {
+		t.Errorf("home win = %v, want the revised 0.45", output.HomeWinProb)
+	}
+
+	if len(provider.data) != 1 {
+		t.Fatalf("provider asked %d times, want once", len(provider.data))
+	}
+	if !strings.Contains(provider.prompts[0], "round 2") {
+		t.Errorf("prompt does not name the round: %q", provider.prompts[0])
+	}
+	data, _ := provider.data[0].(map[string]any)
+	if own, _ := data["yourAnalysis"].(AgentOutput); own.AgentType != AgentTypeForm {
+		t.Errorf("own analysis from %q, want %q", own.AgentType, AgentTypeForm)
+	}
+	others, _ := data["otherAnalyses"].([]AgentOutput)
+	if len(others) != 2 || others[0].AgentType != AgentTypeStatistical || others[1].AgentType != AgentTypeHeadToHead {
+		t.Errorf("other analyses = %+v, want the statistical and head-to-head ones", others)
+	}
+}
+
+func TestSetDebateRounds(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		rounds       int
+		wantRounds   int
+		wantPipeline string
+	}{
+		{rounds: -1, wantRounds: 0, wantPipeline: PipelineStandard},
+		{rounds: 0, wantRounds: 0, wantPipeline: PipelineStandard},
+		{rounds: 2, wantRounds: 2, wantPipeline: "debate-2"},
+		{rounds: maxDebateRounds, wantRounds: maxDebateRounds, wantPipeline: fmt.Sprintf("debate-%d", maxDebateRounds)},
+		{rounds: maxDebateRounds + 4, wantRounds: maxDebateRounds, wantPipeline: fmt.Sprintf("debate-%d", maxDebateRounds)},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.rounds), func(t *testing.T) {
+			t.Parallel()
+
+			s := NewService(nil, "key")
+			s.SetDebateRounds(tt.rounds)
+
+			cfg := s.pipelineConfig("PL")
+			if cfg.DebateRounds != tt.wantRounds || cfg.Name != tt.wantPipeline {
+				t.Errorf("pipeline = %s with %d rounds, want %s with %d", cfg.Name, cfg.DebateRounds, tt.wantPipeline, tt.wantRounds)
+			}
+			if _, err := s.buildPipeline(cfg); err != nil {
+				t.Errorf("buildPipeline() error = %v", err)
+			}
+		})
+	}
+}
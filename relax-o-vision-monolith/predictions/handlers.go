@@ -1,6 +1,7 @@
 package predictions
 
 import (
+	"errors"
 	"strconv"
 
 	"github.com/gofiber/fiber/v2"
@@ -20,7 +21,8 @@ func NewHandlers(service *Service) *Handlers {
 	}
 }
 
-// CreatePrediction handles POST /api/predictions
+// CreatePrediction handles POST /api/predictions. An Idempotency-Key header
+// makes retries return the same prediction, and force=true skips reuse
 func (h *Handlers) CreatePrediction(c *fiber.Ctx) error {
 	var req PredictionRequest
 	if err := c.BodyParser(&req); err != nil {
@@ -35,13 +37,26 @@ func (h *Handlers) CreatePrediction(c *fiber.Ctx) error {
 		})
 	}
 
-	prediction, err := h.service.CreatePrediction(c.Context(), req.MatchID)
+	opts := CreateOptions{
+		IdempotencyKey: c.Get("Idempotency-Key"),
+		Force:          req.Force || c.QueryBool("force"),
+	}
+
+	prediction, err := h.service.CreatePrediction(c.Context(), req.MatchID, opts)
+	if errors.Is(err, ErrIdempotencyKeyReused) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": err.Error(),
 		})
 	}
 
+	if prediction.Reused {
+		return c.JSON(prediction)
+	}
 	return c.Status(fiber.StatusCreated).JSON(prediction)
 }
 
@@ -81,12 +96,142 @@ func (h *Handlers) GetMatchPredictions(c *fiber.Ctx) error {
 		})
 	}
 
+	var current *PredictionResult
+	if len(predictions) > 0 {
+		current = &predictions[0]
+	}
+
 	return c.JSON(fiber.Map{
 		"predictions": predictions,
 		"count":       len(predictions),
+		"current":     current,
 	})
 }
 
+// CreateBatch handles POST /api/predictions/batch
+func (h *Handlers) CreateBatch(c *fiber.Ctx) error {
+	var req BatchRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if len(req.MatchIDs) == 0 && (req.CompetitionID <= 0 || req.Matchday <= 0) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Either matchIds or competitionId and matchday are required",
+		})
+	}
+
+	batch, err := h.service.StartBatch(c.Context(), req)
+	if errors.Is(err, ErrInvalidBatch) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(batch)
+}
+
+// GetBatch handles GET /api/predictions/batch/:id
+func (h *Handlers) GetBatch(c *fiber.Ctx) error {
+	batch, err := h.service.GetBatch(c.Context(), c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(batch)
+}
+
+// CreateHypothetical handles POST /api/predictions/hypothetical
+func (h *Handlers) CreateHypothetical(c *fiber.Ctx) error {
+	var req HypotheticalRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	prediction, err := h.service.CreateHypothetical(c.Context(), req)
+	if errors.Is(err, ErrInvalidHypothetical) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(prediction)
+}
+
+// GetHypothetical handles GET /api/predictions/hypothetical/:id
+func (h *Handlers) GetHypothetical(c *fiber.Ctx) error {
+	prediction, err := h.service.GetHypothetical(c.Context(), c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(prediction)
+}
+
+// GetCurrentPrediction handles GET /api/predictions/match/:matchId/current
+func (h *Handlers) GetCurrentPrediction(c *fiber.Ctx) error {
+	matchID, err := strconv.Atoi(c.Params("matchId"))
+	if err != nil || matchID <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid match ID",
+		})
+	}
+
+	prediction, err := h.service.GetCurrentPrediction(c.Context(), matchID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(prediction)
+}
+
+// DiffRevisions handles GET /api/predictions/match/:matchId/diff?from=1&to=2.
+// Without to it compares the current revision, and without from the one before
+func (h *Handlers) DiffRevisions(c *fiber.Ctx) error {
+	matchID, err := strconv.Atoi(c.Params("matchId"))
+	if err != nil || matchID <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid match ID",
+		})
+	}
+
+	from, to := c.QueryInt("from", 0), c.QueryInt("to", 0)
+	if from < 0 || to < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid revision",
+		})
+	}
+
+	diff, err := h.service.DiffRevisions(c.Context(), matchID, from, to)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(diff)
+}
+
 // GetAccuracyStats handles GET /api/predictions/accuracy
 func (h *Handlers) GetAccuracyStats(c *fiber.Ctx) error {
 	stats, err := h.accuracyService.GetOverallStats(c.Context())
@@ -133,3 +278,63 @@ func (h *Handlers) GetLeaderboard(c *fiber.Ctx) error {
 		"count":       len(leaderboard),
 	})
 }
+
+// FitCalibrator handles POST /api/predictions/calibration/fit
+func (h *Handlers) FitCalibrator(c *fiber.Ctx) error {
+	var req CalibrationRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+	}
+
+	calibrator, err := h.service.FitCalibrator(c.Context(), req)
+	if errors.Is(err, ErrInvalidCalibration) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(calibrator)
+}
+
+// GetCalibrators handles GET /api/predictions/calibration
+func (h *Handlers) GetCalibrators(c *fiber.Ctx) error {
+	calibrators, err := h.service.Calibrators(c.Context())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"calibrators": calibrators,
+		"count":       len(calibrators),
+	})
+}
+
+// ActivateCalibrator handles POST /api/predictions/calibration/:id/activate
+func (h *Handlers) ActivateCalibrator(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid calibrator ID",
+		})
+	}
+
+	calibrator, err := h.service.ActivateCalibrator(c.Context(), id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(calibrator)
+}
@@ -0,0 +1,342 @@
+package predictions
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/edd/relaxovisionmonolith/features"
+	"github.com/google/uuid"
+)
+
+// Venues of a hypothetical match, from the first team's side
+const (
+	VenueHome    = "home"
+	VenueAway    = "away"
+	VenueNeutral = "neutral"
+)
+
+// ErrInvalidHypothetical is returned when a hypothetical match cannot be set up
+var ErrInvalidHypothetical = errors.New("invalid hypothetical match")
+
+// HypotheticalRequest describes a match that is not scheduled between two teams
+type HypotheticalRequest struct {
+	TeamID     int    `json:"teamId"`
+	OpponentID int    `json:"opponentId"`
+	Venue      string `json:"venue"`          // home, away or neutral for teamId, neutral by default
+	Date       string `json:"date,omitempty"` // RFC 3339 or YYYY-MM-DD, now by default
+	// CompetitionID supplies league standings, when both teams play in it
+	CompetitionID int `json:"competitionId,omitempty"`
+}
+
+// HypotheticalPrediction is a prediction of a match that is not scheduled.
+// It is stored apart from match predictions and never graded
+type HypotheticalPrediction struct {
+	PredictionResult
+	HomeTeamID    int       `json:"homeTeamId"`
+	AwayTeamID    int       `json:"awayTeamId"`
+	Neutral       bool      `json:"neutral"`
+	CompetitionID int       `json:"competitionId,omitempty"`
+	MatchDate     time.Time `json:"matchDate"`
+}
+
+// CreateHypothetical runs the agents on a hypothetical match built from the
+// teams' stored history up to its date
+func (s *Service) CreateHypothetical(ctx context.Context, req HypotheticalRequest) (*HypotheticalPrediction, error) {
+	homeTeamID, awayTeamID, neutral, err := hypotheticalTeams(req)
+	if err != nil {
+		return nil, err
+	}
+
+	matchDate := time.Now()
+	if req.Date != "" {
+		if matchDate, err = parseMatchDate(req.Date); err != nil {
+			return nil, fmt.Errorf("%w: date must be RFC 3339 or YYYY-MM-DD", ErrInvalidHypothetical)
+		}
+	}
+
+	var teams int
+	err = s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM teams WHERE id IN ($1, $2)`, homeTeamID, awayTeamID).Scan(&teams)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check teams: %w", err)
+	}
+	if teams < 2 {
+		return nil, fmt.Errorf("%w: team not found", ErrInvalidHypothetical)
+	}
+
+	matchFeatures, err := s.features.Hypothetical(ctx, homeTeamID, awayTeamID, req.CompetitionID, matchDate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute hypothetical features: %w", err)
+	}
+
+	analysis := hypotheticalAnalysis(matchFeatures, neutral)
+
+	p, err := s.pipelineFor(matchFeatures.CompetitionCode)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.runAgents(ctx, analysis, p)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshotJSON, err := json.Marshal(analysis)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal input snapshot: %w", err)
+	}
+	result.ID = uuid.New().String()
+	result.Status = "completed"
+	if result.InputHash, err = hashAnalysis(analysis); err != nil {
+		return nil, err
+	}
+
+	if err := s.calibrate(ctx, req.CompetitionID, result); err != nil {
+		slog.Warn("Failed to calibrate hypothetical prediction, keeping raw probabilities", "error", err)
+	}
+
+	prediction := &HypotheticalPrediction{
+		PredictionResult: *result,
+		HomeTeamID:       homeTeamID,
+		AwayTeamID:       awayTeamID,
+		Neutral:          neutral,
+		CompetitionID:    req.CompetitionID,
+		MatchDate:        matchDate,
+	}
+
+	if err := s.saveHypothetical(ctx, prediction, snapshotJSON); err != nil {
+		return nil, fmt.Errorf("failed to save hypothetical prediction: %w", err)
+	}
+
+	return prediction, nil
+}
+
+// hypotheticalTeams orders the teams of a hypothetical match as home and
+// away, and reports whether it is played on neutral ground
+func hypotheticalTeams(req HypotheticalRequest) (homeTeamID, awayTeamID int, neutral bool, err error) {
+	if req.TeamID <= 0 || req.OpponentID <= 0 || req.TeamID == req.OpponentID {
+		return 0, 0, false, fmt.Errorf("%w: two different teams are required", ErrInvalidHypothetical)
+	}
+
+	homeTeamID, awayTeamID = req.TeamID, req.OpponentID
+	switch req.Venue {
+	case "", VenueNeutral, VenueHome:
+	case VenueAway:
+		homeTeamID, awayTeamID = awayTeamID, homeTeamID
+	default:
+		return 0, 0, false, fmt.Errorf("%w: venue must be home, away or neutral", ErrInvalidHypothetical)
+	}
+
+	return homeTeamID, awayTeamID, req.Venue == "" || req.Venue == VenueNeutral, nil
+}
+
+// hypotheticalAnalysis builds the analysis the agents see of a hypothetical
+// match. It has no match ID, so nothing of it can be graded
+func hypotheticalAnalysis(f *features.MatchFeatures, neutral bool) *MatchAnalysis {
+	analysis := newMatchAnalysis(f)
+	analysis.Metadata["hypothetical"] = true
+	if f.CompetitionID > 0 {
+		analysis.Metadata["competitionId"] = f.CompetitionID
+	}
+	if neutral {
+		analysis.Metadata["venue"] = "Neutral ground: neither team has home advantage"
+	}
+	return analysis
+}
+
+// parseMatchDate parses an RFC 3339 time or a date
+func parseMatchDate(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, value)
+}
+
+// saveHypothetical stores a hypothetical prediction
+func (s *Service) saveHypothetical(ctx context.Context, p *HypotheticalPrediction, snapshotJSON []byte) error {
+	reasoningJSON, err := json.Marshal(map[string]any{"text": p.Reasoning, "keyFactors": p.KeyFactors})
+	if err != nil {
+		return fmt.Errorf("failed to marshal reasoning: %w", err)
+	}
+
+	agentOutputsJSON, err := json.Marshal(p.AgentOutputs)
+	if err != nil {
+		return fmt.Errorf("failed to marshal agent outputs: %w", err)
+	}
+
+	modelsJSON, err := json.Marshal(p.Models)
+	if err != nil {
+		return fmt.Errorf("failed to marshal models: %w", err)
+	}
+
+	var calibrationJSON []byte
+	if p.Calibration != nil {
+		if calibrationJSON, err = json.Marshal(p.Calibration); err != nil {
+			return fmt.Errorf("failed to marshal calibration: %w", err)
+		}
+	}
+
+	var dispersionJSON []byte
+	if p.Dispersion != nil {
+		if dispersionJSON, err = json.Marshal(p.Dispersion); err != nil {
+			return fmt.Errorf("failed to marshal dispersion: %w", err)
+		}
+	}
+
+	var debateJSON []byte
+	if p.Debate != nil {
+		if debateJSON, err = json.Marshal(p.Debate); err != nil {
+			return fmt.Errorf("failed to marshal debate: %w", err)
+		}
+	}
+
+	var homeGoals, awayGoals sql.NullFloat64
+	var marketsJSON []byte
+	if p.ScoreDistribution != nil {
+		homeGoals = sql.NullFloat64{Float64: p.ScoreDistribution.HomeExpectedGoals, Valid: true}
+		awayGoals = sql.NullFloat64{Float64: p.ScoreDistribution.AwayExpectedGoals, Valid: true}
+		if marketsJSON, err = json.Marshal(p.Markets); err != nil {
+			return fmt.Errorf("failed to marshal markets: %w", err)
+		}
+	}
+
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO hypothetical_predictions (id, home_team_id, away_team_id, neutral, competition_id, match_date,
+		                                      home_win_prob, draw_prob, away_win_prob, confidence, reasoning, agent_outputs,
+		                                      home_expected_goals, away_expected_goals, markets,
+		                                      input_hash, input_snapshot, prompt_version, models, calibration,
+		                                      dispersion, derived_confidence, pipeline, llm_calls, debate, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23,
+		        $24, $25, $26)
+	`,
+		p.ID,
+		p.HomeTeamID,
+		p.AwayTeamID,
+		p.Neutral,
+		sql.NullInt64{Int64: int64(p.CompetitionID), Valid: p.CompetitionID > 0},
+		p.MatchDate,
+		p.HomeWinProb,
+		p.DrawProb,
+		p.AwayWinProb,
+		p.Confidence,
+		reasoningJSON,
+		agentOutputsJSON,
+		homeGoals,
+		awayGoals,
+		marketsJSON,
+		p.InputHash,
+		snapshotJSON,
+		p.PromptVersion,
+		modelsJSON,
+		calibrationJSON,
+		dispersionJSON,
+		p.DerivedConfidence,
+		p.Pipeline,
+		p.LLMCalls,
+		debateJSON,
+		p.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert hypothetical prediction: %w", err)
+	}
+
+	return nil
+}
+
+// GetHypothetical retrieves a hypothetical prediction by ID
+func (s *Service) GetHypothetical(ctx context.Context, id string) (*HypotheticalPrediction, error) {
+	var p HypotheticalPrediction
+	var competitionID sql.NullInt64
+	var reasoningJSON, agentOutputsJSON, marketsJSON, snapshotJSON, modelsJSON, calibrationJSON, dispersionJSON, debateJSON []byte
+	var homeGoals, awayGoals, derivedConfidence sql.NullFloat64
+	var llmCalls sql.NullInt64
+
+	err := s.db.QueryRowContext(ctx, `
+		SELECT id, home_team_id, away_team_id, neutral, competition_id, match_date,
+		       home_win_prob, draw_prob, away_win_prob, confidence, reasoning, agent_outputs,
+		       home_expected_goals, away_expected_goals, markets,
+		       input_hash, input_snapshot, prompt_version, models, calibration,
+		       dispersion, derived_confidence, pipeline, llm_calls, debate, created_at
+		FROM hypothetical_predictions
+		WHERE id = $1
+	`, id).Scan(
+		&p.ID,
+		&p.HomeTeamID,
+		&p.AwayTeamID,
+		&p.Neutral,
+		&competitionID,
+		&p.MatchDate,
+		&p.HomeWinProb,
+		&p.DrawProb,
+		&p.AwayWinProb,
+		&p.Confidence,
+		&reasoningJSON,
+		&agentOutputsJSON,
+		&homeGoals,
+		&awayGoals,
+		&marketsJSON,
+		&p.InputHash,
+		&snapshotJSON,
+		&p.PromptVersion,
+		&modelsJSON,
+		&calibrationJSON,
+		&dispersionJSON,
+		&derivedConfidence,
+		&p.Pipeline,
+		&llmCalls,
+		&debateJSON,
+		&p.CreatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("hypothetical prediction not found")
+		}
+		return nil, fmt.Errorf("failed to get hypothetical prediction: %w", err)
+	}
+
+	p.Status = "completed"
+	p.UpdatedAt = p.CreatedAt
+	p.CompetitionID = int(competitionID.Int64)
+	p.DerivedConfidence = derivedConfidence.Float64
+	p.LLMCalls = int(llmCalls.Int64)
+	p.setGoalMarkets(homeGoals, awayGoals, marketsJSON)
+
+	var reasoning struct {
+		Text       string   `json:"text"`
+		KeyFactors []string `json:"keyFactors"`
+	}
+	if err := json.Unmarshal(reasoningJSON, &reasoning); err == nil {
+		p.Reasoning, p.KeyFactors = reasoning.Text, reasoning.KeyFactors
+	}
+
+	if err := json.Unmarshal(agentOutputsJSON, &p.AgentOutputs); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal agent outputs: %w", err)
+	}
+	if err := json.Unmarshal(snapshotJSON, &p.InputSnapshot); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal input snapshot: %w", err)
+	}
+	if err := json.Unmarshal(modelsJSON, &p.Models); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal models: %w", err)
+	}
+	if len(calibrationJSON) > 0 {
+		if err := json.Unmarshal(calibrationJSON, &p.Calibration); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal calibration: %w", err)
+		}
+	}
+	if len(dispersionJSON) > 0 {
+		if err := json.Unmarshal(dispersionJSON, &p.Dispersion); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal dispersion: %w", err)
+		}
+	}
+	if len(debateJSON) > 0 {
+		if err := json.Unmarshal(debateJSON, &p.Debate); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal debate: %w", err)
+		}
+	}
+
+	return &p, nil
+}
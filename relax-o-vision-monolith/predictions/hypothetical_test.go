@@ -0,0 +1,127 @@
+package predictions
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/edd/relaxovisionmonolith/features"
+)
+
+func TestHypotheticalTeams(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		req         HypotheticalRequest
+		wantHome    int
+		wantAway    int
+		wantNeutral bool
+		wantErr     bool
+	}{
+		{name: "neutral by default", req: HypotheticalRequest{TeamID: 65, OpponentID: 86}, wantHome: 65, wantAway: 86, wantNeutral: true},
+		{name: "neutral", req: HypotheticalRequest{TeamID: 65, OpponentID: 86, Venue: VenueNeutral}, wantHome: 65, wantAway: 86, wantNeutral: true},
+		{name: "home", req: HypotheticalRequest{TeamID: 65, OpponentID: 86, Venue: VenueHome}, wantHome: 65, wantAway: 86},
+		{name: "away", req: HypotheticalRequest{TeamID: 65, OpponentID: 86, Venue: VenueAway}, wantHome: 86, wantAway: 65},
+		{name: "unknown venue", req: HypotheticalRequest{TeamID: 65, OpponentID: 86, Venue: "stadium"}, wantErr: true},
+		{name: "same team", req: HypotheticalRequest{TeamID: 65, OpponentID: 65}, wantErr: true},
+		{name: "missing opponent", req: HypotheticalRequest{TeamID: 65}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			home, away, neutral, err := hypotheticalTeams(tt.req)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidHypothetical) {
+					t.Errorf("error = %v, want ErrInvalidHypothetical", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if home != tt.wantHome || away != tt.wantAway || neutral != tt.wantNeutral {
+				t.Errorf("teams = %d v %d, neutral %v, want %d v %d, neutral %v",
+					home, away, neutral, tt.wantHome, tt.wantAway, tt.wantNeutral)
+			}
+		})
+	}
+}
+
+func TestHypotheticalAnalysis(t *testing.T) {
+	t.Parallel()
+
+	kickoff := time.Date(2025, 5, 1, 19, 0, 0, 0, time.UTC)
+	// Teams of different leagues that have never met
+	pairing := func(competitionID int) *features.MatchFeatures {
+		return &features.MatchFeatures{
+			Version:       features.Version,
+			CompetitionID: competitionID,
+			Kickoff:       kickoff,
+			Home:          features.TeamFeatures{TeamID: 65, Name: "Manchester City FC", Elo: 1950},
+			Away:          features.TeamFeatures{TeamID: 86, Name: "Real Madrid CF", Elo: 1980},
+		}
+	}
+
+	tests := []struct {
+		name              string
+		features          *features.MatchFeatures
+		neutral           bool
+		wantCompetitionID int
+	}{
+		{name: "neutral ground", features: pairing(0), neutral: true},
+		{name: "home and away", features: pairing(0)},
+		{name: "with a competition", features: pairing(2001), neutral: true, wantCompetitionID: 2001},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			analysis := hypotheticalAnalysis(tt.features, tt.neutral)
+
+			if analysis.MatchID != 0 {
+				t.Errorf("match ID = %d, want none", analysis.MatchID)
+			}
+			if analysis.HomeTeam.ID != 65 || analysis.HomeTeam.Name != "Manchester City FC" || analysis.HomeTeam.Elo != 1950 {
+				t.Errorf("home team = %+v, want Manchester City FC", analysis.HomeTeam)
+			}
+			if analysis.AwayTeam.ID != 86 || analysis.AwayTeam.Name != "Real Madrid CF" || analysis.AwayTeam.Elo != 1980 {
+				t.Errorf("away team = %+v, want Real Madrid CF", analysis.AwayTeam)
+			}
+			if !analysis.MatchDate.Equal(kickoff) {
+				t.Errorf("match date = %v, want %v", analysis.MatchDate, kickoff)
+			}
+			if len(analysis.HeadToHead) != 0 {
+				t.Errorf("head to head = %v, want none", analysis.HeadToHead)
+			}
+
+			if analysis.Metadata["hypothetical"] != true {
+				t.Errorf("hypothetical = %v, want true", analysis.Metadata["hypothetical"])
+			}
+			venue, ok := analysis.Metadata["venue"].(string)
+			if ok != tt.neutral || (ok && !strings.Contains(venue, "Neutral ground")) {
+				t.Errorf("venue = %q, want neutral ground %v", venue, tt.neutral)
+			}
+			competitionID, ok := analysis.Metadata["competitionId"]
+			if ok != (tt.wantCompetitionID > 0) || (ok && competitionID != tt.wantCompetitionID) {
+				t.Errorf("competitionId = %v, want %d", competitionID, tt.wantCompetitionID)
+			}
+		})
+	}
+}
+
+func TestCompletedPredictionsQuery_MatchPredictionsOnly(t *testing.T) {
+	t.Parallel()
+
+	// Hypothetical predictions are stored apart, so grading never picks them up
+	if strings.Contains(completedPredictionsQuery, "hypothetical_predictions") {
+		t.Error("grading query reads hypothetical predictions")
+	}
+	if !strings.Contains(completedPredictionsQuery, "FROM predictions p") {
+		t.Error("grading query does not read match predictions")
+	}
+}
@@ -0,0 +1,89 @@
+package predictions
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"maps"
+	"time"
+)
+
+const (
+	// predictionFreshness is how long a prediction is reused for unchanged inputs
+	predictionFreshness = 6 * time.Hour
+	// idempotencyKeyTTL is how long a request's idempotency key returns its prediction
+	idempotencyKeyTTL = 24 * time.Hour
+)
+
+// ErrIdempotencyKeyReused is returned when an idempotency key already belongs
+// to a request for another match
+var ErrIdempotencyKeyReused = errors.New("idempotency key was used for another match")
+
+// CreateOptions controls when CreatePrediction reuses an existing prediction
+type CreateOptions struct {
+	// IdempotencyKey makes retries of a request return the prediction it created
+	IdempotencyKey string
+	// Force runs the agents even when a fresh prediction has the same inputs
+	Force bool
+}
+
+// predictionForKey returns the prediction created for an idempotency key, or
+// nil if the key is new or has expired
+func (s *Service) predictionForKey(ctx context.Context, key string, matchID int) (*PredictionResult, error) {
+	var predictionID string
+	var keyMatchID int
+	err := s.db.QueryRowContext(ctx, `
+		SELECT k.prediction_id, p.match_id
+		FROM prediction_idempotency_keys k
+		JOIN predictions p ON p.id = k.prediction_id
+		WHERE k.key = $1 AND k.created_at > $2
+	`, key, time.Now().Add(-idempotencyKeyTTL)).Scan(&predictionID, &keyMatchID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up idempotency key: %w", err)
+	}
+	if keyMatchID != matchID {
+		return nil, ErrIdempotencyKeyReused
+	}
+
+	prediction, err := s.GetPrediction(ctx, predictionID)
+	if err != nil {
+		return nil, err
+	}
+	prediction.Reused = true
+	return prediction, nil
+}
+
+// saveIdempotencyKey records the prediction returned for an idempotency key
+func (s *Service) saveIdempotencyKey(ctx context.Context, key, predictionID string) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO prediction_idempotency_keys (key, prediction_id, created_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (key) DO UPDATE SET prediction_id = EXCLUDED.prediction_id, created_at = EXCLUDED.created_at
+	`, key, predictionID)
+	if err != nil {
+		return fmt.Errorf("failed to save idempotency key: %w", err)
+	}
+	return nil
+}
+
+// reusablePrediction returns the match's current prediction if it was made
+// within the freshness window from the same inputs, prompts and pipeline
+func (s *Service) reusablePrediction(ctx context.Context, matchID int, inputHash string, p *pipeline) (*PredictionResult, error) {
+	latest, err := s.GetLatestPredictions(ctx, []int{matchID})
+	if err != nil {
+		return nil, err
+	}
+
+	current, ok := latest[matchID]
+	if !ok || current.InputHash != inputHash || current.PromptVersion != PromptVersion || current.Pipeline != p.config.Name ||
+		!maps.Equal(current.Models, p.models()) || time.Since(current.CreatedAt) > predictionFreshness {
+		return nil, nil
+	}
+
+	current.Reused = true
+	return current, nil
+}
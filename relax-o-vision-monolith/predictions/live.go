@@ -0,0 +1,297 @@
+package predictions
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"time"
+)
+
+const (
+	// matchMinutes is the length of a match in regular time
+	matchMinutes = 90
+	// halfTimeMinutes is the length of the half-time break, used to estimate the minute from kickoff
+	halfTimeMinutes = 15
+	// liveLookback is how long after kickoff a finished match still gets its final timeline point
+	liveLookback = 4 * time.Hour
+)
+
+// LiveScore is the score of a match in play at a minute
+type LiveScore struct {
+	MatchID   int
+	HomeScore int
+	AwayScore int
+	Minute    int
+	Status    string
+}
+
+// LiveProbability is a point on a match's in-play probability timeline
+type LiveProbability struct {
+	MatchID      int     `json:"matchId"`
+	PredictionID string  `json:"predictionId,omitempty"`
+	Minute       int     `json:"minute"`
+	HomeScore    int     `json:"homeScore"`
+	AwayScore    int     `json:"awayScore"`
+	Status       string  `json:"status"`
+	HomeWinProb  float64 `json:"homeWinProb"`
+	DrawProb     float64 `json:"drawProb"`
+	AwayWinProb  float64 `json:"awayWinProb"`
+	// HomeRemainingGoals and AwayRemainingGoals are the goals each side is still expected to score
+	HomeRemainingGoals float64   `json:"homeRemainingGoals"`
+	AwayRemainingGoals float64   `json:"awayRemainingGoals"`
+	CreatedAt          time.Time `json:"createdAt"`
+}
+
+// ProbabilityTimeline is a match's pre-match prediction followed by its in-play probabilities
+type ProbabilityTimeline struct {
+	MatchID  int               `json:"matchId"`
+	PreMatch *LiveProbability  `json:"preMatch"`
+	Points   []LiveProbability `json:"points"` // by minute
+}
+
+// LiveProbabilityHook is called with each new point on a match's timeline
+type LiveProbabilityHook func(point LiveProbability)
+
+// OnLiveProbability registers a hook to run as in-play probabilities change
+func (s *Service) OnLiveProbability(hook LiveProbabilityHook) {
+	s.liveHooks = append(s.liveHooks, hook)
+}
+
+// UpdateLive computes the match's outcome probabilities from its current
+// prediction and live score, and records them on its timeline
+func (s *Service) UpdateLive(ctx context.Context, score LiveScore) (*LiveProbability, error) {
+	prediction, err := s.GetCurrentPrediction(ctx, score.MatchID)
+	if err != nil {
+		return nil, err
+	}
+
+	homeGoals, awayGoals := prematchExpectedGoals(prediction)
+	point := liveProbability(score, homeGoals, awayGoals)
+	point.PredictionID = prediction.ID
+
+	// Syncs repeat the same minute and score; only changes are recorded and pushed
+	result, err := s.db.ExecContext(ctx, `
+		INSERT INTO match_probability_timeline (match_id, prediction_id, minute, home_score, away_score, status,
+		                                        home_win_prob, draw_prob, away_win_prob,
+		                                        home_remaining_goals, away_remaining_goals, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		ON CONFLICT (match_id, minute, home_score, away_score, status) DO NOTHING
+	`, point.MatchID, point.PredictionID, point.Minute, point.HomeScore, point.AwayScore, point.Status,
+		point.HomeWinProb, point.DrawProb, point.AwayWinProb, point.HomeRemainingGoals, point.AwayRemainingGoals,
+		point.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save live probability: %w", err)
+	}
+
+	if inserted, err := result.RowsAffected(); err == nil && inserted > 0 {
+		for _, hook := range s.liveHooks {
+			hook(*point)
+		}
+	}
+
+	return point, nil
+}
+
+// UpdateLiveMatches updates the probabilities of the competition's matches in
+// play, and of those that finished recently. The minute is estimated from kickoff
+func (s *Service) UpdateLiveMatches(ctx context.Context, competitionID int) error {
+	now := time.Now()
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT m.id, m.utc_date, m.status, COALESCE(m.home_score_ft, 0), COALESCE(m.away_score_ft, 0)
+		FROM matches m
+		WHERE m.competition_id = $1
+		  AND (m.status IN ('IN_PLAY', 'PAUSED') OR (m.status = 'FINISHED' AND m.utc_date > $2))
+		  AND EXISTS (SELECT 1 FROM predictions p WHERE p.match_id = m.id AND p.status = 'completed')
+		ORDER BY m.utc_date, m.id
+	`, competitionID, now.Add(-liveLookback))
+	if err != nil {
+		return fmt.Errorf("failed to query live matches: %w", err)
+	}
+	defer rows.Close()
+
+	var scores []LiveScore
+	for rows.Next() {
+		var score LiveScore
+		var kickoff time.Time
+		if err := rows.Scan(&score.MatchID, &kickoff, &score.Status, &score.HomeScore, &score.AwayScore); err != nil {
+			return fmt.Errorf("failed to scan live match: %w", err)
+		}
+		score.Minute = estimateMinute(kickoff, now, score.Status)
+		scores = append(scores, score)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read live matches: %w", err)
+	}
+
+	for _, score := range scores {
+		if _, err := s.UpdateLive(ctx, score); err != nil {
+			slog.Warn("Failed to update live probabilities", "matchId", score.MatchID, "error", err)
+		}
+	}
+
+	return nil
+}
+
+// GetProbabilityTimeline returns the match's current prediction and its
+// recorded in-play probabilities
+func (s *Service) GetProbabilityTimeline(ctx context.Context, matchID int) (*ProbabilityTimeline, error) {
+	prediction, err := s.GetCurrentPrediction(ctx, matchID)
+	if err != nil {
+		return nil, err
+	}
+
+	homeGoals, awayGoals := prematchExpectedGoals(prediction)
+	timeline := &ProbabilityTimeline{
+		MatchID: matchID,
+		PreMatch: &LiveProbability{
+			MatchID:            matchID,
+			PredictionID:       prediction.ID,
+			Status:             "SCHEDULED",
+			HomeWinProb:        prediction.HomeWinProb,
+			DrawProb:           prediction.DrawProb,
+			AwayWinProb:        prediction.AwayWinProb,
+			HomeRemainingGoals: homeGoals,
+			AwayRemainingGoals: awayGoals,
+			CreatedAt:          prediction.CreatedAt,
+		},
+		Points: []LiveProbability{},
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT match_id, COALESCE(prediction_id::text, ''), minute, home_score, away_score, status,
+		       home_win_prob, draw_prob, away_win_prob, home_remaining_goals, away_remaining_goals, created_at
+		FROM match_probability_timeline
+		WHERE match_id = $1
+		ORDER BY minute, created_at, id
+	`, matchID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query probability timeline: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var p LiveProbability
+		err := rows.Scan(&p.MatchID, &p.PredictionID, &p.Minute, &p.HomeScore, &p.AwayScore, &p.Status,
+			&p.HomeWinProb, &p.DrawProb, &p.AwayWinProb, &p.HomeRemainingGoals, &p.AwayRemainingGoals, &p.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan probability timeline: %w", err)
+		}
+		timeline.Points = append(timeline.Points, p)
+	}
+
+	return timeline, rows.Err()
+}
+
+// liveProbability conditions the pre-match expected goals on the score: the
+// goals still to come are Poisson with the expected goals of the time remaining
+func liveProbability(score LiveScore, homeGoals, awayGoals float64) *LiveProbability {
+	minute := min(max(score.Minute, 0), matchMinutes)
+	if score.Status == "FINISHED" {
+		minute = matchMinutes
+	}
+	remaining := float64(matchMinutes-minute) / matchMinutes
+
+	point := &LiveProbability{
+		MatchID:            score.MatchID,
+		Minute:             minute,
+		HomeScore:          score.HomeScore,
+		AwayScore:          score.AwayScore,
+		Status:             score.Status,
+		HomeRemainingGoals: round4(homeGoals * remaining),
+		AwayRemainingGoals: round4(awayGoals * remaining),
+		CreatedAt:          time.Now(),
+	}
+
+	homeWin, draw, awayWin := outcomeProbabilities(homeGoals*remaining, awayGoals*remaining, score.HomeScore-score.AwayScore)
+	point.HomeWinProb = round4(homeWin)
+	point.DrawProb = round4(draw)
+	point.AwayWinProb = round4(awayWin)
+	return point
+}
+
+// outcomeProbabilities returns the final outcome probabilities when the home
+// side leads by lead goals and each side scores Poisson goals from here
+func outcomeProbabilities(homeGoals, awayGoals float64, lead int) (homeWin, draw, awayWin float64) {
+	home := poissonProbabilities(homeGoals)
+	away := poissonProbabilities(awayGoals)
+
+	total := 0.0
+	for h := range home {
+		for a := range away {
+			p := home[h] * away[a]
+			total += p
+			switch diff := lead + h - a; {
+			case diff > 0:
+				homeWin += p
+			case diff < 0:
+				awayWin += p
+			default:
+				draw += p
+			}
+		}
+	}
+
+	return homeWin / total, draw / total, awayWin / total
+}
+
+// prematchExpectedGoals returns the prediction's expected goals, or those
+// implied by its outcome probabilities when the agents gave none
+func prematchExpectedGoals(p *PredictionResult) (float64, float64) {
+	if p.ScoreDistribution != nil {
+		return p.ScoreDistribution.HomeExpectedGoals, p.ScoreDistribution.AwayExpectedGoals
+	}
+	return impliedExpectedGoals(p.HomeWinProb, p.DrawProb, p.AwayWinProb)
+}
+
+// impliedExpectedGoals finds the Poisson expected goals whose outcome
+// probabilities are closest to the given ones, by a coarse then a fine grid search
+func impliedExpectedGoals(homeWin, draw, awayWin float64) (float64, float64) {
+	loss := func(homeGoals, awayGoals float64) float64 {
+		h, d, a := outcomeProbabilities(homeGoals, awayGoals, 0)
+		return (h-homeWin)*(h-homeWin) + (d-draw)*(d-draw) + (a-awayWin)*(a-awayWin)
+	}
+
+	bestHome, bestAway, best := 1.0, 1.0, math.Inf(1)
+	search := func(homeFrom, awayFrom, step float64, steps int) {
+		for i := range steps + 1 {
+			for j := range steps + 1 {
+				homeGoals, awayGoals := homeFrom+float64(i)*step, awayFrom+float64(j)*step
+				if homeGoals <= 0 || awayGoals <= 0 {
+					continue
+				}
+				if l := loss(homeGoals, awayGoals); l < best {
+					bestHome, bestAway, best = homeGoals, awayGoals, l
+				}
+			}
+		}
+	}
+
+	search(0.1, 0.1, 0.1, 40)
+	search(bestHome-0.1, bestAway-0.1, 0.01, 20)
+	return round4(bestHome), round4(bestAway)
+}
+
+// estimateMinute estimates the minute of a match from its kickoff, allowing
+// for the half-time break
+func estimateMinute(kickoff, now time.Time, status string) int {
+	elapsed := int(now.Sub(kickoff) / time.Minute)
+	switch {
+	case status == "FINISHED":
+		return matchMinutes
+	case status == "PAUSED":
+		return matchMinutes / 2
+	case elapsed < matchMinutes/2:
+		return max(elapsed+1, 1)
+	case elapsed < matchMinutes/2+halfTimeMinutes:
+		// Stoppage time at the end of the first half
+		return matchMinutes / 2
+	default:
+		return min(elapsed-halfTimeMinutes+1, matchMinutes)
+	}
+}
+
+// round4 rounds a probability to the four decimals it is stored with
+func round4(v float64) float64 {
+	return math.Round(v*1e4) / 1e4
+}
@@ -0,0 +1,171 @@
+package predictions
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestLiveProbability(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		score LiveScore
+		check func(t *testing.T, p *LiveProbability)
+	}{
+		{
+			name:  "kickoff keeps the pre-match probabilities",
+			score: LiveScore{Minute: 0, Status: "IN_PLAY"},
+			check: func(t *testing.T, p *LiveProbability) {
+				homeWin, draw, awayWin := outcomeProbabilities(1.5, 1.1, 0)
+				if p.HomeWinProb != round4(homeWin) || p.DrawProb != round4(draw) || p.AwayWinProb != round4(awayWin) {
+					t.Errorf("probabilities = %v/%v/%v, want %v/%v/%v", p.HomeWinProb, p.DrawProb, p.AwayWinProb,
+						round4(homeWin), round4(draw), round4(awayWin))
+				}
+				if p.HomeRemainingGoals != 1.5 || p.AwayRemainingGoals != 1.1 {
+					t.Errorf("remaining goals = %v/%v, want 1.5/1.1", p.HomeRemainingGoals, p.AwayRemainingGoals)
+				}
+			},
+		},
+		{
+			name:  "home lead late on",
+			score: LiveScore{HomeScore: 1, AwayScore: 0, Minute: 80, Status: "IN_PLAY"},
+			check: func(t *testing.T, p *LiveProbability) {
+				if p.HomeWinProb <= 0.8 {
+					t.Errorf("home win = %v, want above 0.8", p.HomeWinProb)
+				}
+				if p.DrawProb <= p.AwayWinProb {
+					t.Errorf("draw = %v, want above away win %v", p.DrawProb, p.AwayWinProb)
+				}
+			},
+		},
+		{
+			name:  "full time collapses to the result",
+			score: LiveScore{HomeScore: 0, AwayScore: 2, Minute: 90, Status: "IN_PLAY"},
+			check: func(t *testing.T, p *LiveProbability) {
+				if p.HomeWinProb != 0 || p.DrawProb != 0 || p.AwayWinProb != 1 {
+					t.Errorf("probabilities = %v/%v/%v, want 0/0/1", p.HomeWinProb, p.DrawProb, p.AwayWinProb)
+				}
+				if p.HomeRemainingGoals != 0 || p.AwayRemainingGoals != 0 {
+					t.Errorf("remaining goals = %v/%v, want none", p.HomeRemainingGoals, p.AwayRemainingGoals)
+				}
+			},
+		},
+		{
+			name:  "finished before the estimated minute",
+			score: LiveScore{HomeScore: 1, AwayScore: 1, Minute: 70, Status: "FINISHED"},
+			check: func(t *testing.T, p *LiveProbability) {
+				if p.Minute != matchMinutes {
+					t.Errorf("minute = %d, want %d", p.Minute, matchMinutes)
+				}
+				if p.DrawProb != 1 {
+					t.Errorf("draw = %v, want 1", p.DrawProb)
+				}
+			},
+		},
+		{
+			name:  "stoppage time is capped",
+			score: LiveScore{HomeScore: 2, AwayScore: 1, Minute: 94, Status: "IN_PLAY"},
+			check: func(t *testing.T, p *LiveProbability) {
+				if p.Minute != matchMinutes || p.HomeWinProb != 1 {
+					t.Errorf("minute %d home win %v, want %d and 1", p.Minute, p.HomeWinProb, matchMinutes)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			p := liveProbability(tt.score, 1.5, 1.1)
+			if sum := p.HomeWinProb + p.DrawProb + p.AwayWinProb; math.Abs(sum-1) > 2e-4 {
+				t.Errorf("probabilities sum to %v, want 1", sum)
+			}
+			tt.check(t, p)
+		})
+	}
+}
+
+func TestOutcomeProbabilities(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name                         string
+		homeGoals, awayGoals         float64
+		lead                         int
+		wantHome, wantDraw, wantAway float64
+	}{
+		{"no goals to come, level", 0, 0, 0, 0, 1, 0},
+		{"no goals to come, home ahead", 0, 0, 1, 1, 0, 0},
+		{"no goals to come, away ahead", 0, 0, -2, 0, 0, 1},
+		// Matches the 1X2 sums of the score distribution with the same expected goals
+		{"kickoff", 1.5, 1.1, 0, 0.464244, 0.257667, 0.278089},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			home, draw, away := outcomeProbabilities(tt.homeGoals, tt.awayGoals, tt.lead)
+			if !almostEqual(home, tt.wantHome) || !almostEqual(draw, tt.wantDraw) || !almostEqual(away, tt.wantAway) {
+				t.Errorf("outcomeProbabilities() = %v/%v/%v, want %v/%v/%v", home, draw, away, tt.wantHome, tt.wantDraw, tt.wantAway)
+			}
+		})
+	}
+}
+
+func TestImpliedExpectedGoals(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		homeGoals, awayGoals float64
+	}{
+		{1.5, 1.1},
+		{1.2, 1.2},
+		{0.8, 2.1},
+		{2.6, 0.6},
+	}
+
+	for _, tt := range tests {
+		homeWin, draw, awayWin := outcomeProbabilities(tt.homeGoals, tt.awayGoals, 0)
+		home, away := impliedExpectedGoals(homeWin, draw, awayWin)
+		if math.Abs(home-tt.homeGoals) > 0.011 || math.Abs(away-tt.awayGoals) > 0.011 {
+			t.Errorf("impliedExpectedGoals(%v, %v, %v) = %v, %v, want %v, %v",
+				homeWin, draw, awayWin, home, away, tt.homeGoals, tt.awayGoals)
+		}
+	}
+}
+
+func TestEstimateMinute(t *testing.T) {
+	t.Parallel()
+
+	kickoff := time.Date(2025, 3, 15, 15, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		elapsed time.Duration
+		status  string
+		want    int
+	}{
+		{"before kickoff", -5 * time.Minute, "IN_PLAY", 1},
+		{"kickoff", 0, "IN_PLAY", 1},
+		{"first half", 30 * time.Minute, "IN_PLAY", 31},
+		{"first half stoppage time", 47 * time.Minute, "IN_PLAY", 45},
+		{"half-time", 52 * time.Minute, "PAUSED", 45},
+		{"second half", 75 * time.Minute, "IN_PLAY", 61},
+		{"second half stoppage time", 110 * time.Minute, "IN_PLAY", 90},
+		{"finished", 100 * time.Minute, "FINISHED", 90},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := estimateMinute(kickoff, kickoff.Add(tt.elapsed), tt.status); got != tt.want {
+				t.Errorf("estimateMinute() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,262 @@
+package predictions
+
+import (
+	"cmp"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"slices"
+)
+
+const (
+	// maxDistributionGoals is the most goals per side in a score distribution
+	maxDistributionGoals = 10
+	// exactScoreCount is the number of most likely exact scores reported
+	exactScoreCount = 5
+	// maxExpectedGoals is the most expected goals per side taken from a model
+	maxExpectedGoals = 10.0
+)
+
+// overUnderLines are the total goals lines priced from a score distribution
+var overUnderLines = []float64{0.5, 1.5, 2.5, 3.5, 4.5}
+
+// ScoreDistribution is the probability of each scoreline, from independent
+// Poisson goals with the expected goals of each side
+type ScoreDistribution struct {
+	HomeExpectedGoals float64 `json:"homeExpectedGoals"`
+	AwayExpectedGoals float64 `json:"awayExpectedGoals"`
+	// Probabilities are indexed by home then away goals, up to maxDistributionGoals
+	Probabilities [][]float64 `json:"probabilities"`
+}
+
+// Markets are the goal markets derived from a score distribution
+type Markets struct {
+	OverUnder        []OverUnder        `json:"overUnder"`
+	BothTeamsToScore float64            `json:"bothTeamsToScore"`
+	DoubleChance     DoubleChance       `json:"doubleChance"`
+	ExactScores      []ScoreProbability `json:"exactScores"` // most likely first
+}
+
+// OverUnder prices a total goals line
+type OverUnder struct {
+	Line  float64 `json:"line"`
+	Over  float64 `json:"over"`
+	Under float64 `json:"under"`
+}
+
+// DoubleChance prices the three pairs of 1X2 outcomes
+type DoubleChance struct {
+	HomeOrDraw float64 `json:"homeOrDraw"`
+	HomeOrAway float64 `json:"homeOrAway"`
+	DrawOrAway float64 `json:"drawOrAway"`
+}
+
+// ScoreProbability is the probability of an exact score
+type ScoreProbability struct {
+	Home        int     `json:"home"`
+	Away        int     `json:"away"`
+	Probability float64 `json:"probability"`
+}
+
+// MarketResult grades a prediction's pick in one goal market
+type MarketResult struct {
+	Market      string  `json:"market"` // e.g. "over_under_2.5", "btts", "double_chance", "exact_score"
+	Pick        string  `json:"pick"`
+	Probability float64 `json:"probability"` // of the pick
+	Correct     bool    `json:"correct"`
+	BrierScore  float64 `json:"brierScore"` // of the pick as a yes/no forecast
+}
+
+// validExpectedGoals returns a model's expected goals for a side, or nil
+// unless they are finite and between 0 and maxExpectedGoals
+func validExpectedGoals(goals *float64) *float64 {
+	if goals == nil || math.IsNaN(*goals) || *goals < 0 || *goals > maxExpectedGoals {
+		return nil
+	}
+	return goals
+}
+
+// NewScoreDistribution builds the score distribution for the expected goals,
+// normalised over the scores it covers
+func NewScoreDistribution(homeExpectedGoals, awayExpectedGoals float64) *ScoreDistribution {
+	home := poissonProbabilities(homeExpectedGoals)
+	away := poissonProbabilities(awayExpectedGoals)
+
+	total := 0.0
+	probabilities := make([][]float64, len(home))
+	for h := range home {
+		probabilities[h] = make([]float64, len(away))
+		for a := range away {
+			probabilities[h][a] = home[h] * away[a]
+			total += probabilities[h][a]
+		}
+	}
+	for h := range probabilities {
+		for a := range probabilities[h] {
+			probabilities[h][a] /= total
+		}
+	}
+
+	return &ScoreDistribution{
+		HomeExpectedGoals: homeExpectedGoals,
+		AwayExpectedGoals: awayExpectedGoals,
+		Probabilities:     probabilities,
+	}
+}
+
+// poissonProbabilities returns P(k goals) for k up to maxDistributionGoals
+func poissonProbabilities(mean float64) []float64 {
+	probabilities := make([]float64, maxDistributionGoals+1)
+	p := math.Exp(-mean)
+	for k := range probabilities {
+		if k > 0 {
+			p *= mean / float64(k)
+		}
+		probabilities[k] = p
+	}
+	return probabilities
+}
+
+// Markets derives the goal markets from the distribution
+func (d *ScoreDistribution) Markets() *Markets {
+	m := &Markets{OverUnder: make([]OverUnder, len(overUnderLines))}
+	for i, line := range overUnderLines {
+		m.OverUnder[i].Line = line
+	}
+
+	var homeWin, draw, awayWin float64
+	var scores []ScoreProbability
+	for h, row := range d.Probabilities {
+		for a, p := range row {
+			switch {
+			case h > a:
+				homeWin += p
+			case h == a:
+				draw += p
+			default:
+				awayWin += p
+			}
+			if h > 0 && a > 0 {
+				m.BothTeamsToScore += p
+			}
+			for i, line := range overUnderLines {
+				if float64(h+a) > line {
+					m.OverUnder[i].Over += p
+				} else {
+					m.OverUnder[i].Under += p
+				}
+			}
+			scores = append(scores, ScoreProbability{Home: h, Away: a, Probability: p})
+		}
+	}
+
+	m.DoubleChance = DoubleChance{
+		HomeOrDraw: homeWin + draw,
+		HomeOrAway: homeWin + awayWin,
+		DrawOrAway: draw + awayWin,
+	}
+
+	slices.SortStableFunc(scores, func(a, b ScoreProbability) int {
+		return cmp.Compare(b.Probability, a.Probability)
+	})
+	m.ExactScores = scores[:min(exactScoreCount, len(scores))]
+
+	return m
+}
+
+// Grade grades the most likely pick of each market against the final score
+func (m *Markets) Grade(homeScore, awayScore int) []MarketResult {
+	var results []MarketResult
+	grade := func(market, pick string, probability float64, correct bool) {
+		outcome := 0.0
+		if correct {
+			outcome = 1
+		}
+		results = append(results, MarketResult{
+			Market:      market,
+			Pick:        pick,
+			Probability: probability,
+			Correct:     correct,
+			BrierScore:  math.Pow(probability-outcome, 2),
+		})
+	}
+
+	goals := float64(homeScore + awayScore)
+	for _, ou := range m.OverUnder {
+		market := fmt.Sprintf("over_under_%.1f", ou.Line)
+		if ou.Over > ou.Under {
+			grade(market, "over", ou.Over, goals > ou.Line)
+		} else {
+			grade(market, "under", ou.Under, goals < ou.Line)
+		}
+	}
+
+	btts := homeScore > 0 && awayScore > 0
+	if m.BothTeamsToScore > 0.5 {
+		grade("btts", "yes", m.BothTeamsToScore, btts)
+	} else {
+		grade("btts", "no", 1-m.BothTeamsToScore, !btts)
+	}
+
+	dc := m.DoubleChance
+	switch {
+	case dc.HomeOrDraw >= dc.HomeOrAway && dc.HomeOrDraw >= dc.DrawOrAway:
+		grade("double_chance", "home_or_draw", dc.HomeOrDraw, homeScore >= awayScore)
+	case dc.DrawOrAway >= dc.HomeOrAway:
+		grade("double_chance", "draw_or_away", dc.DrawOrAway, awayScore >= homeScore)
+	default:
+		grade("double_chance", "home_or_away", dc.HomeOrAway, homeScore != awayScore)
+	}
+
+	if len(m.ExactScores) > 0 {
+		top := m.ExactScores[0]
+		grade("exact_score", fmt.Sprintf("%d-%d", top.Home, top.Away), top.Probability,
+			top.Home == homeScore && top.Away == awayScore)
+	}
+
+	return results
+}
+
+// expectedGoals returns the expected goals of a prediction: the aggregator's
+// when it gave valid ones, otherwise the average over the agents that did
+func expectedGoals(final *AgentOutput, outputs []AgentOutput) (home, away float64, ok bool) {
+	if final != nil {
+		if h, a := validExpectedGoals(final.HomeExpectedGoals), validExpectedGoals(final.AwayExpectedGoals); h != nil && a != nil {
+			return *h, *a, true
+		}
+	}
+
+	n := 0
+	for _, output := range outputs {
+		h, a := validExpectedGoals(output.HomeExpectedGoals), validExpectedGoals(output.AwayExpectedGoals)
+		if h == nil || a == nil {
+			continue
+		}
+		home += *h
+		away += *a
+		n++
+	}
+	if n == 0 {
+		return 0, 0, false
+	}
+	return home / float64(n), away / float64(n), true
+}
+
+// setGoalMarkets restores a stored prediction's score distribution and markets
+func (p *PredictionResult) setGoalMarkets(homeGoals, awayGoals sql.NullFloat64, marketsJSON []byte) {
+	if !homeGoals.Valid || !awayGoals.Valid {
+		return
+	}
+
+	p.ScoreDistribution = NewScoreDistribution(homeGoals.Float64, awayGoals.Float64)
+	if len(marketsJSON) > 0 {
+		err := json.Unmarshal(marketsJSON, &p.Markets)
+		if err == nil {
+			return
+		}
+		slog.Error("Failed to unmarshal markets", "predictionId", p.ID, "error", err)
+	}
+	p.Markets = p.ScoreDistribution.Markets()
+}
@@ -0,0 +1,294 @@
+package predictions
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+// almostEqual compares probabilities up to the mass the score distribution
+// leaves out beyond maxDistributionGoals
+func almostEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-6
+}
+
+func TestNewScoreDistribution(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		home, away float64
+	}{
+		{"typical", 1.5, 1.1},
+		{"goalless", 0, 0},
+		{"lopsided", 4.5, 0.3},
+		// Most of the mass is beyond maxDistributionGoals, so normalising matters
+		{"beyond the covered scores", 9, 8},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			d := NewScoreDistribution(tt.home, tt.away)
+			if len(d.Probabilities) != maxDistributionGoals+1 {
+				t.Fatalf("got %d home scores, want %d", len(d.Probabilities), maxDistributionGoals+1)
+			}
+
+			total := 0.0
+			for h, row := range d.Probabilities {
+				if len(row) != maxDistributionGoals+1 {
+					t.Fatalf("got %d away scores for %d home goals, want %d", len(row), h, maxDistributionGoals+1)
+				}
+				for _, p := range row {
+					if p < 0 {
+						t.Fatalf("negative probability %v", p)
+					}
+					total += p
+				}
+			}
+			if math.Abs(total-1) > 1e-9 {
+				t.Errorf("probabilities sum to %v, want 1", total)
+			}
+		})
+	}
+}
+
+func TestNewScoreDistribution_Poisson(t *testing.T) {
+	t.Parallel()
+
+	d := NewScoreDistribution(1.5, 1.1)
+
+	tests := []struct {
+		home, away int
+		want       float64
+	}{
+		{0, 0, math.Exp(-2.6)},
+		{1, 0, 1.5 * math.Exp(-2.6)},
+		{1, 1, 1.5 * 1.1 * math.Exp(-2.6)},
+		{2, 1, 1.5 * 1.5 / 2 * 1.1 * math.Exp(-2.6)},
+	}
+
+	for _, tt := range tests {
+		if got := d.Probabilities[tt.home][tt.away]; !almostEqual(got, tt.want) {
+			t.Errorf("P(%d-%d) = %v, want %v", tt.home, tt.away, got, tt.want)
+		}
+	}
+}
+
+func TestScoreDistribution_Markets(t *testing.T) {
+	t.Parallel()
+
+	m := NewScoreDistribution(1.5, 1.1).Markets()
+
+	// Total goals are Poisson with mean 2.6
+	wantOver := map[float64]float64{
+		0.5: 0.925726,
+		1.5: 0.732615,
+		2.5: 0.481570,
+		3.5: 0.263998,
+		4.5: 0.122576,
+	}
+	if len(m.OverUnder) != len(wantOver) {
+		t.Fatalf("got %d over/under lines, want %d", len(m.OverUnder), len(wantOver))
+	}
+	for _, ou := range m.OverUnder {
+		if !almostEqual(ou.Over, wantOver[ou.Line]) {
+			t.Errorf("over %.1f = %v, want %v", ou.Line, ou.Over, wantOver[ou.Line])
+		}
+		if !almostEqual(ou.Over+ou.Under, 1) {
+			t.Errorf("over/under %.1f sum to %v, want 1", ou.Line, ou.Over+ou.Under)
+		}
+	}
+
+	wantBTTS := (1 - math.Exp(-1.5)) * (1 - math.Exp(-1.1))
+	if !almostEqual(m.BothTeamsToScore, wantBTTS) {
+		t.Errorf("both teams to score = %v, want %v", m.BothTeamsToScore, wantBTTS)
+	}
+
+	// Each outcome is in two of the three pairs
+	dc := m.DoubleChance
+	if sum := dc.HomeOrDraw + dc.HomeOrAway + dc.DrawOrAway; !almostEqual(sum, 2) {
+		t.Errorf("double chance sums to %v, want 2", sum)
+	}
+	if !almostEqual(dc.HomeOrDraw, 0.721911) || !almostEqual(dc.HomeOrAway, 0.742333) || !almostEqual(dc.DrawOrAway, 0.535756) {
+		t.Errorf("double chance = %+v, want 0.721911, 0.742333 and 0.535756", dc)
+	}
+
+	if len(m.ExactScores) != exactScoreCount {
+		t.Fatalf("got %d exact scores, want %d", len(m.ExactScores), exactScoreCount)
+	}
+	wantScores := [][2]int{{1, 1}, {1, 0}, {2, 1}, {2, 0}, {0, 1}}
+	for i, want := range wantScores {
+		got := m.ExactScores[i]
+		if got.Home != want[0] || got.Away != want[1] {
+			t.Errorf("exact score %d = %d-%d, want %d-%d", i, got.Home, got.Away, want[0], want[1])
+		}
+	}
+}
+
+func TestMarkets_Grade(t *testing.T) {
+	t.Parallel()
+
+	m := NewScoreDistribution(1.5, 1.1).Markets()
+
+	tests := []struct {
+		name       string
+		home, away int
+		want       map[string]bool // correct by market
+	}{
+		{
+			name: "home win with both scoring",
+			home: 2, away: 1,
+			want: map[string]bool{
+				"over_under_0.5": true,
+				"over_under_1.5": true,
+				"over_under_2.5": false,
+				"over_under_3.5": true,
+				"over_under_4.5": true,
+				"btts":           true,
+				"double_chance":  true,
+				"exact_score":    false,
+			},
+		},
+		{
+			name: "goalless draw",
+			home: 0, away: 0,
+			want: map[string]bool{
+				"over_under_0.5": false,
+				"over_under_1.5": false,
+				"over_under_2.5": true,
+				"over_under_3.5": true,
+				"over_under_4.5": true,
+				"btts":           false,
+				"double_chance":  false,
+				"exact_score":    false,
+			},
+		},
+		{
+			name: "most likely score",
+			home: 1, away: 1,
+			want: map[string]bool{
+				"over_under_0.5": true,
+				"over_under_1.5": true,
+				"over_under_2.5": true,
+				"over_under_3.5": true,
+				"over_under_4.5": true,
+				"btts":           true,
+				"double_chance":  false,
+				"exact_score":    true,
+			},
+		},
+	}
+
+	wantPicks := map[string]string{
+		"over_under_0.5": "over",
+		"over_under_1.5": "over",
+		"over_under_2.5": "under",
+		"over_under_3.5": "under",
+		"over_under_4.5": "under",
+		"btts":           "yes",
+		"double_chance":  "home_or_away",
+		"exact_score":    "1-1",
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			results := m.Grade(tt.home, tt.away)
+			if len(results) != len(tt.want) {
+				t.Fatalf("got %d results, want %d", len(results), len(tt.want))
+			}
+			for _, r := range results {
+				if r.Pick != wantPicks[r.Market] {
+					t.Errorf("%s pick = %q, want %q", r.Market, r.Pick, wantPicks[r.Market])
+				}
+				if r.Correct != tt.want[r.Market] {
+					t.Errorf("%s correct = %v, want %v", r.Market, r.Correct, tt.want[r.Market])
+				}
+
+				outcome := 0.0
+				if r.Correct {
+					outcome = 1
+				}
+				if !almostEqual(r.BrierScore, (r.Probability-outcome)*(r.Probability-outcome)) {
+					t.Errorf("%s Brier score = %v for probability %v", r.Market, r.BrierScore, r.Probability)
+				}
+			}
+		})
+	}
+}
+
+func TestParseAgentResponse_ExpectedGoals(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		home      string
+		wantValid bool
+	}{
+		{"typical", "1.4", true},
+		{"none", "0", true},
+		{"negative", "-0.5", false},
+		{"huge", "1e308", false},
+		{"beyond the distribution", "10.5", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			response := `{"homeWinProb": 0.5, "drawProb": 0.3, "awayWinProb": 0.2, "homeExpectedGoals": ` + tt.home + `, "awayExpectedGoals": 1.1}`
+			output, err := parseAgentResponse("statistical", response)
+			if err != nil {
+				t.Fatalf("parseAgentResponse() error = %v", err)
+			}
+			if valid := output.HomeExpectedGoals != nil; valid != tt.wantValid {
+				t.Errorf("home expected goals kept = %v, want %v", valid, tt.wantValid)
+			}
+			if output.AwayExpectedGoals == nil {
+				t.Error("away expected goals dropped")
+			}
+
+			home, away, ok := expectedGoals(output, nil)
+			if ok != tt.wantValid {
+				t.Fatalf("expectedGoals() ok = %v, want %v", ok, tt.wantValid)
+			}
+			if !ok {
+				return
+			}
+			m := NewScoreDistribution(home, away).Markets()
+			if _, err := json.Marshal(m); err != nil {
+				t.Errorf("markets do not marshal: %v", err)
+			}
+			for _, ou := range m.OverUnder {
+				if ou.Over < 0 || ou.Under < 0 {
+					t.Errorf("over/under %.1f = %v/%v, want non-negative", ou.Line, ou.Over, ou.Under)
+				}
+			}
+		})
+	}
+}
+
+func TestExpectedGoals_InvalidInputs(t *testing.T) {
+	t.Parallel()
+
+	negative, huge, inf, nan, valid := -1.0, 1e6, math.Inf(1), math.NaN(), 2.0
+	outputs := []AgentOutput{
+		{HomeExpectedGoals: &negative, AwayExpectedGoals: &valid},
+		{HomeExpectedGoals: &valid, AwayExpectedGoals: &huge},
+		{HomeExpectedGoals: &inf, AwayExpectedGoals: &valid},
+		{HomeExpectedGoals: &valid, AwayExpectedGoals: &nan},
+	}
+
+	final := &AgentOutput{HomeExpectedGoals: &huge, AwayExpectedGoals: &valid}
+	if home, away, ok := expectedGoals(final, outputs); ok {
+		t.Errorf("expectedGoals() = %v, %v from invalid inputs, want none", home, away)
+	}
+
+	outputs = append(outputs, AgentOutput{HomeExpectedGoals: &valid, AwayExpectedGoals: &valid})
+	if home, away, ok := expectedGoals(final, outputs); !ok || home != valid || away != valid {
+		t.Errorf("expectedGoals() = %v, %v, %v, want the valid agent's %v", home, away, ok, valid)
+	}
+}
@@ -2,11 +2,14 @@ package predictions
 
 import (
 	"time"
+
+	"github.com/edd/relaxovisionmonolith/footballdata"
 )
 
 // PredictionRequest represents a request for match prediction
 type PredictionRequest struct {
-	MatchID int `json:"matchId"`
+	MatchID int  `json:"matchId"`
+	Force   bool `json:"force"` // run the agents even if a fresh prediction exists
 }
 
 // AgentOutput represents the output from a single AI agent
@@ -19,6 +22,23 @@ type AgentOutput struct {
 	Reasoning   string             `json:"reasoning"`
 	KeyFactors  []string           `json:"keyFactors"`
 	Metadata    map[string]any     `json:"metadata,omitempty"`
+	// Expected goals per side, when the agent provides them
+	HomeExpectedGoals *float64 `json:"homeExpectedGoals,omitempty"`
+	AwayExpectedGoals *float64 `json:"awayExpectedGoals,omitempty"`
+	// ProviderOutputs are the probabilities of each LLM provider before weighting
+	ProviderOutputs []ProviderOutput `json:"providerOutputs,omitempty"`
+	// Dispersion is the spread across providers, or across samples of a single provider
+	Dispersion *Dispersion `json:"dispersion,omitempty"`
+	// DerivedConfidence is computed from the probabilities and dispersion, unlike Confidence
+	DerivedConfidence float64 `json:"derivedConfidence"`
+}
+
+// ProviderOutput holds a single LLM provider's probabilities for an agent
+type ProviderOutput struct {
+	Provider    string  `json:"provider"`
+	HomeWinProb float64 `json:"homeWinProb"`
+	DrawProb    float64 `json:"drawProb"`
+	AwayWinProb float64 `json:"awayWinProb"`
 }
 
 // PredictionResult represents the final prediction output
@@ -36,6 +56,32 @@ type PredictionResult struct {
 	KeyFactors   []string      `json:"keyFactors"`
 	CreatedAt    time.Time     `json:"createdAt"`
 	UpdatedAt    time.Time     `json:"updatedAt"`
+	// Score distribution and goal markets, when the agents provided expected goals
+	ScoreDistribution *ScoreDistribution `json:"scoreDistribution,omitempty"`
+	Markets           *Markets           `json:"markets,omitempty"`
+	// InputFingerprint identifies the feature inputs the prediction was made from
+	InputFingerprint string `json:"inputFingerprint,omitempty"`
+	// Revision numbers the match's predictions from 1, each linked to the one it replaced
+	Revision   int    `json:"revision"`
+	PreviousID string `json:"previousId,omitempty"`
+	// InputHash is the SHA-256 of the analysis the agents were given, in canonical form
+	InputHash     string            `json:"inputHash,omitempty"`
+	InputSnapshot *MatchAnalysis    `json:"inputSnapshot,omitempty"`
+	PromptVersion int               `json:"promptVersion,omitempty"`
+	Models        map[string]string `json:"models,omitempty"` // by agent type
+	// Dispersion is the spread across agents, and DerivedConfidence is computed from it
+	// and the probabilities, unlike the aggregator's Confidence
+	Dispersion        *Dispersion `json:"dispersion,omitempty"`
+	DerivedConfidence float64     `json:"derivedConfidence"`
+	// Pipeline is the agent pipeline the prediction ran, standard or debate-<rounds>,
+	// and LLMCalls the agent calls it made
+	Pipeline string  `json:"pipeline"`
+	LLMCalls int     `json:"llmCalls"`
+	Debate   *Debate `json:"debate,omitempty"`
+	// Calibration is set when a calibrator replaced the aggregator's probabilities and confidence
+	Calibration *AppliedCalibration `json:"calibration,omitempty"`
+	// Reused is set when an existing prediction was returned instead of running the agents
+	Reused bool `json:"reused,omitempty"`
 }
 
 // WorkflowInput represents input data for the prediction workflow
@@ -51,6 +97,10 @@ type WorkflowOutput struct {
 	Confidence   float64       `json:"confidence"`
 	Reasoning    string        `json:"reasoning"`
 	AgentOutputs []AgentOutput `json:"agentOutputs"`
+	HomeExpectedGoals *float64 `json:"homeExpectedGoals,omitempty"`
+	AwayExpectedGoals *float64 `json:"awayExpectedGoals,omitempty"`
+	Pipeline     string        `json:"pipeline"`
+	Debate       *Debate       `json:"debate,omitempty"`
 }
 
 // MatchAnalysis represents data about a match for analysis
@@ -71,6 +121,15 @@ type TeamAnalysis struct {
 	RecentForm    []string               `json:"recentForm"` // W, D, L for last 5 games
 	Statistics    TeamStatistics         `json:"statistics"`
 	CurrentForm   string                 `json:"currentForm"`
+	TablePosition int                    `json:"tablePosition,omitempty"` // league position before kickoff
+	Points        int                    `json:"points,omitempty"`
+	Elo           float64                `json:"elo,omitempty"` // rating across all competitions before kickoff
+	// Form is recent form across all competitions
+	Form *footballdata.TeamForm `json:"form,omitempty"`
+	// UnderlyingStats holds rolling xG and shot aggregates when match statistics have been imported
+	UnderlyingStats *footballdata.TeamStatsAggregate `json:"underlyingStats,omitempty"`
+	// Schedule captures fixture congestion, rest days and travel before the match
+	Schedule *footballdata.ScheduleFeatures `json:"schedule,omitempty"`
 }
 
 // TeamStatistics represents team performance statistics
@@ -0,0 +1,403 @@
+package predictions
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// maxPipelineName is the width of the pipeline column
+const maxPipelineName = 20
+
+// AgentConfig configures one agent of a pipeline
+type AgentConfig struct {
+	Type string `json:"type"`
+	// Name labels the agent's outputs, its type by default. Names are unique in a pipeline
+	Name string `json:"name,omitempty"`
+	// Providers are the LLM providers an LLM agent asks; without them it asks OpenAI
+	Providers []string `json:"providers,omitempty"`
+	// ProviderWeights weight the providers' answers, 1 each by default
+	ProviderWeights map[string]float64 `json:"providerWeights,omitempty"`
+	// Weight is the agent's weight in the mean aggregation, 1 by default
+	Weight float64 `json:"weight,omitempty"`
+	// System and Prompt are the instructions of a prompt agent
+	System string `json:"system,omitempty"`
+	Prompt string `json:"prompt,omitempty"`
+}
+
+// ModelConfig sets the LLM providers a pipeline's aggregator or critic asks
+type ModelConfig struct {
+	// Providers are the LLM providers asked; without them OpenAI is
+	Providers []string `json:"providers,omitempty"`
+	// ProviderWeights weight the providers' answers, 1 each by default
+	ProviderWeights map[string]float64 `json:"providerWeights,omitempty"`
+}
+
+// agent returns the configuration of the agent asking the providers
+func (c ModelConfig) agent(agentType string) AgentConfig {
+	return AgentConfig{Type: agentType, Providers: c.Providers, ProviderWeights: c.ProviderWeights}
+}
+
+// name returns the label of the agent's outputs
+func (c AgentConfig) name() string {
+	if c.Name != "" {
+		return c.Name
+	}
+	return c.Type
+}
+
+// PipelineConfig defines the agents a prediction runs and how their outputs
+// are combined
+type PipelineConfig struct {
+	// Name is recorded with each prediction and groups them in the accuracy stats.
+	// Rename a pipeline when changing it, so its predictions are not reused
+	Name   string        `json:"name"`
+	Agents []AgentConfig `json:"agents"`
+	// Aggregation is a registered aggregation strategy, llm by default
+	Aggregation string `json:"aggregation,omitempty"`
+	// DebateRounds has the agents revise their outputs before a critic challenges them
+	DebateRounds int `json:"debateRounds,omitempty"`
+	// Aggregator and Critic set the providers of the LLM aggregator and the debate's critic
+	Aggregator ModelConfig `json:"aggregator,omitempty"`
+	Critic     ModelConfig `json:"critic,omitempty"`
+}
+
+// PipelinesConfig assigns pipelines to competitions
+type PipelinesConfig struct {
+	// Default runs for competitions without their own pipeline; the built-in one when nil
+	Default *PipelineConfig `json:"default,omitempty"`
+	// Competitions maps competition codes, e.g. PL, to their pipelines
+	Competitions map[string]PipelineConfig `json:"competitions,omitempty"`
+}
+
+// LoadPipelines reads a pipelines configuration from a JSON file
+func LoadPipelines(path string) (PipelinesConfig, error) {
+	var cfg PipelinesConfig
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, fmt.Errorf("failed to read pipelines: %w", err)
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("failed to parse pipelines: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// builtinPipeline is the pipeline run when none is configured: the statistical,
+// form and head-to-head analysts and the LLM aggregator, debating for the rounds
+func builtinPipeline(rounds int) PipelineConfig {
+	name := PipelineStandard
+	if rounds > 0 {
+		name = fmt.Sprintf("debate-%d", rounds)
+	}
+
+	return PipelineConfig{
+		Name: name,
+		Agents: []AgentConfig{
+			{Type: AgentTypeStatistical},
+			{Type: AgentTypeForm},
+			{Type: AgentTypeHeadToHead},
+		},
+		Aggregation:  AggregationLLM,
+		DebateRounds: rounds,
+	}
+}
+
+// SetPipelines configures the pipelines per competition. Each pipeline is built
+// once to check it, so its agent types and providers must be registered first
+func (s *Service) SetPipelines(cfg PipelinesConfig) error {
+	if cfg.Default != nil {
+		if _, err := s.buildPipeline(*cfg.Default); err != nil {
+			return fmt.Errorf("invalid default pipeline: %w", err)
+		}
+	}
+	for code, p := range cfg.Competitions {
+		if _, err := s.buildPipeline(p); err != nil {
+			return fmt.Errorf("invalid pipeline for %s: %w", code, err)
+		}
+	}
+
+	s.pipelines = cfg
+	return nil
+}
+
+// pipelineConfig returns the definition of the pipeline predicting a
+// competition's matches
+func (s *Service) pipelineConfig(competitionCode string) PipelineConfig {
+	if cfg, ok := s.pipelines.Competitions[competitionCode]; ok && competitionCode != "" {
+		return cfg
+	}
+	if s.pipelines.Default != nil {
+		return *s.pipelines.Default
+	}
+	return builtinPipeline(s.debateRounds)
+}
+
+// pipelineFor builds the pipeline predicting a competition's matches
+func (s *Service) pipelineFor(competitionCode string) (*pipeline, error) {
+	p, err := s.buildPipeline(s.pipelineConfig(competitionCode))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build pipeline: %w", err)
+	}
+	return p, nil
+}
+
+// pipeline is a pipeline definition with its agents built
+type pipeline struct {
+	config     PipelineConfig
+	agents     []Agent
+	critic     *CriticAgent // on debate pipelines
+	aggregator Aggregator
+}
+
+// buildPipeline checks a pipeline definition and builds its agents from the
+// registry
+func (s *Service) buildPipeline(cfg PipelineConfig) (*pipeline, error) {
+	if cfg.Name == "" || len(cfg.Name) > maxPipelineName {
+		return nil, fmt.Errorf("name is required and at most %d characters", maxPipelineName)
+	}
+	if len(cfg.Agents) == 0 {
+		return nil, fmt.Errorf("at least one agent is required")
+	}
+	if cfg.DebateRounds < 0 || cfg.DebateRounds > maxDebateRounds {
+		return nil, fmt.Errorf("debateRounds must be between 0 and %d", maxDebateRounds)
+	}
+	if cfg.Aggregation == "" {
+		cfg.Aggregation = AggregationLLM
+	}
+
+	deps := s.agentDeps()
+	p := &pipeline{config: cfg}
+
+	names := make(map[string]bool, len(cfg.Agents))
+	for _, agentCfg := range cfg.Agents {
+		name := agentCfg.name()
+		switch {
+		case name == AgentTypeAggregator || name == AgentTypeCritic:
+			return nil, fmt.Errorf("agent name %s is reserved", name)
+		case names[name]:
+			return nil, fmt.Errorf("duplicate agent name %s", name)
+		case agentCfg.Weight < 0:
+			return nil, fmt.Errorf("agent %s: weight must not be negative", name)
+		}
+		names[name] = true
+
+		agent, err := s.registry.newAgent(deps, agentCfg)
+		if err != nil {
+			return nil, fmt.Errorf("agent %s: %w", name, err)
+		}
+		p.agents = append(p.agents, agent)
+	}
+
+	if cfg.DebateRounds > 0 {
+		critic, err := deps.NewLLMAgent(cfg.Critic.agent(AgentTypeCritic))
+		if err != nil {
+			return nil, fmt.Errorf("critic: %w", err)
+		}
+		p.critic = &CriticAgent{LLMAgent: critic}
+	}
+
+	aggregator, err := s.registry.newAggregator(deps, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("aggregation %s: %w", cfg.Aggregation, err)
+	}
+	p.aggregator = aggregator
+
+	return p, nil
+}
+
+// models returns what each agent of the pipeline runs on, with the critic
+// and the aggregator
+func (p *pipeline) models() map[string]string {
+	models := make(map[string]string, len(p.agents)+2)
+	for _, agent := range p.agents {
+		models[agent.Type()] = agent.Model()
+	}
+	if p.critic != nil {
+		models[AgentTypeCritic] = p.critic.Model()
+	}
+	if aggregator, ok := p.aggregator.(interface{ Model() string }); ok {
+		models[AgentTypeAggregator] = aggregator.Model()
+	}
+	return models
+}
+
+// llmCalls is the number of agent calls a prediction makes on the pipeline:
+// each LLM agent's analysis and revisions, the critic and an LLM aggregator
+func (p *pipeline) llmCalls() int {
+	calls := 0
+	for _, agent := range p.agents {
+		if !usesLLM(agent) {
+			continue
+		}
+		calls++
+		if _, ok := agent.(Reviser); ok {
+			calls += p.config.DebateRounds
+		}
+	}
+	if p.critic != nil {
+		calls++
+	}
+	if usesLLM(p.aggregator) {
+		calls++
+	}
+	return calls
+}
+
+// usesLLM reports whether an agent or aggregator calls an LLM
+func usesLLM(v any) bool {
+	l, ok := v.(interface{ usesLLM() bool })
+	return ok && l.usesLLM()
+}
+
+// analyzeAgent runs the analysis of the agent at index i
+func (p *pipeline) analyzeAgent(ctx context.Context, analysis *MatchAnalysis, i int) (*AgentOutput, error) {
+	agent := p.agents[i]
+	output, err := agent.Analyze(ctx, analysis)
+	if err != nil {
+		return nil, fmt.Errorf("%s analysis failed: %w", agent.Type(), err)
+	}
+	return output, nil
+}
+
+// reviseAgent has the agent at index i revise its output in a debate round.
+// Agents that cannot revise keep their output
+func (p *pipeline) reviseAgent(ctx context.Context, analysis *MatchAnalysis, outputs []AgentOutput, i, round int) (*AgentOutput, error) {
+	reviser, ok := p.agents[i].(Reviser)
+	if !ok {
+		return &outputs[i], nil
+	}
+
+	output, err := reviser.Revise(ctx, analysis, outputs, i, round)
+	if err != nil {
+		return nil, fmt.Errorf("%s revision in round %d failed: %w", p.agents[i].Type(), round, err)
+	}
+	return output, nil
+}
+
+// critique has the critic challenge the agents' outputs after the debate
+func (p *pipeline) critique(ctx context.Context, analysis *MatchAnalysis, outputs []AgentOutput) (*AgentOutput, error) {
+	if p.critic == nil {
+		return nil, fmt.Errorf("pipeline %s has no debate", p.config.Name)
+	}
+
+	critique, err := p.critic.Critique(ctx, analysis, outputs)
+	if err != nil {
+		return nil, fmt.Errorf("critique failed: %w", err)
+	}
+	return critique, nil
+}
+
+// aggregate combines the outputs into the final prediction
+func (p *pipeline) aggregate(ctx context.Context, outputs []AgentOutput) (*AgentOutput, error) {
+	output, err := p.aggregator.Aggregate(ctx, outputs)
+	if err != nil {
+		return nil, fmt.Errorf("aggregation failed: %w", err)
+	}
+	return output, nil
+}
+
+// pipelineSteps runs the steps of a pipeline: in process for the service, and
+// as activities for the workflow
+type pipelineSteps interface {
+	analyze() ([]AgentOutput, error)
+	revise(round int, outputs []AgentOutput) ([]AgentOutput, error)
+	critique(outputs []AgentOutput) (*AgentOutput, error)
+	aggregate(outputs []AgentOutput) (*AgentOutput, error)
+}
+
+// pipelineRun is the outcome of a pipeline
+type pipelineRun struct {
+	outputs []AgentOutput // the agents' outputs after the last round
+	debate  *Debate
+	final   *AgentOutput
+}
+
+// executePipeline runs a pipeline definition's steps: the agents' analyses,
+// the debate rounds and critique when it has them, then the aggregation
+func executePipeline(cfg PipelineConfig, steps pipelineSteps) (*pipelineRun, error) {
+	outputs, err := steps.analyze()
+	if err != nil {
+		return nil, err
+	}
+	run := &pipelineRun{outputs: outputs}
+
+	// On a debate pipeline the agents revise their outputs, and the
+	// aggregator also reads the critic's challenge
+	inputs := outputs
+	if cfg.DebateRounds > 0 {
+		run.debate = &Debate{Rounds: []DebateRound{newDebateRound(0, outputs)}}
+		for round := 1; round <= cfg.DebateRounds; round++ {
+			if outputs, err = steps.revise(round, outputs); err != nil {
+				return nil, fmt.Errorf("debate failed: %w", err)
+			}
+			run.debate.Rounds = append(run.debate.Rounds, newDebateRound(round, outputs))
+		}
+
+		if run.debate.Critique, err = steps.critique(outputs); err != nil {
+			return nil, fmt.Errorf("debate failed: %w", err)
+		}
+		run.outputs = outputs
+		inputs = append(slices.Clone(outputs), *run.debate.Critique)
+	}
+
+	if run.final, err = steps.aggregate(inputs); err != nil {
+		return nil, err
+	}
+
+	return run, nil
+}
+
+// localSteps runs a pipeline's steps in process, the agents of a step
+// concurrently. The first agent to fail fails the step
+type localSteps struct {
+	ctx      context.Context
+	pipeline *pipeline
+	analysis *MatchAnalysis
+}
+
+func (l *localSteps) analyze() ([]AgentOutput, error) {
+	return l.each(func(ctx context.Context, i int) (*AgentOutput, error) {
+		return l.pipeline.analyzeAgent(ctx, l.analysis, i)
+	})
+}
+
+func (l *localSteps) revise(round int, outputs []AgentOutput) ([]AgentOutput, error) {
+	return l.each(func(ctx context.Context, i int) (*AgentOutput, error) {
+		return l.pipeline.reviseAgent(ctx, l.analysis, outputs, i, round)
+	})
+}
+
+func (l *localSteps) critique(outputs []AgentOutput) (*AgentOutput, error) {
+	return l.pipeline.critique(l.ctx, l.analysis, outputs)
+}
+
+func (l *localSteps) aggregate(outputs []AgentOutput) (*AgentOutput, error) {
+	return l.pipeline.aggregate(l.ctx, outputs)
+}
+
+// each runs the step for every agent of the pipeline
+func (l *localSteps) each(step func(ctx context.Context, i int) (*AgentOutput, error)) ([]AgentOutput, error) {
+	outputs := make([]AgentOutput, len(l.pipeline.agents))
+	g, gctx := errgroup.WithContext(l.ctx)
+	for i := range l.pipeline.agents {
+		g.Go(func() error {
+			output, err := step(gctx, i)
+			if err != nil {
+				return err
+			}
+			outputs[i] = *output
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	return outputs, nil
+}
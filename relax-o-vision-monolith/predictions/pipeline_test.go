@@ -0,0 +1,242 @@
+package predictions
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/edd/relaxovisionmonolith/predictions/providers"
+)
+
+func TestBuildPipeline_Models(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		config  PipelineConfig
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name:   "OpenAI by default",
+			config: PipelineConfig{Name: "default", Agents: []AgentConfig{{Type: AgentTypeForm}}, DebateRounds: 1},
+			want: map[string]string{
+				AgentTypeForm:       agentModel,
+				AgentTypeCritic:     agentModel,
+				AgentTypeAggregator: agentModel,
+			},
+		},
+		{
+			name: "configured providers",
+			config: PipelineConfig{
+				Name:         "providers",
+				Agents:       []AgentConfig{{Type: AgentTypeElo}},
+				DebateRounds: 1,
+				Aggregator:   ModelConfig{Providers: []string{"fake"}},
+				Critic:       ModelConfig{Providers: []string{"fake"}, ProviderWeights: map[string]float64{"fake": 2}},
+			},
+			want: map[string]string{
+				AgentTypeElo:        AgentTypeElo,
+				AgentTypeCritic:     "fake",
+				AgentTypeAggregator: "fake",
+			},
+		},
+		{
+			name: "unknown critic provider",
+			config: PipelineConfig{
+				Name:         "unknown",
+				Agents:       []AgentConfig{{Type: AgentTypeElo}},
+				DebateRounds: 1,
+				Critic:       ModelConfig{Providers: []string{"missing"}},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := NewService(nil, "key")
+			s.SetProviders([]providers.LLMProvider{&sequenceProvider{}})
+
+			p, err := s.buildPipeline(tt.config)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("buildPipeline() succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("buildPipeline() error = %v", err)
+			}
+
+			got := p.models()
+			if len(got) != len(tt.want) {
+				t.Fatalf("models = %v, want %v", got, tt.want)
+			}
+			for agent, model := range tt.want {
+				if got[agent] != model {
+					t.Errorf("%s model = %q, want %q", agent, got[agent], model)
+				}
+			}
+		})
+	}
+}
+
+// fixedAgent answers with its output from the table, and fails when it has none
+type fixedAgent struct {
+	name    string
+	outputs map[string]AgentOutput
+}
+
+func (a *fixedAgent) Type() string  { return a.name }
+func (a *fixedAgent) Model() string { return "fixed" }
+
+func (a *fixedAgent) Analyze(ctx context.Context, analysis *MatchAnalysis) (*AgentOutput, error) {
+	output, ok := a.outputs[a.name]
+	if !ok {
+		return nil, fmt.Errorf("no output for %s", a.name)
+	}
+	output.AgentType = a.name
+	return &output, nil
+}
+
+// activityResult is a completed activity, decoded as the workflow runtime would
+type activityResult struct {
+	output *AgentOutput
+	err    error
+}
+
+func (r activityResult) Await(v any) error {
+	if r.err != nil {
+		return r.err
+	}
+	data, err := json.Marshal(r.output)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// serviceActivities runs the workflow's activities on the service
+func serviceActivities(s *Service) func(activity string, input AgentActivityInput) awaitable {
+	return func(activity string, input AgentActivityInput) awaitable {
+		run := map[string]func(context.Context, AgentActivityInput) (*AgentOutput, error){
+			AnalyzeAgentActivity:      s.AnalyzeWithAgent,
+			ReviseAgentActivity:       s.ReviseWithAgent,
+			CritiqueActivity:          s.CritiqueOutputs,
+			AggregateAnalysisActivity: s.AggregateOutputs,
+		}[activity]
+		output, err := run(context.Background(), input)
+		return activityResult{output: output, err: err}
+	}
+}
+
+func TestExecutePipeline(t *testing.T) {
+	t.Parallel()
+
+	outputs := map[string]AgentOutput{
+		"first":  {HomeWinProb: 0.5, DrawProb: 0.3, AwayWinProb: 0.2, Confidence: 0.5},
+		"second": {HomeWinProb: 0.3, DrawProb: 0.3, AwayWinProb: 0.4, Confidence: 0.4},
+	}
+
+	tests := []struct {
+		name     string
+		config   PipelineConfig
+		wantHome float64
+		wantErr  bool
+	}{
+		{
+			name: "mean of the agents",
+			config: PipelineConfig{
+				Name:        "mean",
+				Agents:      []AgentConfig{{Type: "fixed", Name: "first", Weight: 3}, {Type: "fixed", Name: "second"}},
+				Aggregation: AggregationMean,
+			},
+			wantHome: 0.45,
+		},
+		{
+			// The agents cannot revise, and the critic's 0.4 counts once in the mean
+			name: "debate",
+			config: PipelineConfig{
+				Name:         "debate",
+				Agents:       []AgentConfig{{Type: "fixed", Name: "first"}, {Type: "fixed", Name: "second"}},
+				Aggregation:  AggregationMean,
+				DebateRounds: 2,
+				Critic:       ModelConfig{Providers: []string{"fake"}},
+			},
+			wantHome: 0.4,
+		},
+		{
+			name: "failed agent",
+			config: PipelineConfig{
+				Name:        "failing",
+				Agents:      []AgentConfig{{Type: "fixed", Name: "first"}, {Type: "fixed", Name: "missing"}},
+				Aggregation: AggregationMean,
+			},
+			wantErr: true,
+		},
+	}
+
+	steps := map[string]func(s *Service, p *pipeline) pipelineSteps{
+		"local": func(s *Service, p *pipeline) pipelineSteps {
+			return &localSteps{ctx: context.Background(), pipeline: p, analysis: &MatchAnalysis{}}
+		},
+		"workflow": func(s *Service, p *pipeline) pipelineSteps {
+			return &workflowSteps{call: serviceActivities(s), input: PipelineInput{Pipeline: p.config}}
+		},
+	}
+
+	for _, tt := range tests {
+		for stepsName, newSteps := range steps {
+			t.Run(tt.name+"/"+stepsName, func(t *testing.T) {
+				t.Parallel()
+
+				s := NewService(nil, "key")
+				s.SetProviders([]providers.LLMProvider{&sequenceProvider{results: []providers.AnalysisResult{
+					{HomeWinProb: 0.4, DrawProb: 0.3, AwayWinProb: 0.3, Confidence: 0.5},
+				}}})
+				s.Registry().RegisterAgent("fixed", func(_ AgentDeps, cfg AgentConfig) (Agent, error) {
+					return &fixedAgent{name: cfg.name(), outputs: outputs}, nil
+				})
+
+				p, err := s.buildPipeline(tt.config)
+				if err != nil {
+					t.Fatalf("buildPipeline() error = %v", err)
+				}
+
+				run, err := executePipeline(p.config, newSteps(s, p))
+				if tt.wantErr {
+					if err == nil {
+						t.Fatalf("executePipeline() = %+v, want an error", run.final)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("executePipeline() error = %v", err)
+				}
+
+				if len(run.outputs) != len(tt.config.Agents) {
+					t.Fatalf("got %d agent outputs, want %d", len(run.outputs), len(tt.config.Agents))
+				}
+				for i, agent := range tt.config.Agents {
+					if run.outputs[i].AgentType != agent.Name {
+						t.Errorf("output %d from %s, want %s", i, run.outputs[i].AgentType, agent.Name)
+					}
+				}
+				if !almostEqual(run.final.HomeWinProb, tt.wantHome) {
+					t.Errorf("home win = %v, want %v", run.final.HomeWinProb, tt.wantHome)
+				}
+				if tt.config.DebateRounds > 0 {
+					if run.debate == nil || len(run.debate.Rounds) != tt.config.DebateRounds+1 || run.debate.Critique == nil {
+						t.Errorf("debate = %+v, want the opening round, %d revisions and a critique", run.debate, tt.config.DebateRounds)
+					}
+				} else if run.debate != nil {
+					t.Errorf("debate = %+v, want none", run.debate)
+				}
+			})
+		}
+	}
+}
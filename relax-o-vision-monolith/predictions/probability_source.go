@@ -0,0 +1,58 @@
+package predictions
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/edd/relaxovisionmonolith/footballdata"
+	"github.com/lib/pq"
+)
+
+// StoredProbabilitySource serves match probabilities from the latest completed
+// prediction of each match
+type StoredProbabilitySource struct {
+	db *sql.DB
+}
+
+// NewStoredProbabilitySource creates a new stored prediction probability source
+func NewStoredProbabilitySource(db *sql.DB) *StoredProbabilitySource {
+	return &StoredProbabilitySource{db: db}
+}
+
+// MatchProbabilities returns probabilities for the matches that have a completed prediction
+func (s *StoredProbabilitySource) MatchProbabilities(ctx context.Context, matchIDs []int) (map[int]footballdata.MatchProbabilities, error) {
+	query := `
+		SELECT DISTINCT ON (match_id) match_id, home_win_prob, draw_prob, away_win_prob
+		FROM predictions
+		WHERE match_id = ANY($1) AND status = 'completed'
+		ORDER BY match_id, created_at DESC
+	`
+
+	ids := make([]int64, len(matchIDs))
+	for i, id := range matchIDs {
+		ids[i] = int64(id)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query predictions: %w", err)
+	}
+	defer rows.Close()
+
+	probabilities := make(map[int]footballdata.MatchProbabilities)
+	for rows.Next() {
+		var matchID int
+		var probs footballdata.MatchProbabilities
+		if err := rows.Scan(&matchID, &probs.HomeWin, &probs.Draw, &probs.AwayWin); err != nil {
+			return nil, fmt.Errorf("failed to scan prediction: %w", err)
+		}
+		probabilities[matchID] = probs
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate predictions: %w", err)
+	}
+
+	return probabilities, nil
+}
@@ -9,11 +9,15 @@ import (
 	"net/http"
 )
 
+// claudeBaseURL is the Anthropic API endpoint
+const claudeBaseURL = "https://api.anthropic.com/v1"
+
 // ClaudeProvider implements LLMProvider for Anthropic Claude
 type ClaudeProvider struct {
-	apiKey string
-	model  string
-	client *http.Client
+	apiKey  string
+	model   string
+	baseURL string
+	client  *http.Client
 }
 
 // NewClaudeProvider creates a new Claude provider
@@ -22,9 +26,10 @@ func NewClaudeProvider(apiKey, model string) *ClaudeProvider {
 		model = "claude-3-5-sonnet-20241022" // Latest Claude 3.5 Sonnet
 	}
 	return &ClaudeProvider{
-		apiKey: apiKey,
-		model:  model,
-		client: &http.Client{},
+		apiKey:  apiKey,
+		model:   model,
+		baseURL: claudeBaseURL,
+		client:  &http.Client{},
 	}
 }
 
@@ -35,26 +40,11 @@ func (p *ClaudeProvider) Name() string {
 
 // Analyze performs analysis using Claude
 func (p *ClaudeProvider) Analyze(ctx context.Context, prompt string, data interface{}) (*AnalysisResult, error) {
-	dataJSON, err := json.MarshalIndent(data, "", "  ")
+	fullPrompt, err := AnalysisPrompt(prompt, data)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal data: %w", err)
+		return nil, err
 	}
 
-	fullPrompt := fmt.Sprintf(`%s
-
-Data:
-%s
-
-Provide your analysis in JSON format:
-{
-  "homeWinProb": <0-1>,
-  "drawProb": <0-1>,
-  "awayWinProb": <0-1>,
-  "confidence": <0-1>,
-  "reasoning": "<explanation>",
-  "keyFactors": ["factor1", "factor2", ...]
-}`, prompt, string(dataJSON))
-
 	requestBody := map[string]interface{}{
 		"model": p.model,
 		"max_tokens": 1024,
@@ -64,15 +54,126 @@ Provide your analysis in JSON format:
 				"content": fullPrompt,
 			},
 		},
-		"system": "You are an expert football analyst. Provide predictions based on the given data.",
+		"system": DefaultSystemPrompt,
 	}
 
+	claudeResp, err := p.createMessage(ctx, requestBody)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(claudeResp.Content) == 0 {
+		return nil, fmt.Errorf("no content in response")
+	}
+
+	return parseAnalysisResponse(claudeResp.Content[0].Text)
+}
+
+// AnalyzeWithTools performs analysis using Claude tool use. The model may
+// call tools for up to req.MaxRounds rounds before it must answer
+func (p *ClaudeProvider) AnalyzeWithTools(ctx context.Context, req ToolRequest) (*AnalysisResult, []ToolCall, error) {
+	tools := make([]map[string]interface{}, len(req.Tools))
+	for i, tool := range req.Tools {
+		tools[i] = map[string]interface{}{
+			"name":         tool.Name,
+			"description":  tool.Description,
+			"input_schema": tool.Parameters,
+		}
+	}
+
+	messages := []map[string]interface{}{
+		{
+			"role":    "user",
+			"content": req.Prompt,
+		},
+	}
+
+	var calls []ToolCall
+	for round := 1; ; round++ {
+		requestBody := map[string]interface{}{
+			"model":      p.model,
+			"max_tokens": 1024,
+			"messages":   messages,
+			"system":     req.System,
+			"tools":      tools,
+		}
+		if round > req.MaxRounds {
+			requestBody["tool_choice"] = map[string]string{"type": "none"}
+		}
+
+		claudeResp, err := p.createMessage(ctx, requestBody)
+		if err != nil {
+			return nil, calls, err
+		}
+
+		var text string
+		var toolUses []claudeContent
+		for _, block := range claudeResp.Content {
+			switch block.Type {
+			case "text":
+				text = block.Text
+			case "tool_use":
+				toolUses = append(toolUses, block)
+			}
+		}
+
+		if len(toolUses) == 0 || round > req.MaxRounds {
+			if text == "" {
+				return nil, calls, fmt.Errorf("no content in response")
+			}
+			result, err := parseAnalysisResponse(text)
+			return result, calls, err
+		}
+
+		results := make([]claudeContent, len(toolUses))
+		for i, toolUse := range toolUses {
+			call, result := callTool(ctx, req, ToolCall{
+				Provider:  p.Name(),
+				Round:     round,
+				Name:      toolUse.Name,
+				Arguments: toolUse.Input,
+			})
+			calls = append(calls, call)
+			results[i] = claudeContent{
+				Type:      "tool_result",
+				ToolUseID: toolUse.ID,
+				Content:   string(result),
+				IsError:   call.Error != "",
+			}
+		}
+
+		messages = append(messages,
+			map[string]interface{}{"role": "assistant", "content": claudeResp.Content},
+			map[string]interface{}{"role": "user", "content": results},
+		)
+	}
+}
+
+// claudeContent is a content block of a Claude message
+type claudeContent struct {
+	Type      string          `json:"type"`
+	Text      string          `json:"text,omitempty"`
+	ID        string          `json:"id,omitempty"`
+	Name      string          `json:"name,omitempty"`
+	Input     json.RawMessage `json:"input,omitempty"`
+	ToolUseID string          `json:"tool_use_id,omitempty"`
+	Content   string          `json:"content,omitempty"`
+	IsError   bool            `json:"is_error,omitempty"`
+}
+
+// claudeResponse is the part of a Messages API response the provider reads
+type claudeResponse struct {
+	Content    []claudeContent `json:"content"`
+}
+
+// createMessage sends a request to the Claude Messages API
+func (p *ClaudeProvider) createMessage(ctx context.Context, requestBody map[string]interface{}) (*claudeResponse, error) {
 	bodyBytes, err := json.Marshal(requestBody)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.anthropic.com/v1/messages", bytes.NewBuffer(bodyBytes))
+	req, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/messages", bytes.NewBuffer(bodyBytes))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -92,21 +193,12 @@ Provide your analysis in JSON format:
 		return nil, fmt.Errorf("claude api returned status %d: %s", resp.StatusCode, string(body))
 	}
 
-	var claudeResp struct {
-		Content []struct {
-			Text string `json:"text"`
-		} `json:"content"`
-	}
-
+	var claudeResp claudeResponse
 	if err := json.NewDecoder(resp.Body).Decode(&claudeResp); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	if len(claudeResp.Content) == 0 {
-		return nil, fmt.Errorf("no content in response")
-	}
-
-	return parseAnalysisResponse(claudeResp.Content[0].Text)
+	return &claudeResp, nil
 }
 
 // GenerateEmbedding generates an embedding using Claude's embeddings API
@@ -0,0 +1,91 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newClaudeModel fakes the Messages API
+func newClaudeModel(toolRounds int) *fakeModel {
+	return &fakeModel{
+		toolRounds: toolRounds,
+		parse: func(t *testing.T, body []byte) modelRequest {
+			var req struct {
+				ToolChoice struct {
+					Type string `json:"type"`
+				} `json:"tool_choice"`
+				Messages []struct {
+					Role    string          `json:"role"`
+					Content json.RawMessage `json:"content"`
+				} `json:"messages"`
+			}
+			if err := json.Unmarshal(body, &req); err != nil {
+				t.Errorf("failed to decode messages request: %v", err)
+			}
+			parsed := modelRequest{forced: req.ToolChoice.Type == "none"}
+			for _, message := range req.Messages {
+				var blocks []claudeContent
+				if json.Unmarshal(message.Content, &blocks) != nil {
+					continue // the prompt is a plain string
+				}
+				for _, block := range blocks {
+					if block.Type == "tool_result" {
+						parsed.toolResults = append(parsed.toolResults, block.Content)
+						if failed := block.Content != testToolResult; block.IsError != failed {
+							t.Errorf("tool result is_error = %v, want %v", block.IsError, failed)
+						}
+					}
+				}
+			}
+			return parsed
+		},
+		toolCall: func(w http.ResponseWriter) {
+			fmt.Fprint(w, `{"content":[{"type":"text","text":"Checking the form"},
+				{"type":"tool_use","id":"toolu_1","name":"get_team_form","input":{"teamId":65}}]}`)
+		},
+		answer: func(w http.ResponseWriter) {
+			text, _ := json.Marshal(testAnswer)
+			fmt.Fprintf(w, `{"content":[{"type":"text","text":%s}]}`, text)
+		},
+	}
+}
+
+func TestClaudeProvider_AnalyzeWithTools(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range toolLoopTests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			model := newClaudeModel(tt.toolRounds)
+			server := httptest.NewServer(model.handler(t))
+			defer server.Close()
+
+			provider := NewClaudeProvider("test-key", "")
+			provider.baseURL = server.URL
+
+			result, calls, err := provider.AnalyzeWithTools(context.Background(), tt.toolRequest(t))
+			checkToolLoop(t, tt, model, result, calls, err)
+		})
+	}
+}
+
+func TestClaudeProvider_AnalyzeWithTools_APIError(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"type":"error","error":{"type":"overloaded_error"}}`, 529)
+	}))
+	defer server.Close()
+
+	provider := NewClaudeProvider("test-key", "")
+	provider.baseURL = server.URL
+
+	if _, _, err := provider.AnalyzeWithTools(context.Background(), toolLoopTests[0].toolRequest(t)); err == nil {
+		t.Error("expected an error")
+	}
+}
@@ -9,11 +9,15 @@ import (
 	"net/http"
 )
 
+// geminiBaseURL is the Gemini API endpoint
+const geminiBaseURL = "https://generativelanguage.googleapis.com/v1beta"
+
 // GeminiProvider implements LLMProvider for Google Gemini
 type GeminiProvider struct {
-	apiKey string
-	model  string
-	client *http.Client
+	apiKey  string
+	model   string
+	baseURL string
+	client  *http.Client
 }
 
 // NewGeminiProvider creates a new Gemini provider
@@ -22,9 +26,10 @@ func NewGeminiProvider(apiKey, model string) *GeminiProvider {
 		model = "gemini-1.5-pro" // Default to Gemini 1.5 Pro
 	}
 	return &GeminiProvider{
-		apiKey: apiKey,
-		model:  model,
-		client: &http.Client{},
+		apiKey:  apiKey,
+		model:   model,
+		baseURL: geminiBaseURL,
+		client:  &http.Client{},
 	}
 }
 
@@ -35,27 +40,11 @@ func (p *GeminiProvider) Name() string {
 
 // Analyze performs analysis using Gemini
 func (p *GeminiProvider) Analyze(ctx context.Context, prompt string, data interface{}) (*AnalysisResult, error) {
-	dataJSON, err := json.MarshalIndent(data, "", "  ")
+	fullPrompt, err := AnalysisPrompt(prompt, data)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal data: %w", err)
+		return nil, err
 	}
-
-	fullPrompt := fmt.Sprintf(`You are an expert football analyst. Provide predictions based on the given data.
-
-%s
-
-Data:
-%s
-
-Provide your analysis in JSON format:
-{
-  "homeWinProb": <0-1>,
-  "drawProb": <0-1>,
-  "awayWinProb": <0-1>,
-  "confidence": <0-1>,
-  "reasoning": "<explanation>",
-  "keyFactors": ["factor1", "factor2", ...]
-}`, prompt, string(dataJSON))
+	fullPrompt = DefaultSystemPrompt + "\n\n" + fullPrompt
 
 	requestBody := map[string]interface{}{
 		"contents": []map[string]interface{}{
@@ -73,12 +62,154 @@ Provide your analysis in JSON format:
 		},
 	}
 
+	geminiResp, err := p.generateContent(ctx, requestBody)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(geminiResp.Candidates) == 0 || len(geminiResp.Candidates[0].Content.Parts) == 0 {
+		return nil, fmt.Errorf("no content in response")
+	}
+
+	return parseAnalysisResponse(geminiResp.Candidates[0].Content.Parts[0].Text)
+}
+
+// AnalyzeWithTools performs analysis using Gemini function calling. The
+// model may call tools for up to req.MaxRounds rounds before it must answer
+func (p *GeminiProvider) AnalyzeWithTools(ctx context.Context, req ToolRequest) (*AnalysisResult, []ToolCall, error) {
+	declarations := geminiDeclarations(req.Tools)
+
+	contents := []geminiContent{
+		{
+			Role:  "user",
+			Parts: []geminiPart{{Text: req.Prompt}},
+		},
+	}
+
+	var calls []ToolCall
+	for round := 1; ; round++ {
+		mode := "AUTO"
+		if round > req.MaxRounds {
+			mode = "NONE"
+		}
+		requestBody := map[string]interface{}{
+			"systemInstruction": geminiContent{Parts: []geminiPart{{Text: req.System}}},
+			"contents":          contents,
+			"tools":             []map[string]interface{}{{"functionDeclarations": declarations}},
+			"toolConfig": map[string]interface{}{
+				"functionCallingConfig": map[string]string{"mode": mode},
+			},
+			"generationConfig": map[string]interface{}{
+				"temperature":     0.7,
+				"maxOutputTokens": 1024,
+			},
+		}
+
+		geminiResp, err := p.generateContent(ctx, requestBody)
+		if err != nil {
+			return nil, calls, err
+		}
+		if len(geminiResp.Candidates) == 0 {
+			return nil, calls, fmt.Errorf("no content in response")
+		}
+
+		content := geminiResp.Candidates[0].Content
+		var text string
+		var functionCalls []*geminiFunctionCall
+		for _, part := range content.Parts {
+			if part.FunctionCall != nil {
+				functionCalls = append(functionCalls, part.FunctionCall)
+			} else if part.Text != "" {
+				text = part.Text
+			}
+		}
+
+		if len(functionCalls) == 0 || round > req.MaxRounds {
+			if text == "" {
+				return nil, calls, fmt.Errorf("no content in response")
+			}
+			result, err := parseAnalysisResponse(text)
+			return result, calls, err
+		}
+
+		responses := make([]geminiPart, len(functionCalls))
+		for i, functionCall := range functionCalls {
+			call, result := callTool(ctx, req, ToolCall{
+				Provider:  p.Name(),
+				Round:     round,
+				Name:      functionCall.Name,
+				Arguments: functionCall.Args,
+			})
+			calls = append(calls, call)
+			// Gemini expects the response to be an object
+			responses[i] = geminiPart{FunctionResponse: &geminiFunctionResponse{
+				Name:     functionCall.Name,
+				Response: map[string]json.RawMessage{"result": result},
+			}}
+		}
+
+		content.Role = "model"
+		contents = append(contents, content, geminiContent{Role: "user", Parts: responses})
+	}
+}
+
+// geminiDeclarations describes the tools as Gemini function declarations.
+// Gemini rejects an object schema without properties, so tools taking no
+// arguments are declared without parameters
+func geminiDeclarations(tools []Tool) []map[string]interface{} {
+	declarations := make([]map[string]interface{}, len(tools))
+	for i, tool := range tools {
+		declarations[i] = map[string]interface{}{
+			"name":        tool.Name,
+			"description": tool.Description,
+		}
+		if properties, _ := tool.Parameters["properties"].(map[string]any); len(properties) > 0 {
+			declarations[i]["parameters"] = tool.Parameters
+		}
+	}
+	return declarations
+}
+
+// geminiContent is a turn of a Gemini conversation
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []geminiPart `json:"parts"`
+}
+
+// geminiPart is a text, function call or function response part of a turn
+type geminiPart struct {
+	Text             string                  `json:"text,omitempty"`
+	FunctionCall     *geminiFunctionCall     `json:"functionCall,omitempty"`
+	FunctionResponse *geminiFunctionResponse `json:"functionResponse,omitempty"`
+	// ThoughtSignature is sent back with the call it came with, as thinking models require
+	ThoughtSignature string `json:"thoughtSignature,omitempty"`
+}
+
+type geminiFunctionCall struct {
+	Name string          `json:"name"`
+	Args json.RawMessage `json:"args,omitempty"`
+}
+
+type geminiFunctionResponse struct {
+	Name     string                     `json:"name"`
+	Response map[string]json.RawMessage `json:"response"`
+}
+
+// geminiResponse is the part of a generateContent response the provider reads
+type geminiResponse struct {
+	Candidates []struct {
+		Content geminiContent `json:"content"`
+	} `json:"candidates"`
+}
+
+// generateContent sends a request to the Gemini generateContent API
+func (p *GeminiProvider) generateContent(ctx context.Context, requestBody map[string]interface{}) (*geminiResponse, error) {
 	bodyBytes, err := json.Marshal(requestBody)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	url := fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models/%s:generateContent?key=%s", p.model, p.apiKey)
+	url := fmt.Sprintf("%s/models/%s:generateContent?key=%s", p.baseURL, p.model, p.apiKey)
 	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(bodyBytes))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
@@ -97,25 +228,12 @@ Provide your analysis in JSON format:
 		return nil, fmt.Errorf("gemini api returned status %d: %s", resp.StatusCode, string(body))
 	}
 
-	var geminiResp struct {
-		Candidates []struct {
-			Content struct {
-				Parts []struct {
-					Text string `json:"text"`
-				} `json:"parts"`
-			} `json:"content"`
-		} `json:"candidates"`
-	}
-
+	var geminiResp geminiResponse
 	if err := json.NewDecoder(resp.Body).Decode(&geminiResp); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	if len(geminiResp.Candidates) == 0 || len(geminiResp.Candidates[0].Content.Parts) == 0 {
-		return nil, fmt.Errorf("no content in response")
-	}
-
-	return parseAnalysisResponse(geminiResp.Candidates[0].Content.Parts[0].Text)
+	return &geminiResp, nil
 }
 
 // GenerateEmbedding generates an embedding using Gemini's embedding API
@@ -137,7 +255,7 @@ func (p *GeminiProvider) GenerateEmbedding(ctx context.Context, text string) ([]
 
 	// Note: Google's Gemini API uses API key in URL as per their official design
 	// This is the standard authentication method for their REST API
-	url := fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models/text-embedding-004:embedContent?key=%s", p.apiKey)
+	url := fmt.Sprintf("%s/models/text-embedding-004:embedContent?key=%s", p.baseURL, p.apiKey)
 	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(bodyBytes))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
@@ -0,0 +1,110 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newGeminiModel fakes the generateContent API
+func newGeminiModel(toolRounds int) *fakeModel {
+	return &fakeModel{
+		toolRounds: toolRounds,
+		parse: func(t *testing.T, body []byte) modelRequest {
+			var req struct {
+				Contents   []geminiContent `json:"contents"`
+				ToolConfig struct {
+					FunctionCallingConfig struct {
+						Mode string `json:"mode"`
+					} `json:"functionCallingConfig"`
+				} `json:"toolConfig"`
+			}
+			if err := json.Unmarshal(body, &req); err != nil {
+				t.Errorf("failed to decode generateContent request: %v", err)
+			}
+			parsed := modelRequest{forced: req.ToolConfig.FunctionCallingConfig.Mode == "NONE"}
+			for _, content := range req.Contents {
+				for _, part := range content.Parts {
+					if part.FunctionResponse != nil {
+						parsed.toolResults = append(parsed.toolResults, string(part.FunctionResponse.Response["result"]))
+					}
+				}
+			}
+			return parsed
+		},
+		toolCall: func(w http.ResponseWriter) {
+			fmt.Fprint(w, `{"candidates":[{"content":{"role":"model","parts":[
+				{"functionCall":{"name":"get_team_form","args":{"teamId":65}},"thoughtSignature":"sig"}]}}]}`)
+		},
+		answer: func(w http.ResponseWriter) {
+			text, _ := json.Marshal(testAnswer)
+			fmt.Fprintf(w, `{"candidates":[{"content":{"role":"model","parts":[{"text":%s}]}}]}`, text)
+		},
+	}
+}
+
+func TestGeminiProvider_AnalyzeWithTools(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range toolLoopTests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			model := newGeminiModel(tt.toolRounds)
+			server := httptest.NewServer(model.handler(t))
+			defer server.Close()
+
+			provider := NewGeminiProvider("test-key", "")
+			provider.baseURL = server.URL
+
+			result, calls, err := provider.AnalyzeWithTools(context.Background(), tt.toolRequest(t))
+			checkToolLoop(t, tt, model, result, calls, err)
+		})
+	}
+}
+
+func TestGeminiProvider_AnalyzeWithTools_APIError(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":{"code":400,"status":"INVALID_ARGUMENT"}}`, http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	provider := NewGeminiProvider("test-key", "")
+	provider.baseURL = server.URL
+
+	if _, _, err := provider.AnalyzeWithTools(context.Background(), toolLoopTests[0].toolRequest(t)); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestGeminiDeclarations(t *testing.T) {
+	t.Parallel()
+
+	withArgs := map[string]any{
+		"type":       "object",
+		"properties": map[string]any{"teamId": map[string]any{"type": "integer"}},
+	}
+	tools := []Tool{
+		{Name: "get_team_form", Description: "Recent form", Parameters: withArgs},
+		{Name: "get_referee_profile", Description: "Referee profile", Parameters: map[string]any{"type": "object", "properties": map[string]any{}}},
+		{Name: "get_time", Description: "No schema"},
+	}
+
+	declarations := geminiDeclarations(tools)
+	if len(declarations) != len(tools) {
+		t.Fatalf("declarations = %d, want %d", len(declarations), len(tools))
+	}
+	if _, ok := declarations[0]["parameters"]; !ok {
+		t.Error("get_team_form declared without parameters")
+	}
+	for _, declaration := range declarations[1:] {
+		if parameters, ok := declaration["parameters"]; ok {
+			t.Errorf("%s declared with parameters %v, want none", declaration["name"], parameters)
+		}
+	}
+}
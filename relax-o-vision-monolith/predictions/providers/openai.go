@@ -32,32 +32,17 @@ func (p *OpenAIProvider) Name() string {
 
 // Analyze performs analysis using OpenAI
 func (p *OpenAIProvider) Analyze(ctx context.Context, prompt string, data interface{}) (*AnalysisResult, error) {
-	dataJSON, err := json.MarshalIndent(data, "", "  ")
+	fullPrompt, err := AnalysisPrompt(prompt, data)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal data: %w", err)
+		return nil, err
 	}
 
-	fullPrompt := fmt.Sprintf(`%s
-
-Data:
-%s
-
-Provide your analysis in JSON format:
-{
-  "homeWinProb": <0-1>,
-  "drawProb": <0-1>,
-  "awayWinProb": <0-1>,
-  "confidence": <0-1>,
-  "reasoning": "<explanation>",
-  "keyFactors": ["factor1", "factor2", ...]
-}`, prompt, string(dataJSON))
-
 	resp, err := p.client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
 		Model: p.model,
 		Messages: []openai.ChatCompletionMessage{
 			{
 				Role:    openai.ChatMessageRoleSystem,
-				Content: "You are an expert football analyst. Provide predictions based on the given data.",
+				Content: DefaultSystemPrompt,
 			},
 			{
 				Role:    openai.ChatMessageRoleUser,
@@ -73,6 +58,76 @@ Provide your analysis in JSON format:
 	return parseAnalysisResponse(resp.Choices[0].Message.Content)
 }
 
+// AnalyzeWithTools performs analysis using OpenAI function calling. The
+// model may call tools for up to req.MaxRounds rounds before it must answer
+func (p *OpenAIProvider) AnalyzeWithTools(ctx context.Context, req ToolRequest) (*AnalysisResult, []ToolCall, error) {
+	tools := make([]openai.Tool, len(req.Tools))
+	for i, tool := range req.Tools {
+		tools[i] = openai.Tool{
+			Type: openai.ToolTypeFunction,
+			Function: &openai.FunctionDefinition{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  tool.Parameters,
+			},
+		}
+	}
+
+	messages := []openai.ChatCompletionMessage{
+		{
+			Role:    openai.ChatMessageRoleSystem,
+			Content: req.System,
+		},
+		{
+			Role:    openai.ChatMessageRoleUser,
+			Content: req.Prompt,
+		},
+	}
+
+	var calls []ToolCall
+	for round := 1; ; round++ {
+		completion := openai.ChatCompletionRequest{
+			Model:       p.model,
+			Messages:    messages,
+			Tools:       tools,
+			Temperature: 0.7,
+		}
+		if round > req.MaxRounds {
+			completion.ToolChoice = "none"
+		}
+
+		resp, err := p.client.CreateChatCompletion(ctx, completion)
+		if err != nil {
+			return nil, calls, fmt.Errorf("openai api error: %w", err)
+		}
+		if len(resp.Choices) == 0 {
+			return nil, calls, fmt.Errorf("no completion returned")
+		}
+
+		message := resp.Choices[0].Message
+		if len(message.ToolCalls) == 0 || round > req.MaxRounds {
+			result, err := parseAnalysisResponse(message.Content)
+			return result, calls, err
+		}
+
+		messages = append(messages, message)
+		for _, toolCall := range message.ToolCalls {
+			call, result := callTool(ctx, req, ToolCall{
+				Provider:  p.Name(),
+				Round:     round,
+				Name:      toolCall.Function.Name,
+				Arguments: json.RawMessage(toolCall.Function.Arguments),
+			})
+			calls = append(calls, call)
+			messages = append(messages, openai.ChatCompletionMessage{
+				Role:       openai.ChatMessageRoleTool,
+				Content:    string(result),
+				ToolCallID: toolCall.ID,
+			})
+		}
+	}
+}
+
 // GenerateEmbedding generates an embedding using OpenAI
 func (p *OpenAIProvider) GenerateEmbedding(ctx context.Context, text string) ([]float32, error) {
 	resp, err := p.client.CreateEmbeddings(ctx, openai.EmbeddingRequest{
@@ -0,0 +1,78 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// newOpenAIModel fakes the Chat Completions API
+func newOpenAIModel(toolRounds int) *fakeModel {
+	return &fakeModel{
+		toolRounds: toolRounds,
+		parse: func(t *testing.T, body []byte) modelRequest {
+			var req openai.ChatCompletionRequest
+			if err := json.Unmarshal(body, &req); err != nil {
+				t.Errorf("failed to decode chat completion request: %v", err)
+			}
+			parsed := modelRequest{forced: req.ToolChoice == "none"}
+			for _, message := range req.Messages {
+				if message.Role == openai.ChatMessageRoleTool {
+					parsed.toolResults = append(parsed.toolResults, message.Content)
+				}
+			}
+			return parsed
+		},
+		toolCall: func(w http.ResponseWriter) {
+			fmt.Fprint(w, `{"choices":[{"index":0,"finish_reason":"tool_calls","message":{"role":"assistant",
+				"tool_calls":[{"id":"call_1","type":"function","function":{"name":"get_team_form","arguments":"{\"teamId\":65}"}}]}}]}`)
+		},
+		answer: func(w http.ResponseWriter) {
+			content, _ := json.Marshal(testAnswer)
+			fmt.Fprintf(w, `{"choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":%s}}]}`, content)
+		},
+	}
+}
+
+func TestOpenAIProvider_AnalyzeWithTools(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range toolLoopTests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			model := newOpenAIModel(tt.toolRounds)
+			server := httptest.NewServer(model.handler(t))
+			defer server.Close()
+
+			config := openai.DefaultConfig("test-key")
+			config.BaseURL = server.URL
+			provider := &OpenAIProvider{client: openai.NewClientWithConfig(config), model: openai.GPT4}
+
+			result, calls, err := provider.AnalyzeWithTools(context.Background(), tt.toolRequest(t))
+			checkToolLoop(t, tt, model, result, calls, err)
+		})
+	}
+}
+
+func TestOpenAIProvider_AnalyzeWithTools_APIError(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":{"message":"rate limited"}}`, http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	config := openai.DefaultConfig("test-key")
+	config.BaseURL = server.URL
+	provider := &OpenAIProvider{client: openai.NewClientWithConfig(config), model: openai.GPT4}
+
+	if _, _, err := provider.AnalyzeWithTools(context.Background(), toolLoopTests[0].toolRequest(t)); err == nil {
+		t.Error("expected an error")
+	}
+}
@@ -30,6 +30,9 @@ type AnalysisResult struct {
 	Reasoning   string             `json:"reasoning"`
 	KeyFactors  []string           `json:"keyFactors"`
 	Metadata    map[string]any     `json:"metadata,omitempty"`
+	// Expected goals per side; optional, so nil when the model left them out
+	HomeExpectedGoals *float64 `json:"homeExpectedGoals,omitempty"`
+	AwayExpectedGoals *float64 `json:"awayExpectedGoals,omitempty"`
 }
 
 // ProviderFactory creates LLM providers based on configuration
@@ -0,0 +1,96 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// DefaultSystemPrompt is the system prompt providers analyze with
+const DefaultSystemPrompt = "You are an expert football analyst. Provide predictions based on the given data."
+
+// Tool is a function the model may call while analyzing
+type Tool struct {
+	Name        string
+	Description string
+	Parameters  map[string]any // JSON Schema of the arguments object
+}
+
+// ToolHandler runs a tool call and returns its result as JSON
+type ToolHandler func(ctx context.Context, name string, arguments json.RawMessage) (json.RawMessage, error)
+
+// ToolCall records a tool call made during an analysis, for auditing
+type ToolCall struct {
+	Provider  string          `json:"provider"`
+	Round     int             `json:"round"`
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments"`
+	Result    json.RawMessage `json:"result,omitempty"`
+	Error     string          `json:"error,omitempty"`
+}
+
+// ToolRequest is an analysis in which the model may call tools before answering
+type ToolRequest struct {
+	System string
+	// Prompt is the complete prompt, including the data and response format
+	Prompt  string
+	Tools   []Tool
+	Handler ToolHandler
+	// MaxRounds bounds the rounds of tool calls; the model must answer after the last
+	MaxRounds int
+}
+
+// ToolCaller is implemented by providers with native function calling
+type ToolCaller interface {
+	AnalyzeWithTools(ctx context.Context, req ToolRequest) (*AnalysisResult, []ToolCall, error)
+}
+
+// AnalysisPrompt appends the data and the expected response format to a prompt
+func AnalysisPrompt(prompt string, data interface{}) (string, error) {
+	dataJSON, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal data: %w", err)
+	}
+
+	return fmt.Sprintf(`%s
+
+Data:
+%s
+
+Provide your analysis in JSON format:
+{
+  "homeWinProb": <0-1>,
+  "drawProb": <0-1>,
+  "awayWinProb": <0-1>,
+  "homeExpectedGoals": <goals the home side is expected to score>,
+  "awayExpectedGoals": <goals the away side is expected to score>,
+  "confidence": <0-1>,
+  "reasoning": "<explanation>",
+  "keyFactors": ["factor1", "factor2", ...]
+}`, prompt, string(dataJSON)), nil
+}
+
+// callTool runs one tool call through the handler and records it. Failures
+// are returned to the model as an error object so it can carry on
+func callTool(ctx context.Context, req ToolRequest, call ToolCall) (ToolCall, json.RawMessage) {
+	if len(call.Arguments) == 0 {
+		call.Arguments = json.RawMessage(`{}`)
+	}
+	if !json.Valid(call.Arguments) {
+		// Kept as a string so the record still marshals
+		call.Arguments, _ = json.Marshal(string(call.Arguments))
+		call.Error = "arguments are not valid JSON"
+		result, _ := json.Marshal(map[string]string{"error": call.Error})
+		return call, result
+	}
+
+	result, err := req.Handler(ctx, call.Name, call.Arguments)
+	if err != nil {
+		call.Error = err.Error()
+		result, _ = json.Marshal(map[string]string{"error": call.Error})
+		return call, result
+	}
+
+	call.Result = result
+	return call, result
+}
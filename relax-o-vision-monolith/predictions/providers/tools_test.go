@@ -0,0 +1,168 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// testAnswer is the analysis the fake models answer with
+const testAnswer = `{"homeWinProb":0.5,"drawProb":0.3,"awayWinProb":0.2,"confidence":0.7,"reasoning":"home form","keyFactors":["form"]}`
+
+// testToolResult is what the tool handler returns when it succeeds
+const testToolResult = `{"formScore":0.8}`
+
+// toolLoopTest is a tool loop case every provider is run through
+type toolLoopTest struct {
+	name      string
+	maxRounds int
+	// toolRounds is how many requests the fake model answers with a tool
+	// call, unless the request forbids tool calls
+	toolRounds   int
+	handlerErr   error
+	wantRequests int
+	wantCalls    int
+	// wantForced is whether the last request forbids tool calls
+	wantForced bool
+}
+
+var toolLoopTests = []toolLoopTest{
+	{name: "answer without tools", maxRounds: 3, toolRounds: 0, wantRequests: 1},
+	{name: "answer after tool rounds", maxRounds: 3, toolRounds: 2, wantRequests: 3, wantCalls: 2},
+	{name: "round cap forces the answer", maxRounds: 2, toolRounds: 10, wantRequests: 3, wantCalls: 2, wantForced: true},
+	{name: "no rounds allowed", maxRounds: 0, toolRounds: 10, wantRequests: 1, wantForced: true},
+	{name: "tool error returned to the model", maxRounds: 3, toolRounds: 1, handlerErr: errors.New("team not found"),
+		wantRequests: 2, wantCalls: 1},
+}
+
+// modelRequest is what a fake model server read from one request
+type modelRequest struct {
+	forced bool
+	// toolResults are the tool results sent back to the model so far
+	toolResults []string
+}
+
+// fakeModel serves a provider's API: it calls get_team_form while it has
+// tool rounds left and tool calls are allowed, then answers
+type fakeModel struct {
+	toolRounds int
+	// parse reads a request's body; toolCall and answer write a response
+	parse    func(t *testing.T, body []byte) modelRequest
+	toolCall func(w http.ResponseWriter)
+	answer   func(w http.ResponseWriter)
+
+	mu       sync.Mutex
+	requests []modelRequest
+}
+
+func (m *fakeModel) handler(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var body json.RawMessage
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		req := m.parse(t, body)
+
+		m.mu.Lock()
+		m.requests = append(m.requests, req)
+		n := len(m.requests)
+		m.mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		if !req.forced && n <= m.toolRounds {
+			m.toolCall(w)
+			return
+		}
+		m.answer(w)
+	}
+}
+
+// toolRequest is the analysis each case runs, with a handler for get_team_form
+func (tt toolLoopTest) toolRequest(t *testing.T) ToolRequest {
+	return ToolRequest{
+		System: DefaultSystemPrompt,
+		Prompt: "Predict the match",
+		Tools: []Tool{{
+			Name:        "get_team_form",
+			Description: "Recent form of a team",
+			Parameters: map[string]any{
+				"type":       "object",
+				"properties": map[string]any{"teamId": map[string]any{"type": "integer"}},
+				"required":   []string{"teamId"},
+			},
+		}},
+		Handler: func(_ context.Context, name string, arguments json.RawMessage) (json.RawMessage, error) {
+			if name != "get_team_form" {
+				t.Errorf("tool = %q, want get_team_form", name)
+			}
+			var args struct {
+				TeamID int `json:"teamId"`
+			}
+			if err := json.Unmarshal(arguments, &args); err != nil || args.TeamID != 65 {
+				t.Errorf("arguments = %s, want teamId 65", arguments)
+			}
+			if tt.handlerErr != nil {
+				return nil, tt.handlerErr
+			}
+			return json.RawMessage(testToolResult), nil
+		},
+		MaxRounds: tt.maxRounds,
+	}
+}
+
+// checkToolLoop checks a provider's tool loop against the case
+func checkToolLoop(t *testing.T, tt toolLoopTest, model *fakeModel, result *AnalysisResult, calls []ToolCall, err error) {
+	t.Helper()
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.HomeWinProb != 0.5 || result.Reasoning != "home form" {
+		t.Errorf("result = %+v, want the model's answer", result)
+	}
+
+	if len(model.requests) != tt.wantRequests {
+		t.Fatalf("requests = %d, want %d", len(model.requests), tt.wantRequests)
+	}
+	for i, req := range model.requests {
+		last := i == len(model.requests)-1
+		if want := last && tt.wantForced; req.forced != want {
+			t.Errorf("request %d forced = %v, want %v", i+1, req.forced, want)
+		}
+		if len(req.toolResults) != i {
+			t.Errorf("request %d has %d tool results, want %d", i+1, len(req.toolResults), i)
+		}
+	}
+
+	if len(calls) != tt.wantCalls {
+		t.Fatalf("calls = %d, want %d", len(calls), tt.wantCalls)
+	}
+	for i, call := range calls {
+		if call.Round != i+1 || call.Name != "get_team_form" {
+			t.Errorf("call %d = round %d %s, want round %d get_team_form", i, call.Round, call.Name, i+1)
+		}
+
+		sent := model.requests[i+1].toolResults[i]
+		if tt.handlerErr != nil {
+			if call.Error != tt.handlerErr.Error() || call.Result != nil {
+				t.Errorf("call %d error = %q, result = %s, want error %q", i, call.Error, call.Result, tt.handlerErr)
+			}
+			if !strings.Contains(sent, tt.handlerErr.Error()) {
+				t.Errorf("tool result sent = %s, want the error", sent)
+			}
+			continue
+		}
+		if call.Error != "" || string(call.Result) != testToolResult {
+			t.Errorf("call %d result = %s, error = %q, want %s", i, call.Result, call.Error, testToolResult)
+		}
+		if !strings.Contains(sent, testToolResult) {
+			t.Errorf("tool result sent = %s, want %s", sent, testToolResult)
+		}
+	}
+}
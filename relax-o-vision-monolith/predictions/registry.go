@@ -0,0 +1,295 @@
+package predictions
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/edd/relaxovisionmonolith/footballdata"
+	"github.com/edd/relaxovisionmonolith/predictions/providers"
+)
+
+// Aggregation strategies
+const (
+	AggregationLLM  = "llm"
+	AggregationMean = "mean"
+)
+
+// eloDrawRate is the draw probability the Elo agent gives evenly rated teams
+const eloDrawRate = 0.28
+
+// AgentDeps are what agent factories build agents from
+type AgentDeps struct {
+	DB        *sql.DB
+	OpenAIKey string
+	// Providers are the LLM providers agents can be configured to ask, by name
+	Providers map[string]providers.LLMProvider
+	// Tools lets analysts query match data; nil unless tools are enabled
+	Tools *MatchTools
+	// Samples is how many answers an agent asking a single model averages
+	Samples int
+}
+
+// NewLLMAgent creates an LLM agent labelled with the configured name, asking
+// the configured providers
+func (d AgentDeps) NewLLMAgent(cfg AgentConfig) (*LLMAgent, error) {
+	agent := NewLLMAgent(cfg.name(), d.OpenAIKey)
+	agent.samples = d.Samples
+	agent.tools = d.Tools
+	agent.weights = cfg.ProviderWeights
+
+	for _, name := range cfg.Providers {
+		p, ok := d.Providers[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown provider %s", name)
+		}
+		agent.providers = append(agent.providers, p)
+	}
+
+	return agent, nil
+}
+
+// AgentFactory builds an agent from its configuration in a pipeline
+type AgentFactory func(deps AgentDeps, cfg AgentConfig) (Agent, error)
+
+// AggregatorFactory builds the aggregator of a pipeline
+type AggregatorFactory func(deps AgentDeps, cfg PipelineConfig) (Aggregator, error)
+
+// Registry holds the agent types and aggregation strategies pipelines are
+// configured from. Register them at startup, before predictions run
+type Registry struct {
+	agents      map[string]AgentFactory
+	aggregators map[string]AggregatorFactory
+}
+
+// NewRegistry creates a registry with the built-in agent types and
+// aggregation strategies
+func NewRegistry() *Registry {
+	r := &Registry{
+		agents:      make(map[string]AgentFactory),
+		aggregators: make(map[string]AggregatorFactory),
+	}
+
+	r.RegisterAgent(AgentTypeStatistical, llmAgentFactory(func(a *LLMAgent) Agent { return &StatisticalAgent{LLMAgent: a} }))
+	r.RegisterAgent(AgentTypeForm, llmAgentFactory(func(a *LLMAgent) Agent { return &FormAgent{LLMAgent: a} }))
+	r.RegisterAgent(AgentTypeHeadToHead, llmAgentFactory(func(a *LLMAgent) Agent { return &HeadToHeadAgent{LLMAgent: a} }))
+	r.RegisterAgent(AgentTypePrompt, newPromptAgent)
+	r.RegisterAgent(AgentTypeElo, func(_ AgentDeps, cfg AgentConfig) (Agent, error) {
+		return &EloAgent{name: cfg.name()}, nil
+	})
+
+	r.RegisterAggregator(AggregationLLM, func(deps AgentDeps, cfg PipelineConfig) (Aggregator, error) {
+		agent, err := deps.NewLLMAgent(cfg.Aggregator.agent(AgentTypeAggregator))
+		if err != nil {
+			return nil, err
+		}
+		return &AggregatorAgent{LLMAgent: agent}, nil
+	})
+	r.RegisterAggregator(AggregationMean, func(_ AgentDeps, cfg PipelineConfig) (Aggregator, error) {
+		weights := make(map[string]float64, len(cfg.Agents))
+		for _, agent := range cfg.Agents {
+			if agent.Weight > 0 {
+				weights[agent.name()] = agent.Weight
+			}
+		}
+		return &MeanAggregator{weights: weights}, nil
+	})
+
+	return r
+}
+
+// RegisterAgent adds an agent type, replacing any registered under the name
+func (r *Registry) RegisterAgent(agentType string, factory AgentFactory) {
+	r.agents[agentType] = factory
+}
+
+// RegisterAggregator adds an aggregation strategy, replacing any registered
+// under the name
+func (r *Registry) RegisterAggregator(strategy string, factory AggregatorFactory) {
+	r.aggregators[strategy] = factory
+}
+
+// newAgent builds an agent of a registered type
+func (r *Registry) newAgent(deps AgentDeps, cfg AgentConfig) (Agent, error) {
+	factory, ok := r.agents[cfg.Type]
+	if !ok {
+		return nil, fmt.Errorf("unknown agent type %q", cfg.Type)
+	}
+	return factory(deps, cfg)
+}
+
+// newAggregator builds the pipeline's registered aggregator
+func (r *Registry) newAggregator(deps AgentDeps, cfg PipelineConfig) (Aggregator, error) {
+	factory, ok := r.aggregators[cfg.Aggregation]
+	if !ok {
+		return nil, fmt.Errorf("unknown aggregation strategy %q", cfg.Aggregation)
+	}
+	return factory(deps, cfg)
+}
+
+// llmAgentFactory builds an LLM agent from the configuration and wraps it in
+// an agent type
+func llmAgentFactory(wrap func(*LLMAgent) Agent) AgentFactory {
+	return func(deps AgentDeps, cfg AgentConfig) (Agent, error) {
+		agent, err := deps.NewLLMAgent(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return wrap(agent), nil
+	}
+}
+
+// newPromptAgent builds an agent with the configured instructions
+func newPromptAgent(deps AgentDeps, cfg AgentConfig) (Agent, error) {
+	if cfg.Prompt == "" {
+		return nil, fmt.Errorf("prompt is required")
+	}
+
+	agent, err := deps.NewLLMAgent(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	system := cfg.System
+	if system == "" {
+		system = providers.DefaultSystemPrompt
+	}
+	return &PromptAgent{LLMAgent: agent, system: system, instructions: cfg.Prompt}, nil
+}
+
+// Registry returns the registry of agent types and aggregation strategies
+// the service's pipelines are built from
+func (s *Service) Registry() *Registry {
+	return s.registry
+}
+
+// SetProviders makes the LLM providers available to pipeline agents by name
+func (s *Service) SetProviders(llmProviders []providers.LLMProvider) {
+	s.providers = make(map[string]providers.LLMProvider, len(llmProviders))
+	for _, p := range llmProviders {
+		s.providers[p.Name()] = p
+	}
+}
+
+// agentDeps returns what the service's agents are built from
+func (s *Service) agentDeps() AgentDeps {
+	return AgentDeps{
+		DB:        s.db,
+		OpenAIKey: s.openAIKey,
+		Providers: s.providers,
+		Tools:     s.tools,
+		Samples:   s.samples,
+	}
+}
+
+// EloAgent predicts from the teams' Elo ratings, without an LLM
+type EloAgent struct {
+	name string
+}
+
+// Type returns the agent's name in its pipeline
+func (a *EloAgent) Type() string {
+	return a.name
+}
+
+// Model names the rating model
+func (a *EloAgent) Model() string {
+	return AgentTypeElo
+}
+
+// Analyze turns the home side's Elo expected score into outcome
+// probabilities, with fewer draws the further apart the teams are rated
+func (a *EloAgent) Analyze(ctx context.Context, analysis *MatchAnalysis) (*AgentOutput, error) {
+	home, away := analysis.HomeTeam.Elo, analysis.AwayTeam.Elo
+	if home == 0 || away == 0 {
+		return nil, fmt.Errorf("no Elo ratings for the match")
+	}
+
+	expected := footballdata.EloExpectedScore(home, away)
+	if _, neutral := analysis.Metadata["venue"]; neutral {
+		// Averaging both venues cancels out home advantage
+		expected = (expected + 1 - footballdata.EloExpectedScore(away, home)) / 2
+	}
+	draw := eloDrawRate * (1 - math.Abs(2*expected-1))
+
+	output := &AgentOutput{
+		AgentType:   a.name,
+		HomeWinProb: expected - draw/2,
+		DrawProb:    draw,
+		AwayWinProb: 1 - expected - draw/2,
+		Reasoning: fmt.Sprintf("%s are rated %.0f and %s %.0f, an expected score of %.2f for the home side",
+			analysis.HomeTeam.Name, home, analysis.AwayTeam.Name, away, expected),
+		KeyFactors: []string{"Elo ratings"},
+	}
+	output.Confidence = max(output.HomeWinProb, output.DrawProb, output.AwayWinProb)
+	output.setUncertainty()
+	return output, nil
+}
+
+// MeanAggregator averages the agents' outputs by their configured weights,
+// without an LLM
+type MeanAggregator struct {
+	weights map[string]float64 // by agent name; 1 when missing
+}
+
+// Model names the aggregation strategy
+func (a *MeanAggregator) Model() string {
+	return AggregationMean
+}
+
+// Aggregate returns the weighted mean of the outputs, with the key factors
+// most of them gave
+func (a *MeanAggregator) Aggregate(ctx context.Context, outputs []AgentOutput) (*AgentOutput, error) {
+	output := &AgentOutput{AgentType: AgentTypeAggregator}
+
+	forecasts := make([][3]float64, len(outputs))
+	reasonings := make([]string, len(outputs))
+	keyFactorCounts := make(map[string]int)
+	var totalWeight, homeGoals, awayGoals, goalsWeight float64
+	for i, o := range outputs {
+		weight, ok := a.weights[o.AgentType]
+		if !ok {
+			weight = 1
+		}
+		totalWeight += weight
+
+		output.HomeWinProb += o.HomeWinProb * weight
+		output.DrawProb += o.DrawProb * weight
+		output.AwayWinProb += o.AwayWinProb * weight
+		output.Confidence += o.Confidence * weight
+		if o.HomeExpectedGoals != nil && o.AwayExpectedGoals != nil {
+			homeGoals += *o.HomeExpectedGoals * weight
+			awayGoals += *o.AwayExpectedGoals * weight
+			goalsWeight += weight
+		}
+
+		forecasts[i] = forecastOf(o)
+		reasonings[i] = fmt.Sprintf("[%s]: %s", o.AgentType, o.Reasoning)
+		for _, factor := range o.KeyFactors {
+			if keyFactorCounts[factor]++; keyFactorCounts[factor] == len(outputs)/2+1 {
+				output.KeyFactors = append(output.KeyFactors, factor)
+			}
+		}
+	}
+	if totalWeight == 0 {
+		return nil, fmt.Errorf("no outputs to aggregate")
+	}
+
+	output.HomeWinProb /= totalWeight
+	output.DrawProb /= totalWeight
+	output.AwayWinProb /= totalWeight
+	output.Confidence /= totalWeight
+	if goalsWeight > 0 {
+		homeGoals /= goalsWeight
+		awayGoals /= goalsWeight
+		output.HomeExpectedGoals = &homeGoals
+		output.AwayExpectedGoals = &awayGoals
+	}
+	output.Reasoning = strings.Join(reasonings, "\n\n")
+
+	output.Dispersion = newDispersion(forecasts)
+	output.setUncertainty()
+	return output, nil
+}
@@ -0,0 +1,296 @@
+package predictions
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math"
+	"reflect"
+	"slices"
+	"time"
+
+	"github.com/edd/relaxovisionmonolith/footballdata"
+)
+
+// revisionLockClass namespaces the per-match advisory locks that serialise revisions
+const revisionLockClass = 41
+
+// probabilityEpsilon is the smallest probability move reported in a diff;
+// probabilities are stored to four decimals
+const probabilityEpsilon = 0.00005
+
+// RevisionDiff explains what changed between two revisions of a match's prediction
+type RevisionDiff struct {
+	MatchID       int          `json:"matchId"`
+	From          RevisionInfo `json:"from"`
+	To            RevisionInfo `json:"to"`
+	InputsChanged bool         `json:"inputsChanged"`
+	// Inputs are the analysis values that changed, empty when either revision has no snapshot
+	Inputs        []ValueChange       `json:"inputs"`
+	PromptChanged bool                `json:"promptChanged"`
+	Models        []ValueChange       `json:"models,omitempty"`
+	Probabilities []ProbabilityChange `json:"probabilities"`
+}
+
+// RevisionInfo identifies a revision in a diff
+type RevisionInfo struct {
+	ID            string    `json:"id"`
+	Revision      int       `json:"revision"`
+	CreatedAt     time.Time `json:"createdAt"`
+	InputHash     string    `json:"inputHash,omitempty"`
+	PromptVersion int       `json:"promptVersion,omitempty"`
+}
+
+// ValueChange is a value that differs between revisions, at a JSON path such
+// as "homeTeam.form.points"
+type ValueChange struct {
+	Path string `json:"path"`
+	From any    `json:"from"`
+	To   any    `json:"to"`
+}
+
+// ProbabilityChange is an outcome probability that moved between revisions
+type ProbabilityChange struct {
+	Source  string  `json:"source"`  // "final", an agent type, or agent type/provider
+	Outcome string  `json:"outcome"` // home, draw or away
+	From    float64 `json:"from"`
+	To      float64 `json:"to"`
+	Delta   float64 `json:"delta"`
+}
+
+// DiffRevisions compares two revisions of a match's prediction. A zero to is
+// the current revision, and a zero from the revision before to
+func (s *Service) DiffRevisions(ctx context.Context, matchID, from, to int) (*RevisionDiff, error) {
+	if to == 0 {
+		current, err := s.GetCurrentPrediction(ctx, matchID)
+		if err != nil {
+			return nil, err
+		}
+		to = current.Revision
+	}
+	if from == 0 {
+		from = to - 1
+	}
+	if from < 1 || from == to {
+		return nil, fmt.Errorf("revision not found")
+	}
+
+	older, err := s.getPrediction(ctx, "match_id = $1 AND revision = $2", matchID, from)
+	if err != nil {
+		return nil, err
+	}
+	newer, err := s.getPrediction(ctx, "match_id = $1 AND revision = $2", matchID, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return diffRevisions(older, newer)
+}
+
+// diffRevisions compares the inputs, prompts, models and probabilities of two revisions
+func diffRevisions(from, to *PredictionResult) (*RevisionDiff, error) {
+	diff := &RevisionDiff{
+		MatchID:       to.MatchID,
+		From:          revisionInfo(from),
+		To:            revisionInfo(to),
+		PromptChanged: from.PromptVersion != to.PromptVersion,
+	}
+
+	if from.InputSnapshot != nil && to.InputSnapshot != nil {
+		var fromInputs, toInputs any
+		if err := roundTrip(from.InputSnapshot, &fromInputs); err != nil {
+			return nil, err
+		}
+		if err := roundTrip(to.InputSnapshot, &toInputs); err != nil {
+			return nil, err
+		}
+		diffValues("", fromInputs, toInputs, &diff.Inputs)
+	}
+	diff.InputsChanged = len(diff.Inputs) > 0 || from.InputHash != to.InputHash
+
+	agents := make(map[string]string, len(to.Models))
+	maps.Copy(agents, from.Models)
+	maps.Copy(agents, to.Models)
+	for _, agent := range slices.Sorted(maps.Keys(agents)) {
+		if from.Models[agent] != to.Models[agent] {
+			diff.Models = append(diff.Models, ValueChange{Path: agent, From: from.Models[agent], To: to.Models[agent]})
+		}
+	}
+
+	diff.Probabilities = probabilityChanges(from, to)
+	return diff, nil
+}
+
+// revisionInfo summarises a revision
+func revisionInfo(p *PredictionResult) RevisionInfo {
+	return RevisionInfo{
+		ID:            p.ID,
+		Revision:      p.Revision,
+		CreatedAt:     p.CreatedAt,
+		InputHash:     p.InputHash,
+		PromptVersion: p.PromptVersion,
+	}
+}
+
+// roundTrip decodes the JSON encoding of v into out
+func roundTrip(v, out any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal input snapshot: %w", err)
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to unmarshal input snapshot: %w", err)
+	}
+	return nil
+}
+
+// diffValues appends the leaf values that differ between two decoded JSON
+// values. Lists of different lengths are reported whole
+func diffValues(path string, from, to any, changes *[]ValueChange) {
+	switch f := from.(type) {
+	case map[string]any:
+		if t, ok := to.(map[string]any); ok {
+			keys := slices.Collect(maps.Keys(f))
+			for key := range t {
+				if _, ok := f[key]; !ok {
+					keys = append(keys, key)
+				}
+			}
+			slices.Sort(keys)
+
+			for _, key := range keys {
+				child := key
+				if path != "" {
+					child = path + "." + key
+				}
+				diffValues(child, f[key], t[key], changes)
+			}
+			return
+		}
+	case []any:
+		if t, ok := to.([]any); ok && len(t) == len(f) {
+			for i := range f {
+				diffValues(fmt.Sprintf("%s[%d]", path, i), f[i], t[i], changes)
+			}
+			return
+		}
+	}
+
+	if !reflect.DeepEqual(from, to) {
+		*changes = append(*changes, ValueChange{Path: path, From: from, To: to})
+	}
+}
+
+// probabilityChanges lists the moved probabilities of the final prediction,
+// then of each agent and its providers present in both revisions
+func probabilityChanges(from, to *PredictionResult) []ProbabilityChange {
+	changes := []ProbabilityChange{}
+	add := func(source string, before, after footballdata.MatchProbabilities) {
+		for _, outcome := range []struct {
+			name          string
+			before, after float64
+		}{
+			{"home", before.HomeWin, after.HomeWin},
+			{"draw", before.Draw, after.Draw},
+			{"away", before.AwayWin, after.AwayWin},
+		} {
+			if delta := outcome.after - outcome.before; math.Abs(delta) >= probabilityEpsilon {
+				changes = append(changes, ProbabilityChange{
+					Source:  source,
+					Outcome: outcome.name,
+					From:    outcome.before,
+					To:      outcome.after,
+					Delta:   math.Round(delta*1e4) / 1e4,
+				})
+			}
+		}
+	}
+
+	add("final",
+		footballdata.MatchProbabilities{HomeWin: from.HomeWinProb, Draw: from.DrawProb, AwayWin: from.AwayWinProb},
+		footballdata.MatchProbabilities{HomeWin: to.HomeWinProb, Draw: to.DrawProb, AwayWin: to.AwayWinProb})
+
+	for _, after := range to.AgentOutputs {
+		i := slices.IndexFunc(from.AgentOutputs, func(o AgentOutput) bool { return o.AgentType == after.AgentType })
+		if i < 0 {
+			continue
+		}
+		before := from.AgentOutputs[i]
+		add(after.AgentType,
+			footballdata.MatchProbabilities{HomeWin: before.HomeWinProb, Draw: before.DrawProb, AwayWin: before.AwayWinProb},
+			footballdata.MatchProbabilities{HomeWin: after.HomeWinProb, Draw: after.DrawProb, AwayWin: after.AwayWinProb})
+
+		for _, provider := range after.ProviderOutputs {
+			j := slices.IndexFunc(before.ProviderOutputs, func(o ProviderOutput) bool { return o.Provider == provider.Provider })
+			if j < 0 {
+				continue
+			}
+			previous := before.ProviderOutputs[j]
+			add(after.AgentType+"/"+provider.Provider,
+				footballdata.MatchProbabilities{HomeWin: previous.HomeWinProb, Draw: previous.DrawProb, AwayWin: previous.AwayWinProb},
+				footballdata.MatchProbabilities{HomeWin: provider.HomeWinProb, Draw: provider.DrawProb, AwayWin: provider.AwayWinProb})
+		}
+	}
+
+	return changes
+}
+
+// nextRevision numbers the prediction after the match's latest revision,
+// holding a per-match lock until the transaction ends
+func nextRevision(ctx context.Context, tx *sql.Tx, prediction *PredictionResult) error {
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1, $2)`, revisionLockClass, prediction.MatchID); err != nil {
+		return fmt.Errorf("failed to lock match predictions: %w", err)
+	}
+
+	var previousID string
+	var revision int
+	err := tx.QueryRowContext(ctx, `
+		SELECT id, revision
+		FROM predictions
+		WHERE match_id = $1
+		ORDER BY revision DESC
+		LIMIT 1
+	`, prediction.MatchID).Scan(&previousID, &revision)
+	switch {
+	case err == sql.ErrNoRows:
+		prediction.Revision = 1
+		prediction.PreviousID = ""
+	case err != nil:
+		return fmt.Errorf("failed to get latest revision: %w", err)
+	default:
+		prediction.Revision = revision + 1
+		prediction.PreviousID = previousID
+	}
+
+	return nil
+}
+
+// hashAnalysis returns the hex SHA-256 of an analysis in canonical form, with
+// times in UTC and the times features were computed at cleared, so that
+// recomputing unchanged features gives the same hash
+func hashAnalysis(analysis *MatchAnalysis) (string, error) {
+	canonical := *analysis
+	canonical.MatchDate = analysis.MatchDate.UTC()
+	canonical.HeadToHead = slices.Clone(analysis.HeadToHead)
+	for i := range canonical.HeadToHead {
+		canonical.HeadToHead[i].Date = canonical.HeadToHead[i].Date.UTC()
+	}
+	for _, team := range []*TeamAnalysis{&canonical.HomeTeam, &canonical.AwayTeam} {
+		if team.Schedule != nil {
+			schedule := *team.Schedule
+			schedule.ComputedAt = time.Time{}
+			team.Schedule = &schedule
+		}
+	}
+
+	data, err := json.Marshal(canonical)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal input snapshot: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
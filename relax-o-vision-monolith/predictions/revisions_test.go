@@ -0,0 +1,233 @@
+package predictions
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/edd/relaxovisionmonolith/features"
+	"github.com/edd/relaxovisionmonolith/footballdata"
+)
+
+func TestDiffValues(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		from, to any
+		want     []ValueChange
+	}{
+		{
+			name: "equal",
+			from: map[string]any{"a": 1.0, "b": []any{"x", "y"}},
+			to:   map[string]any{"a": 1.0, "b": []any{"x", "y"}},
+		},
+		{
+			name: "nested maps",
+			from: map[string]any{"homeTeam": map[string]any{"form": map[string]any{"points": 10.0, "wins": 3.0}}},
+			to:   map[string]any{"homeTeam": map[string]any{"form": map[string]any{"points": 12.0, "wins": 3.0}}},
+			want: []ValueChange{{Path: "homeTeam.form.points", From: 10.0, To: 12.0}},
+		},
+		{
+			name: "keys in only one revision",
+			from: map[string]any{"a": 1.0, "removed": "x"},
+			to:   map[string]any{"a": 1.0, "added": "y"},
+			want: []ValueChange{
+				{Path: "added", From: nil, To: "y"},
+				{Path: "removed", From: "x", To: nil},
+			},
+		},
+		{
+			name: "arrays of the same length",
+			from: map[string]any{"results": []any{"W", "D", "L"}},
+			to:   map[string]any{"results": []any{"W", "W", "L"}},
+			want: []ValueChange{{Path: "results[1]", From: "D", To: "W"}},
+		},
+		{
+			name: "arrays of different lengths",
+			from: map[string]any{"results": []any{"W", "D"}},
+			to:   map[string]any{"results": []any{"W", "D", "L"}},
+			want: []ValueChange{{Path: "results", From: []any{"W", "D"}, To: []any{"W", "D", "L"}}},
+		},
+		{
+			name: "maps in arrays",
+			from: []any{map[string]any{"goals": 1.0}},
+			to:   []any{map[string]any{"goals": 2.0}},
+			want: []ValueChange{{Path: "[0].goals", From: 1.0, To: 2.0}},
+		},
+		{
+			name: "type change",
+			from: map[string]any{"standing": map[string]any{"position": 3.0}},
+			to:   map[string]any{"standing": nil},
+			want: []ValueChange{{Path: "standing", From: map[string]any{"position": 3.0}, To: nil}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var got []ValueChange
+			diffValues("", tt.from, tt.to, &got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diffValues() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProbabilityChanges(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		from, to *PredictionResult
+		want     []ProbabilityChange
+	}{
+		{
+			name: "unchanged",
+			from: &PredictionResult{HomeWinProb: 0.5, DrawProb: 0.3, AwayWinProb: 0.2},
+			to:   &PredictionResult{HomeWinProb: 0.5, DrawProb: 0.3, AwayWinProb: 0.2},
+			want: []ProbabilityChange{},
+		},
+		{
+			name: "moves below the stored precision",
+			from: &PredictionResult{HomeWinProb: 0.5, DrawProb: 0.3, AwayWinProb: 0.2},
+			to:   &PredictionResult{HomeWinProb: 0.50001, DrawProb: 0.29999, AwayWinProb: 0.2},
+			want: []ProbabilityChange{},
+		},
+		{
+			name: "final",
+			from: &PredictionResult{HomeWinProb: 0.5, DrawProb: 0.3, AwayWinProb: 0.2},
+			to:   &PredictionResult{HomeWinProb: 0.45, DrawProb: 0.3, AwayWinProb: 0.25},
+			want: []ProbabilityChange{
+				{Source: "final", Outcome: "home", From: 0.5, To: 0.45, Delta: -0.05},
+				{Source: "final", Outcome: "away", From: 0.2, To: 0.25, Delta: 0.05},
+			},
+		},
+		{
+			name: "agents in only one revision",
+			from: &PredictionResult{AgentOutputs: []AgentOutput{
+				{AgentType: "statistical", HomeWinProb: 0.5},
+				{AgentType: "head_to_head", HomeWinProb: 0.4},
+			}},
+			to: &PredictionResult{AgentOutputs: []AgentOutput{
+				{AgentType: "statistical", HomeWinProb: 0.6},
+				{AgentType: "elo", HomeWinProb: 0.7},
+			}},
+			want: []ProbabilityChange{
+				{Source: "statistical", Outcome: "home", From: 0.5, To: 0.6, Delta: 0.1},
+			},
+		},
+		{
+			name: "providers in only one revision",
+			from: &PredictionResult{AgentOutputs: []AgentOutput{{
+				AgentType: "form",
+				ProviderOutputs: []ProviderOutput{
+					{Provider: "openai", DrawProb: 0.3},
+					{Provider: "gemini", DrawProb: 0.2},
+				},
+			}}},
+			to: &PredictionResult{AgentOutputs: []AgentOutput{{
+				AgentType: "form",
+				ProviderOutputs: []ProviderOutput{
+					{Provider: "claude", DrawProb: 0.4},
+					{Provider: "openai", DrawProb: 0.25},
+				},
+			}}},
+			want: []ProbabilityChange{
+				{Source: "form/openai", Outcome: "draw", From: 0.3, To: 0.25, Delta: -0.05},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := probabilityChanges(tt.from, tt.to)
+			if len(got) != len(tt.want) {
+				t.Fatalf("probabilityChanges() = %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				g, w := got[i], tt.want[i]
+				if g.Source != w.Source || g.Outcome != w.Outcome || g.From != w.From || g.To != w.To || !almostEqual(g.Delta, w.Delta) {
+					t.Errorf("change %d = %+v, want %+v", i, g, w)
+				}
+			}
+		})
+	}
+}
+
+func TestDiffRevisions_Models(t *testing.T) {
+	t.Parallel()
+
+	from := &PredictionResult{Models: map[string]string{"statistical": "gpt-4", "head_to_head": "gpt-4"}}
+	to := &PredictionResult{Models: map[string]string{"statistical": "gpt-4o", "elo": "elo"}}
+
+	diff, err := diffRevisions(from, to)
+	if err != nil {
+		t.Fatalf("diffRevisions() error = %v", err)
+	}
+
+	want := []ValueChange{
+		{Path: "elo", From: "", To: "elo"},
+		{Path: "head_to_head", From: "gpt-4", To: ""},
+		{Path: "statistical", From: "gpt-4", To: "gpt-4o"},
+	}
+	if !reflect.DeepEqual(diff.Models, want) {
+		t.Errorf("models = %+v, want %+v", diff.Models, want)
+	}
+}
+
+func TestHashAnalysis(t *testing.T) {
+	t.Parallel()
+
+	kickoff := time.Date(2025, 3, 15, 15, 0, 0, 0, time.UTC)
+	matchFeatures := func(computedAt time.Time, loc *time.Location) *features.MatchFeatures {
+		rest := 3.5
+		return &features.MatchFeatures{
+			MatchID:     1,
+			Version:     features.Version,
+			Competition: "Premier League",
+			Kickoff:     kickoff.In(loc),
+			Home: features.TeamFeatures{
+				TeamID:   10,
+				Name:     "Home",
+				Elo:      1550,
+				Schedule: &footballdata.ScheduleFeatures{MatchID: 1, TeamID: 10, DaysSinceLastMatch: &rest, ComputedAt: computedAt},
+			},
+			Away: features.TeamFeatures{
+				TeamID:   20,
+				Name:     "Away",
+				Elo:      1480,
+				Schedule: &footballdata.ScheduleFeatures{MatchID: 1, TeamID: 20, ComputedAt: computedAt},
+			},
+			HeadToHead: &footballdata.HeadToHead{
+				RecentMatches: []footballdata.MatchSummary{{Date: kickoff.AddDate(-1, 0, 0).In(loc), HomeTeamID: 10, AwayTeamID: 20, HomeScore: 2, AwayScore: 1}},
+			},
+			ComputedAt: computedAt,
+		}
+	}
+
+	hash := func(f *features.MatchFeatures) string {
+		t.Helper()
+		h, err := hashAnalysis(newMatchAnalysis(f))
+		if err != nil {
+			t.Fatalf("hashAnalysis() error = %v", err)
+		}
+		return h
+	}
+
+	first := hash(matchFeatures(kickoff.Add(-48*time.Hour), time.UTC))
+	second := hash(matchFeatures(kickoff.Add(-time.Hour), time.FixedZone("CET", 3600)))
+	if first != second {
+		t.Errorf("recomputed features hash to %s, want %s", second, first)
+	}
+
+	changed := matchFeatures(kickoff.Add(-time.Hour), time.UTC)
+	changed.Home.Elo = 1560
+	if hash(changed) == first {
+		t.Error("changed features hash the same")
+	}
+}
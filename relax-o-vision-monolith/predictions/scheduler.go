@@ -0,0 +1,379 @@
+package predictions
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// schedulerLockKey is the Postgres advisory lock held by the instance running the scheduler job
+const schedulerLockKey = 7_040_001
+
+// ErrJobRunning is returned when another instance is already running the scheduler job
+var ErrJobRunning = errors.New("prediction job already running")
+
+// Job run statuses
+const (
+	JobRunning   = "running"
+	JobCompleted = "completed"
+	JobFailed    = "failed"
+)
+
+// SchedulerConfig controls which matches are predicted ahead of kickoff and
+// how many LLM predictions the scheduler may make
+type SchedulerConfig struct {
+	CompetitionCodes []string
+	// Window is how long before kickoff a match is first predicted
+	Window time.Duration
+	// RepredictBefore is how long before kickoff a prediction is refreshed if its inputs changed
+	RepredictBefore time.Duration
+	Interval        time.Duration
+	// MaxPerRun and MaxPerDay cap the predictions made, zero for no limit
+	MaxPerRun int
+	MaxPerDay int
+}
+
+// DefaultSchedulerConfig returns the default config for the competitions
+func DefaultSchedulerConfig(competitionCodes []string) SchedulerConfig {
+	return SchedulerConfig{
+		CompetitionCodes: competitionCodes,
+		Window:           48 * time.Hour,
+		RepredictBefore:  time.Hour,
+		Interval:         15 * time.Minute,
+		MaxPerRun:        20,
+		MaxPerDay:        200,
+	}
+}
+
+// JobRun records one run of the scheduler job
+type JobRun struct {
+	ID          int64      `json:"id"`
+	Instance    string     `json:"instance"`
+	Status      string     `json:"status"`
+	Candidates  int        `json:"candidates"`  // upcoming matches in the window
+	Created     int        `json:"created"`     // first predictions
+	Repredicted int        `json:"repredicted"` // refreshed before kickoff
+	Skipped     int        `json:"skipped"`     // left over once the budget ran out
+	Failed      int        `json:"failed"`
+	Attempted   int        `json:"attempted"` // predictions that made LLM calls, failed ones included
+	Error       string     `json:"error,omitempty"`
+	StartedAt   time.Time  `json:"startedAt"`
+	FinishedAt  *time.Time `json:"finishedAt,omitempty"`
+}
+
+// Scheduler predicts upcoming matches of tracked competitions ahead of
+// kickoff. Runs are serialised across instances by an advisory lock
+type Scheduler struct {
+	service  *Service
+	config   SchedulerConfig
+	instance string
+	stopChan chan struct{}
+	// inputFingerprint is the service's, replaced in tests
+	inputFingerprint func(ctx context.Context, matchID int) (string, error)
+}
+
+// scheduledMatch is an upcoming match with its latest prediction, if any
+type scheduledMatch struct {
+	matchID     int
+	kickoff     time.Time
+	predictedAt sql.NullTime
+	fingerprint string
+}
+
+// NewScheduler creates a new prediction scheduler
+func NewScheduler(service *Service, config SchedulerConfig) *Scheduler {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+
+	return &Scheduler{
+		service:          service,
+		config:           config,
+		instance:         fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+		stopChan:         make(chan struct{}),
+		inputFingerprint: service.InputFingerprint,
+	}
+}
+
+// Start runs the job periodically until stopped
+func (s *Scheduler) Start(ctx context.Context) {
+	slog.Info("Starting prediction scheduler", "interval", s.config.Interval, "window", s.config.Window,
+		"competitions", s.config.CompetitionCodes)
+
+	s.run(ctx)
+
+	ticker := time.NewTicker(s.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.run(ctx)
+		case <-s.stopChan:
+			slog.Info("Stopping prediction scheduler")
+			return
+		case <-ctx.Done():
+			slog.Info("Context cancelled, stopping prediction scheduler")
+			return
+		}
+	}
+}
+
+// Stop stops the scheduler
+func (s *Scheduler) Stop() {
+	close(s.stopChan)
+}
+
+// run executes a scheduled run, logging its outcome
+func (s *Scheduler) run(ctx context.Context) {
+	run, err := s.RunOnce(ctx)
+	switch {
+	case errors.Is(err, ErrJobRunning):
+		slog.Info("Prediction job already running on another instance")
+	case err != nil:
+		slog.Error("Prediction job failed", "error", err)
+	default:
+		slog.Info("Prediction job completed", "candidates", run.Candidates, "created", run.Created,
+			"repredicted", run.Repredicted, "skipped", run.Skipped, "failed", run.Failed)
+	}
+}
+
+// RunOnce predicts the upcoming matches that need it and records the run.
+// It returns ErrJobRunning if another instance holds the job lock
+func (s *Scheduler) RunOnce(ctx context.Context) (*JobRun, error) {
+	conn, err := s.service.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	// The lock belongs to the session, so it is released on the same connection
+	var locked bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, schedulerLockKey).Scan(&locked); err != nil {
+		return nil, fmt.Errorf("failed to acquire job lock: %w", err)
+	}
+	if !locked {
+		return nil, ErrJobRunning
+	}
+	defer func() {
+		if _, err := conn.ExecContext(context.WithoutCancel(ctx), `SELECT pg_advisory_unlock($1)`, schedulerLockKey); err != nil {
+			slog.Warn("Failed to release prediction job lock", "error", err)
+		}
+	}()
+
+	run := &JobRun{Instance: s.instance, Status: JobRunning, StartedAt: time.Now()}
+	err = s.service.db.QueryRowContext(ctx, `
+		INSERT INTO prediction_job_runs (instance, status, started_at)
+		VALUES ($1, $2, $3)
+		RETURNING id
+	`, run.Instance, run.Status, run.StartedAt).Scan(&run.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record job run: %w", err)
+	}
+
+	runErr := s.predictUpcoming(ctx, run)
+
+	finishedAt := time.Now()
+	run.FinishedAt = &finishedAt
+	run.Status = JobCompleted
+	if runErr != nil {
+		run.Status = JobFailed
+		run.Error = runErr.Error()
+	}
+
+	_, err = s.service.db.ExecContext(context.WithoutCancel(ctx), `
+		UPDATE prediction_job_runs
+		SET status = $2, candidates = $3, created = $4, repredicted = $5, skipped = $6, failed = $7,
+		    attempted = $8, error = $9, finished_at = $10
+		WHERE id = $1
+	`, run.ID, run.Status, run.Candidates, run.Created, run.Repredicted, run.Skipped, run.Failed, run.Attempted,
+		sql.NullString{String: run.Error, Valid: run.Error != ""}, finishedAt)
+	if err != nil {
+		slog.Error("Failed to update job run", "runId", run.ID, "error", err)
+	}
+
+	return run, runErr
+}
+
+// predictUpcoming predicts the matches in the window that have no prediction
+// yet, and refreshes those close to kickoff whose inputs changed
+func (s *Scheduler) predictUpcoming(ctx context.Context, run *JobRun) error {
+	now := time.Now()
+	matches, err := s.upcomingMatches(ctx, now)
+	if err != nil {
+		return err
+	}
+	run.Candidates = len(matches)
+
+	budget, err := s.budget(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range matches {
+		repredict := m.predictedAt.Valid
+		if repredict {
+			stale, err := s.needsRepredict(ctx, m, now)
+			if err != nil {
+				slog.Warn("Failed to check prediction inputs", "matchId", m.matchID, "error", err)
+				run.Failed++
+				continue
+			}
+			if !stale {
+				continue
+			}
+		}
+
+		if budget <= 0 {
+			run.Skipped++
+			continue
+		}
+		budget--
+		run.Attempted++
+
+		prediction, err := s.service.CreatePrediction(ctx, m.matchID, CreateOptions{})
+		if err != nil {
+			slog.Warn("Failed to create scheduled prediction", "matchId", m.matchID, "error", err)
+			run.Failed++
+			continue
+		}
+		switch {
+		case prediction.Reused:
+			// The analysis the agents see was unchanged, so no LLM calls were made
+			budget++
+			run.Attempted--
+		case repredict:
+			run.Repredicted++
+		default:
+			run.Created++
+		}
+	}
+
+	return nil
+}
+
+// needsRepredict reports whether a predicted match is within the re-predict
+// window, was last predicted before it, and its inputs have changed since
+func (s *Scheduler) needsRepredict(ctx context.Context, m scheduledMatch, now time.Time) (bool, error) {
+	cutoff := m.kickoff.Add(-s.config.RepredictBefore)
+	if now.Before(cutoff) || !m.predictedAt.Time.Before(cutoff) {
+		return false, nil
+	}
+
+	fingerprint, err := s.inputFingerprint(ctx, m.matchID)
+	if err != nil {
+		return false, err
+	}
+	return fingerprint != m.fingerprint, nil
+}
+
+// upcomingMatches returns the scheduled matches of the tracked competitions
+// kicking off within the window, soonest first
+func (s *Scheduler) upcomingMatches(ctx context.Context, now time.Time) ([]scheduledMatch, error) {
+	rows, err := s.service.db.QueryContext(ctx, `
+		SELECT m.id, m.utc_date, p.created_at, COALESCE(p.input_fingerprint, '')
+		FROM matches m
+		JOIN competitions c ON c.id = m.competition_id
+		LEFT JOIN LATERAL (
+			SELECT created_at, input_fingerprint
+			FROM predictions
+			WHERE match_id = m.id AND status = 'completed'
+			ORDER BY created_at DESC
+			LIMIT 1
+		) p ON true
+		WHERE m.status IN ('SCHEDULED', 'TIMED')
+		  AND m.utc_date > $1
+		  AND m.utc_date <= $2
+		  AND c.code = ANY($3)
+		ORDER BY m.utc_date, m.id
+	`, now, now.Add(s.config.Window), pq.Array(s.config.CompetitionCodes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query upcoming matches: %w", err)
+	}
+	defer rows.Close()
+
+	var matches []scheduledMatch
+	for rows.Next() {
+		var m scheduledMatch
+		if err := rows.Scan(&m.matchID, &m.kickoff, &m.predictedAt, &m.fingerprint); err != nil {
+			return nil, fmt.Errorf("failed to scan upcoming match: %w", err)
+		}
+		matches = append(matches, m)
+	}
+
+	return matches, rows.Err()
+}
+
+// budget returns how many predictions the run may make within the per-run
+// and rolling daily limits. Failed predictions count towards the daily limit
+// too, as they made LLM calls
+func (s *Scheduler) budget(ctx context.Context) (int, error) {
+	if s.config.MaxPerDay <= 0 {
+		return s.config.runBudget(0), nil
+	}
+
+	var used int
+	err := s.service.db.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(attempted), 0)
+		FROM prediction_job_runs
+		WHERE started_at > NOW() - INTERVAL '24 hours'
+	`).Scan(&used)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count recent predictions: %w", err)
+	}
+
+	return s.config.runBudget(used), nil
+}
+
+// runBudget is how many predictions a run may make when the runs of the last
+// 24 hours attempted used of them
+func (c SchedulerConfig) runBudget(used int) int {
+	budget := math.MaxInt
+	if c.MaxPerRun > 0 {
+		budget = c.MaxPerRun
+	}
+	if c.MaxPerDay <= 0 {
+		return budget
+	}
+	return min(budget, max(c.MaxPerDay-used, 0))
+}
+
+// Runs returns the most recent job runs, newest first
+func (s *Scheduler) Runs(ctx context.Context, limit int) ([]JobRun, error) {
+	rows, err := s.service.db.QueryContext(ctx, `
+		SELECT id, instance, status, candidates, created, repredicted, skipped, failed, attempted,
+		       COALESCE(error, ''), started_at, finished_at
+		FROM prediction_job_runs
+		ORDER BY started_at DESC, id DESC
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query job runs: %w", err)
+	}
+	defer rows.Close()
+
+	var runs []JobRun
+	for rows.Next() {
+		var run JobRun
+		var finishedAt sql.NullTime
+		err := rows.Scan(&run.ID, &run.Instance, &run.Status, &run.Candidates, &run.Created, &run.Repredicted,
+			&run.Skipped, &run.Failed, &run.Attempted, &run.Error, &run.StartedAt, &finishedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan job run: %w", err)
+		}
+		if finishedAt.Valid {
+			run.FinishedAt = &finishedAt.Time
+		}
+		runs = append(runs, run)
+	}
+
+	return runs, rows.Err()
+}
@@ -0,0 +1,172 @@
+package predictions
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestScheduler_NeedsRepredict(t *testing.T) {
+	t.Parallel()
+
+	kickoff := time.Date(2025, 3, 15, 15, 0, 0, 0, time.UTC)
+	errFingerprint := errors.New("match not found")
+
+	tests := []struct {
+		name           string
+		now            time.Time
+		predictedAt    time.Time
+		fingerprint    string // of the stored prediction
+		current        string // of the match's inputs now
+		fingerprintErr error
+		want           bool
+		wantErr        bool
+		wantLookup     bool
+	}{
+		{
+			name:        "before the re-predict window",
+			now:         kickoff.Add(-3 * time.Hour),
+			predictedAt: kickoff.Add(-24 * time.Hour),
+			fingerprint: "a",
+			current:     "b",
+		},
+		{
+			name:        "predicted within the window",
+			now:         kickoff.Add(-30 * time.Minute),
+			predictedAt: kickoff.Add(-45 * time.Minute),
+			fingerprint: "a",
+			current:     "b",
+		},
+		{
+			name:        "inputs unchanged",
+			now:         kickoff.Add(-30 * time.Minute),
+			predictedAt: kickoff.Add(-24 * time.Hour),
+			fingerprint: "a",
+			current:     "a",
+			wantLookup:  true,
+		},
+		{
+			name:        "inputs changed",
+			now:         kickoff.Add(-30 * time.Minute),
+			predictedAt: kickoff.Add(-24 * time.Hour),
+			fingerprint: "a",
+			current:     "b",
+			want:        true,
+			wantLookup:  true,
+		},
+		{
+			name:        "window starts at the cutoff",
+			now:         kickoff.Add(-time.Hour),
+			predictedAt: kickoff.Add(-time.Hour - time.Second),
+			fingerprint: "a",
+			current:     "b",
+			want:        true,
+			wantLookup:  true,
+		},
+		{
+			name:           "fingerprint fails",
+			now:            kickoff.Add(-30 * time.Minute),
+			predictedAt:    kickoff.Add(-24 * time.Hour),
+			fingerprint:    "a",
+			fingerprintErr: errFingerprint,
+			wantErr:        true,
+			wantLookup:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var lookedUp bool
+			s := &Scheduler{
+				config: SchedulerConfig{RepredictBefore: time.Hour},
+				inputFingerprint: func(_ context.Context, matchID int) (string, error) {
+					lookedUp = true
+					if matchID != 42 {
+						t.Errorf("fingerprint of match %d, want 42", matchID)
+					}
+					return tt.current, tt.fingerprintErr
+				},
+			}
+			m := scheduledMatch{
+				matchID:     42,
+				kickoff:     kickoff,
+				predictedAt: sql.NullTime{Time: tt.predictedAt, Valid: true},
+				fingerprint: tt.fingerprint,
+			}
+
+			got, err := s.needsRepredict(context.Background(), m, tt.now)
+			if tt.wantErr {
+				if !errors.Is(err, errFingerprint) {
+					t.Errorf("error = %v, want %v", err, errFingerprint)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("needsRepredict = %v, want %v", got, tt.want)
+			}
+			if lookedUp != tt.wantLookup {
+				t.Errorf("fingerprint looked up = %v, want %v", lookedUp, tt.wantLookup)
+			}
+		})
+	}
+}
+
+func TestSchedulerConfig_RunBudget(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		config SchedulerConfig
+		used   int // attempted by the runs of the last 24 hours
+		want   int
+	}{
+		{name: "no limits", config: SchedulerConfig{}, used: 500, want: math.MaxInt},
+		{name: "per run only", config: SchedulerConfig{MaxPerRun: 20}, used: 500, want: 20},
+		{name: "per day only", config: SchedulerConfig{MaxPerDay: 200}, used: 150, want: 50},
+		{name: "per run below what is left of the day", config: SchedulerConfig{MaxPerRun: 20, MaxPerDay: 200}, used: 100, want: 20},
+		{name: "day nearly used up", config: SchedulerConfig{MaxPerRun: 20, MaxPerDay: 200}, used: 195, want: 5},
+		{name: "day used up", config: SchedulerConfig{MaxPerRun: 20, MaxPerDay: 200}, used: 200, want: 0},
+		{name: "day overspent", config: SchedulerConfig{MaxPerRun: 20, MaxPerDay: 200}, used: 230, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := tt.config.runBudget(tt.used); got != tt.want {
+				t.Errorf("runBudget(%d) = %d, want %d", tt.used, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSchedulerConfig_RunBudget_AcrossRuns(t *testing.T) {
+	t.Parallel()
+
+	// Each run attempts its whole budget, failed predictions included, so the
+	// day's runs sum to MaxPerDay and no more
+	config := SchedulerConfig{MaxPerRun: 30, MaxPerDay: 100}
+	var used int
+	var budgets []int
+	for range 5 {
+		budget := config.runBudget(used)
+		budgets = append(budgets, budget)
+		used += budget
+	}
+
+	want := []int{30, 30, 30, 10, 0}
+	for i := range want {
+		if budgets[i] != want[i] {
+			t.Fatalf("budgets = %v, want %v", budgets, want)
+		}
+	}
+	if used != config.MaxPerDay {
+		t.Errorf("used = %d, want %d", used, config.MaxPerDay)
+	}
+}
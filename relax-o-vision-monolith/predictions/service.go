@@ -6,80 +6,153 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/edd/relaxovisionmonolith/features"
+	"github.com/edd/relaxovisionmonolith/footballdata"
+	"github.com/edd/relaxovisionmonolith/predictions/providers"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"golang.org/x/sync/singleflight"
 )
 
 // Service handles business logic for predictions
 type Service struct {
 	db                *sql.DB
-	statisticalAgent  *StatisticalAgent
-	formAgent         *FormAgent
-	headToHeadAgent   *HeadToHeadAgent
-	aggregatorAgent   *AggregatorAgent
+	registry          *Registry
+	pipelines         PipelinesConfig
+	debateRounds      int // revision rounds of the built-in pipeline; 0 runs the standard one
+	providers         map[string]providers.LLMProvider
+	samples           int
+	tools             *MatchTools
+	features          *features.Store
 	openAIKey         string // Keep for backward compatibility
+	inFlight          singleflight.Group
+	batchHooks        []BatchProgressHook
+	liveHooks         []LiveProbabilityHook
+	// pipelineMu guards nextPipelineStart, used to space out batch predictions
+	pipelineMu        sync.Mutex
+	nextPipelineStart time.Time
+	// ctx is the context of the batches run in the background, cancelled by Stop
+	ctx        context.Context
+	cancel     context.CancelFunc
+	background sync.WaitGroup
 }
 
 // NewService creates a new prediction service (legacy)
 func NewService(db *sql.DB, openAIKey string) *Service {
+	ctx, cancel := context.WithCancel(context.Background())
 	return &Service{
-		db:               db,
-		statisticalAgent: NewStatisticalAgent(openAIKey),
-		formAgent:        NewFormAgent(openAIKey),
-		headToHeadAgent:  NewHeadToHeadAgent(openAIKey),
-		aggregatorAgent:  NewAggregatorAgent(openAIKey),
-		openAIKey:        openAIKey,
+		db:        db,
+		registry:  NewRegistry(),
+		features:  features.NewStore(db),
+		openAIKey: openAIKey,
+		ctx:       ctx,
+		cancel:    cancel,
 	}
 }
 
-// CreatePrediction creates a new prediction for a match
-func (s *Service) CreatePrediction(ctx context.Context, matchID int) (*PredictionResult, error) {
+// Stop cancels the batches running in the background and waits for them to
+// record their outcome
+func (s *Service) Stop() {
+	s.cancel()
+	s.background.Wait()
+}
+
+// SetAgentSamples makes each agent ask its provider for n answers at its
+// temperature and average them, so a single provider's uncertainty is measured
+func (s *Service) SetAgentSamples(n int) {
+	s.samples = n
+}
+
+// EnableTools lets the analyst agents query match data through tool calls,
+// using their providers' native function calling
+func (s *Service) EnableTools() {
+	s.tools = NewMatchTools(s.db)
+}
+
+// CreatePrediction creates a new prediction for a match. Concurrent requests
+// for the match share one run of the agents, and unless forced, a fresh
+// prediction from the same inputs and configuration is returned instead
+func (s *Service) CreatePrediction(ctx context.Context, matchID int, opts CreateOptions) (*PredictionResult, error) {
+	if opts.IdempotencyKey != "" {
+		prediction, err := s.predictionForKey(ctx, opts.IdempotencyKey, matchID)
+		if err != nil || prediction != nil {
+			return prediction, err
+		}
+	}
+
+	// The shared run outlives the caller that started it
+	flight := s.inFlight.DoChan(fmt.Sprintf("%d:%t", matchID, opts.Force), func() (any, error) {
+		return s.createPrediction(context.WithoutCancel(ctx), matchID, opts.Force)
+	})
+
+	var prediction *PredictionResult
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case result := <-flight:
+		if result.Err != nil {
+			return nil, result.Err
+		}
+		prediction = result.Val.(*PredictionResult)
+	}
+
+	if opts.IdempotencyKey != "" {
+		if err := s.saveIdempotencyKey(ctx, opts.IdempotencyKey, prediction.ID); err != nil {
+			slog.Warn("Failed to save idempotency key", "predictionId", prediction.ID, "error", err)
+		}
+	}
+
+	return prediction, nil
+}
+
+// createPrediction runs the agents on the match's analysis and saves the
+// result, unless a reusable prediction exists and force is false
+func (s *Service) createPrediction(ctx context.Context, matchID int, force bool) (*PredictionResult, error) {
 	// Fetch match analysis data
-	analysis, err := s.fetchMatchAnalysis(ctx, matchID)
+	analysis, matchFeatures, err := s.fetchMatchAnalysis(ctx, matchID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch match analysis: %w", err)
 	}
 
-	// Run agents in parallel (simplified version - in production use Dapr workflow)
-	statOutput, err := s.statisticalAgent.Analyze(ctx, analysis)
+	inputHash, err := hashAnalysis(analysis)
 	if err != nil {
-		return nil, fmt.Errorf("statistical analysis failed: %w", err)
+		return nil, err
 	}
 
-	formOutput, err := s.formAgent.Analyze(ctx, analysis)
+	p, err := s.pipelineFor(matchFeatures.CompetitionCode)
 	if err != nil {
-		return nil, fmt.Errorf("form analysis failed: %w", err)
+		return nil, err
 	}
 
-	h2hOutput, err := s.headToHeadAgent.Analyze(ctx, analysis)
-	if err != nil {
-		return nil, fmt.Errorf("head-to-head analysis failed: %w", err)
+	if !force {
+		current, err := s.reusablePrediction(ctx, matchID, inputHash, p)
+		if err != nil {
+			return nil, err
+		}
+		if current != nil {
+			return current, nil
+		}
 	}
 
-	// Aggregate results
-	agentOutputs := []AgentOutput{*statOutput, *formOutput, *h2hOutput}
-	finalOutput, err := s.aggregatorAgent.Aggregate(ctx, agentOutputs)
+	prediction, err := s.runAgents(ctx, analysis, p)
 	if err != nil {
-		return nil, fmt.Errorf("aggregation failed: %w", err)
+		return nil, err
 	}
-
-	// Save prediction to database
-	prediction := &PredictionResult{
-		ID:           uuid.New().String(),
-		MatchID:      matchID,
-		HomeWinProb:  finalOutput.HomeWinProb,
-		DrawProb:     finalOutput.DrawProb,
-		AwayWinProb:  finalOutput.AwayWinProb,
-		Confidence:   finalOutput.Confidence,
-		Status:       "completed",
-		AgentOutputs: agentOutputs,
-		Reasoning:    finalOutput.Reasoning,
-		KeyFactors:   finalOutput.KeyFactors,
-		CreatedAt:    time.Now(),
-		UpdatedAt:    time.Now(),
+	prediction.ID = uuid.New().String()
+	prediction.MatchID = matchID
+	prediction.Status = "completed"
+	prediction.InputFingerprint = inputFingerprint(matchFeatures)
+	prediction.InputHash = inputHash
+
+	if err := s.calibrate(ctx, matchFeatures.CompetitionID, prediction); err != nil {
+		slog.Warn("Failed to calibrate prediction, keeping raw probabilities", "matchId", matchID, "error", err)
 	}
 
+	// Save prediction to database
 	if err := s.savePrediction(ctx, prediction); err != nil {
 		return nil, fmt.Errorf("failed to save prediction: %w", err)
 	}
@@ -87,20 +160,176 @@ func (s *Service) CreatePrediction(ctx context.Context, matchID int) (*Predictio
 	return prediction, nil
 }
 
-// GetPrediction retrieves a prediction by ID
+// runAgents runs the pipeline on an analysis and derives the goal markets,
+// leaving the result unidentified and unsaved
+func (s *Service) runAgents(ctx context.Context, analysis *MatchAnalysis, p *pipeline) (*PredictionResult, error) {
+	run, err := executePipeline(p.config, &localSteps{ctx: ctx, pipeline: p, analysis: analysis})
+	if err != nil {
+		return nil, err
+	}
+	finalOutput, agentOutputs := run.final, run.outputs
+
+	prediction := &PredictionResult{
+		HomeWinProb:   finalOutput.HomeWinProb,
+		DrawProb:      finalOutput.DrawProb,
+		AwayWinProb:   finalOutput.AwayWinProb,
+		Confidence:    finalOutput.Confidence,
+		AgentOutputs:  agentOutputs,
+		Reasoning:     finalOutput.Reasoning,
+		KeyFactors:    finalOutput.KeyFactors,
+		CreatedAt:     time.Now(),
+		UpdatedAt:     time.Now(),
+		InputSnapshot: analysis,
+		PromptVersion: PromptVersion,
+		Models:        p.models(),
+		Pipeline:      p.config.Name,
+		LLMCalls:      p.llmCalls(),
+		Debate:        run.debate,
+	}
+	if homeGoals, awayGoals, ok := expectedGoals(finalOutput, agentOutputs); ok {
+		prediction.ScoreDistribution = NewScoreDistribution(homeGoals, awayGoals)
+		prediction.Markets = prediction.ScoreDistribution.Markets()
+	}
+	prediction.setUncertainty()
+
+	return prediction, nil
+}
+
+// predictionColumns are the predictions columns read by scanPrediction
+const predictionColumns = `id, match_id, home_win_prob, draw_prob, away_win_prob, confidence,
+		       reasoning, agent_outputs, workflow_id, status, created_at, updated_at,
+		       home_expected_goals, away_expected_goals, markets, input_fingerprint,
+		       revision, previous_id, input_hash, prompt_version, models, calibration,
+		       dispersion, derived_confidence, pipeline, llm_calls, debate`
+
+// rowScanner is satisfied by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// GetPrediction retrieves a prediction by ID, with the snapshot of its inputs
 func (s *Service) GetPrediction(ctx context.Context, id string) (*PredictionResult, error) {
+	return s.getPrediction(ctx, "id = $1", id)
+}
+
+// getPrediction retrieves the prediction matching the condition, with the
+// snapshot of its inputs
+func (s *Service) getPrediction(ctx context.Context, condition string, args ...any) (*PredictionResult, error) {
 	query := `
-		SELECT id, match_id, home_win_prob, draw_prob, away_win_prob, confidence, 
-		       reasoning, agent_outputs, workflow_id, status, created_at, updated_at
+		SELECT ` + predictionColumns + `, input_snapshot
 		FROM predictions
-		WHERE id = $1
+		WHERE ` + condition
+
+	var snapshotJSON []byte
+	prediction, err := scanPrediction(s.db.QueryRowContext(ctx, query, args...), &snapshotJSON)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("prediction not found")
+		}
+		return nil, fmt.Errorf("failed to get prediction: %w", err)
+	}
+
+	if len(snapshotJSON) > 0 {
+		if err := json.Unmarshal(snapshotJSON, &prediction.InputSnapshot); err != nil {
+			slog.Error("Failed to unmarshal input snapshot", "predictionId", prediction.ID, "error", err)
+		}
+	}
+
+	return prediction, nil
+}
+
+// GetPredictionsByMatch retrieves all revisions of a match's prediction, newest first
+func (s *Service) GetPredictionsByMatch(ctx context.Context, matchID int) ([]PredictionResult, error) {
+	query := `
+		SELECT ` + predictionColumns + `
+		FROM predictions
+		WHERE match_id = $1
+		ORDER BY revision DESC, created_at DESC
 	`
 
+	rows, err := s.db.QueryContext(ctx, query, matchID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query predictions: %w", err)
+	}
+	defer rows.Close()
+
+	var predictions []PredictionResult
+	for rows.Next() {
+		prediction, err := scanPrediction(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan prediction: %w", err)
+		}
+		predictions = append(predictions, *prediction)
+	}
+
+	return predictions, nil
+}
+
+// GetCurrentPrediction retrieves the latest revision of a match's prediction
+func (s *Service) GetCurrentPrediction(ctx context.Context, matchID int) (*PredictionResult, error) {
+	latest, err := s.GetLatestPredictions(ctx, []int{matchID})
+	if err != nil {
+		return nil, err
+	}
+
+	prediction, ok := latest[matchID]
+	if !ok {
+		return nil, fmt.Errorf("prediction not found")
+	}
+	return prediction, nil
+}
+
+// GetLatestPredictions retrieves the most recent prediction for each of the given matches
+func (s *Service) GetLatestPredictions(ctx context.Context, matchIDs []int) (map[int]*PredictionResult, error) {
+	latest := make(map[int]*PredictionResult, len(matchIDs))
+	if len(matchIDs) == 0 {
+		return latest, nil
+	}
+
+	query := `
+		SELECT DISTINCT ON (match_id) ` + predictionColumns + `
+		FROM predictions
+		WHERE match_id = ANY($1)
+		ORDER BY match_id, revision DESC, created_at DESC
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, pq.Array(matchIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query latest predictions: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		prediction, err := scanPrediction(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan prediction: %w", err)
+		}
+		latest[prediction.MatchID] = prediction
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate latest predictions: %w", err)
+	}
+
+	return latest, nil
+}
+
+// scanPrediction scans a row of predictionColumns followed by extra columns.
+// Agent outputs that fail to unmarshal are logged and left empty
+func scanPrediction(row rowScanner, extra ...any) (*PredictionResult, error) {
 	var prediction PredictionResult
 	var reasoningJSON, agentOutputsJSON []byte
 	var workflowID sql.NullString
-
-	err := s.db.QueryRowContext(ctx, query, id).Scan(
+	var homeGoals, awayGoals sql.NullFloat64
+	var marketsJSON []byte
+	var fingerprint sql.NullString
+	var previousID, inputHash sql.NullString
+	var promptVersion sql.NullInt64
+	var modelsJSON, calibrationJSON, dispersionJSON, debateJSON []byte
+	var derivedConfidence sql.NullFloat64
+	var llmCalls sql.NullInt64
+
+	dest := []any{
 		&prediction.ID,
 		&prediction.MatchID,
 		&prediction.HomeWinProb,
@@ -113,15 +342,34 @@ func (s *Service) GetPrediction(ctx context.Context, id string) (*PredictionResu
 		&prediction.Status,
 		&prediction.CreatedAt,
 		&prediction.UpdatedAt,
-	)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("prediction not found")
-		}
-		return nil, fmt.Errorf("failed to get prediction: %w", err)
+		&homeGoals,
+		&awayGoals,
+		&marketsJSON,
+		&fingerprint,
+		&prediction.Revision,
+		&previousID,
+		&inputHash,
+		&promptVersion,
+		&modelsJSON,
+		&calibrationJSON,
+		&dispersionJSON,
+		&derivedConfidence,
+		&prediction.Pipeline,
+		&llmCalls,
+		&debateJSON,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
 	}
 
 	prediction.WorkflowID = workflowID.String
+	prediction.InputFingerprint = fingerprint.String
+	prediction.PreviousID = previousID.String
+	prediction.InputHash = inputHash.String
+	prediction.PromptVersion = int(promptVersion.Int64)
+	prediction.DerivedConfidence = derivedConfidence.Float64
+	prediction.LLMCalls = int(llmCalls.Int64)
+	prediction.setGoalMarkets(homeGoals, awayGoals, marketsJSON)
 
 	// Parse JSON fields
 	var reasoning map[string]any
@@ -132,139 +380,138 @@ func (s *Service) GetPrediction(ctx context.Context, id string) (*PredictionResu
 	}
 
 	if err := json.Unmarshal(agentOutputsJSON, &prediction.AgentOutputs); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal agent outputs: %w", err)
+		slog.Error("Failed to unmarshal agent outputs", "predictionId", prediction.ID, "error", err)
 	}
 
-	return &prediction, nil
-}
-
-// GetPredictionsByMatch retrieves all predictions for a match
-func (s *Service) GetPredictionsByMatch(ctx context.Context, matchID int) ([]PredictionResult, error) {
-	query := `
-		SELECT id, match_id, home_win_prob, draw_prob, away_win_prob, confidence,
-		       reasoning, agent_outputs, workflow_id, status, created_at, updated_at
-		FROM predictions
-		WHERE match_id = $1
-		ORDER BY created_at DESC
-	`
-
-	rows, err := s.db.QueryContext(ctx, query, matchID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to query predictions: %w", err)
+	if len(modelsJSON) > 0 {
+		if err := json.Unmarshal(modelsJSON, &prediction.Models); err != nil {
+			slog.Error("Failed to unmarshal models", "predictionId", prediction.ID, "error", err)
+		}
 	}
-	defer rows.Close()
 
-	var predictions []PredictionResult
-	for rows.Next() {
-		var prediction PredictionResult
-		var reasoningJSON, agentOutputsJSON []byte
-		var workflowID sql.NullString
-
-		err := rows.Scan(
-			&prediction.ID,
-			&prediction.MatchID,
-			&prediction.HomeWinProb,
-			&prediction.DrawProb,
-			&prediction.AwayWinProb,
-			&prediction.Confidence,
-			&reasoningJSON,
-			&agentOutputsJSON,
-			&workflowID,
-			&prediction.Status,
-			&prediction.CreatedAt,
-			&prediction.UpdatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan prediction: %w", err)
+	if len(calibrationJSON) > 0 {
+		if err := json.Unmarshal(calibrationJSON, &prediction.Calibration); err != nil {
+			slog.Error("Failed to unmarshal calibration", "predictionId", prediction.ID, "error", err)
 		}
+	}
 
-		prediction.WorkflowID = workflowID.String
-
-		// Parse JSON fields
-		var reasoning map[string]any
-		if err := json.Unmarshal(reasoningJSON, &reasoning); err == nil {
-			if r, ok := reasoning["text"].(string); ok {
-				prediction.Reasoning = r
-			}
+	if len(dispersionJSON) > 0 {
+		if err := json.Unmarshal(dispersionJSON, &prediction.Dispersion); err != nil {
+			slog.Error("Failed to unmarshal dispersion", "predictionId", prediction.ID, "error", err)
 		}
+	}
 
-		if err := json.Unmarshal(agentOutputsJSON, &prediction.AgentOutputs); err != nil {
-			slog.Error("Failed to unmarshal agent outputs", "predictionId", prediction.ID, "error", err)
-			continue
+	if len(debateJSON) > 0 {
+		if err := json.Unmarshal(debateJSON, &prediction.Debate); err != nil {
+			slog.Error("Failed to unmarshal debate", "predictionId", prediction.ID, "error", err)
 		}
-
-		predictions = append(predictions, prediction)
 	}
 
-	return predictions, nil
+	return &prediction, nil
 }
 
 // Helper functions
 
-func (s *Service) fetchMatchAnalysis(ctx context.Context, matchID int) (*MatchAnalysis, error) {
-	// Fetch match details from database
-	query := `
-		SELECT id, home_team, away_team, utc_date
-		FROM matches
-		WHERE id = $1
-	`
+// fetchMatchAnalysis builds the analysis from the match's point-in-time
+// features, returning it with the features it was built from
+func (s *Service) fetchMatchAnalysis(ctx context.Context, matchID int) (*MatchAnalysis, *features.MatchFeatures, error) {
+	matchFeatures, err := s.features.Get(ctx, matchID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load match features: %w", err)
+	}
+
+	return newMatchAnalysis(matchFeatures), matchFeatures, nil
+}
 
-	var homeTeamJSON, awayTeamJSON []byte
-	var utcDate time.Time
-	err := s.db.QueryRowContext(ctx, query, matchID).Scan(&matchID, &homeTeamJSON, &awayTeamJSON, &utcDate)
+// InputFingerprint returns the fingerprint of the inputs a prediction of the
+// match would use now, to tell whether a stored prediction is out of date
+func (s *Service) InputFingerprint(ctx context.Context, matchID int) (string, error) {
+	matchFeatures, err := s.features.Get(ctx, matchID)
 	if err != nil {
-		return nil, fmt.Errorf("match not found: %w", err)
+		return "", fmt.Errorf("failed to load match features: %w", err)
 	}
 
-	// Parse team data
-	var homeTeamData, awayTeamData map[string]any
-	if err := json.Unmarshal(homeTeamJSON, &homeTeamData); err != nil {
-		return nil, err
+	return inputFingerprint(matchFeatures), nil
+}
+
+// inputFingerprint identifies the feature set version and inputs behind a prediction
+func inputFingerprint(f *features.MatchFeatures) string {
+	return fmt.Sprintf("v%d:%s", f.Version, f.Fingerprint)
+}
+
+// newMatchAnalysis converts match features into the analysis given to the agents
+func newMatchAnalysis(f *features.MatchFeatures) *MatchAnalysis {
+	analysis := &MatchAnalysis{
+		MatchID:     f.MatchID,
+		HomeTeam:    newTeamAnalysis(f.Home),
+		AwayTeam:    newTeamAnalysis(f.Away),
+		Competition: f.Competition,
+		MatchDate:   f.Kickoff,
+		HeadToHead:  []HistoricalMatch{},
+		Metadata:    map[string]any{"featureVersion": f.Version},
 	}
-	if err := json.Unmarshal(awayTeamJSON, &awayTeamData); err != nil {
-		return nil, err
+
+	if f.HeadToHead != nil {
+		for _, m := range f.HeadToHead.RecentMatches {
+			analysis.HeadToHead = append(analysis.HeadToHead, HistoricalMatch{
+				Date:        m.Date,
+				HomeTeamID:  m.HomeTeamID,
+				AwayTeamID:  m.AwayTeamID,
+				HomeScore:   m.HomeScore,
+				AwayScore:   m.AwayScore,
+				Competition: m.Competition,
+			})
+		}
+	}
+
+	// The referee is known once appointed; the profile only covers earlier matches
+	if f.Referee != nil {
+		analysis.Metadata["referee"] = f.Referee
 	}
 
-	// Safely extract team IDs and names
-	homeID, homeIDOk := homeTeamData["id"].(float64)
-	homeName, homeNameOk := homeTeamData["name"].(string)
-	awayID, awayIDOk := awayTeamData["id"].(float64)
-	awayName, awayNameOk := awayTeamData["name"].(string)
+	return analysis
+}
 
-	if !homeIDOk || !homeNameOk || !awayIDOk || !awayNameOk {
-		return nil, fmt.Errorf("invalid team data structure")
+// newTeamAnalysis converts a team's features into its analysis
+func newTeamAnalysis(t features.TeamFeatures) TeamAnalysis {
+	team := TeamAnalysis{
+		ID:              t.TeamID,
+		Name:            t.Name,
+		Elo:             t.Elo,
+		Form:            t.Form,
+		UnderlyingStats: t.UnderlyingStats,
+		Schedule:        t.Schedule,
+	}
+	if t.Standing != nil {
+		applyStanding(&team, t.Standing)
 	}
+	return team
+}
 
-	// Build analysis structure (simplified version)
-	analysis := &MatchAnalysis{
-		MatchID:   matchID,
-		MatchDate: utcDate,
-		HomeTeam: TeamAnalysis{
-			ID:   int(homeID),
-			Name: homeName,
-			Statistics: TeamStatistics{
-				MatchesPlayed: 10,
-				Wins:          5,
-				Draws:         3,
-				Losses:        2,
-			},
-		},
-		AwayTeam: TeamAnalysis{
-			ID:   int(awayID),
-			Name: awayName,
-			Statistics: TeamStatistics{
-				MatchesPlayed: 10,
-				Wins:          4,
-				Draws:         4,
-				Losses:        2,
-			},
-		},
-		HeadToHead: []HistoricalMatch{},
-	}
-
-	return analysis, nil
+// applyStanding fills team statistics from a league table row
+func applyStanding(team *TeamAnalysis, standing *footballdata.TeamStanding) {
+	team.TablePosition = standing.Position
+	team.Points = standing.Points
+	team.Statistics = TeamStatistics{
+		GoalsScored:    standing.GoalsFor,
+		GoalsConceded:  standing.GoalsAgainst,
+		MatchesPlayed:  standing.PlayedGames,
+		Wins:           standing.Won,
+		Draws:          standing.Draw,
+		Losses:         standing.Lost,
+		GoalDifference: standing.GoalDifference,
+	}
+	if standing.PlayedGames > 0 {
+		team.Statistics.AvgGoalsScored = float64(standing.GoalsFor) / float64(standing.PlayedGames)
+		team.Statistics.AvgConceded = float64(standing.GoalsAgainst) / float64(standing.PlayedGames)
+	}
+	if standing.Form != nil {
+		team.CurrentForm = *standing.Form
+		team.RecentForm = strings.Split(*standing.Form, ",")
+	}
 }
 
+// savePrediction stores the prediction as the next revision of its match's prediction
 func (s *Service) savePrediction(ctx context.Context, prediction *PredictionResult) error {
 	reasoningJSON, err := json.Marshal(map[string]any{"text": prediction.Reasoning})
 	if err != nil {
@@ -276,12 +523,67 @@ func (s *Service) savePrediction(ctx context.Context, prediction *PredictionResu
 		return fmt.Errorf("failed to marshal agent outputs: %w", err)
 	}
 
+	var homeGoals, awayGoals sql.NullFloat64
+	var marketsJSON []byte
+	if prediction.ScoreDistribution != nil {
+		homeGoals = sql.NullFloat64{Float64: prediction.ScoreDistribution.HomeExpectedGoals, Valid: true}
+		awayGoals = sql.NullFloat64{Float64: prediction.ScoreDistribution.AwayExpectedGoals, Valid: true}
+		if marketsJSON, err = json.Marshal(prediction.Markets); err != nil {
+			return fmt.Errorf("failed to marshal markets: %w", err)
+		}
+	}
+
+	snapshotJSON, err := json.Marshal(prediction.InputSnapshot)
+	if err != nil {
+		return fmt.Errorf("failed to marshal input snapshot: %w", err)
+	}
+
+	modelsJSON, err := json.Marshal(prediction.Models)
+	if err != nil {
+		return fmt.Errorf("failed to marshal models: %w", err)
+	}
+
+	var calibrationJSON []byte
+	if prediction.Calibration != nil {
+		if calibrationJSON, err = json.Marshal(prediction.Calibration); err != nil {
+			return fmt.Errorf("failed to marshal calibration: %w", err)
+		}
+	}
+
+	var dispersionJSON []byte
+	if prediction.Dispersion != nil {
+		if dispersionJSON, err = json.Marshal(prediction.Dispersion); err != nil {
+			return fmt.Errorf("failed to marshal dispersion: %w", err)
+		}
+	}
+
+	var debateJSON []byte
+	if prediction.Debate != nil {
+		if debateJSON, err = json.Marshal(prediction.Debate); err != nil {
+			return fmt.Errorf("failed to marshal debate: %w", err)
+		}
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := nextRevision(ctx, tx, prediction); err != nil {
+		return err
+	}
+
 	query := `
-		INSERT INTO predictions (id, match_id, home_win_prob, draw_prob, away_win_prob, confidence, reasoning, agent_outputs, workflow_id, status, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		INSERT INTO predictions (id, match_id, home_win_prob, draw_prob, away_win_prob, confidence, reasoning, agent_outputs, workflow_id, status, created_at, updated_at,
+		                         home_expected_goals, away_expected_goals, markets, input_fingerprint,
+		                         revision, previous_id, input_hash, input_snapshot, prompt_version, models, calibration,
+		                         dispersion, derived_confidence, pipeline, llm_calls, debate)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25,
+		        $26, $27, $28)
 	`
 
-	_, err = s.db.ExecContext(ctx, query,
+	_, err = tx.ExecContext(ctx, query,
 		prediction.ID,
 		prediction.MatchID,
 		prediction.HomeWinProb,
@@ -294,10 +596,30 @@ func (s *Service) savePrediction(ctx context.Context, prediction *PredictionResu
 		prediction.Status,
 		prediction.CreatedAt,
 		prediction.UpdatedAt,
+		homeGoals,
+		awayGoals,
+		marketsJSON,
+		sql.NullString{String: prediction.InputFingerprint, Valid: prediction.InputFingerprint != ""},
+		prediction.Revision,
+		sql.NullString{String: prediction.PreviousID, Valid: prediction.PreviousID != ""},
+		prediction.InputHash,
+		snapshotJSON,
+		prediction.PromptVersion,
+		modelsJSON,
+		calibrationJSON,
+		dispersionJSON,
+		prediction.DerivedConfidence,
+		prediction.Pipeline,
+		prediction.LLMCalls,
+		debateJSON,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to insert prediction: %w", err)
 	}
 
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit prediction: %w", err)
+	}
+
 	return nil
 }
@@ -0,0 +1,247 @@
+package predictions
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/edd/relaxovisionmonolith/footballdata"
+	"github.com/edd/relaxovisionmonolith/predictions/providers"
+)
+
+const (
+	// maxToolRounds bounds the rounds of tool calls before an agent must answer
+	maxToolRounds = 4
+	// defaultRecentMatches and maxRecentMatches size get_recent_matches results
+	defaultRecentMatches = 5
+	maxRecentMatches     = 20
+)
+
+// toolInstructions is appended to an analyst's prompt when it can call tools
+const toolInstructions = `
+
+You can call tools to look up more data from before kickoff: team form, head-to-head records,
+standings, recent matches and the referee's profile. Call them only for data the analysis
+above lacks, then answer in the JSON format above.`
+
+// matchToolDefinitions describes the match data tools to the model
+var matchToolDefinitions = []providers.Tool{
+	{
+		Name:        "get_team_form",
+		Description: "Recent form of a team: last results, goals, home and away points per game and a form score",
+		Parameters:  objectSchema(map[string]any{"teamId": integerSchema("Team ID")}, "teamId"),
+	},
+	{
+		Name:        "get_head_to_head",
+		Description: "Head-to-head record between two teams, with their recent meetings",
+		Parameters: objectSchema(map[string]any{
+			"team1Id": integerSchema("First team ID"),
+			"team2Id": integerSchema("Second team ID"),
+		}, "team1Id", "team2Id"),
+	},
+	{
+		Name:        "get_standings",
+		Description: "League table of a competition, by default the match's own",
+		Parameters: objectSchema(map[string]any{
+			"competitionId": integerSchema("Competition ID; defaults to the match's competition"),
+			"type": map[string]any{
+				"type":        "string",
+				"description": "Which matches count: all, home only or away only",
+				"enum":        []string{string(footballdata.TableTotal), string(footballdata.TableHome), string(footballdata.TableAway)},
+			},
+		}),
+	},
+	{
+		Name:        "get_recent_matches",
+		Description: "A team's most recent finished matches in any competition, newest first",
+		Parameters: objectSchema(map[string]any{
+			"teamId": integerSchema("Team ID"),
+			"limit":  integerSchema(fmt.Sprintf("Number of matches, default %d and at most %d", defaultRecentMatches, maxRecentMatches)),
+		}, "teamId"),
+	},
+	{
+		Name:        "get_referee_profile",
+		Description: "Profile of the match's referee: outcome rates, goals and cards per game",
+		// No arguments; Gemini, which rejects an empty schema, gets no parameters
+		Parameters: objectSchema(map[string]any{}),
+	},
+}
+
+// objectSchema returns the JSON Schema of an arguments object
+func objectSchema(properties map[string]any, required ...string) map[string]any {
+	schema := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func integerSchema(description string) map[string]any {
+	return map[string]any{"type": "integer", "description": description}
+}
+
+// toolArguments are the arguments of any match data tool
+type toolArguments struct {
+	TeamID        int    `json:"teamId"`
+	Team1ID       int    `json:"team1Id"`
+	Team2ID       int    `json:"team2Id"`
+	CompetitionID int    `json:"competitionId"`
+	Type          string `json:"type"`
+	Limit         int    `json:"limit"`
+}
+
+// toolMatch is a finished match as returned by get_recent_matches
+type toolMatch struct {
+	Date        time.Time `json:"date"`
+	Competition string    `json:"competition"`
+	HomeTeamID  int       `json:"homeTeamId"`
+	HomeTeam    string    `json:"homeTeam"`
+	AwayTeamID  int       `json:"awayTeamId"`
+	AwayTeam    string    `json:"awayTeam"`
+	HomeScore   *int      `json:"homeScore"`
+	AwayScore   *int      `json:"awayScore"`
+}
+
+// MatchTools answers agents' tool calls from the football data analyzers and
+// repository, as the data stood before the analysed match kicked off
+type MatchTools struct {
+	db        *sql.DB
+	repo      *footballdata.Repository
+	form      *footballdata.FormAnalyzer
+	h2h       *footballdata.H2HAnalyzer
+	standings *footballdata.StandingsCalculator
+}
+
+// NewMatchTools creates the match data tools
+func NewMatchTools(db *sql.DB) *MatchTools {
+	return &MatchTools{
+		db:        db,
+		repo:      footballdata.NewRepository(db),
+		form:      footballdata.NewFormAnalyzer(db),
+		h2h:       footballdata.NewH2HAnalyzer(db),
+		standings: footballdata.NewStandingsCalculator(db),
+	}
+}
+
+// request returns the tool request for an analyst's prompt about the analysis
+func (t *MatchTools) request(analysis *MatchAnalysis, system, prompt string) providers.ToolRequest {
+	return providers.ToolRequest{
+		System:    system,
+		Prompt:    prompt + toolInstructions,
+		Tools:     matchToolDefinitions,
+		Handler:   t.handler(analysis),
+		MaxRounds: maxToolRounds,
+	}
+}
+
+// handler runs tool calls about the analysis. Every query is limited to data
+// from before its kickoff, so tools cannot leak the result
+func (t *MatchTools) handler(analysis *MatchAnalysis) providers.ToolHandler {
+	kickoff := analysis.MatchDate
+
+	return func(ctx context.Context, name string, arguments json.RawMessage) (json.RawMessage, error) {
+		var args toolArguments
+		if err := json.Unmarshal(arguments, &args); err != nil {
+			return nil, fmt.Errorf("invalid arguments: %w", err)
+		}
+
+		var result any
+		var err error
+		switch name {
+		case "get_team_form":
+			result, err = t.form.AnalyzeTeamFormAt(ctx, args.TeamID, kickoff)
+		case "get_head_to_head":
+			result, err = t.h2h.AnalyzeHeadToHeadAt(ctx, args.Team1ID, args.Team2ID, kickoff)
+		case "get_standings":
+			result, err = t.getStandings(ctx, analysis, args)
+		case "get_recent_matches":
+			result, err = t.getRecentMatches(ctx, args.TeamID, args.Limit, kickoff)
+		case "get_referee_profile":
+			result, err = t.getRefereeProfile(ctx, analysis.MatchID, kickoff)
+		default:
+			return nil, fmt.Errorf("unknown tool: %s", name)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		return json.Marshal(result)
+	}
+}
+
+// getStandings computes a competition's table at kickoff
+func (t *MatchTools) getStandings(ctx context.Context, analysis *MatchAnalysis, args toolArguments) (*footballdata.ComputedStandings, error) {
+	competitionID := args.CompetitionID
+	if competitionID == 0 {
+		if analysis.MatchID == 0 {
+			return nil, fmt.Errorf("competitionId is required for a hypothetical match")
+		}
+		err := t.db.QueryRowContext(ctx, `SELECT competition_id FROM matches WHERE id = $1`, analysis.MatchID).Scan(&competitionID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get match competition: %w", err)
+		}
+	}
+
+	tableType := footballdata.TableType(args.Type)
+	switch tableType {
+	case "", footballdata.TableTotal, footballdata.TableHome, footballdata.TableAway:
+	default:
+		return nil, fmt.Errorf("type must be TOTAL, HOME or AWAY")
+	}
+
+	return t.standings.ComputeStandings(ctx, competitionID, footballdata.StandingsOptions{AsOf: analysis.MatchDate, Type: tableType})
+}
+
+// getRecentMatches lists a team's latest finished matches before kickoff
+func (t *MatchTools) getRecentMatches(ctx context.Context, teamID, limit int, before time.Time) ([]toolMatch, error) {
+	if limit <= 0 {
+		limit = defaultRecentMatches
+	}
+
+	page, err := t.repo.ListMatches(ctx, footballdata.MatchFilter{
+		TeamID: teamID,
+		Status: []string{"FINISHED"},
+		To:     before,
+		Sort:   footballdata.SortDateDesc,
+		Limit:  min(limit, maxRecentMatches),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	matches := make([]toolMatch, len(page.Items))
+	for i, m := range page.Items {
+		matches[i] = toolMatch{
+			Date:        m.UTCDate,
+			Competition: m.Competition.Name,
+			HomeTeamID:  m.HomeTeam.ID,
+			HomeTeam:    m.HomeTeam.Name,
+			AwayTeamID:  m.AwayTeam.ID,
+			AwayTeam:    m.AwayTeam.Name,
+			HomeScore:   m.Score.FullTime.Home,
+			AwayScore:   m.Score.FullTime.Away,
+		}
+	}
+	return matches, nil
+}
+
+// getRefereeProfile returns the profile of the match's referee from matches before kickoff
+func (t *MatchTools) getRefereeProfile(ctx context.Context, matchID int, before time.Time) (*footballdata.RefereeProfile, error) {
+	if matchID == 0 {
+		return nil, fmt.Errorf("a hypothetical match has no referee")
+	}
+
+	referee, err := t.repo.GetMatchReferee(ctx, matchID)
+	if err != nil {
+		return nil, err
+	}
+
+	profile, err := t.repo.GetRefereeProfile(ctx, referee.ID, before)
+	if err != nil {
+		return nil, err
+	}
+	profile.Referee.Type = referee.Type
+	return profile, nil
+}
@@ -0,0 +1,125 @@
+package predictions
+
+import (
+	"math"
+)
+
+// maxSpread is the largest standard deviation a set of probabilities can have
+const maxSpread = 0.5
+
+// Dispersion summarises how much a set of 1X2 forecasts disagree: the
+// providers or samples behind an agent, or the agents behind a prediction
+type Dispersion struct {
+	Sources int           `json:"sources"` // forecasts compared
+	HomeWin OutcomeSpread `json:"homeWin"`
+	Draw    OutcomeSpread `json:"draw"`
+	AwayWin OutcomeSpread `json:"awayWin"`
+	// Entropy of the mean forecast over the entropy of an even one: 0 is certain, 1 is a coin toss
+	Entropy float64 `json:"entropy"`
+	// Disagreement is the mean standard deviation over its maximum: 0 when all forecasts agree
+	Disagreement float64 `json:"disagreement"`
+}
+
+// OutcomeSpread is the spread of one outcome's probability across forecasts
+type OutcomeSpread struct {
+	Mean   float64 `json:"mean"`
+	StdDev float64 `json:"stdDev"`
+	Min    float64 `json:"min"`
+	Max    float64 `json:"max"`
+}
+
+// newDispersion measures the spread of the forecasts, or returns nil without any
+func newDispersion(forecasts [][3]float64) *Dispersion {
+	if len(forecasts) == 0 {
+		return nil
+	}
+
+	var spreads [3]OutcomeSpread
+	var mean [3]float64
+	for i := range spreads {
+		values := make([]float64, len(forecasts))
+		for j, f := range forecasts {
+			values[j] = f[i]
+		}
+		spreads[i] = newOutcomeSpread(values)
+		mean[i] = spreads[i].Mean
+	}
+
+	d := &Dispersion{
+		Sources: len(forecasts),
+		HomeWin: spreads[0],
+		Draw:    spreads[1],
+		AwayWin: spreads[2],
+		Entropy: round4(normalisedEntropy(mean)),
+	}
+	d.Disagreement = round4((spreads[0].StdDev + spreads[1].StdDev + spreads[2].StdDev) / 3 / maxSpread)
+	return d
+}
+
+// newOutcomeSpread returns the mean, population standard deviation and range of the values
+func newOutcomeSpread(values []float64) OutcomeSpread {
+	s := OutcomeSpread{Min: values[0], Max: values[0]}
+	for _, v := range values {
+		s.Mean += v
+		s.Min = min(s.Min, v)
+		s.Max = max(s.Max, v)
+	}
+	s.Mean /= float64(len(values))
+
+	for _, v := range values {
+		s.StdDev += (v - s.Mean) * (v - s.Mean)
+	}
+	s.StdDev = math.Sqrt(s.StdDev / float64(len(values)))
+
+	s.Mean, s.StdDev = round4(s.Mean), round4(s.StdDev)
+	return s
+}
+
+// normalisedEntropy is the Shannon entropy of a forecast over that of an even forecast
+func normalisedEntropy(probs [3]float64) float64 {
+	total := probs[0] + probs[1] + probs[2]
+	if total <= 0 {
+		return 1
+	}
+
+	entropy := 0.0
+	for _, p := range probs {
+		if p /= total; p > 0 {
+			entropy -= p * math.Log(p)
+		}
+	}
+	return entropy / math.Log(3)
+}
+
+// derivedConfidence is computed from the forecast rather than self-reported:
+// its sharpness (one minus its normalised entropy) scaled by how well the
+// forecasts behind it agree
+func derivedConfidence(probs [3]float64, d *Dispersion) float64 {
+	confidence := 1 - normalisedEntropy(probs)
+	if d != nil {
+		confidence *= 1 - d.Disagreement
+	}
+	return round4(min(max(confidence, 0), 1))
+}
+
+// forecastOf returns an agent's 1X2 probabilities
+func forecastOf(o AgentOutput) [3]float64 {
+	return [3]float64{o.HomeWinProb, o.DrawProb, o.AwayWinProb}
+}
+
+// setUncertainty sets the agent's derived confidence from its probabilities
+// and dispersion
+func (o *AgentOutput) setUncertainty() {
+	o.DerivedConfidence = derivedConfidence(forecastOf(*o), o.Dispersion)
+}
+
+// setUncertainty measures the disagreement between the prediction's agents
+// and derives its confidence from it
+func (p *PredictionResult) setUncertainty() {
+	forecasts := make([][3]float64, len(p.AgentOutputs))
+	for i, o := range p.AgentOutputs {
+		forecasts[i] = forecastOf(o)
+	}
+	p.Dispersion = newDispersion(forecasts)
+	p.DerivedConfidence = derivedConfidence([3]float64{p.HomeWinProb, p.DrawProb, p.AwayWinProb}, p.Dispersion)
+}
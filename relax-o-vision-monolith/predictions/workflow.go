@@ -8,7 +8,9 @@ import (
 	"github.com/dapr/go-sdk/workflow"
 )
 
-// PredictionWorkflow defines the Dapr workflow for match predictions
+// PredictionWorkflow defines the Dapr workflow for match predictions. It runs
+// the pipeline of the match's competition, as the service does, with each
+// agent's analysis, revision, the critique and the aggregation as activities
 func PredictionWorkflow(ctx *workflow.WorkflowContext) (any, error) {
 	var input WorkflowInput
 	if err := ctx.GetInput(&input); err != nil {
@@ -17,88 +19,204 @@ func PredictionWorkflow(ctx *workflow.WorkflowContext) (any, error) {
 
 	slog.Info("Starting prediction workflow", "matchId", input.MatchID)
 
-	// Step 1: Fetch match data
-	var matchAnalysis MatchAnalysis
-	if err := ctx.CallActivity(FetchMatchDataActivity, workflow.ActivityInput(input.MatchID)).Await(&matchAnalysis); err != nil {
+	// Step 1: Fetch match data and the pipeline of its competition
+	var pipelineInput PipelineInput
+	if err := ctx.CallActivity(FetchMatchDataActivity, workflow.ActivityInput(input.MatchID)).Await(&pipelineInput); err != nil {
 		return nil, fmt.Errorf("failed to fetch match data: %w", err)
 	}
 
-	// Step 2: Run statistical agent
-	var statOutput AgentOutput
-	if err := ctx.CallActivity(StatisticalAnalysisActivity, workflow.ActivityInput(matchAnalysis)).Await(&statOutput); err != nil {
-		slog.Error("Statistical agent failed", "error", err)
-		statOutput = AgentOutput{
-			AgentType:   AgentTypeStatistical,
-			Confidence:  0.0,
-			Reasoning:   "Analysis failed",
-		}
+	// Step 2: Run the pipeline's agents, debate and aggregation
+	run, err := executePipeline(pipelineInput.Pipeline, newWorkflowSteps(ctx, pipelineInput))
+	if err != nil {
+		return nil, err
 	}
 
-	// Step 3: Run form agent
-	var formOutput AgentOutput
-	if err := ctx.CallActivity(FormAnalysisActivity, workflow.ActivityInput(matchAnalysis)).Await(&formOutput); err != nil {
-		slog.Error("Form agent failed", "error", err)
-		formOutput = AgentOutput{
-			AgentType:   AgentTypeForm,
-			Confidence:  0.0,
-			Reasoning:   "Analysis failed",
-		}
+	// Build final output
+	output := WorkflowOutput{
+		HomeWinProb:  run.final.HomeWinProb,
+		DrawProb:     run.final.DrawProb,
+		AwayWinProb:  run.final.AwayWinProb,
+		Confidence:   run.final.Confidence,
+		Reasoning:    run.final.Reasoning,
+		AgentOutputs: run.outputs,
+		Pipeline:     pipelineInput.Pipeline.Name,
+		Debate:       run.debate,
+	}
+	if homeGoals, awayGoals, ok := expectedGoals(run.final, run.outputs); ok {
+		output.HomeExpectedGoals = &homeGoals
+		output.AwayExpectedGoals = &awayGoals
 	}
 
-	// Step 4: Run head-to-head agent
-	var h2hOutput AgentOutput
-	if err := ctx.CallActivity(HeadToHeadAnalysisActivity, workflow.ActivityInput(matchAnalysis)).Await(&h2hOutput); err != nil {
-		slog.Error("Head-to-head agent failed", "error", err)
-		h2hOutput = AgentOutput{
-			AgentType:   AgentTypeHeadToHead,
-			Confidence:  0.0,
-			Reasoning:   "Analysis failed",
-		}
+	slog.Info("Prediction workflow completed", "matchId", input.MatchID, "pipeline", output.Pipeline, "confidence", output.Confidence)
+	return output, nil
+}
+
+// awaitable is a scheduled activity
+type awaitable interface {
+	Await(v any) error
+}
+
+// workflowSteps runs a pipeline's steps as workflow activities, the agents of
+// a step concurrently. As in the service, the first agent to fail fails the step
+type workflowSteps struct {
+	// call schedules an activity
+	call  func(activity string, input AgentActivityInput) awaitable
+	input PipelineInput
+}
+
+// newWorkflowSteps runs the pipeline's steps as activities of the workflow
+func newWorkflowSteps(ctx *workflow.WorkflowContext, input PipelineInput) *workflowSteps {
+	return &workflowSteps{
+		call: func(activity string, input AgentActivityInput) awaitable {
+			return ctx.CallActivity(activity, workflow.ActivityInput(input))
+		},
+		input: input,
 	}
+}
 
-	// Step 5: Aggregate predictions
-	agentOutputs := []AgentOutput{statOutput, formOutput, h2hOutput}
+func (w *workflowSteps) analyze() ([]AgentOutput, error) {
+	return w.each(AnalyzeAgentActivity, 0, nil)
+}
+
+func (w *workflowSteps) revise(round int, outputs []AgentOutput) ([]AgentOutput, error) {
+	return w.each(ReviseAgentActivity, round, outputs)
+}
+
+func (w *workflowSteps) critique(outputs []AgentOutput) (*AgentOutput, error) {
+	var critique AgentOutput
+	input := AgentActivityInput{PipelineInput: w.input, Outputs: outputs}
+	if err := w.call(CritiqueActivity, input).Await(&critique); err != nil {
+		return nil, fmt.Errorf("critique failed: %w", err)
+	}
+	return &critique, nil
+}
+
+func (w *workflowSteps) aggregate(outputs []AgentOutput) (*AgentOutput, error) {
 	var aggregateOutput AgentOutput
-	if err := ctx.CallActivity(AggregateAnalysisActivity, workflow.ActivityInput(agentOutputs)).Await(&aggregateOutput); err != nil {
+	input := AgentActivityInput{PipelineInput: w.input, Outputs: outputs}
+	if err := w.call(AggregateAnalysisActivity, input).Await(&aggregateOutput); err != nil {
 		return nil, fmt.Errorf("failed to aggregate predictions: %w", err)
 	}
+	return &aggregateOutput, nil
+}
 
-	// Build final output
-	output := WorkflowOutput{
-		HomeWinProb:  aggregateOutput.HomeWinProb,
-		DrawProb:     aggregateOutput.DrawProb,
-		AwayWinProb:  aggregateOutput.AwayWinProb,
-		Confidence:   aggregateOutput.Confidence,
-		Reasoning:    aggregateOutput.Reasoning,
-		AgentOutputs: agentOutputs,
+// each schedules the activity for every agent of the pipeline, then awaits them
+func (w *workflowSteps) each(activity string, round int, outputs []AgentOutput) ([]AgentOutput, error) {
+	agents := w.input.Pipeline.Agents
+
+	tasks := make([]awaitable, len(agents))
+	for i := range agents {
+		input := AgentActivityInput{PipelineInput: w.input, Agent: i, Round: round, Outputs: outputs}
+		tasks[i] = w.call(activity, input)
 	}
 
-	slog.Info("Prediction workflow completed", "matchId", input.MatchID, "confidence", output.Confidence)
-	return output, nil
+	results := make([]AgentOutput, len(agents))
+	for i, task := range tasks {
+		if err := task.Await(&results[i]); err != nil {
+			return nil, fmt.Errorf("agent %s: %w", agents[i].name(), err)
+		}
+	}
+	return results, nil
 }
 
 // Activity names
 const (
-	FetchMatchDataActivity          = "FetchMatchDataActivity"
-	StatisticalAnalysisActivity     = "StatisticalAnalysisActivity"
-	FormAnalysisActivity            = "FormAnalysisActivity"
-	HeadToHeadAnalysisActivity      = "HeadToHeadAnalysisActivity"
-	AggregateAnalysisActivity       = "AggregateAnalysisActivity"
+	FetchMatchDataActivity    = "FetchMatchDataActivity"
+	AnalyzeAgentActivity      = "AnalyzeAgentActivity"
+	ReviseAgentActivity       = "ReviseAgentActivity"
+	CritiqueActivity          = "CritiqueActivity"
+	AggregateAnalysisActivity = "AggregateAnalysisActivity"
 )
 
-// Activity functions (to be implemented by the service)
+// PipelineInput is a match's analysis with the pipeline that predicts it
+type PipelineInput struct {
+	Analysis MatchAnalysis  `json:"analysis"`
+	Pipeline PipelineConfig `json:"pipeline"`
+}
+
+// AgentActivityInput is the input of the agent, critique and aggregation activities
+type AgentActivityInput struct {
+	PipelineInput
+	Agent int `json:"agent,omitempty"` // index of the agent in the pipeline
+	Round int `json:"round,omitempty"`
+	// Outputs are the latest outputs to revise, critique or aggregate
+	Outputs []AgentOutput `json:"outputs,omitempty"`
+}
 
-// FetchMatchDataActivityFunc fetches match data for analysis
-type FetchMatchDataActivityFunc func(ctx context.Context, matchID int) (*MatchAnalysis, error)
+// Activity functions (implemented by the service)
 
-// StatisticalAnalysisActivityFunc performs statistical analysis
-type StatisticalAnalysisActivityFunc func(ctx context.Context, analysis *MatchAnalysis) (*AgentOutput, error)
+// FetchMatchDataActivityFunc fetches match data with the pipeline of its competition
+type FetchMatchDataActivityFunc func(ctx context.Context, matchID int) (*PipelineInput, error)
 
-// FormAnalysisActivityFunc performs form analysis
-type FormAnalysisActivityFunc func(ctx context.Context, analysis *MatchAnalysis) (*AgentOutput, error)
+// AnalyzeAgentActivityFunc runs the analysis of one agent of the pipeline
+type AnalyzeAgentActivityFunc func(ctx context.Context, input AgentActivityInput) (*AgentOutput, error)
 
-// HeadToHeadAnalysisActivityFunc performs head-to-head analysis
-type HeadToHeadAnalysisActivityFunc func(ctx context.Context, analysis *MatchAnalysis) (*AgentOutput, error)
+// ReviseAgentActivityFunc has one agent of the pipeline revise its output in a debate round
+type ReviseAgentActivityFunc func(ctx context.Context, input AgentActivityInput) (*AgentOutput, error)
+
+// CritiqueActivityFunc has the critic challenge the agents' outputs after a debate
+type CritiqueActivityFunc func(ctx context.Context, input AgentActivityInput) (*AgentOutput, error)
 
 // AggregateAnalysisActivityFunc aggregates multiple agent outputs
-type AggregateAnalysisActivityFunc func(ctx context.Context, outputs []AgentOutput) (*AgentOutput, error)
+type AggregateAnalysisActivityFunc func(ctx context.Context, input AgentActivityInput) (*AgentOutput, error)
+
+// FetchPipelineInput implements FetchMatchDataActivityFunc
+func (s *Service) FetchPipelineInput(ctx context.Context, matchID int) (*PipelineInput, error) {
+	analysis, matchFeatures, err := s.fetchMatchAnalysis(ctx, matchID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch match analysis: %w", err)
+	}
+
+	return &PipelineInput{Analysis: *analysis, Pipeline: s.pipelineConfig(matchFeatures.CompetitionCode)}, nil
+}
+
+// AnalyzeWithAgent implements AnalyzeAgentActivityFunc
+func (s *Service) AnalyzeWithAgent(ctx context.Context, input AgentActivityInput) (*AgentOutput, error) {
+	p, err := s.activityPipeline(input)
+	if err != nil {
+		return nil, err
+	}
+	return p.analyzeAgent(ctx, &input.Analysis, input.Agent)
+}
+
+// ReviseWithAgent implements ReviseAgentActivityFunc
+func (s *Service) ReviseWithAgent(ctx context.Context, input AgentActivityInput) (*AgentOutput, error) {
+	p, err := s.activityPipeline(input)
+	if err != nil {
+		return nil, err
+	}
+	if len(input.Outputs) != len(p.agents) {
+		return nil, fmt.Errorf("expected %d outputs to revise, got %d", len(p.agents), len(input.Outputs))
+	}
+	return p.reviseAgent(ctx, &input.Analysis, input.Outputs, input.Agent, input.Round)
+}
+
+// CritiqueOutputs implements CritiqueActivityFunc
+func (s *Service) CritiqueOutputs(ctx context.Context, input AgentActivityInput) (*AgentOutput, error) {
+	p, err := s.activityPipeline(input)
+	if err != nil {
+		return nil, err
+	}
+	return p.critique(ctx, &input.Analysis, input.Outputs)
+}
+
+// AggregateOutputs implements AggregateAnalysisActivityFunc
+func (s *Service) AggregateOutputs(ctx context.Context, input AgentActivityInput) (*AgentOutput, error) {
+	p, err := s.activityPipeline(input)
+	if err != nil {
+		return nil, err
+	}
+	return p.aggregate(ctx, input.Outputs)
+}
+
+// activityPipeline builds the pipeline an activity runs a step of
+func (s *Service) activityPipeline(input AgentActivityInput) (*pipeline, error) {
+	p, err := s.buildPipeline(input.Pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build pipeline: %w", err)
+	}
+	if input.Agent < 0 || input.Agent >= len(p.agents) {
+		return nil, fmt.Errorf("pipeline %s has no agent %d", p.config.Name, input.Agent)
+	}
+	return p, nil
+}
@@ -1,15 +1,25 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"slices"
 	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
+	"github.com/edd/relaxovisionmonolith/betting"
 	"github.com/edd/relaxovisionmonolith/cache"
 	"github.com/edd/relaxovisionmonolith/embeddings"
+	"github.com/edd/relaxovisionmonolith/features"
 	"github.com/edd/relaxovisionmonolith/footballdata"
 	"github.com/edd/relaxovisionmonolith/predictions"
 	"github.com/edd/relaxovisionmonolith/predictions/providers"
@@ -25,8 +35,15 @@ import (
 var (
 	db                  *sql.DB
 	footballService     *footballdata.Service
+	standingsCalculator *footballdata.StandingsCalculator
+	seasonSimulator     *footballdata.SeasonSimulator
+	bracketSimulator    *footballdata.BracketSimulator
+	calendarBuilder     *footballdata.CalendarBuilder
+	featureStore        *features.Store
+	bettingService      *betting.Service
 	predictionsService  *predictions.Service
 	predictionsHandlers *predictions.Handlers
+	predictionScheduler *predictions.Scheduler
 	embeddingsService   *embeddings.Service
 	embeddingsHandlers  *embeddings.Handlers
 	wsHub               *websocket.Hub
@@ -57,6 +74,10 @@ func runServer() error {
 	wsHandler = websocket.NewHandler(wsHub)
 	go wsHub.Run()
 
+	// Batch prediction progress is pushed to the batch's room
+	predictionsService.OnBatchProgress(broadcastBatchProgress)
+	predictionsService.OnLiveProbability(broadcastLiveProbability)
+
 	// Validate environment variables.
 	port, err := strconv.Atoi(gowebly.Getenv("BACKEND_PORT", "7000"))
 	if err != nil {
@@ -81,25 +102,70 @@ func runServer() error {
 
 	// Handle index page view.
 	server.Get("/", indexViewHandler)
+	server.Get("/accuracy", accuracyViewHandler)
 
 	// Handle API endpoints.
 	server.Get("/api/hello-world", showContentAPIHandler)
 
 	// Football data endpoints
+	server.Get("/api/football/competitions", listCompetitionsHandler)
 	server.Get("/api/football/competitions/:id", getCompetitionHandler)
+	server.Get("/api/football/competitions/:id/standings", getCompetitionStandingsHandler)
+	server.Get("/api/football/teams", listTeamsHandler)
 	server.Get("/api/football/teams/:id", getTeamHandler)
+	server.Put("/api/football/teams/:id/venue", updateTeamVenueHandler)
+	server.Get("/api/football/matches", listMatchesHandler)
 	server.Get("/api/football/matches/:id", getMatchHandler)
+	server.Get("/api/football/matches/:id/statistics", getMatchStatisticsHandler)
+	server.Post("/api/football/statistics/import", importStatisticsHandler)
+	server.Post("/api/football/odds/import", importOddsHandler)
+	server.Get("/api/matches/:id/odds", getMatchOddsHandler)
+
+	// Betting endpoints
+	server.Get("/api/betting/value-bets", getValueBetsHandler)
+	server.Get("/api/betting/backtest", getBacktestHandler)
+
+	// Referee endpoints
+	server.Get("/api/referees/:id", getRefereeHandler)
+	server.Get("/api/matches/:id/referee-profile", getMatchRefereeProfileHandler)
+
+	// Feature store endpoints
+	server.Get("/api/matches/:id/features", getMatchFeaturesHandler)
+	server.Get("/api/matches/:id/probability-timeline", getProbabilityTimelineHandler)
+
+	// Calendar feed endpoints
+	server.Get("/api/calendar/teams/:id.ics", getTeamCalendarHandler)
+	server.Get("/api/calendar/competitions/:id.ics", getCompetitionCalendarHandler)
+
+	// Season simulation endpoints
+	server.Get("/api/competitions/:id/simulation", getSimulationHandler)
+	server.Post("/api/competitions/:id/simulation/what-if", whatIfSimulationHandler)
+	server.Get("/api/competitions/:id/bracket", getBracketHandler)
+	server.Get("/api/competitions/:id/bracket/simulation", getBracketSimulationHandler)
 
 	// Prediction endpoints
 	server.Post("/api/predictions", predictionsHandlers.CreatePrediction)
+	server.Post("/api/predictions/batch", predictionsHandlers.CreateBatch)
+	server.Get("/api/predictions/batch/:id", predictionsHandlers.GetBatch)
+	server.Post("/api/predictions/hypothetical", predictionsHandlers.CreateHypothetical)
+	server.Get("/api/predictions/hypothetical/:id", predictionsHandlers.GetHypothetical)
+	server.Get("/api/predictions/calibration", predictionsHandlers.GetCalibrators)
+	server.Post("/api/predictions/calibration/fit", predictionsHandlers.FitCalibrator)
+	server.Post("/api/predictions/calibration/:id/activate", predictionsHandlers.ActivateCalibrator)
 	server.Get("/api/predictions/:id", predictionsHandlers.GetPrediction)
 	server.Get("/api/predictions/match/:matchId", predictionsHandlers.GetMatchPredictions)
+	server.Get("/api/predictions/match/:matchId/current", predictionsHandlers.GetCurrentPrediction)
+	server.Get("/api/predictions/match/:matchId/diff", predictionsHandlers.DiffRevisions)
 
 	// Prediction accuracy endpoints
 	server.Get("/api/predictions/accuracy", predictionsHandlers.GetAccuracyStats)
 	server.Get("/api/predictions/accuracy/competition/:id", predictionsHandlers.GetCompetitionAccuracy)
 	server.Get("/api/predictions/leaderboard", predictionsHandlers.GetLeaderboard)
 
+	// Prediction scheduler endpoints
+	server.Get("/api/predictions/scheduler/runs", getPredictionJobRunsHandler)
+	server.Post("/api/predictions/scheduler/run", runPredictionJobHandler)
+
 	// Semantic search endpoints
 	server.Post("/api/search/teams", embeddingsHandlers.SearchTeams)
 	server.Get("/api/teams/:id/similar", embeddingsHandlers.FindSimilarTeams)
@@ -113,9 +179,45 @@ func runServer() error {
 	})
 	server.Get("/ws", fiberws.New(wsHandler.HandleConnection))
 
+	// On SIGINT or SIGTERM, running batches are cancelled and record their
+	// outcome before the server and database close
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		slog.Info("Shutting down server")
+		predictionsService.Stop()
+		if err := server.Shutdown(); err != nil {
+			slog.Error("Failed to shut down server", "error", err)
+		}
+	}()
+
+	// Batches of instances stopped without a shutdown are failed once their heartbeat is stale
+	go func() {
+		ticker := time.NewTicker(5 * time.Minute)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				failInterruptedBatches(ctx)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
 	return server.Listen(fmt.Sprintf(":%d", port))
 }
 
+// failInterruptedBatches fails the prediction batches left unfinished by stopped instances
+func failInterruptedBatches(ctx context.Context) {
+	if n, err := predictionsService.FailInterruptedBatches(ctx); err != nil {
+		slog.Error("Failed to fail interrupted prediction batches", "error", err)
+	} else if n > 0 {
+		slog.Warn("Marked prediction batches interrupted by a restart as failed", "batches", n)
+	}
+}
+
 // initServices initializes all application services
 func initServices() {
 	// Get API keys from environment
@@ -169,12 +271,43 @@ func initServices() {
 		cacheImpl = cache.NewMemoryCache(1000)
 	}
 
-	// Initialize football data service with caching and cache manager
-	footballClient := footballdata.NewClient(footballAPIKey)
+	// Initialize football data service with caching and cache manager.
+	// FOOTBALL_DATA_BASE_URL can point at the local fake server (cmd/fakefootballdata).
+	footballBaseURL := gowebly.Getenv("FOOTBALL_DATA_BASE_URL", footballdata.DefaultBaseURL)
+	if footballBaseURL != footballdata.DefaultBaseURL {
+		slog.Info("Using alternative football-data.org endpoint", "url", footballBaseURL)
+	}
+	footballClient := footballdata.NewClientWithBaseURL(footballAPIKey, footballBaseURL)
 	cachedClient := footballdata.NewCachedClient(footballAPIKey, cacheImpl)
 	_ = cachedClient // Available for future use
 	footballRepo := footballdata.NewRepository(db)
 	footballService = footballdata.NewService(footballClient, footballRepo)
+	standingsCalculator = footballdata.NewStandingsCalculator(db)
+
+	// Season simulations draw on stored predictions and are refreshed after each sync
+	seasonSimulator = footballdata.NewSeasonSimulator(db, cacheImpl, predictions.NewStoredProbabilitySource(db))
+	bracketSimulator = footballdata.NewBracketSimulator(db)
+	calendarBuilder = footballdata.NewCalendarBuilder(footballRepo, predictions.NewStoredProbabilitySource(db))
+	footballService.OnMatchesSynced(func(_ context.Context, competitionID int) {
+		go func() {
+			if _, err := seasonSimulator.Refresh(context.Background(), competitionID); err != nil {
+				slog.Warn("Failed to refresh season simulation", "competitionId", competitionID, "error", err)
+			}
+		}()
+	})
+
+	// Features of upcoming matches are recomputed when their inputs change
+	featureStore = features.NewStore(db)
+	footballService.OnMatchesSynced(func(_ context.Context, competitionID int) {
+		go func() {
+			if err := featureStore.RefreshCompetition(context.Background(), competitionID); err != nil {
+				slog.Warn("Failed to refresh match features", "competitionId", competitionID, "error", err)
+			}
+		}()
+	})
+
+	// Value bets and backtests compare stored predictions with bookmaker odds
+	bettingService = betting.NewService(db)
 
 	// Initialize cache manager for 30-day TTL caching
 	cacheManager := footballdata.NewCacheManager(cacheImpl, db)
@@ -217,8 +350,64 @@ func initServices() {
 
 	// Initialize predictions service
 	predictionsService = predictions.NewService(db, openAIKey)
+	predictionsService.SetProviders(llmProviders)
+	// Each agent samples PREDICTION_SAMPLES completions to measure its own uncertainty
+	if samples, err := strconv.Atoi(os.Getenv("PREDICTION_SAMPLES")); err == nil && samples > 1 {
+		predictionsService.SetAgentSamples(samples)
+	}
+	// PREDICTION_DEBATE_ROUNDS switches to the debate pipeline with that many revision rounds
+	if rounds, err := strconv.Atoi(os.Getenv("PREDICTION_DEBATE_ROUNDS")); err == nil && rounds > 0 {
+		predictionsService.SetDebateRounds(rounds)
+	}
+	// With PREDICTION_TOOLS the analysts can query match data through tool calls
+	if tools, err := strconv.ParseBool(os.Getenv("PREDICTION_TOOLS")); err == nil && tools {
+		predictionsService.EnableTools()
+	}
+	// PREDICTION_PIPELINES names a JSON file of agent pipelines per competition
+	if path := os.Getenv("PREDICTION_PIPELINES"); path != "" {
+		pipelines, err := predictions.LoadPipelines(path)
+		if err == nil {
+			err = predictionsService.SetPipelines(pipelines)
+		}
+		if err != nil {
+			slog.Error("Failed to configure prediction pipelines, using the built-in one", "error", err)
+		}
+	}
 	predictionsHandlers = predictions.NewHandlers(predictionsService)
 
+	// Batches run in the background, so a restart leaves them unfinished
+	failInterruptedBatches(context.Background())
+
+	// Matches in play get their probabilities updated from the score after each sync
+	footballService.OnMatchesSynced(func(_ context.Context, competitionID int) {
+		go func() {
+			if err := predictionsService.UpdateLiveMatches(context.Background(), competitionID); err != nil {
+				slog.Warn("Failed to update live probabilities", "competitionId", competitionID, "error", err)
+			}
+		}()
+	})
+
+	// Upcoming matches of the PREDICTION_COMPETITIONS codes are predicted ahead
+	// of kickoff; without them the job only runs when triggered over the API
+	schedulerConfig := predictions.DefaultSchedulerConfig(nil)
+	if codes := os.Getenv("PREDICTION_COMPETITIONS"); codes != "" {
+		for _, code := range strings.Split(codes, ",") {
+			if code = strings.TrimSpace(code); code != "" {
+				schedulerConfig.CompetitionCodes = append(schedulerConfig.CompetitionCodes, code)
+			}
+		}
+	}
+	if window, err := time.ParseDuration(os.Getenv("PREDICTION_WINDOW")); err == nil && window > 0 {
+		schedulerConfig.Window = window
+	}
+	if maxPerDay, err := strconv.Atoi(os.Getenv("PREDICTION_MAX_PER_DAY")); err == nil {
+		schedulerConfig.MaxPerDay = maxPerDay
+	}
+	predictionScheduler = predictions.NewScheduler(predictionsService, schedulerConfig)
+	if len(schedulerConfig.CompetitionCodes) > 0 {
+		go predictionScheduler.Start(context.Background())
+	}
+
 	// Initialize embeddings service
 	embeddingsService = embeddings.NewService(db, llmProviders)
 	embeddingsHandlers = embeddings.NewHandlers(embeddingsService)
@@ -259,6 +448,614 @@ func getCompetitionHandler(c *fiber.Ctx) error {
 	return c.JSON(competition)
 }
 
+// getCompetitionStandingsHandler computes a competition's table from stored
+// results. Query params: asOf (RFC 3339 or YYYY-MM-DD), season, type
+// (TOTAL, HOME, AWAY) and lastN for a form table.
+func getCompetitionStandingsHandler(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid competition ID",
+		})
+	}
+
+	opts := footballdata.StandingsOptions{
+		SeasonID: c.QueryInt("season", 0),
+		Type:     footballdata.TableType(strings.ToUpper(c.Query("type", string(footballdata.TableTotal)))),
+		LastN:    c.QueryInt("lastN", 0),
+	}
+
+	switch opts.Type {
+	case footballdata.TableTotal, footballdata.TableHome, footballdata.TableAway:
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "type must be TOTAL, HOME or AWAY",
+		})
+	}
+
+	if asOf := c.Query("asOf"); asOf != "" {
+		opts.AsOf, _, err = parseTimeParam(asOf)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "asOf must be an RFC 3339 timestamp or YYYY-MM-DD date",
+			})
+		}
+	}
+
+	standings, err := standingsCalculator.ComputeStandings(c.Context(), id, opts)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(standings)
+}
+
+// getSimulationHandler returns the cached season simulation for a competition.
+// refresh=true or a custom iterations count runs a new one.
+func getSimulationHandler(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid competition ID",
+		})
+	}
+
+	var result *footballdata.SimulationResult
+	switch {
+	case c.QueryInt("iterations", 0) > 0:
+		result, err = seasonSimulator.Simulate(c.Context(), id, footballdata.SimulationOptions{
+			Iterations: c.QueryInt("iterations", 0),
+		})
+	case c.QueryBool("refresh", false):
+		result, err = seasonSimulator.Refresh(c.Context(), id)
+	default:
+		result, err = seasonSimulator.GetSimulation(c.Context(), id)
+	}
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(result)
+}
+
+// whatIfSimulationHandler simulates the season with some results fixed by the caller
+func whatIfSimulationHandler(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid competition ID",
+		})
+	}
+
+	var req struct {
+		FixedResults []footballdata.FixedResult `json:"fixedResults"`
+		Iterations   int                        `json:"iterations"`
+		Seed         uint64                     `json:"seed"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	if len(req.FixedResults) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "fixedResults is required",
+		})
+	}
+
+	result, err := seasonSimulator.Simulate(c.Context(), id, footballdata.SimulationOptions{
+		Iterations:   req.Iterations,
+		Seed:         req.Seed,
+		FixedResults: req.FixedResults,
+	})
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(result)
+}
+
+// getBracketHandler returns the knockout bracket built from a cup competition's matches
+func getBracketHandler(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid competition ID",
+		})
+	}
+
+	bracket, err := bracketSimulator.GetBracket(c.Context(), id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(bracket)
+}
+
+// getBracketSimulationHandler returns each team's odds of reaching each knockout round
+func getBracketSimulationHandler(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid competition ID",
+		})
+	}
+
+	result, err := bracketSimulator.Simulate(c.Context(), id, footballdata.SimulationOptions{
+		Iterations: c.QueryInt("iterations", 0),
+	})
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(result)
+}
+
+func getMatchStatisticsHandler(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid match ID",
+		})
+	}
+
+	stats, err := footballService.GetRepository().GetMatchStatistics(c.Context(), id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(stats)
+}
+
+// importStatisticsHandler imports extended match statistics from a JSON or
+// CSV request body (chosen by Content-Type)
+func importStatisticsHandler(c *fiber.Ctx) error {
+	source := c.Query("source", "import")
+	body := bytes.NewReader(c.Body())
+
+	var stats []footballdata.MatchStatistics
+	var err error
+	if strings.HasPrefix(c.Get(fiber.HeaderContentType), "text/csv") {
+		stats, err = footballdata.ParseStatsCSV(body, source)
+	} else {
+		stats, err = footballdata.ParseStatsJSON(body, source)
+	}
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	importer := footballdata.NewStatsImporter(footballService.GetRepository())
+	return c.JSON(importer.Import(c.Context(), stats))
+}
+
+// importOddsHandler imports bookmaker odds snapshots from a JSON or CSV
+// request body (chosen by Content-Type)
+func importOddsHandler(c *fiber.Ctx) error {
+	source := c.Query("source", "import")
+	body := bytes.NewReader(c.Body())
+
+	var snapshots []footballdata.OddsSnapshot
+	var err error
+	if strings.HasPrefix(c.Get(fiber.HeaderContentType), "text/csv") {
+		snapshots, err = footballdata.ParseOddsCSV(body, source)
+	} else {
+		snapshots, err = footballdata.ParseOddsJSON(body, source, time.Now())
+	}
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(footballService.GetRepository().ImportOddsSnapshots(c.Context(), snapshots))
+}
+
+// getMatchOddsHandler returns a match's opening and closing odds per bookmaker
+func getMatchOddsHandler(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid match ID",
+		})
+	}
+
+	history, err := footballService.GetRepository().GetOddsHistory(c.Context(), id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(history)
+}
+
+// getValueBetsHandler lists upcoming matches where a prediction beats the
+// best bookmaker price. Query params: competition, provider (defaults to the
+// ensemble) and minEdge.
+func getValueBetsHandler(c *fiber.Ctx) error {
+	filter := betting.ValueBetFilter{
+		CompetitionID: c.QueryInt("competition", 0),
+		Provider:      c.Query("provider"),
+		MinEdge:       c.QueryFloat("minEdge", betting.DefaultMinEdge),
+	}
+
+	bets, err := bettingService.ValueBets(c.Context(), filter)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(bets)
+}
+
+// getBacktestHandler replays value bets on finished matches with flat,
+// proportional and fractional Kelly staking. Query params: competition,
+// provider, dateFrom and dateTo (dates are inclusive), bankroll, flatStake,
+// proportion, kellyFraction and minEdge.
+func getBacktestHandler(c *fiber.Ctx) error {
+	defaults := betting.DefaultStakingOptions()
+	filter := betting.BacktestFilter{
+		CompetitionID: c.QueryInt("competition", 0),
+		Provider:      c.Query("provider"),
+		Options: betting.StakingOptions{
+			InitialBankroll: c.QueryFloat("bankroll", defaults.InitialBankroll),
+			FlatStake:       c.QueryFloat("flatStake", defaults.FlatStake),
+			Proportion:      c.QueryFloat("proportion", defaults.Proportion),
+			KellyFraction:   c.QueryFloat("kellyFraction", defaults.KellyFraction),
+			MinEdge:         c.QueryFloat("minEdge", defaults.MinEdge),
+		},
+	}
+
+	opts := filter.Options
+	if opts.InitialBankroll <= 0 || opts.FlatStake <= 0 ||
+		opts.Proportion <= 0 || opts.Proportion > 1 || opts.KellyFraction <= 0 || opts.KellyFraction > 1 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "bankroll and flatStake must be positive, proportion and kellyFraction within (0, 1]",
+		})
+	}
+
+	if dateFrom := c.Query("dateFrom"); dateFrom != "" {
+		from, _, err := parseTimeParam(dateFrom)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "dateFrom must be an RFC 3339 timestamp or YYYY-MM-DD date",
+			})
+		}
+		filter.From = from
+	}
+
+	if dateTo := c.Query("dateTo"); dateTo != "" {
+		to, dateOnly, err := parseTimeParam(dateTo)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "dateTo must be an RFC 3339 timestamp or YYYY-MM-DD date",
+			})
+		}
+		if dateOnly {
+			to = to.AddDate(0, 0, 1)
+		}
+		filter.To = to
+	}
+
+	backtest, err := bettingService.Backtest(c.Context(), filter)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(backtest)
+}
+
+// broadcastBatchProgress sends batch progress to clients subscribed to "batch:<id>"
+func broadcastBatchProgress(progress predictions.BatchProgress) {
+	msg, err := websocket.NewMessage(websocket.EventBatchProgress, progress)
+	if err != nil {
+		slog.Warn("Failed to build batch progress message", "batchId", progress.BatchID, "error", err)
+		return
+	}
+	wsHub.BroadcastToRoom("batch:"+progress.BatchID, msg)
+}
+
+// broadcastLiveProbability sends a match's in-play probabilities to clients subscribed to "match:<id>"
+func broadcastLiveProbability(point predictions.LiveProbability) {
+	msg, err := websocket.NewMessage(websocket.EventPredictionUpdate, websocket.PredictionUpdatePayload{
+		PredictionID: point.PredictionID,
+		MatchID:      point.MatchID,
+		Status:       "live",
+		HomeWinProb:  point.HomeWinProb,
+		DrawProb:     point.DrawProb,
+		AwayWinProb:  point.AwayWinProb,
+		Live: &websocket.LiveScorePayload{
+			MatchID:   point.MatchID,
+			HomeScore: point.HomeScore,
+			AwayScore: point.AwayScore,
+			Minute:    point.Minute,
+			Status:    point.Status,
+		},
+	})
+	if err != nil {
+		slog.Warn("Failed to build prediction update message", "matchId", point.MatchID, "error", err)
+		return
+	}
+	wsHub.BroadcastToRoom(fmt.Sprintf("match:%d", point.MatchID), msg)
+}
+
+// getPredictionJobRunsHandler returns the prediction scheduler's recent runs
+func getPredictionJobRunsHandler(c *fiber.Ctx) error {
+	limit := c.QueryInt("limit", 20)
+	if limit <= 0 || limit > 200 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "limit must be between 1 and 200",
+		})
+	}
+
+	runs, err := predictionScheduler.Runs(c.Context(), limit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(runs)
+}
+
+// runPredictionJobHandler runs the prediction scheduler job now
+func runPredictionJobHandler(c *fiber.Ctx) error {
+	run, err := predictionScheduler.RunOnce(c.Context())
+	if errors.Is(err, predictions.ErrJobRunning) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err != nil && run == nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(run)
+}
+
+// getRefereeHandler returns a referee's profile over all finished matches
+func getRefereeHandler(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid referee ID",
+		})
+	}
+
+	profile, err := footballService.GetRepository().GetRefereeProfile(c.Context(), id, time.Now())
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(profile)
+}
+
+// getMatchRefereeProfileHandler returns the profile of a match's referee as it stood before kickoff
+func getMatchRefereeProfileHandler(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid match ID",
+		})
+	}
+
+	profile, err := footballService.GetRepository().GetMatchRefereeProfile(c.Context(), id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(profile)
+}
+
+// getMatchFeaturesHandler returns a match's stored point-in-time features for debugging.
+// recompute=true recomputes them even when the inputs are unchanged.
+func getMatchFeaturesHandler(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid match ID",
+		})
+	}
+
+	var matchFeatures *features.MatchFeatures
+	if c.QueryBool("recompute") {
+		matchFeatures, err = featureStore.Compute(c.Context(), id)
+	} else {
+		matchFeatures, err = featureStore.Get(c.Context(), id)
+	}
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"features": matchFeatures,
+		"vector":   matchFeatures.Vector(),
+	})
+}
+
+// getProbabilityTimelineHandler returns a match's pre-match and in-play probabilities
+func getProbabilityTimelineHandler(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid match ID",
+		})
+	}
+
+	timeline, err := predictionsService.GetProbabilityTimeline(c.Context(), id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(timeline)
+}
+
+// parseTimeParam parses an RFC 3339 timestamp or a YYYY-MM-DD date (reported by dateOnly)
+func parseTimeParam(value string) (t time.Time, dateOnly bool, err error) {
+	if t, err = time.Parse(time.RFC3339, value); err == nil {
+		return t, false, nil
+	}
+	t, err = time.Parse(time.DateOnly, value)
+	return t, err == nil, err
+}
+
+// matchWithPrediction is a listed match with its latest prediction embedded
+type matchWithPrediction struct {
+	footballdata.Match
+	Prediction *predictions.PredictionResult `json:"prediction"`
+}
+
+// listMatchesHandler lists stored matches. Query params: competition, team,
+// status (comma separated), matchday, season, dateFrom and dateTo (dates are
+// inclusive), sort (utcDate or -utcDate), limit, cursor and include=prediction.
+func listMatchesHandler(c *fiber.Ctx) error {
+	filter := footballdata.MatchFilter{
+		CompetitionID: c.QueryInt("competition", 0),
+		TeamID:        c.QueryInt("team", 0),
+		Matchday:      c.QueryInt("matchday", 0),
+		SeasonID:      c.QueryInt("season", 0),
+		Sort:          c.Query("sort", footballdata.SortDateAsc),
+		Limit:         c.QueryInt("limit", 0),
+		Cursor:        c.Query("cursor"),
+	}
+
+	if filter.Sort != footballdata.SortDateAsc && filter.Sort != footballdata.SortDateDesc {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "sort must be utcDate or -utcDate",
+		})
+	}
+
+	if status := c.Query("status"); status != "" {
+		for _, s := range strings.Split(status, ",") {
+			filter.Status = append(filter.Status, strings.ToUpper(strings.TrimSpace(s)))
+		}
+	}
+
+	if dateFrom := c.Query("dateFrom"); dateFrom != "" {
+		from, _, err := parseTimeParam(dateFrom)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "dateFrom must be an RFC 3339 timestamp or YYYY-MM-DD date",
+			})
+		}
+		filter.From = from
+	}
+
+	if dateTo := c.Query("dateTo"); dateTo != "" {
+		to, dateOnly, err := parseTimeParam(dateTo)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "dateTo must be an RFC 3339 timestamp or YYYY-MM-DD date",
+			})
+		}
+		if dateOnly {
+			to = to.AddDate(0, 0, 1)
+		}
+		filter.To = to
+	}
+
+	page, err := footballService.ListMatches(c.Context(), filter)
+	if err != nil {
+		return listErrorResponse(c, err)
+	}
+
+	if !slices.Contains(strings.Split(c.Query("include"), ","), "prediction") {
+		return c.JSON(page)
+	}
+
+	matchIDs := make([]int, len(page.Items))
+	for i, match := range page.Items {
+		matchIDs[i] = match.ID
+	}
+
+	latest, err := predictionsService.GetLatestPredictions(c.Context(), matchIDs)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	withPredictions := footballdata.Page[matchWithPrediction]{
+		Items:      make([]matchWithPrediction, len(page.Items)),
+		NextCursor: page.NextCursor,
+	}
+	for i, match := range page.Items {
+		withPredictions.Items[i] = matchWithPrediction{Match: match, Prediction: latest[match.ID]}
+	}
+
+	return c.JSON(withPredictions)
+}
+
+// listTeamsHandler lists stored teams by name. Query params: competition,
+// name (prefix of the name or short name), limit and cursor.
+func listTeamsHandler(c *fiber.Ctx) error {
+	page, err := footballService.ListTeams(c.Context(), footballdata.TeamFilter{
+		CompetitionID: c.QueryInt("competition", 0),
+		NamePrefix:    c.Query("name"),
+		Limit:         c.QueryInt("limit", 0),
+		Cursor:        c.Query("cursor"),
+	})
+	if err != nil {
+		return listErrorResponse(c, err)
+	}
+
+	return c.JSON(page)
+}
+
+// listCompetitionsHandler lists stored competitions by name. Query params:
+// type (LEAGUE or CUP), area (area code), limit and cursor.
+func listCompetitionsHandler(c *fiber.Ctx) error {
+	page, err := footballService.ListCompetitions(c.Context(), footballdata.CompetitionFilter{
+		Type:     strings.ToUpper(c.Query("type")),
+		AreaCode: strings.ToUpper(c.Query("area")),
+		Limit:    c.QueryInt("limit", 0),
+		Cursor:   c.Query("cursor"),
+	})
+	if err != nil {
+		return listErrorResponse(c, err)
+	}
+
+	return c.JSON(page)
+}
+
+// listErrorResponse reports a list query error, as a bad request for invalid cursors
+func listErrorResponse(c *fiber.Ctx, err error) error {
+	status := fiber.StatusInternalServerError
+	if errors.Is(err, footballdata.ErrInvalidCursor) {
+		status = fiber.StatusBadRequest
+	}
+	return c.Status(status).JSON(fiber.Map{
+		"error": err.Error(),
+	})
+}
+
 func getTeamHandler(c *fiber.Ctx) error {
 	idStr := c.Params("id")
 	id, err := strconv.Atoi(idStr)
@@ -278,6 +1075,81 @@ func getTeamHandler(c *fiber.Ctx) error {
 	return c.JSON(team)
 }
 
+// updateTeamVenueHandler sets a team's venue coordinates, used for travel distances
+func updateTeamVenueHandler(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid team ID",
+		})
+	}
+
+	var coords footballdata.Coordinates
+	if err := c.BodyParser(&coords); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	if coords.Latitude < -90 || coords.Latitude > 90 || coords.Longitude < -180 || coords.Longitude > 180 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "latitude must be within ±90 and longitude within ±180",
+		})
+	}
+
+	if err := footballService.GetRepository().UpdateTeamVenueCoordinates(c.Context(), id, coords); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// getTeamCalendarHandler serves a team's fixtures as an iCalendar feed
+func getTeamCalendarHandler(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid team ID",
+		})
+	}
+
+	var buf bytes.Buffer
+	if err := calendarBuilder.TeamCalendar(c.Context(), &buf, id, time.Now()); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return sendCalendar(c, fmt.Sprintf("team-%d.ics", id), buf.Bytes())
+}
+
+// getCompetitionCalendarHandler serves a competition's fixtures as an iCalendar feed
+func getCompetitionCalendarHandler(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid competition ID",
+		})
+	}
+
+	var buf bytes.Buffer
+	if err := calendarBuilder.CompetitionCalendar(c.Context(), &buf, id, time.Now()); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return sendCalendar(c, fmt.Sprintf("competition-%d.ics", id), buf.Bytes())
+}
+
+// sendCalendar sends an iCalendar body
+func sendCalendar(c *fiber.Ctx, filename string, body []byte) error {
+	c.Set(fiber.HeaderContentType, "text/calendar; charset=utf-8")
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`inline; filename="%s"`, filename))
+	return c.Send(body)
+}
+
 func getMatchHandler(c *fiber.Ctx) error {
 	idStr := c.Params("id")
 	id, err := strconv.Atoi(idStr)
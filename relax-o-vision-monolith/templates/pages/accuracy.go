@@ -0,0 +1,84 @@
+package pages
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/edd/relaxovisionmonolith/betting"
+	"github.com/edd/relaxovisionmonolith/predictions"
+)
+
+// Size of the bankroll curve charts
+const (
+	curveWidth  = 600
+	curveHeight = 160
+)
+
+// percent formats a share as a percentage
+func percent(share float64) string {
+	return fmt.Sprintf("%.1f%%", share*100)
+}
+
+// decimal formats a number with the given precision
+func decimal(value float64, precision int) string {
+	return fmt.Sprintf("%.*f", precision, value)
+}
+
+// sortedCompetitions returns competition accuracy ordered by name
+func sortedCompetitions(byCompetition map[string]*predictions.CompetitionAcc) []*predictions.CompetitionAcc {
+	return slices.SortedFunc(maps.Values(byCompetition), func(a, b *predictions.CompetitionAcc) int {
+		return cmp.Compare(a.CompetitionName, b.CompetitionName)
+	})
+}
+
+// sortedGoalMarkets returns goal market accuracy ordered by market
+func sortedGoalMarkets(byMarket map[string]*predictions.GoalMarketAcc) []*predictions.GoalMarketAcc {
+	return slices.SortedFunc(maps.Values(byMarket), func(a, b *predictions.GoalMarketAcc) int {
+		return cmp.Compare(a.Market, b.Market)
+	})
+}
+
+// sortedKeys returns the keys of backtest segments in order
+func sortedKeys(segments map[string]betting.StrategyResults) []string {
+	return slices.Sorted(maps.Keys(segments))
+}
+
+// curvePoints scales a bankroll curve to an SVG polyline, starting from the
+// initial bankroll
+func curvePoints(result *betting.SimulationResult, initial float64) string {
+	values := make([]float64, 0, len(result.Curve)+1)
+	values = append(values, initial)
+	for _, point := range result.Curve {
+		values = append(values, point.Bankroll)
+	}
+
+	low, high := slices.Min(values), slices.Max(values)
+	if high == low {
+		high = low + 1
+	}
+
+	points := make([]string, len(values))
+	for i, v := range values {
+		x := 0.0
+		if len(values) > 1 {
+			x = float64(i) / float64(len(values)-1) * curveWidth
+		}
+		y := (high - v) / (high - low) * curveHeight
+		points[i] = fmt.Sprintf("%.1f,%.1f", x, y)
+	}
+	return strings.Join(points, " ")
+}
+
+// strategyColor picks a line colour per staking strategy
+func strategyColor(strategy betting.Strategy) string {
+	switch strategy {
+	case betting.Flat:
+		return "#02BF87"
+	case betting.Proportional:
+		return "#3B82F6"
+	}
+	return "#F59E0B"
+}
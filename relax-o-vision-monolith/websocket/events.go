@@ -13,6 +13,7 @@ const (
 	EventPredictionUpdate WSEventType = "prediction_update"
 	EventLiveScore        WSEventType = "live_score"
 	EventNewPrediction    WSEventType = "new_prediction"
+	EventBatchProgress    WSEventType = "batch_progress"
 	EventError            WSEventType = "error"
 	EventSubscribed       WSEventType = "subscribed"
 	EventUnsubscribed     WSEventType = "unsubscribed"
@@ -51,6 +52,8 @@ type PredictionUpdatePayload struct {
 	DrawProb     float64 `json:"drawProb"`
 	AwayWinProb  float64 `json:"awayWinProb"`
 	Confidence   float64 `json:"confidence"`
+	// Live is the score the probabilities were updated for during a match
+	Live *LiveScorePayload `json:"live,omitempty"`
 }
 
 // LiveScorePayload represents live score update data